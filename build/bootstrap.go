@@ -13,6 +13,8 @@ import (
 	"go-synth/environment"
 	"go-synth/log"
 	"go-synth/pkg"
+
+	"github.com/google/uuid"
 )
 
 // bootstrapPkg builds ports-mgmt/pkg before starting the worker pool.
@@ -141,13 +143,17 @@ func bootstrapPkg(ctx context.Context, packages []*pkg.Package, cfg *config.Conf
 		cleanupEnv()
 	}()
 
+	jobs, _ := makeJobs(cfg, pkgPkg, 0, 0)
 	worker := &Worker{
-		ID:     bootstrapWorkerID,
-		Env:    env,
-		Status: "bootstrap-pkg",
+		ID:       bootstrapWorkerID,
+		Env:      env,
+		Status:   "bootstrap-pkg",
+		Jobs:     jobs,
+		Limits:   cfg.LimitsFor(pkgPkg.PortDir, pkgPkg.Flavor),
+		Timeouts: cfg.TimeoutsFor(pkgPkg.PortDir, pkgPkg.Flavor),
 	}
 
-	pkgLogger := log.NewPackageLogger(cfg, pkgPkg.PortDir)
+	pkgLogger := log.NewPackageLogger(cfg, pkgPkg.PortDir, uuid.New().String())
 	defer pkgLogger.Close()
 
 	registry.AddFlags(pkgPkg, pkg.PkgFRunning)
@@ -155,29 +161,15 @@ func bootstrapPkg(ctx context.Context, packages []*pkg.Package, cfg *config.Conf
 	startTime := time.Now()
 	recordPackage(builddb.RunStatusRunning, startTime, time.Time{}, "")
 
-	phases := []string{
-		"install-pkgs",
-		"check-sanity",
-		"fetch-depends",
-		"fetch",
-		"checksum",
-		"extract-depends",
-		"extract",
-		"patch-depends",
-		"patch",
-		"build-depends",
-		"lib-depends",
-		"configure",
-		"build",
-		"run-depends",
-		"stage",
-		"check-plist",
-		"package",
-	}
+	phases := cfg.Pipeline()
 
 	var buildErr error
 	var lastPhase string
 	for _, phase := range phases {
+		if phase == "test" {
+			// ports-mgmt/pkg is bootstrapped, not tested
+			continue
+		}
 		lastPhase = phase
 		registry.SetLastPhase(pkgPkg, phase)
 		logger.Info("  [bootstrap] %s: %s", pkgPkg.PortDir, phase)
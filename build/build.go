@@ -69,7 +69,9 @@ import (
 	"go-synth/builddb"
 	"go-synth/config"
 	"go-synth/environment"
+	"go-synth/hooks"
 	"go-synth/log"
+	"go-synth/notify"
 	"go-synth/pkg"
 	"go-synth/stats"
 
@@ -86,6 +88,10 @@ type BuildStats struct {
 	SkippedPre int // Skipped because already built (CRC match, pre-queue)
 	Ignored    int
 	Duration   time.Duration
+
+	// Test mode only; test results do not affect Success or Failed
+	TestsPassed int
+	TestsFailed int
 }
 
 // Worker represents a build worker
@@ -95,6 +101,10 @@ type Worker struct {
 	Current   *pkg.Package
 	Status    string
 	StartTime time.Time
+	Jobs      int             // MAKE_JOBS_NUMBER for the current phase, 0 leaves it to the ports
+	PeakRSS   int64           // Largest RSS of a single process during the current build, in bytes
+	Limits    config.Limits   // Nice value and rlimits for the current build's commands
+	Timeouts  config.Timeouts // Timeouts for the current build
 	mu        sync.Mutex
 }
 
@@ -120,6 +130,9 @@ type BuildContext struct {
 	wg             sync.WaitGroup
 	statsCollector *stats.StatsCollector  // Real-time stats collection and monitoring
 	throttler      *stats.WorkerThrottler // Dynamic worker throttling based on system load/swap
+	memory         *memoryBudget          // Memory_target admission, nil when unset
+	metrics        *stats.MetricsExporter // Metrics_listen endpoint, nil when unset
+	notifier       *notify.Notifier       // Port failure notifications, nil in tests
 
 	runID    string
 	outputMu sync.Mutex
@@ -199,6 +212,7 @@ func DoBuild(packages []*pkg.Package, cfg *config.Config, logger *log.Logger, bu
 		queue:     make(chan *pkg.Package, 100),
 		startTime: time.Now(),
 		runID:     runID,
+		notifier:  notify.New(cfg, buildDB, runID, logger),
 	}
 
 	// Initialize UI based on configuration and TTY detection
@@ -234,6 +248,19 @@ func DoBuild(packages []*pkg.Package, cfg *config.Config, logger *log.Logger, bu
 	// Register UI as stats consumer
 	ctx.statsCollector.AddConsumer(ctx.ui)
 
+	// Serve metrics for scrapers when Metrics_listen is set; the build
+	// goes on without them if the address is taken
+	if cfg.MetricsListen != "" {
+		ctx.metrics = stats.NewMetricsExporter(cfg.Database.Path)
+		if addr, err := ctx.metrics.Listen(cfg.MetricsListen); err != nil {
+			logger.Warn("Not serving metrics: %v", err)
+			ctx.metrics = nil
+		} else {
+			logger.Info("Serving metrics on http://%s/metrics", addr)
+			ctx.statsCollector.AddConsumer(ctx.metrics)
+		}
+	}
+
 	// Set up interrupt handler for ncurses UI (Ctrl+C handling)
 	// This will be called when the cleanup function is created below
 	var setupInterruptHandler func(cleanup func())
@@ -309,6 +336,12 @@ func DoBuild(packages []*pkg.Package, cfg *config.Config, logger *log.Logger, bu
 			}
 		}
 
+		if ctx.metrics != nil {
+			if err := ctx.metrics.Close(); err != nil {
+				logger.Warn("Failed to stop metrics listener: %v", err)
+			}
+		}
+
 		// Stop UI before cleaning up environments
 		if ctx.ui != nil {
 			ctx.ui.Stop()
@@ -416,6 +449,17 @@ func DoBuild(packages []*pkg.Package, cfg *config.Config, logger *log.Logger, bu
 		}
 	}
 
+	// Hold builds back when their memory estimates would exceed the target
+	if cfg.Memory.Target > 0 {
+		peaks, err := buildDB.PeakRSSByPort()
+		if err != nil {
+			logger.Warn("Failed to load memory history, using Memory_default_estimate for all ports: %v", err)
+		}
+		ctx.memory = newMemoryBudget(cfg, peaks)
+		logger.Info("Memory target %s (default estimate %s, %d ports with recorded peaks)",
+			config.FormatSize(cfg.Memory.Target), config.FormatSize(ctx.memory.fallback), len(peaks))
+	}
+
 	// Create workers
 	numWorkers := cfg.MaxWorkers
 	if cfg.SlowStart > 0 && cfg.SlowStart < numWorkers {
@@ -488,6 +532,7 @@ func DoBuild(packages []*pkg.Package, cfg *config.Config, logger *log.Logger, bu
 				now := time.Now()
 				ctx.recordRunPackage(p, builddb.RunStatusSkipped, -1, now, now, "")
 				logger.Skipped(p.PortDir)
+				ctx.portHook(hooks.PortSkip, p, builddb.RunStatusSkipped, "")
 				// Record skipped (note: BuildSkipped does NOT count toward rate)
 				if ctx.statsCollector != nil {
 					ctx.statsCollector.RecordCompletion(stats.BuildSkipped)
@@ -503,9 +548,18 @@ func DoBuild(packages []*pkg.Package, cfg *config.Config, logger *log.Logger, bu
 	// Wait for all workers to finish
 	ctx.wg.Wait()
 
+	// Deliver the port-failure notifications before the run-end one
+	if ctx.notifier != nil {
+		ctx.notifier.Drain()
+	}
+
 	// Calculate duration
 	ctx.stats.Duration = time.Since(ctx.startTime)
 
+	if cfg.TestMode {
+		logger.WriteTestSummary(ctx.stats.TestsPassed, ctx.stats.TestsFailed)
+	}
+
 	// Stop UI after all workers finish
 	if ctx.ui != nil {
 		ctx.ui.Stop()
@@ -544,6 +598,26 @@ func (ctx *BuildContext) workerLoop(worker *Worker) {
 				return
 			}
 
+			// Wait until the build's memory estimate fits the target
+			var memNeed int64
+			if ctx.memory != nil {
+				jobs, _ := makeJobs(ctx.cfg, p, ctx.remainingBuilds(), len(ctx.workers))
+				memNeed = ctx.memory.estimate(p, jobs)
+				worker.mu.Lock()
+				worker.Current = p
+				worker.Status = "waiting for memory"
+				worker.mu.Unlock()
+
+				admitted := ctx.memory.acquire(ctx.ctx, memNeed, func(inUse int64) {
+					ctx.logWorkerEvent(worker.ID, fmt.Sprintf("waiting for memory: %s needs ~%s, %s of %s in use",
+						p.PortDir, config.FormatSize(memNeed), config.FormatSize(inUse), config.FormatSize(ctx.memory.target)))
+				})
+				if !admitted {
+					ctx.logger.Info("Worker %d: stopping due to context cancellation", worker.ID)
+					return
+				}
+			}
+
 			worker.mu.Lock()
 			worker.Current = p
 			worker.Status = "building"
@@ -558,16 +632,29 @@ func (ctx *BuildContext) workerLoop(worker *Worker) {
 			ctx.statsMu.Unlock()
 			if ctx.statsCollector != nil {
 				ctx.statsCollector.UpdateWorkerCount(activeCount)
+				ctx.statsCollector.StartWorkerSlot(worker.ID, p.PortDir, p.Version, log.PackageLogPath(ctx.cfg, p.PortDir), startTime)
+			}
+			memoryHeavy := ctx.memory != nil && ctx.memory.heavy(memNeed, ctx.cfg.MaxWorkers)
+			if memoryHeavy && ctx.statsCollector != nil {
+				ctx.statsCollector.SetWorkerMemoryHeavy(worker.ID, memNeed)
 			}
 
 			// Mark as running
 			ctx.registry.AddFlags(p, pkg.PkgFRunning)
 
 			ctx.recordRunPackage(p, builddb.RunStatusRunning, worker.ID, startTime, time.Time{}, "")
-			ctx.logWorkerEvent(worker.ID, fmt.Sprintf("start build: %s (deps: %d)", p.PortDir, len(p.IDependOn)))
+			startMsg := fmt.Sprintf("start build: %s (deps: %d)", p.PortDir, len(p.IDependOn))
+			if memoryHeavy {
+				startMsg += fmt.Sprintf(" [memory-heavy ~%s]", config.FormatSize(memNeed))
+			}
+			ctx.logWorkerEvent(worker.ID, startMsg)
+			ctx.portHook(hooks.PortStart, p, builddb.RunStatusRunning, "")
 
 			// Build the package (context will propagate to env.Execute())
-			success := ctx.buildPackage(worker, p)
+			success, timeout := ctx.buildPackage(worker, p)
+			if ctx.memory != nil {
+				ctx.memory.release(memNeed)
+			}
 
 			// Update stats
 			ctx.statsMu.Lock()
@@ -584,7 +671,11 @@ func (ctx *BuildContext) workerLoop(worker *Worker) {
 				ctx.stats.Failed++
 				ctx.registry.AddFlags(p, pkg.PkgFFailed)
 				ctx.registry.ClearFlags(p, pkg.PkgFRunning)
-				ctx.logger.Failed(p.PortDir, ctx.registry.GetLastPhase(p))
+				if timeout != "" {
+					ctx.logger.TimedOut(p.PortDir, ctx.registry.GetLastPhase(p), timeout)
+				} else {
+					ctx.logger.Failed(p.PortDir, ctx.registry.GetLastPhase(p))
+				}
 				// Record completion in StatsCollector (rate/impulse tracking)
 				if ctx.statsCollector != nil {
 					ctx.statsCollector.RecordCompletion(stats.BuildFailed)
@@ -606,15 +697,39 @@ func (ctx *BuildContext) workerLoop(worker *Worker) {
 			ctx.statsMu.Unlock()
 			if ctx.statsCollector != nil {
 				ctx.statsCollector.UpdateWorkerCount(activeCount)
+				ctx.statsCollector.ClearWorkerSlot(worker.ID)
+			}
+
+			if ctx.metrics != nil {
+				status := builddb.RunStatusSuccess
+				if timeout != "" {
+					status = builddb.RunStatusTimeout
+				} else if !success {
+					status = builddb.RunStatusFailed
+				}
+				ctx.metrics.ObservePortBuild(status, duration)
 			}
 
 			if success {
 				ctx.recordRunPackage(p, builddb.RunStatusSuccess, worker.ID, startTime, endTime, "")
 				ctx.logWorkerEvent(worker.ID, fmt.Sprintf("build success: %s (%s)", p.PortDir, formatDuration(duration)))
+				ctx.portHook(hooks.PortSuccess, p, builddb.RunStatusSuccess, "")
+			} else if timeout != "" {
+				lastPhase := ctx.registry.GetLastPhase(p)
+				ctx.recordRunPackage(p, builddb.RunStatusTimeout, worker.ID, startTime, endTime, lastPhase)
+				ctx.logWorkerEvent(worker.ID, fmt.Sprintf("build timed out: %s (phase: %s, %s)", p.PortDir, lastPhase, timeout))
+				ctx.portHook(hooks.PortFailure, p, builddb.RunStatusTimeout, lastPhase)
+				if ctx.notifier != nil {
+					ctx.notifier.QueuePortFailure(p.PortDir, p.Version, builddb.RunStatusTimeout, lastPhase)
+				}
 			} else {
 				lastPhase := ctx.registry.GetLastPhase(p)
 				ctx.recordRunPackage(p, builddb.RunStatusFailed, worker.ID, startTime, endTime, lastPhase)
 				ctx.logWorkerEvent(worker.ID, fmt.Sprintf("build failed: %s (phase: %s)", p.PortDir, lastPhase))
+				ctx.portHook(hooks.PortFailure, p, builddb.RunStatusFailed, lastPhase)
+				if ctx.notifier != nil {
+					ctx.notifier.QueuePortFailure(p.PortDir, p.Version, builddb.RunStatusFailed, lastPhase)
+				}
 			}
 
 			// Print progress
@@ -628,20 +743,21 @@ func (ctx *BuildContext) workerLoop(worker *Worker) {
 // Lifecycle:
 //  1. Generate build UUID
 //  2. Create build record (status="running")
-//  3. Execute all build phases sequentially
-//  4. Update record status to "success" or "failed"
+//  3. Execute the pipeline's phases sequentially, under the port's timeouts
+//  4. Update record status to "success", "failed" or "timeout"
 //  5. On success: update CRC and package index
 //
+// When a timeout stops the build, the returned string says which one.
 // Database operations are fail-safe - errors are logged but don't fail the build.
-func (ctx *BuildContext) buildPackage(worker *Worker, p *pkg.Package) bool {
-	pkgLogger := log.NewPackageLogger(ctx.cfg, p.PortDir)
+func (ctx *BuildContext) buildPackage(worker *Worker, p *pkg.Package) (bool, string) {
+	// Generate UUID for this build attempt
+	p.BuildUUID = uuid.New().String()
+
+	pkgLogger := log.NewPackageLogger(ctx.cfg, p.PortDir, p.BuildUUID)
 	defer pkgLogger.Close()
 
 	pkgLogger.WriteHeader()
 
-	// Generate UUID for this build attempt
-	p.BuildUUID = uuid.New().String()
-
 	// Create context logger with UUID and worker info
 	ctxLogger := ctx.logger.WithContext(log.LogContext{
 		BuildID:  p.BuildUUID,
@@ -651,6 +767,22 @@ func (ctx *BuildContext) buildPackage(worker *Worker, p *pkg.Package) bool {
 
 	ctxLogger.Info("Starting build")
 
+	jobs, reason := makeJobs(ctx.cfg, p, ctx.remainingBuilds(), len(ctx.workers))
+	limits := ctx.cfg.LimitsFor(p.PortDir, p.Flavor)
+	timeouts := ctx.cfg.TimeoutsFor(p.PortDir, p.Flavor)
+	worker.mu.Lock()
+	worker.Jobs = jobs
+	worker.Limits = limits
+	worker.Timeouts = timeouts
+	worker.PeakRSS = 0
+	worker.mu.Unlock()
+	pkgLogger.WriteString(fmt.Sprintf("Make jobs: %d (%s)\n", jobs, reason))
+	pkgLogger.WriteString(fmt.Sprintf("Limits: %s\n", limits))
+	if t := timeouts.String(); t != "" {
+		pkgLogger.WriteString(fmt.Sprintf("Timeouts: %s\n", t))
+	}
+	ctxLogger.Info("Make jobs: %d (%s)", jobs, reason)
+
 	startTime := time.Now()
 
 	buildRecord := &builddb.BuildRecord{
@@ -664,48 +796,60 @@ func (ctx *BuildContext) buildPackage(worker *Worker, p *pkg.Package) bool {
 		ctxLogger.Warn("Failed to save build record: %v", err)
 	}
 
-	// Execute all build phases
-	phases := []string{
-		"install-pkgs",
-		"check-sanity",
-		"fetch-depends",
-		"fetch",
-		"checksum",
-		"extract-depends",
-		"extract",
-		"patch-depends",
-		"patch",
-		"build-depends",
-		"lib-depends",
-		"configure",
-		"build",
-		"run-depends",
-		"stage",
-		"check-plist",
-		"package",
-	}
+	// Execute the phases of the configured pipeline
+	phases := ctx.cfg.Pipeline()
+
+	// Stop the build when it runs too long or its output stalls
+	phaseCtx, stopWatch := watchBuild(ctx.ctx, timeouts, pkgLogger)
+	defer stopWatch()
 
 	for _, phase := range phases {
+		if phase == "test" && !ctx.registry.HasFlags(p, pkg.PkgFManualSel) {
+			// Only the ports named on the command line are tested
+			continue
+		}
 		ctx.registry.SetLastPhase(p, phase)
+		if ctx.statsCollector != nil {
+			ctx.statsCollector.SetWorkerPhase(worker.ID, phase)
+		}
 		pkgLogger.WritePhase(phase)
+		ctxLogger.SetPhase(phase)
 		ctxLogger.Info("Starting phase: %s", phase)
+		ctx.updateJobs(worker, p, pkgLogger, ctxLogger)
+
+		phaseStart := time.Now()
+		if phase == "test" {
+			ctx.runTests(phaseCtx, worker, p, timeouts, pkgLogger, ctxLogger)
+			ctx.observePhase(phase, phaseStart)
+			continue
+		}
 
-		if err := executePhase(ctx.ctx, worker, p, phase, ctx.cfg, ctx.registry, pkgLogger); err != nil {
+		err := executePhase(phaseCtx, worker, p, phase, ctx.cfg, ctx.registry, pkgLogger)
+		ctx.observePhase(phase, phaseStart)
+		if err != nil {
 			duration := time.Since(startTime)
+			status := "failed"
+			timeout := timeoutReason(phaseCtx, err, phase, timeouts)
+			if timeout != "" {
+				status = builddb.RunStatusTimeout
+				err = fmt.Errorf("timed out: %s", timeout)
+			}
 			pkgLogger.WriteFailure(duration, fmt.Sprintf("Phase %s failed: %v", phase, err))
 			ctxLogger.Failed(phase, fmt.Sprintf("%v", err))
+			ctx.recordPeakRSS(worker, p, pkgLogger, ctxLogger)
 
-			if err := ctx.buildDB.UpdateRecordStatus(p.BuildUUID, "failed", time.Now()); err != nil {
+			if err := ctx.buildDB.UpdateRecordStatus(p.BuildUUID, status, time.Now()); err != nil {
 				ctxLogger.Warn("Failed to update build record status: %v", err)
 			}
 
-			return false
+			return false, timeout
 		}
 	}
 
 	duration := time.Since(startTime)
 	pkgLogger.WriteSuccess(duration)
 	ctxLogger.Success(fmt.Sprintf("Build completed in %v", duration))
+	ctx.recordPeakRSS(worker, p, pkgLogger, ctxLogger)
 
 	// Update build record status to success
 	if err := ctx.buildDB.UpdateRecordStatus(p.BuildUUID, "success", time.Now()); err != nil {
@@ -728,7 +872,60 @@ func (ctx *BuildContext) buildPackage(worker *Worker, p *pkg.Package) bool {
 		ctxLogger.Warn("Failed to update package index: %v", err)
 	}
 
-	return true
+	return true, ""
+}
+
+// runTests runs the test phase for p. The outcome is recorded in the build
+// record, the test summary log and the build stats, but a test failure does
+// not fail the build.
+func (ctx *BuildContext) runTests(phaseCtx context.Context, worker *Worker, p *pkg.Package, timeouts config.Timeouts, pkgLogger *log.PackageLogger, ctxLogger *log.ContextLogger) {
+	start := time.Now()
+	err := executePhase(phaseCtx, worker, p, "test", ctx.cfg, ctx.registry, pkgLogger)
+
+	status := builddb.TestStatusPassed
+	var detail string
+	if err != nil {
+		status = builddb.TestStatusFailed
+		detail = err.Error()
+		if timeout := timeoutReason(phaseCtx, err, "test", timeouts); timeout != "" {
+			detail = "timed out: " + timeout
+		}
+		pkgLogger.WriteWarning(fmt.Sprintf("Tests failed: %s", detail))
+		ctxLogger.Warn("Tests failed: %s", detail)
+		ctx.logWorkerEvent(worker.ID, fmt.Sprintf("tests failed: %s", p.PortDir))
+	} else {
+		ctxLogger.Info("Tests passed")
+	}
+	ctx.logger.TestResult(p.PortDir, err == nil, time.Since(start), detail)
+
+	ctx.statsMu.Lock()
+	if err == nil {
+		ctx.stats.TestsPassed++
+	} else {
+		ctx.stats.TestsFailed++
+	}
+	ctx.statsMu.Unlock()
+
+	if err := ctx.buildDB.SetTestStatus(p.BuildUUID, status); err != nil {
+		ctxLogger.Warn("Failed to record test status: %v", err)
+	}
+}
+
+// recordPeakRSS stores the worker's per-process memory peak for the build
+// of p in the build record, where later runs scale it by their make jobs to
+// estimate the port's memory footprint.
+func (ctx *BuildContext) recordPeakRSS(worker *Worker, p *pkg.Package, pkgLogger *log.PackageLogger, ctxLogger *log.ContextLogger) {
+	worker.mu.Lock()
+	peak := worker.PeakRSS
+	worker.mu.Unlock()
+	if peak <= 0 {
+		return
+	}
+
+	pkgLogger.WriteString(fmt.Sprintf("Peak process memory: %s\n", config.FormatSize(peak)))
+	if err := ctx.buildDB.SetPeakRSS(p.BuildUUID, peak); err != nil {
+		ctxLogger.Warn("Failed to record peak memory: %v", err)
+	}
 }
 
 // waitForDependencies waits for all dependencies to complete
@@ -812,6 +1009,34 @@ func (ctx *BuildContext) recordRunPackage(p *pkg.Package, status string, workerI
 	}
 }
 
+// observePhase records the duration of a phase started at start in the
+// metrics, if served.
+func (ctx *BuildContext) observePhase(phase string, start time.Time) {
+	if ctx.metrics != nil {
+		ctx.metrics.ObservePhase(phase, time.Since(start))
+	}
+}
+
+// portHook runs the hook for a port event. Hook failures are logged and do
+// not affect the build.
+func (ctx *BuildContext) portHook(event string, p *pkg.Package, status, phase string) {
+	env := hooks.Env{
+		RunID:   ctx.runID,
+		Port:    p.PortDir,
+		Version: p.Version,
+		Status:  status,
+		Phase:   phase,
+	}
+	switch event {
+	case hooks.PortSuccess:
+		env.Log = log.BuildLogPath(ctx.cfg, p.PortDir, p.BuildUUID)
+		env.Package = filepath.Join(ctx.cfg.PackagesPath, "All", p.PkgFile)
+	case hooks.PortFailure:
+		env.Log = log.BuildLogPath(ctx.cfg, p.PortDir, p.BuildUUID)
+	}
+	_ = hooks.Run(ctx.ctx, ctx.cfg, event, env, ctx.logger)
+}
+
 // formatDuration formats a duration for display
 func formatDuration(d time.Duration) string {
 	d = d.Round(time.Second)
@@ -0,0 +1,86 @@
+package build
+
+import (
+	"fmt"
+	"runtime"
+
+	"go-synth/config"
+	"go-synth/log"
+	"go-synth/pkg"
+)
+
+// makeJobs returns the MAKE_JOBS_NUMBER for a build of p and the reason it
+// was chosen, for the build log. 0 leaves MAKE_JOBS_NUMBER to the ports
+// framework.
+//
+// Ports marked MAKE_JOBS_UNSAFE always get one job. Otherwise a
+// Max_jobs_overrides entry for the port wins over Max_jobs_per_builder, and
+// without either the ports default applies. With Adaptive_jobs, builds
+// running when fewer builds remain than there are workers share the job
+// budget the idle workers would have used; remaining counts the builds not
+// yet finished, including this one. It is evaluated again before each make
+// phase, so a long build picks up the jobs of workers that went idle.
+func makeJobs(cfg *config.Config, p *pkg.Package, remaining, workers int) (int, string) {
+	if p.MakeJobsUnsafe {
+		return 1, "MAKE_JOBS_UNSAFE"
+	}
+
+	if p.Flavor != "" {
+		if n, ok := cfg.JobsOverrides[p.PortDir+"@"+p.Flavor]; ok {
+			return n, "Max_jobs_overrides"
+		}
+	}
+	if n, ok := cfg.JobsOverrides[p.PortDir]; ok {
+		return n, "Max_jobs_overrides"
+	}
+
+	// The ports default already uses every CPU, so there is nothing for
+	// Adaptive_jobs to add
+	if cfg.Source("Max_jobs_per_builder") == config.SourceDefault {
+		return 0, "ports default"
+	}
+
+	jobs := cfg.MaxJobs
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	if cfg.AdaptiveJobs && remaining > 0 && remaining < workers {
+		limit := runtime.NumCPU()
+		if limit < jobs {
+			limit = jobs
+		}
+		boosted := workers * jobs / remaining
+		if boosted > limit {
+			boosted = limit
+		}
+		if boosted > jobs {
+			return boosted, fmt.Sprintf("Adaptive_jobs: %d builds left for %d workers", remaining, workers)
+		}
+	}
+
+	return jobs, "Max_jobs_per_builder"
+}
+
+// updateJobs re-evaluates the make jobs of the build of p on worker before
+// a phase, logging the new count when builds finishing elsewhere changed it.
+func (ctx *BuildContext) updateJobs(worker *Worker, p *pkg.Package, pkgLogger *log.PackageLogger, ctxLogger *log.ContextLogger) {
+	jobs, reason := makeJobs(ctx.cfg, p, ctx.remainingBuilds(), len(ctx.workers))
+	worker.mu.Lock()
+	changed := worker.Jobs != jobs
+	worker.Jobs = jobs
+	worker.mu.Unlock()
+	if changed {
+		pkgLogger.WriteString(fmt.Sprintf("Make jobs: %d (%s)\n", jobs, reason))
+		ctxLogger.Info("Make jobs: %d (%s)", jobs, reason)
+	}
+}
+
+// remainingBuilds returns the number of queued packages that have not
+// finished building, including those currently running.
+func (ctx *BuildContext) remainingBuilds() int {
+	ctx.statsMu.Lock()
+	defer ctx.statsMu.Unlock()
+	s := ctx.stats
+	return s.Total - s.SkippedPre - s.Ignored - s.Success - s.Failed - s.Skipped
+}
@@ -0,0 +1,68 @@
+package build
+
+import (
+	"runtime"
+	"testing"
+
+	"go-synth/config"
+	"go-synth/pkg"
+)
+
+func TestMakeJobs(t *testing.T) {
+	cfg := &config.Config{
+		MaxJobs: 2,
+		JobsOverrides: map[string]int{
+			"lang/rust":          8,
+			"devel/py-six@py311": 3,
+		},
+	}
+	cfg.SetSource("Max_jobs_per_builder", "dsynth.ini")
+	adaptive := *cfg
+	adaptive.AdaptiveJobs = true
+	unset := &config.Config{MaxJobs: 1, AdaptiveJobs: true, JobsOverrides: cfg.JobsOverrides}
+
+	tests := []struct {
+		name      string
+		cfg       *config.Config
+		pkg       *pkg.Package
+		remaining int
+		workers   int
+		want      int
+	}{
+		{"default", cfg, &pkg.Package{PortDir: "editors/vim"}, 10, 4, 2},
+		{"unsafe", cfg, &pkg.Package{PortDir: "lang/rust", MakeJobsUnsafe: true}, 10, 4, 1},
+		{"override", cfg, &pkg.Package{PortDir: "lang/rust"}, 10, 4, 8},
+		{"flavor override", cfg, &pkg.Package{PortDir: "devel/py-six", Flavor: "py311"}, 10, 4, 3},
+		{"other flavor", cfg, &pkg.Package{PortDir: "devel/py-six", Flavor: "py39"}, 10, 4, 2},
+		{"adaptive with work queued", &adaptive, &pkg.Package{PortDir: "editors/vim"}, 10, 4, 2},
+		{"adaptive unsafe", &adaptive, &pkg.Package{PortDir: "editors/vim", MakeJobsUnsafe: true}, 1, 4, 1},
+		{"not adaptive at tail", cfg, &pkg.Package{PortDir: "editors/vim"}, 1, 4, 2},
+		{"ports default", unset, &pkg.Package{PortDir: "editors/vim"}, 1, 4, 0},
+		{"override without default", unset, &pkg.Package{PortDir: "lang/rust"}, 10, 4, 8},
+		{"unsafe without default", unset, &pkg.Package{PortDir: "editors/vim", MakeJobsUnsafe: true}, 10, 4, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := makeJobs(tt.cfg, tt.pkg, tt.remaining, tt.workers); got != tt.want {
+				t.Errorf("makeJobs() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMakeJobs_AdaptiveTail(t *testing.T) {
+	cfg := &config.Config{MaxJobs: 1, AdaptiveJobs: true}
+	cfg.SetSource("Max_jobs_per_builder", "dsynth.ini")
+	p := &pkg.Package{PortDir: "editors/vim"}
+
+	// Two builds left for four single-job workers: each may use two jobs,
+	// but never more than the CPUs available.
+	want := 2
+	if runtime.NumCPU() < want {
+		want = 1
+	}
+	if got, _ := makeJobs(cfg, p, 2, 4); got != want {
+		t.Errorf("makeJobs() = %d, want %d", got, want)
+	}
+}
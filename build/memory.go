@@ -0,0 +1,121 @@
+package build
+
+import (
+	"context"
+	"runtime"
+	"sync"
+
+	"go-synth/config"
+	"go-synth/pkg"
+)
+
+// memoryBudget limits the summed memory estimates of the builds running at
+// once to Memory_target. A build that does not fit waits until enough
+// running builds finish; a build is always admitted when nothing else is
+// running, so ports estimated above the target still build, alone.
+type memoryBudget struct {
+	mu        sync.Mutex
+	target    int64
+	inUse     int64
+	running   int
+	estimates map[string]int64 // Recorded per-process peaks by port directory
+	fallback  int64            // Estimate for ports without history
+	changed   chan struct{}    // Closed and replaced whenever a build releases memory
+}
+
+// newMemoryBudget returns the budget for cfg.Memory.Target. peaks holds
+// the recorded per-process memory peaks by port directory, as returned by
+// builddb.PeakRSSByPort; ports without one use Memory_default_estimate.
+func newMemoryBudget(cfg *config.Config, peaks map[string]int64) *memoryBudget {
+	b := &memoryBudget{
+		target:    cfg.Memory.Target,
+		estimates: peaks,
+		fallback:  cfg.Memory.Estimate,
+		changed:   make(chan struct{}),
+	}
+	if b.fallback <= 0 {
+		b.fallback = config.DefaultMemoryEstimate
+	}
+	return b
+}
+
+// estimate returns the expected memory footprint of a build of p running
+// jobs make jobs, as returned by makeJobs. The recorded peak is that of the
+// largest single process, so it is scaled by the number of jobs that may
+// run side by side; 0 jobs means the ports default of one per CPU.
+// Memory_default_estimate already covers a whole build and is not scaled.
+func (b *memoryBudget) estimate(p *pkg.Package, jobs int) int64 {
+	peak, ok := b.estimates[p.PortDir]
+	if !ok {
+		return b.fallback
+	}
+	if jobs < 1 {
+		jobs = runtime.NumCPU()
+	}
+	return peak * int64(jobs)
+}
+
+// heavy reports whether need is more than a build's fair share of the
+// target, i.e. more than target divided by workers.
+func (b *memoryBudget) heavy(need int64, workers int) bool {
+	if workers < 1 {
+		workers = 1
+	}
+	return need > b.target/int64(workers)
+}
+
+// fits reports whether need can be admitted now.
+func (b *memoryBudget) fits(need int64) bool {
+	return b.running == 0 || b.inUse+need <= b.target
+}
+
+// acquire blocks until need fits within the target, then reserves it. The
+// onWait callback, if not nil, is called once with the memory in use if the
+// build has to wait. It returns false if ctx is cancelled first.
+func (b *memoryBudget) acquire(ctx context.Context, need int64, onWait func(inUse int64)) bool {
+	waited := false
+	for {
+		b.mu.Lock()
+		if b.fits(need) {
+			b.inUse += need
+			b.running++
+			b.mu.Unlock()
+			return true
+		}
+		inUse := b.inUse
+		changed := b.changed
+		b.mu.Unlock()
+
+		if !waited && onWait != nil {
+			onWait(inUse)
+		}
+		waited = true
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-changed:
+		}
+	}
+}
+
+// release returns memory reserved by acquire and wakes waiting builds.
+func (b *memoryBudget) release(need int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.inUse -= need
+	b.running--
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// notePeakRSS records rss, the peak of the largest process of a phase, as
+// the worker's peak for the current build if it exceeds the peak seen so far.
+func (w *Worker) notePeakRSS(rss int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if rss > w.PeakRSS {
+		w.PeakRSS = rss
+	}
+}
@@ -0,0 +1,94 @@
+package build
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"go-synth/config"
+	"go-synth/pkg"
+)
+
+func TestMemoryBudget_Estimates(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Memory.Target = 16 << 30
+	b := newMemoryBudget(cfg, map[string]int64{"www/chromium": 3 << 30})
+
+	chromium := &pkg.Package{PortDir: "www/chromium"}
+	vim := &pkg.Package{PortDir: "editors/vim"}
+
+	// The recorded per-process peak is scaled by the make jobs
+	if got := b.estimate(chromium, 4); got != 12<<30 {
+		t.Errorf("estimate(chromium, 4) = %d, want recorded peak times 4 jobs", got)
+	}
+	if got := b.estimate(chromium, 0); got != int64(runtime.NumCPU())*3<<30 {
+		t.Errorf("estimate(chromium, 0) = %d, want recorded peak times the CPUs", got)
+	}
+	if got := b.estimate(vim, 4); got != config.DefaultMemoryEstimate {
+		t.Errorf("estimate(vim, 4) = %d, want default estimate", got)
+	}
+	if !b.heavy(b.estimate(chromium, 4), 4) || b.heavy(b.estimate(vim, 4), 4) {
+		t.Error("only chromium should be memory-heavy with 4 builders")
+	}
+}
+
+func TestMemoryBudget_Admission(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Memory.Target = 10 << 30
+	b := newMemoryBudget(cfg, nil)
+	ctx := context.Background()
+
+	// A build larger than the target still runs when nothing else does
+	if !b.acquire(ctx, 12<<30, nil) {
+		t.Fatal("oversized build not admitted on an idle budget")
+	}
+	b.release(12 << 30)
+
+	if !b.acquire(ctx, 6<<30, nil) {
+		t.Fatal("first build not admitted")
+	}
+
+	waited := make(chan int64, 1)
+	admitted := make(chan bool, 1)
+	go func() {
+		admitted <- b.acquire(ctx, 6<<30, func(inUse int64) { waited <- inUse })
+	}()
+
+	select {
+	case inUse := <-waited:
+		if inUse != 6<<30 {
+			t.Errorf("onWait inUse = %d, want %d", inUse, int64(6<<30))
+		}
+	case <-admitted:
+		t.Fatal("second build admitted beyond the target")
+	case <-time.After(time.Second):
+		t.Fatal("second build neither waited nor was admitted")
+	}
+
+	b.release(6 << 30)
+	select {
+	case ok := <-admitted:
+		if !ok {
+			t.Error("second build not admitted after release")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("second build not woken by release")
+	}
+}
+
+func TestMemoryBudget_Cancel(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Memory.Target = 4 << 30
+	b := newMemoryBudget(cfg, nil)
+
+	if !b.acquire(context.Background(), 4<<30, nil) {
+		t.Fatal("first build not admitted")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if b.acquire(ctx, 1<<30, nil) {
+		t.Error("build admitted after cancellation")
+	}
+}
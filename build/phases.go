@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"go-synth/config"
@@ -24,76 +25,110 @@ func (lw *loggerWriter) Write(p []byte) (n int, err error) {
 	return len(p), nil
 }
 
-// executePhase executes a single build phase
-func executePhase(ctx context.Context, worker *Worker, p *pkg.Package, phase string, cfg *config.Config, registry *pkg.BuildStateRegistry, logger *log.PackageLogger) error {
-	// Build the make command
-	portPath := filepath.Join("/xports", p.Category, p.Name)
+// makePhase is a built-in phase run as a target of the port's Makefile
+type makePhase struct {
+	target string
+	batch  bool // Run with BATCH=yes
+}
 
-	args := []string{
-		"-C", portPath,
-	}
+// makePhases maps built-in phase names to their make targets
+var makePhases = map[string]makePhase{
+	"check-sanity": {target: "check-sanity"},
+	"fetch":        {target: "fetch", batch: true},
+	"checksum":     {target: "checksum", batch: true},
+	"extract":      {target: "extract", batch: true},
+	"patch":        {target: "patch", batch: true},
+	"configure":    {target: "configure", batch: true},
+	"build":        {target: "build", batch: true},
+	"stage":        {target: "stage", batch: true},
+	"test":         {target: "test", batch: true},
+	"check-plist":  {target: "check-plist"},
+	"package":      {target: "package", batch: true},
+}
 
-	// Add flavor if specified
-	if p.Flavor != "" {
-		args = append(args, "FLAVOR="+p.Flavor)
-	}
+// implicitPhases are built-in phases with nothing to run: the ports
+// framework handles them as part of the main phases.
+var implicitPhases = map[string]bool{
+	"fetch-depends":   true,
+	"extract-depends": true,
+	"patch-depends":   true,
+	"build-depends":   true,
+	"lib-depends":     true,
+	"run-depends":     true,
+}
 
-	// Add common overrides - CRITICAL: Set PORTSDIR to where we mounted ports
-	args = append(args,
-		"PORTSDIR=/xports",
-		"WRKDIRPREFIX=/construction",
-		"DISTDIR=/distfiles",
-		"PACKAGES=/packages",
-		"PKG_DBDIR=/var/db/pkg",
-	)
-
-	// Phase-specific handling
-	switch phase {
-	case "install-pkgs":
+// executePhase executes a single build phase: a built-in phase, or a
+// custom phase defined by Build_phase_hooks. Build_phase_env variables for
+// the phase are added to the command's environment.
+func executePhase(ctx context.Context, worker *Worker, p *pkg.Package, phase string, cfg *config.Config, registry *pkg.BuildStateRegistry, logger *log.PackageLogger) error {
+	if phase == "install-pkgs" {
 		// Install dependency packages before building
 		return installDependencyPackages(ctx, worker, p, cfg, registry, logger)
+	}
+	if implicitPhases[phase] {
+		return nil
+	}
 
-	case "fetch":
-		args = append(args, "BATCH=yes", "fetch")
-
-	case "checksum":
-		args = append(args, "BATCH=yes", "checksum")
-
-	case "extract":
-		args = append(args, "BATCH=yes", "extract")
-
-	case "patch":
-		args = append(args, "BATCH=yes", "patch")
-
-	case "configure":
-		args = append(args, "BATCH=yes", "configure")
-
-	case "build":
-		args = append(args, "BATCH=yes", "build")
-
-	case "stage":
-		args = append(args, "BATCH=yes", "stage")
+	portPath := filepath.Join("/xports", p.Category, p.Name)
+	env := map[string]string{
+		"PATH": "/sbin:/bin:/usr/sbin:/usr/bin:/usr/local/sbin:/usr/local/bin",
+	}
 
-	case "package":
-		args = append(args, "BATCH=yes", "package")
+	var command, workDir string
+	var args []string
+	if script, ok := cfg.PhaseHooks[phase]; ok {
+		// Custom phase: the script runs in the port directory and sees the
+		// port and the worker's layout through its environment
+		command = "/bin/sh"
+		args = []string{script}
+		workDir = portPath
+		env["GOSYNTH_PHASE"] = phase
+		env["GOSYNTH_PORT"] = p.PortDir
+		env["PORTDIR"] = portPath
+		env["FLAVOR"] = p.Flavor
+		env["PORTSDIR"] = "/xports"
+		env["WRKDIRPREFIX"] = "/construction"
+		env["DISTDIR"] = "/distfiles"
+		env["PACKAGES"] = "/packages"
+		if worker.Jobs > 0 {
+			env["MAKE_JOBS_NUMBER"] = strconv.Itoa(worker.Jobs)
+		}
+	} else {
+		mp, ok := makePhases[phase]
+		if !ok {
+			return fmt.Errorf("unknown phase: %s", phase)
+		}
 
-	case "check-plist":
-		if cfg.CheckPlist {
-			args = append(args, "check-plist")
-		} else {
-			// Skip check-plist if not enabled
-			return nil
+		command = "/usr/bin/make"
+		args = []string{
+			"-C", portPath,
 		}
 
-	case "check-sanity":
-		args = append(args, "check-sanity")
+		// Add flavor if specified
+		if p.Flavor != "" {
+			args = append(args, "FLAVOR="+p.Flavor)
+		}
 
-	case "fetch-depends", "extract-depends", "patch-depends", "build-depends", "lib-depends", "run-depends":
-		// These are handled implicitly by the main phases
-		return nil
+		// Add common overrides - CRITICAL: Set PORTSDIR to where we mounted ports
+		args = append(args,
+			"PORTSDIR=/xports",
+			"WRKDIRPREFIX=/construction",
+			"DISTDIR=/distfiles",
+			"PACKAGES=/packages",
+			"PKG_DBDIR=/var/db/pkg",
+			"PORT_DBDIR=/options", // Saved port options (see config-options)
+		)
+		if worker.Jobs > 0 {
+			args = append(args, fmt.Sprintf("MAKE_JOBS_NUMBER=%d", worker.Jobs))
+		}
+		if mp.batch {
+			args = append(args, "BATCH=yes")
+		}
+		args = append(args, mp.target)
+	}
 
-	default:
-		return fmt.Errorf("unknown phase: %s", phase)
+	for name, value := range cfg.PhaseEnv[phase] {
+		env[name] = value
 	}
 
 	// Build environment command
@@ -101,21 +136,25 @@ func executePhase(ctx context.Context, worker *Worker, p *pkg.Package, phase str
 	logWriter := &loggerWriter{logger: logger}
 
 	execCmd := &environment.ExecCommand{
-		Command: "/usr/bin/make",
+		Command: command,
 		Args:    args,
-		Env: map[string]string{
-			"PATH": "/sbin:/bin:/usr/sbin:/usr/bin:/usr/local/sbin:/usr/local/bin",
-		},
-		Stdout: logWriter,
-		Stderr: logWriter,
+		WorkDir: workDir,
+		Env:     env,
+		Stdout:  logWriter,
+		Stderr:  logWriter,
+		Limits:  worker.Limits,
+		Timeout: worker.Timeouts.Phases[phase],
 	}
 
 	// Log command for debugging
-	cmdStr := fmt.Sprintf("/usr/bin/make %s", strings.Join(args, " "))
+	cmdStr := fmt.Sprintf("%s %s", command, strings.Join(args, " "))
 	logger.WriteCommand(cmdStr)
 
 	// Execute in isolated environment
 	result, err := worker.Env.Execute(ctx, execCmd)
+	if result != nil {
+		worker.notePeakRSS(result.MaxRSS)
+	}
 	if err != nil {
 		// Execution failure (not non-zero exit code)
 		return fmt.Errorf("phase execution failed: %w", err)
@@ -0,0 +1,18 @@
+package build
+
+import (
+	"testing"
+
+	"go-synth/config"
+)
+
+// TestBuiltinPhasesHandled verifies that executePhase knows every phase
+// config accepts as built in.
+func TestBuiltinPhasesHandled(t *testing.T) {
+	for _, phase := range config.BuiltinPhases {
+		_, isMake := makePhases[phase]
+		if !isMake && !implicitPhases[phase] && phase != "install-pkgs" {
+			t.Errorf("built-in phase %s is not handled by executePhase", phase)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package build
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-synth/config"
+	"go-synth/environment"
+	"go-synth/log"
+)
+
+// timeoutError is the cancellation cause of a build stopped by its total
+// or inactivity timeout.
+type timeoutError struct {
+	reason string
+}
+
+func (e *timeoutError) Error() string {
+	return "build timed out: " + e.reason
+}
+
+// watchBuild returns a context for the phases of one build that is
+// cancelled when the build exceeds its total timeout, or when pkgLogger has
+// seen no output for the inactivity timeout. The returned function stops
+// the watchers and must be called when the build ends.
+func watchBuild(parent context.Context, t config.Timeouts, pkgLogger *log.PackageLogger) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(parent)
+
+	var timer *time.Timer
+	if t.Total > 0 {
+		timer = time.AfterFunc(t.Total, func() {
+			cancel(&timeoutError{reason: fmt.Sprintf("build exceeded %s", t.Total)})
+		})
+	}
+
+	if t.Inactivity > 0 {
+		// Check often enough that a hung build is stopped within about a
+		// quarter of the timeout
+		interval := min(t.Inactivity/4, 30*time.Second)
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if time.Since(pkgLogger.LastOutput()) >= t.Inactivity {
+						cancel(&timeoutError{reason: fmt.Sprintf("no output for %s", t.Inactivity)})
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	return ctx, func() {
+		if timer != nil {
+			timer.Stop()
+		}
+		cancel(nil)
+	}
+}
+
+// timeoutReason returns why phase failed with err if a timeout caused it,
+// or an empty string for other failures. ctx is the build context from
+// watchBuild.
+func timeoutReason(ctx context.Context, err error, phase string, t config.Timeouts) string {
+	var te *timeoutError
+	if errors.As(context.Cause(ctx), &te) {
+		return te.reason
+	}
+
+	var execErr *environment.ErrExecutionFailed
+	if errors.As(err, &execErr) && execErr.Op == "timeout" {
+		return fmt.Sprintf("%s phase exceeded %s", phase, t.Phases[phase])
+	}
+	return ""
+}
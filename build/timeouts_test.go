@@ -0,0 +1,92 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"go-synth/config"
+	"go-synth/environment"
+	"go-synth/log"
+)
+
+func newTestPackageLogger(t *testing.T) *log.PackageLogger {
+	t.Helper()
+	cfg := &config.Config{LogsPath: t.TempDir()}
+	pl := log.NewPackageLogger(cfg, "editors/vim", "")
+	t.Cleanup(pl.Close)
+	return pl
+}
+
+func TestWatchBuild_Inactivity(t *testing.T) {
+	pl := newTestPackageLogger(t)
+	timeouts := config.Timeouts{Inactivity: 200 * time.Millisecond}
+
+	ctx, stop := watchBuild(context.Background(), timeouts, pl)
+	defer stop()
+
+	// Output keeps the build alive
+	for i := 0; i < 4; i++ {
+		time.Sleep(100 * time.Millisecond)
+		pl.Write([]byte("still compiling\n"))
+	}
+	if ctx.Err() != nil {
+		t.Fatal("build cancelled while producing output")
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("silent build not cancelled")
+	}
+	reason := timeoutReason(ctx, ctx.Err(), "configure", timeouts)
+	if !strings.Contains(reason, "no output for 200ms") {
+		t.Errorf("timeoutReason() = %q, want inactivity", reason)
+	}
+}
+
+func TestWatchBuild_Total(t *testing.T) {
+	pl := newTestPackageLogger(t)
+	timeouts := config.Timeouts{Total: 50 * time.Millisecond}
+
+	ctx, stop := watchBuild(context.Background(), timeouts, pl)
+	defer stop()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("build not cancelled after its total timeout")
+	}
+	if reason := timeoutReason(ctx, ctx.Err(), "build", timeouts); reason != "build exceeded 50ms" {
+		t.Errorf("timeoutReason() = %q", reason)
+	}
+}
+
+func TestTimeoutReason(t *testing.T) {
+	pl := newTestPackageLogger(t)
+	timeouts := config.Timeouts{Phases: map[string]time.Duration{"configure": 30 * time.Minute}}
+
+	parent, cancel := context.WithCancel(context.Background())
+	ctx, stop := watchBuild(parent, timeouts, pl)
+	defer stop()
+
+	phaseErr := fmt.Errorf("phase execution failed: %w", &environment.ErrExecutionFailed{Op: "timeout", Command: "/usr/bin/make"})
+	if reason := timeoutReason(ctx, phaseErr, "configure", timeouts); reason != "configure phase exceeded 30m0s" {
+		t.Errorf("timeoutReason(phase timeout) = %q", reason)
+	}
+
+	exitErr := fmt.Errorf("phase failed with exit code 1")
+	if reason := timeoutReason(ctx, exitErr, "configure", timeouts); reason != "" {
+		t.Errorf("timeoutReason(exit code) = %q, want none", reason)
+	}
+
+	// Interrupting the whole run is not a timeout
+	cancel()
+	<-ctx.Done()
+	cancelErr := &environment.ErrExecutionFailed{Op: "cancel", Command: "/usr/bin/make"}
+	if reason := timeoutReason(ctx, cancelErr, "configure", timeouts); reason != "" {
+		t.Errorf("timeoutReason(cancelled) = %q, want none", reason)
+	}
+}
@@ -0,0 +1,106 @@
+package builddb
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// backupSuffix separates the database path from the backup timestamp.
+const backupSuffix = ".backup-"
+
+// backupTimeFormat sorts lexically in time order.
+const backupTimeFormat = "20060102-150405.000"
+
+// BackupPath returns the path of a backup of the database at dbPath taken
+// at t, e.g. "builds.db.backup-20250101-120000.000".
+func BackupPath(dbPath string, t time.Time) string {
+	return dbPath + backupSuffix + t.Format(backupTimeFormat)
+}
+
+// Backup writes a consistent copy of the database to path and returns its
+// size. The copy is taken inside a read transaction, so it is safe while
+// builds are writing; it never captures a half-written file. The copy is
+// written next to path and renamed into place.
+func (db *DB) Backup(path string) (int64, error) {
+	tmpPath := path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return 0, &DatabaseError{Op: "backup", Err: err}
+	}
+
+	var size int64
+	err = db.view(func(tx *bolt.Tx) error {
+		size, err = tx.WriteTo(f)
+		return err
+	})
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return 0, &DatabaseError{Op: "backup", Err: err}
+	}
+	return size, nil
+}
+
+// ListBackups returns the backups of the database at dbPath, oldest first.
+func ListBackups(dbPath string) ([]string, error) {
+	matches, err := filepath.Glob(globEscape(dbPath) + backupSuffix + "*")
+	if err != nil {
+		return nil, err
+	}
+	backups := matches[:0]
+	for _, m := range matches {
+		stamp := m[len(dbPath)+len(backupSuffix):]
+		if _, err := time.Parse(backupTimeFormat, stamp); err == nil {
+			backups = append(backups, m)
+		}
+	}
+	sort.Strings(backups)
+	return backups, nil
+}
+
+// RotateBackups removes all but the keep most recent backups of the
+// database at dbPath and returns the paths removed. A keep of 0 removes
+// nothing.
+func RotateBackups(dbPath string, keep int) ([]string, error) {
+	if keep <= 0 {
+		return nil, nil
+	}
+	backups, err := ListBackups(dbPath)
+	if err != nil || len(backups) <= keep {
+		return nil, err
+	}
+
+	var removed []string
+	for _, path := range backups[:len(backups)-keep] {
+		if err := os.Remove(path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, path)
+	}
+	return removed, nil
+}
+
+// globEscape quotes the glob metacharacters of path.
+func globEscape(path string) string {
+	escaped := make([]byte, 0, len(path))
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '*', '?', '[', '\\':
+			escaped = append(escaped, '\\')
+		}
+		escaped = append(escaped, path[i])
+	}
+	return string(escaped)
+}
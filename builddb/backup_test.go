@@ -0,0 +1,168 @@
+package builddb
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackup(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+
+	if err := db.SaveRecord(createTestRecord("uuid-1", "editors/vim", "9.1", "success")); err != nil {
+		t.Fatalf("SaveRecord failed: %v", err)
+	}
+
+	path := BackupPath(db.path, time.Now())
+	size, err := db.Backup(path)
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Size() != size {
+		t.Fatalf("backup file: size %d, stat %v", size, err)
+	}
+
+	backup, err := OpenDB(path)
+	if err != nil {
+		t.Fatalf("OpenDB(backup) failed: %v", err)
+	}
+	defer backup.Close()
+	if _, err := backup.GetRecord("uuid-1"); err != nil {
+		t.Errorf("backup is missing uuid-1: %v", err)
+	}
+}
+
+func TestRotateBackups(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "builds.db")
+
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	var paths []string
+	for i := 0; i < 4; i++ {
+		path := BackupPath(dbPath, start.Add(time.Duration(i)*time.Hour))
+		if err := os.WriteFile(path, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	// Not backups
+	for _, name := range []string{"builds.db", "builds.db.backup", "builds.db.backup-x"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backups, err := ListBackups(dbPath)
+	if err != nil {
+		t.Fatalf("ListBackups failed: %v", err)
+	}
+	if strings.Join(backups, ",") != strings.Join(paths, ",") {
+		t.Fatalf("ListBackups = %v, want %v", backups, paths)
+	}
+
+	if removed, err := RotateBackups(dbPath, 0); err != nil || len(removed) != 0 {
+		t.Errorf("RotateBackups(0) = %v, %v; want nothing removed", removed, err)
+	}
+
+	removed, err := RotateBackups(dbPath, 2)
+	if err != nil {
+		t.Fatalf("RotateBackups failed: %v", err)
+	}
+	if strings.Join(removed, ",") != strings.Join(paths[:2], ",") {
+		t.Errorf("RotateBackups removed %v, want %v", removed, paths[:2])
+	}
+	backups, _ = ListBackups(dbPath)
+	if strings.Join(backups, ",") != strings.Join(paths[2:], ",") {
+		t.Errorf("backups left %v, want %v", backups, paths[2:])
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	src, _ := setupTestDB(t)
+	defer cleanupTestDB(t, src)
+
+	if err := src.SaveRecord(createTestRecord("uuid-1", "editors/vim", "9.1", "success")); err != nil {
+		t.Fatalf("SaveRecord failed: %v", err)
+	}
+	if err := src.UpdatePackageIndex("editors/vim", "9.1", "uuid-1"); err != nil {
+		t.Fatalf("UpdatePackageIndex failed: %v", err)
+	}
+	if err := src.UpdateCRC("editors/vim", 0xdeadbeef); err != nil {
+		t.Fatalf("UpdateCRC failed: %v", err)
+	}
+	if err := src.StartRun("run-1", time.Now()); err != nil {
+		t.Fatalf("StartRun failed: %v", err)
+	}
+	if err := src.PutRunPackage("run-1", &RunPackageRecord{PortDir: "editors/vim", Version: "9.1", Status: RunStatusSuccess}); err != nil {
+		t.Fatalf("PutRunPackage failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	exported, err := src.Export(&buf)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if exported.Total() != 5 {
+		t.Errorf("Export wrote %d entries, want 5: %v", exported.Total(), exported)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.Contains(lines[0], `"format":"go-synth-builddb"`) {
+		t.Errorf("header = %s", lines[0])
+	}
+	// Records are embedded as JSON, not as strings
+	if !strings.Contains(buf.String(), `"value":{"uuid":"uuid-1"`) {
+		t.Errorf("build record not embedded as JSON:\n%s", buf.String())
+	}
+
+	dst, _ := setupTestDB(t)
+	defer cleanupTestDB(t, dst)
+	imported, err := dst.Import(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if imported.Total() != exported.Total() {
+		t.Errorf("Import stored %d entries, want %d", imported.Total(), exported.Total())
+	}
+
+	rec, err := dst.LatestFor("editors/vim", "9.1")
+	if err != nil || rec == nil || rec.UUID != "uuid-1" {
+		t.Errorf("LatestFor after import = %v, %v", rec, err)
+	}
+	crc, ok, err := dst.GetCRC("editors/vim")
+	if err != nil || !ok || crc != 0xdeadbeef {
+		t.Errorf("GetCRC after import = %x, %v, %v", crc, ok, err)
+	}
+	pkgs, err := dst.ListRunPackages("run-1")
+	if err != nil || len(pkgs) != 1 {
+		t.Errorf("ListRunPackages after import = %v, %v", pkgs, err)
+	}
+}
+
+func TestImport_Rejects(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+
+	tests := []struct {
+		name string
+		dump string
+		want string
+	}{
+		{"newer version", `{"format":"go-synth-builddb","version":99}`, "unsupported dump version 99"},
+		{"other format", `{"format":"something","version":1}`, "not a build database dump"},
+		{"newer schema", `{"format":"go-synth-builddb","version":1,"schema":99}`, "schema version 99"},
+		{"meta bucket", "{\"format\":\"go-synth-builddb\",\"version\":1}\n{\"bucket\":\"meta\",\"key\":\"schema_version\",\"text\":\"1\"}", "cannot be imported"},
+		{"no value", "{\"format\":\"go-synth-builddb\",\"version\":1}\n{\"bucket\":\"builds\",\"key\":\"a\"}", "line 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := db.Import(strings.NewReader(tt.dump))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Import error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"hash/crc32"
 	"os"
 	"path/filepath"
+	"sort"
+	"sync"
 	"time"
 
 	bolt "go.etcd.io/bbolt"
@@ -21,12 +23,20 @@ const (
 	BucketCRCIndex    = "crc_index"
 	BucketBuildRuns   = "build_runs"
 	BucketRunPackages = "run_packages"
+	BucketMeta        = "meta" // Schema version; see SchemaVersion
 )
 
 // DB wraps a bbolt database for build tracking and CRC indexing
 type DB struct {
 	db   *bolt.DB
 	path string
+
+	// Shared DBs (OpenDBShared) open db per transaction; mu guards db,
+	// users and closed.
+	shared bool
+	mu     sync.Mutex
+	users  int // transactions using db
+	closed bool
 }
 
 // BuildRecord represents a single build attempt with status and timestamps
@@ -37,8 +47,19 @@ type BuildRecord struct {
 	Status    string    `json:"status"` // "running" | "success" | "failed"
 	StartTime time.Time `json:"start_time"`
 	EndTime   time.Time `json:"end_time"`
+	PeakRSS   int64     `json:"peak_rss,omitempty"` // Largest resident set of a single build process, in bytes
+
+	// TestStatus is the outcome of the test phase, recorded separately from
+	// Status. Empty unless the build ran in test mode.
+	TestStatus string `json:"test_status,omitempty"` // "" | "passed" | "failed"
 }
 
+// Test phase outcomes for BuildRecord.TestStatus
+const (
+	TestStatusPassed = "passed"
+	TestStatusFailed = "failed"
+)
+
 // DBStats contains database statistics for overview display
 type DBStats struct {
 	TotalBuilds  int    // Total build records in database
@@ -46,12 +67,17 @@ type DBStats struct {
 	TotalCRCs    int    // Ports with CRC data
 	DatabasePath string // Path to database file
 	DatabaseSize int64  // File size in bytes
+	Schema       int    // Schema version; see SchemaVersion
 }
 
 // OpenDB opens or creates a bbolt database at the given path.
 // It automatically initializes the required buckets (builds, packages, crc_index)
 // if they don't exist. The database is opened with 0600 permissions.
 //
+// New databases are created at SchemaVersion. Existing databases are not
+// migrated; use migration.OpenDB for that. A database with a newer schema
+// version than SchemaVersion is refused with a *SchemaError.
+//
 // Parameters:
 //   - path: Filesystem path to the database file
 //
@@ -67,14 +93,31 @@ type DBStats struct {
 //	}
 //	defer db.Close()
 func OpenDB(path string) (*DB, error) {
+	return openDB(path, nil)
+}
+
+// openDB opens the database at path with the given bbolt options and
+// initializes it.
+func openDB(path string, options *bolt.Options) (*DB, error) {
 	// Open database with user read/write permissions only (0600)
-	bdb, err := bolt.Open(path, 0600, nil)
+	bdb, err := bolt.Open(path, 0600, options)
 	if err != nil {
 		return nil, &DatabaseError{Op: "open", Err: err}
 	}
 
 	// Initialize required buckets in a single write transaction
 	err = bdb.Update(func(tx *bolt.Tx) error {
+		// Refuse databases of a newer go-synth before touching anything
+		version, err := readSchemaVersion(tx)
+		if err != nil {
+			return &DatabaseError{Op: "read schema version", Bucket: BucketMeta, Err: err}
+		}
+		if version > SchemaVersion {
+			return &SchemaError{Found: version, Supported: SchemaVersion}
+		}
+		first, _ := tx.Cursor().First()
+		fresh := first == nil
+
 		// Legacy builds bucket (kept for backward compatibility)
 		if _, err := tx.CreateBucketIfNotExists([]byte(BucketBuilds)); err != nil {
 			return &DatabaseError{Op: "create bucket", Bucket: BucketBuilds, Err: err}
@@ -100,6 +143,11 @@ func OpenDB(path string) (*DB, error) {
 			return &DatabaseError{Op: "create bucket", Bucket: BucketCRCIndex, Err: err}
 		}
 
+		// New databases start at the current schema; existing ones keep
+		// their version until migrated
+		if fresh {
+			return writeSchemaVersion(tx, SchemaVersion)
+		}
 		return nil
 	})
 
@@ -130,6 +178,14 @@ func OpenDB(path string) (*DB, error) {
 //	}
 //	defer db.Close()
 func (db *DB) Close() error {
+	if db.shared {
+		db.mu.Lock()
+		defer db.mu.Unlock()
+		db.closed = true
+		if db.users > 0 {
+			return nil // the last release closes the file
+		}
+	}
 	if db.db == nil {
 		return nil
 	}
@@ -169,7 +225,7 @@ func (db *DB) SaveRecord(rec *BuildRecord) error {
 	}
 
 	// Store in builds bucket
-	err = db.db.Update(func(tx *bolt.Tx) error {
+	err = db.update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(BucketBuilds))
 		if bucket == nil {
 			return &DatabaseError{Op: "get bucket", Bucket: BucketBuilds, Err: ErrBucketNotFound}
@@ -207,7 +263,7 @@ func (db *DB) GetRecord(uuid string) (*BuildRecord, error) {
 
 	var rec BuildRecord
 
-	err := db.db.View(func(tx *bolt.Tx) error {
+	err := db.view(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(BucketBuilds))
 		if bucket == nil {
 			return &DatabaseError{Op: "get bucket", Bucket: BucketBuilds, Err: ErrBucketNotFound}
@@ -251,7 +307,7 @@ func (db *DB) UpdateRecordStatus(uuid, status string, endTime time.Time) error {
 		return &ValidationError{Field: "uuid", Err: ErrEmptyUUID}
 	}
 
-	err := db.db.Update(func(tx *bolt.Tx) error {
+	err := db.update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(BucketBuilds))
 		if bucket == nil {
 			return &DatabaseError{Op: "get bucket", Bucket: BucketBuilds, Err: ErrBucketNotFound}
@@ -288,6 +344,159 @@ func (db *DB) UpdateRecordStatus(uuid, status string, endTime time.Time) error {
 	return nil
 }
 
+// SetPeakRSS records the largest resident set size of a single process
+// observed during a build.
+//
+// Parameters:
+//   - uuid: The UUID of the build record to update
+//   - peak: Peak RSS in bytes
+//
+// Returns:
+//   - error: ValidationError if uuid is empty, RecordError if not found
+func (db *DB) SetPeakRSS(uuid string, peak int64) error {
+	if uuid == "" {
+		return &ValidationError{Field: "uuid", Err: ErrEmptyUUID}
+	}
+
+	err := db.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketBuilds))
+		if bucket == nil {
+			return &DatabaseError{Op: "get bucket", Bucket: BucketBuilds, Err: ErrBucketNotFound}
+		}
+
+		data := bucket.Get([]byte(uuid))
+		if data == nil {
+			return &RecordError{Op: "set peak rss", UUID: uuid, Err: ErrRecordNotFound}
+		}
+
+		var rec BuildRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return &RecordError{Op: "unmarshal", UUID: uuid, Err: err}
+		}
+
+		rec.PeakRSS = peak
+
+		updatedData, err := json.Marshal(&rec)
+		if err != nil {
+			return &RecordError{Op: "marshal", UUID: uuid, Err: err}
+		}
+		return bucket.Put([]byte(uuid), updatedData)
+	})
+
+	if err != nil {
+		return &RecordError{Op: "set peak rss", UUID: uuid, Err: err}
+	}
+
+	return nil
+}
+
+// SetTestStatus records the outcome of a build's test phase.
+//
+// Parameters:
+//   - uuid: The UUID of the build record to update
+//   - status: TestStatusPassed or TestStatusFailed
+//
+// Returns:
+//   - error: ValidationError if uuid is empty, RecordError if not found
+func (db *DB) SetTestStatus(uuid, status string) error {
+	if uuid == "" {
+		return &ValidationError{Field: "uuid", Err: ErrEmptyUUID}
+	}
+
+	err := db.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketBuilds))
+		if bucket == nil {
+			return &DatabaseError{Op: "get bucket", Bucket: BucketBuilds, Err: ErrBucketNotFound}
+		}
+
+		data := bucket.Get([]byte(uuid))
+		if data == nil {
+			return &RecordError{Op: "set test status", UUID: uuid, Err: ErrRecordNotFound}
+		}
+
+		var rec BuildRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return &RecordError{Op: "unmarshal", UUID: uuid, Err: err}
+		}
+
+		rec.TestStatus = status
+
+		updatedData, err := json.Marshal(&rec)
+		if err != nil {
+			return &RecordError{Op: "marshal", UUID: uuid, Err: err}
+		}
+		return bucket.Put([]byte(uuid), updatedData)
+	})
+
+	if err != nil {
+		return &RecordError{Op: "set test status", UUID: uuid, Err: err}
+	}
+
+	return nil
+}
+
+// PeakRSSByPort returns the recorded per-process memory peak of each port
+// directory, for estimating the footprint of its next build.
+//
+// A port's estimate is the peak of its most recent successful build. Ports
+// that never built successfully use the largest peak of any attempt, so a
+// build that died of memory exhaustion is not underestimated. Records
+// without a peak are ignored.
+//
+// Returns:
+//   - map[string]int64: Peak RSS in bytes keyed by port directory
+//   - error: Any database or unmarshaling errors
+func (db *DB) PeakRSSByPort() (map[string]int64, error) {
+	type estimate struct {
+		peak    int64
+		success time.Time
+	}
+	found := make(map[string]*estimate)
+
+	err := db.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketBuilds))
+		if bucket == nil {
+			return &DatabaseError{Op: "get bucket", Bucket: BucketBuilds, Err: ErrBucketNotFound}
+		}
+
+		return bucket.ForEach(func(k, v []byte) error {
+			var rec BuildRecord
+			if err := json.Unmarshal(v, &rec); err != nil {
+				return &RecordError{Op: "unmarshal", UUID: string(k), Err: err}
+			}
+			if rec.PeakRSS <= 0 {
+				return nil
+			}
+
+			e := found[rec.PortDir]
+			if e == nil {
+				e = &estimate{}
+				found[rec.PortDir] = e
+			}
+			switch {
+			case rec.Status == "success":
+				if e.success.IsZero() || rec.StartTime.After(e.success) {
+					e.peak = rec.PeakRSS
+					e.success = rec.StartTime
+				}
+			case e.success.IsZero() && rec.PeakRSS > e.peak:
+				e.peak = rec.PeakRSS
+			}
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, &DatabaseError{Op: "peak rss", Bucket: BucketBuilds, Err: err}
+	}
+
+	peaks := make(map[string]int64, len(found))
+	for portDir, e := range found {
+		peaks[portDir] = e.peak
+	}
+	return peaks, nil
+}
+
 // LatestFor retrieves the most recent successful build record for a given port
 // directory and version combination.
 //
@@ -305,7 +514,7 @@ func (db *DB) UpdateRecordStatus(uuid, status string, endTime time.Time) error {
 func (db *DB) LatestFor(portDir, version string) (*BuildRecord, error) {
 	var rec *BuildRecord
 
-	err := db.db.View(func(tx *bolt.Tx) error {
+	err := db.view(func(tx *bolt.Tx) error {
 		packages := tx.Bucket([]byte("packages"))
 		if packages == nil {
 			return &DatabaseError{Op: "get bucket", Bucket: BucketPackages, Err: ErrBucketNotFound}
@@ -366,6 +575,42 @@ func (db *DB) LatestFor(portDir, version string) (*BuildRecord, error) {
 	return rec, nil
 }
 
+// BuildsFor returns the build records of portDir, all versions, newest
+// first. A limit of 0 returns all of them. The builds bucket is keyed by
+// UUID, so this scans every record.
+func (db *DB) BuildsFor(portDir string, limit int) ([]BuildRecord, error) {
+	var records []BuildRecord
+
+	err := db.view(func(tx *bolt.Tx) error {
+		builds := tx.Bucket([]byte(BucketBuilds))
+		if builds == nil {
+			return &DatabaseError{Op: "get bucket", Bucket: BucketBuilds, Err: ErrBucketNotFound}
+		}
+
+		return builds.ForEach(func(k, v []byte) error {
+			var rec BuildRecord
+			if err := json.Unmarshal(v, &rec); err != nil {
+				return &RecordError{Op: "unmarshal", UUID: string(k), Err: err}
+			}
+			if rec.PortDir == portDir {
+				records = append(records, rec)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].StartTime.After(records[j].StartTime)
+	})
+	if limit > 0 && len(records) > limit {
+		records = records[:limit]
+	}
+	return records, nil
+}
+
 // UpdatePackageIndex updates the package index to point to the latest successful
 // build for a given port directory and version combination.
 //
@@ -384,7 +629,7 @@ func (db *DB) UpdatePackageIndex(portDir, version, uuid string) error {
 	key := []byte(portDir + "@" + version)
 	value := []byte(uuid)
 
-	err := db.db.Update(func(tx *bolt.Tx) error {
+	err := db.update(func(tx *bolt.Tx) error {
 		packages := tx.Bucket([]byte("packages"))
 		if packages == nil {
 			return &DatabaseError{Op: "get bucket", Bucket: BucketPackages, Err: ErrBucketNotFound}
@@ -453,7 +698,7 @@ func (db *DB) UpdateCRC(portDir string, crc uint32) error {
 	value[2] = byte(crc >> 16)
 	value[3] = byte(crc >> 24)
 
-	err := db.db.Update(func(tx *bolt.Tx) error {
+	err := db.update(func(tx *bolt.Tx) error {
 		crcIndex := tx.Bucket([]byte("crc_index"))
 		if crcIndex == nil {
 			return &DatabaseError{Op: "get bucket", Bucket: BucketCRCIndex, Err: ErrBucketNotFound}
@@ -487,7 +732,7 @@ func (db *DB) GetCRC(portDir string) (uint32, bool, error) {
 	var crc uint32
 	var found bool
 
-	err := db.db.View(func(tx *bolt.Tx) error {
+	err := db.view(func(tx *bolt.Tx) error {
 		crcIndex := tx.Bucket([]byte("crc_index"))
 		if crcIndex == nil {
 			return &DatabaseError{Op: "get bucket", Bucket: BucketCRCIndex, Err: ErrBucketNotFound}
@@ -544,7 +789,7 @@ func (db *DB) Stats() (*DBStats, error) {
 		stats.DatabaseSize = fi.Size()
 	}
 
-	err := db.db.View(func(tx *bolt.Tx) error {
+	err := db.view(func(tx *bolt.Tx) error {
 		// Count builds
 		b := tx.Bucket([]byte(BucketBuilds))
 		if b != nil {
@@ -563,7 +808,9 @@ func (db *DB) Stats() (*DBStats, error) {
 			stats.TotalCRCs = c.Stats().KeyN
 		}
 
-		return nil
+		var err error
+		stats.Schema, err = readSchemaVersion(tx)
+		return err
 	})
 
 	if err != nil {
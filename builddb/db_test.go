@@ -415,6 +415,87 @@ func TestUpdateRecordStatus(t *testing.T) {
 	})
 }
 
+func TestPeakRSS(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+
+	base := time.Now()
+	save := func(uuid, portDir, status string, start time.Time, peak int64) {
+		t.Helper()
+		rec := createTestRecord(uuid, portDir, "1.0", status)
+		rec.StartTime = start
+		if err := db.SaveRecord(rec); err != nil {
+			t.Fatalf("SaveRecord() failed: %v", err)
+		}
+		if peak > 0 {
+			if err := db.SetPeakRSS(uuid, peak); err != nil {
+				t.Fatalf("SetPeakRSS() failed: %v", err)
+			}
+		}
+	}
+
+	// Latest successful build wins over older and failed attempts
+	save("llvm-1", "devel/llvm", "success", base, 6<<30)
+	save("llvm-2", "devel/llvm", "success", base.Add(time.Hour), 8<<30)
+	save("llvm-3", "devel/llvm", "failed", base.Add(2*time.Hour), 12<<30)
+	// Never succeeded: largest attempt
+	save("chromium-1", "www/chromium", "failed", base, 10<<30)
+	save("chromium-2", "www/chromium", "failed", base.Add(time.Hour), 2<<30)
+	// No peak recorded
+	save("vim-1", "editors/vim", "success", base, 0)
+
+	peaks, err := db.PeakRSSByPort()
+	if err != nil {
+		t.Fatalf("PeakRSSByPort() failed: %v", err)
+	}
+	want := map[string]int64{
+		"devel/llvm":   8 << 30,
+		"www/chromium": 10 << 30,
+	}
+	if len(peaks) != len(want) {
+		t.Errorf("PeakRSSByPort() = %v, want %v", peaks, want)
+	}
+	for portDir, peak := range want {
+		if peaks[portDir] != peak {
+			t.Errorf("peak for %s = %d, want %d", portDir, peaks[portDir], peak)
+		}
+	}
+
+	rec, _ := db.GetRecord("llvm-2")
+	if rec.Status != "success" || rec.PeakRSS != 8<<30 {
+		t.Errorf("SetPeakRSS changed other fields or lost the peak: %+v", rec)
+	}
+
+	if err := db.SetPeakRSS("nonexistent-uuid", 1); !IsRecordNotFound(err) {
+		t.Errorf("SetPeakRSS() on missing record: got %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestSetTestStatus(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+
+	rec := createTestRecord("six-1", "devel/py-six", "1.0", "success")
+	if err := db.SaveRecord(rec); err != nil {
+		t.Fatalf("SaveRecord() failed: %v", err)
+	}
+	if err := db.SetTestStatus("six-1", TestStatusFailed); err != nil {
+		t.Fatalf("SetTestStatus() failed: %v", err)
+	}
+
+	got, err := db.GetRecord("six-1")
+	if err != nil {
+		t.Fatalf("GetRecord() failed: %v", err)
+	}
+	if got.TestStatus != TestStatusFailed || got.Status != "success" {
+		t.Errorf("record = %+v, want test status failed with build status success", got)
+	}
+
+	if err := db.SetTestStatus("nonexistent-uuid", TestStatusPassed); !IsRecordNotFound(err) {
+		t.Errorf("SetTestStatus() on missing record: got %v, want ErrRecordNotFound", err)
+	}
+}
+
 // ==================== Group 3: Package Index Tests ====================
 
 func TestUpdatePackageIndex(t *testing.T) {
@@ -1339,3 +1420,73 @@ func TestActiveRunSnapshot(t *testing.T) {
 		}
 	})
 }
+
+func TestListRuns(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+
+	base := time.Now().Add(-time.Hour)
+	for i, id := range []string{"run-b", "run-c", "run-a"} {
+		if err := db.StartRun(id, base.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatalf("StartRun(%s) failed: %v", id, err)
+		}
+	}
+
+	runs, err := db.ListRuns(0)
+	if err != nil {
+		t.Fatalf("ListRuns failed: %v", err)
+	}
+	var ids []string
+	for _, r := range runs {
+		ids = append(ids, r.ID)
+	}
+	if fmt.Sprint(ids) != "[run-a run-c run-b]" {
+		t.Errorf("ListRuns order = %v, want newest first [run-a run-c run-b]", ids)
+	}
+
+	runs, err = db.ListRuns(2)
+	if err != nil {
+		t.Fatalf("ListRuns(2) failed: %v", err)
+	}
+	if len(runs) != 2 || runs[0].ID != "run-a" {
+		t.Errorf("ListRuns(2) = %+v, want the 2 newest runs", runs)
+	}
+}
+
+func TestBuildsFor(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+
+	base := time.Now().Add(-time.Hour)
+	for i, rec := range []*BuildRecord{
+		createTestRecord("uuid-1", "editors/vim", "9.0", "failed"),
+		createTestRecord("uuid-2", "editors/vim", "9.1", "success"),
+		createTestRecord("uuid-3", "shells/bash", "5.2", "success"),
+	} {
+		rec.StartTime = base.Add(time.Duration(i) * time.Minute)
+		if err := db.SaveRecord(rec); err != nil {
+			t.Fatalf("SaveRecord(%s) failed: %v", rec.UUID, err)
+		}
+	}
+
+	records, err := db.BuildsFor("editors/vim", 0)
+	if err != nil {
+		t.Fatalf("BuildsFor failed: %v", err)
+	}
+	if len(records) != 2 || records[0].UUID != "uuid-2" || records[1].UUID != "uuid-1" {
+		t.Errorf("BuildsFor(editors/vim) = %+v, want uuid-2, uuid-1", records)
+	}
+
+	records, err = db.BuildsFor("editors/vim", 1)
+	if err != nil {
+		t.Fatalf("BuildsFor(limit 1) failed: %v", err)
+	}
+	if len(records) != 1 || records[0].UUID != "uuid-2" {
+		t.Errorf("BuildsFor(limit 1) = %+v, want uuid-2", records)
+	}
+
+	records, err = db.BuildsFor("devel/none", 0)
+	if err != nil || len(records) != 0 {
+		t.Errorf("BuildsFor(unknown) = %+v, %v; want none", records, err)
+	}
+}
@@ -0,0 +1,187 @@
+package builddb
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// DumpFormat identifies go-synth build database dumps.
+const DumpFormat = "go-synth-builddb"
+
+// DumpVersion is the version of the dump format Export writes. Import
+// refuses dumps of newer versions.
+const DumpVersion = 1
+
+// dumpHeader is the first line of a dump. Schema is the schema version of
+// the exported database; the meta bucket itself is not dumped.
+type dumpHeader struct {
+	Format   string    `json:"format"`
+	Version  int       `json:"version"`
+	Schema   int       `json:"schema"`
+	Exported time.Time `json:"exported"`
+}
+
+// dumpEntry is one key of one bucket. Exactly one of Value, Text and Data
+// is set: JSON records are embedded as is so standard tools can query
+// them, other UTF-8 values are strings, and binary values (CRCs) are
+// base64 encoded.
+type dumpEntry struct {
+	Bucket string          `json:"bucket"`
+	Key    string          `json:"key"`
+	Value  json.RawMessage `json:"value,omitempty"`
+	Text   *string         `json:"text,omitempty"`
+	Data   []byte          `json:"data,omitempty"`
+}
+
+// DumpCounts holds the number of entries exported or imported per bucket.
+type DumpCounts map[string]int
+
+// Total returns the number of entries in all buckets.
+func (c DumpCounts) Total() int {
+	total := 0
+	for _, n := range c {
+		total += n
+	}
+	return total
+}
+
+// Buckets returns the bucket names of c, sorted.
+func (c DumpCounts) Buckets() []string {
+	names := make([]string, 0, len(c))
+	for name := range c {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Export writes every bucket of the database to w as JSON lines: a header
+// naming the format and version, then one line per key. The dump is taken
+// inside a single read transaction and is consistent.
+func (db *DB) Export(w io.Writer) (DumpCounts, error) {
+	counts := make(DumpCounts)
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+
+	err := db.view(func(tx *bolt.Tx) error {
+		schema, err := readSchemaVersion(tx)
+		if err != nil {
+			return err
+		}
+		header := dumpHeader{Format: DumpFormat, Version: DumpVersion, Schema: schema, Exported: time.Now().UTC()}
+		if err := enc.Encode(header); err != nil {
+			return err
+		}
+		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			bucket := string(name)
+			if bucket == BucketMeta {
+				return nil
+			}
+			counts[bucket] = 0 // list empty buckets too
+			return b.ForEach(func(k, v []byte) error {
+				if v == nil {
+					return nil // nested buckets are not used
+				}
+				entry := dumpEntry{Bucket: bucket, Key: string(k)}
+				switch {
+				case len(v) > 0 && v[0] == '{' && json.Valid(v):
+					entry.Value = v
+				case utf8.Valid(v):
+					text := string(v)
+					entry.Text = &text
+				default:
+					entry.Data = v
+				}
+				counts[bucket]++
+				return enc.Encode(entry)
+			})
+		})
+	})
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err != nil {
+		return nil, &DatabaseError{Op: "export", Err: err}
+	}
+	return counts, nil
+}
+
+// Import reads a dump written by Export and stores its entries, creating
+// buckets as needed. Dumps of a newer schema than the database are
+// refused. Entries overwrite existing keys; keys not in the dump
+// are left alone, so dumps from several hosts can be merged. The import
+// runs in a single transaction and stores nothing if any line is bad.
+func (db *DB) Import(r io.Reader) (DumpCounts, error) {
+	counts := make(DumpCounts)
+	dec := json.NewDecoder(bufio.NewReader(r))
+
+	var header dumpHeader
+	if err := dec.Decode(&header); err != nil {
+		return nil, &DatabaseError{Op: "import", Err: fmt.Errorf("read header: %w", err)}
+	}
+	if header.Format != DumpFormat {
+		return nil, &DatabaseError{Op: "import", Err: fmt.Errorf("not a build database dump (format %q)", header.Format)}
+	}
+	if header.Version < 1 || header.Version > DumpVersion {
+		return nil, &DatabaseError{Op: "import", Err: fmt.Errorf("unsupported dump version %d (this go-synth reads up to %d)", header.Version, DumpVersion)}
+	}
+
+	err := db.update(func(tx *bolt.Tx) error {
+		// Records of an older schema are read the same way; the import
+		// must not hold records this go-synth cannot read
+		schema, err := readSchemaVersion(tx)
+		if err != nil {
+			return err
+		}
+		if header.Schema > schema {
+			return fmt.Errorf("dump has schema version %d, the database is at %d; upgrade go-synth", header.Schema, schema)
+		}
+
+		for line := 2; ; line++ {
+			var entry dumpEntry
+			if err := dec.Decode(&entry); err == io.EOF {
+				return nil
+			} else if err != nil {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+
+			var value []byte
+			switch {
+			case entry.Value != nil:
+				value = entry.Value
+			case entry.Text != nil:
+				value = []byte(*entry.Text)
+			case entry.Data != nil:
+				value = entry.Data
+			default:
+				return fmt.Errorf("line %d: entry %s/%q has no value", line, entry.Bucket, entry.Key)
+			}
+			if entry.Bucket == "" || entry.Key == "" {
+				return fmt.Errorf("line %d: entry without bucket or key", line)
+			}
+			if entry.Bucket == BucketMeta {
+				return fmt.Errorf("line %d: the %s bucket cannot be imported", line, BucketMeta)
+			}
+
+			b, err := tx.CreateBucketIfNotExists([]byte(entry.Bucket))
+			if err != nil {
+				return &DatabaseError{Op: "create bucket", Bucket: entry.Bucket, Err: err}
+			}
+			if err := b.Put([]byte(entry.Key), value); err != nil {
+				return err
+			}
+			counts[entry.Bucket]++
+		}
+	})
+	if err != nil {
+		return nil, &DatabaseError{Op: "import", Err: err}
+	}
+	return counts, nil
+}
@@ -44,6 +44,9 @@ var (
 
 	// ErrOrphanedRecord is returned when a record references non-existent data
 	ErrOrphanedRecord = fmt.Errorf("orphaned record reference")
+
+	// ErrSchemaTooNew is returned when opening a database written by a newer go-synth
+	ErrSchemaTooNew = fmt.Errorf("database schema is newer than supported")
 )
 
 // ==================== Structured Error Types ====================
@@ -82,6 +85,26 @@ func (e *DatabaseError) Unwrap() error {
 	return e.Err
 }
 
+// SchemaError reports a database whose schema version this go-synth cannot
+// use. It unwraps to ErrSchemaTooNew.
+type SchemaError struct {
+	// Found is the schema version stored in the database
+	Found int
+
+	// Supported is the newest schema version this go-synth knows
+	Supported int
+}
+
+// Error implements the error interface
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("database schema version %d is newer than the supported version %d; upgrade go-synth", e.Found, e.Supported)
+}
+
+// Unwrap allows errors.Is(err, ErrSchemaTooNew)
+func (e *SchemaError) Unwrap() error {
+	return ErrSchemaTooNew
+}
+
 // RecordError wraps build record operation errors with context about which
 // record was involved and what operation failed.
 //
@@ -0,0 +1,278 @@
+package builddb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// RetentionPolicy says which history Prune keeps. A record is kept if any
+// rule keeps it; a zero rule keeps nothing on its own, and a zero policy
+// keeps everything.
+type RetentionPolicy struct {
+	KeepRuns int           // Keep the most recent N build runs, with their packages
+	MaxAge   time.Duration // Keep runs and builds that started within this age
+}
+
+// IsZero reports whether p keeps everything.
+func (p RetentionPolicy) IsZero() bool {
+	return p.KeepRuns == 0 && p.MaxAge == 0
+}
+
+// PruneResult counts the records Prune deleted.
+type PruneResult struct {
+	Runs        int // build_runs entries
+	RunPackages int // run_packages entries of the deleted runs
+	Builds      int // builds entries
+	IndexKeys   int // packages entries pointing to deleted builds
+}
+
+// Total returns the number of records deleted.
+func (r PruneResult) Total() int {
+	return r.Runs + r.RunPackages + r.Builds + r.IndexKeys
+}
+
+// Prune deletes the build history policy does not keep, as of now.
+//
+// Runs still in progress are always kept. Build records are kept if they
+// started within MaxAge or no earlier than the oldest run kept by
+// KeepRuns. The latest successful build of every port is always kept, so
+// LatestFor and memory estimates keep working, and builds that are still
+// running are never touched. CRC entries are not history and stay.
+//
+// Deleting records does not shrink the file; see Compact.
+func (db *DB) Prune(policy RetentionPolicy, now time.Time) (PruneResult, error) {
+	var result PruneResult
+	if policy.IsZero() {
+		return result, nil
+	}
+
+	var cutoff time.Time
+	if policy.MaxAge > 0 {
+		cutoff = now.Add(-policy.MaxAge)
+	}
+
+	err := db.update(func(tx *bolt.Tx) error {
+		runs := tx.Bucket([]byte(BucketBuildRuns))
+		runPackages := tx.Bucket([]byte(BucketRunPackages))
+		builds := tx.Bucket([]byte(BucketBuilds))
+		packages := tx.Bucket([]byte(BucketPackages))
+		for name, b := range map[string]*bolt.Bucket{
+			BucketBuildRuns: runs, BucketRunPackages: runPackages,
+			BucketBuilds: builds, BucketPackages: packages,
+		} {
+			if b == nil {
+				return &DatabaseError{Op: "get bucket", Bucket: name, Err: ErrBucketNotFound}
+			}
+		}
+
+		// Runs, newest first
+		type run struct {
+			id  string
+			rec RunRecord
+		}
+		var all []run
+		err := runs.ForEach(func(k, v []byte) error {
+			var rec RunRecord
+			if err := json.Unmarshal(v, &rec); err != nil {
+				return &RecordError{Op: "unmarshal run", UUID: string(k), Err: err}
+			}
+			all = append(all, run{id: string(k), rec: rec})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		sort.Slice(all, func(i, j int) bool {
+			return all[i].rec.StartTime.After(all[j].rec.StartTime)
+		})
+
+		for i, r := range all {
+			keep := r.rec.EndTime.IsZero() ||
+				i < policy.KeepRuns ||
+				(!cutoff.IsZero() && !r.rec.StartTime.Before(cutoff))
+			if keep {
+				continue
+			}
+
+			n, err := deletePrefix(runPackages, runPackagePrefix(r.id))
+			if err != nil {
+				return err
+			}
+			result.RunPackages += n
+			if err := runs.Delete([]byte(r.id)); err != nil {
+				return err
+			}
+			result.Runs++
+		}
+
+		// Builds are kept if they started within MaxAge or since the
+		// oldest run KeepRuns keeps. While there are no more runs than
+		// KeepRuns, that is all of them.
+		buildCutoff := cutoff
+		if policy.KeepRuns > 0 {
+			if len(all) <= policy.KeepRuns {
+				return nil
+			}
+			oldest := all[policy.KeepRuns-1].rec.StartTime
+			if buildCutoff.IsZero() || oldest.Before(buildCutoff) {
+				buildCutoff = oldest
+			}
+		}
+
+		// The latest success per port survives any cutoff
+		latest := make(map[string]BuildRecord)
+		var expired []BuildRecord
+		err = builds.ForEach(func(k, v []byte) error {
+			var rec BuildRecord
+			if err := json.Unmarshal(v, &rec); err != nil {
+				return &RecordError{Op: "unmarshal", UUID: string(k), Err: err}
+			}
+			if rec.Status == "success" {
+				if cur, ok := latest[rec.PortDir]; !ok || rec.StartTime.After(cur.StartTime) {
+					latest[rec.PortDir] = rec
+				}
+			}
+			if rec.Status != "running" && rec.StartTime.Before(buildCutoff) {
+				expired = append(expired, rec)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		deleted := make(map[string]bool)
+		for _, rec := range expired {
+			if latest[rec.PortDir].UUID == rec.UUID {
+				continue
+			}
+			if err := builds.Delete([]byte(rec.UUID)); err != nil {
+				return err
+			}
+			deleted[rec.UUID] = true
+			result.Builds++
+		}
+
+		// Drop index entries that would now point nowhere
+		var stale [][]byte
+		err = packages.ForEach(func(k, v []byte) error {
+			if deleted[string(v)] {
+				stale = append(stale, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range stale {
+			if err := packages.Delete(k); err != nil {
+				return err
+			}
+			result.IndexKeys++
+		}
+		return nil
+	})
+
+	if err != nil {
+		return PruneResult{}, &DatabaseError{Op: "prune", Err: err}
+	}
+	return result, nil
+}
+
+// deletePrefix deletes every key of b starting with prefix and returns how
+// many there were.
+func deletePrefix(b *bolt.Bucket, prefix []byte) (int, error) {
+	var keys [][]byte
+	c := b.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		keys = append(keys, append([]byte(nil), k...))
+	}
+	for _, k := range keys {
+		if err := b.Delete(k); err != nil {
+			return 0, err
+		}
+	}
+	return len(keys), nil
+}
+
+// CompactResult reports the database file size around a Compact.
+type CompactResult struct {
+	SizeBefore int64
+	SizeAfter  int64
+}
+
+// compactTxMaxSize bounds the size of each transaction Compact copies in.
+const compactTxMaxSize = 64 << 20
+
+// Compact rewrites the database file without its free pages, reclaiming
+// the space left by deleted records. bbolt never shrinks a file on its
+// own.
+//
+// The copy is written next to the database and renamed over it, so a
+// failure leaves the original untouched. The database is reopened
+// afterwards; the DB stays usable either way. Compact must not run
+// concurrently with other operations on db.
+func (db *DB) Compact() (*CompactResult, error) {
+	src, err := db.acquire()
+	if err != nil {
+		return nil, err
+	}
+	released := false
+	defer func() {
+		if !released {
+			db.release()
+		}
+	}()
+
+	result := &CompactResult{}
+	if fi, err := os.Stat(db.path); err == nil {
+		result.SizeBefore = fi.Size()
+	}
+
+	tmpPath := db.path + ".compact"
+	os.Remove(tmpPath)
+	dst, err := bolt.Open(tmpPath, 0600, nil)
+	if err != nil {
+		return nil, &DatabaseError{Op: "compact", Err: err}
+	}
+	if err := bolt.Compact(dst, src, compactTxMaxSize); err != nil {
+		dst.Close()
+		os.Remove(tmpPath)
+		return nil, &DatabaseError{Op: "compact", Err: err}
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(tmpPath)
+		return nil, &DatabaseError{Op: "compact", Err: err}
+	}
+
+	// The original stays locked until the copy replaces it
+	renameErr := os.Rename(tmpPath, db.path)
+	if db.shared {
+		db.release() // closes the file
+		released = true
+	} else {
+		if err := db.db.Close(); err != nil {
+			return nil, &DatabaseError{Op: "compact", Err: err}
+		}
+		bdb, err := bolt.Open(db.path, 0600, nil)
+		if err != nil {
+			return nil, &DatabaseError{Op: "reopen", Err: err}
+		}
+		db.db = bdb
+	}
+	if renameErr != nil {
+		os.Remove(tmpPath)
+		return nil, &DatabaseError{Op: "compact", Err: fmt.Errorf("replace database: %w", renameErr)}
+	}
+
+	if fi, err := os.Stat(db.path); err == nil {
+		result.SizeAfter = fi.Size()
+	}
+	return result, nil
+}
@@ -0,0 +1,165 @@
+package builddb
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrune(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	// Nightly runs 1 to 10 days ago, plus one still running
+	for i := 1; i <= 10; i++ {
+		runID := fmt.Sprintf("run-%02d", i)
+		start := now.Add(-time.Duration(i) * day)
+		if err := db.StartRun(runID, start); err != nil {
+			t.Fatalf("StartRun failed: %v", err)
+		}
+		if err := db.FinishRun(runID, RunStats{Total: 1}, start.Add(time.Hour), false); err != nil {
+			t.Fatalf("FinishRun failed: %v", err)
+		}
+		if err := db.PutRunPackage(runID, &RunPackageRecord{PortDir: "editors/vim", Version: "9.1", Status: RunStatusSuccess}); err != nil {
+			t.Fatalf("PutRunPackage failed: %v", err)
+		}
+	}
+	if err := db.StartRun("run-active", now.Add(-30*day)); err != nil {
+		t.Fatalf("StartRun failed: %v", err)
+	}
+
+	// vim's only success is old; its later attempts failed
+	builds := []*BuildRecord{
+		{UUID: "vim-ok", PortDir: "editors/vim", Status: "success", StartTime: now.Add(-9 * day)},
+		{UUID: "vim-bad", PortDir: "editors/vim", Status: "failed", StartTime: now.Add(-8 * day)},
+		{UUID: "vim-new", PortDir: "editors/vim", Status: "failed", StartTime: now.Add(-1 * day)},
+		{UUID: "bash-old", PortDir: "shells/bash", Status: "success", StartTime: now.Add(-9 * day)},
+		{UUID: "bash-new", PortDir: "shells/bash", Status: "success", StartTime: now.Add(-2 * day)},
+		{UUID: "zsh-run", PortDir: "shells/zsh", Status: "running", StartTime: now.Add(-9 * day)},
+	}
+	for _, rec := range builds {
+		rec.EndTime = rec.StartTime.Add(time.Minute)
+		if err := db.SaveRecord(rec); err != nil {
+			t.Fatalf("SaveRecord failed: %v", err)
+		}
+	}
+	if err := db.UpdatePackageIndex("shells/bash", "5.1", "bash-old"); err != nil {
+		t.Fatalf("UpdatePackageIndex failed: %v", err)
+	}
+	if err := db.UpdatePackageIndex("shells/bash", "5.2", "bash-new"); err != nil {
+		t.Fatalf("UpdatePackageIndex failed: %v", err)
+	}
+
+	// A zero policy keeps everything
+	if result, err := db.Prune(RetentionPolicy{}, now); err != nil || result.Total() != 0 {
+		t.Fatalf("Prune(zero policy) = %+v, %v", result, err)
+	}
+
+	// Three runs, or a week: runs 1 to 7 and builds since then stay
+	result, err := db.Prune(RetentionPolicy{KeepRuns: 3, MaxAge: 7*day + time.Hour}, now)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	want := PruneResult{Runs: 3, RunPackages: 3, Builds: 2, IndexKeys: 1}
+	if result != want {
+		t.Errorf("Prune() = %+v, want %+v", result, want)
+	}
+
+	for _, runID := range []string{"run-07", "run-active"} {
+		if _, err := db.GetRun(runID); err != nil {
+			t.Errorf("run %s was pruned: %v", runID, err)
+		}
+	}
+	if _, err := db.GetRun("run-08"); err == nil {
+		t.Error("run-08 was kept")
+	}
+	if pkgs, _ := db.ListRunPackages("run-08"); len(pkgs) != 0 {
+		t.Errorf("run-08 packages were kept: %v", pkgs)
+	}
+
+	for _, uuid := range []string{"vim-ok", "vim-new", "bash-new", "zsh-run"} {
+		if _, err := db.GetRecord(uuid); err != nil {
+			t.Errorf("build %s was pruned: %v", uuid, err)
+		}
+	}
+	for _, uuid := range []string{"vim-bad", "bash-old"} {
+		if _, err := db.GetRecord(uuid); err == nil {
+			t.Errorf("build %s was kept", uuid)
+		}
+	}
+	if rec, err := db.LatestFor("shells/bash", "5.1"); err != nil || rec != nil {
+		t.Errorf("LatestFor(pruned build) = %v, %v; want nil, nil", rec, err)
+	}
+}
+
+func TestPrune_KeepRunsOnly(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+
+	now := time.Now()
+	for i := 1; i <= 3; i++ {
+		runID := fmt.Sprintf("run-%d", i)
+		start := now.Add(-time.Duration(i) * time.Hour)
+		db.StartRun(runID, start)
+		db.FinishRun(runID, RunStats{}, start.Add(time.Minute), false)
+	}
+	db.SaveRecord(&BuildRecord{UUID: "ancient", PortDir: "editors/vim", Status: "failed", StartTime: now.AddDate(-1, 0, 0)})
+
+	// Fewer runs than KeepRuns: nothing goes, however old
+	result, err := db.Prune(RetentionPolicy{KeepRuns: 5}, now)
+	if err != nil || result.Total() != 0 {
+		t.Errorf("Prune() = %+v, %v; want nothing pruned", result, err)
+	}
+
+	result, err = db.Prune(RetentionPolicy{KeepRuns: 2}, now)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if result.Runs != 1 || result.Builds != 1 {
+		t.Errorf("Prune() = %+v, want 1 run and 1 build", result)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	db, path := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+
+	log := strings.Repeat("x", 4096)
+	for i := 0; i < 500; i++ {
+		rec := createTestRecord(fmt.Sprintf("uuid-%d", i), "editors/vim", log, "failed")
+		rec.StartTime = time.Now().AddDate(-1, 0, 0)
+		if err := db.SaveRecord(rec); err != nil {
+			t.Fatalf("SaveRecord failed: %v", err)
+		}
+	}
+	if _, err := db.Prune(RetentionPolicy{MaxAge: time.Hour}, time.Now()); err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+
+	result, err := db.Compact()
+	if err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if result.SizeAfter >= result.SizeBefore {
+		t.Errorf("Compact() shrank %d bytes to %d", result.SizeBefore, result.SizeAfter)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Size() != result.SizeAfter {
+		t.Errorf("database file after Compact: %v, %v", fi, err)
+	}
+	if _, err := os.Stat(path + ".compact"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+
+	// The database is still usable
+	if err := db.UpdateCRC("editors/vim", 42); err != nil {
+		t.Errorf("UpdateCRC after Compact failed: %v", err)
+	}
+	if stats, err := db.Stats(); err != nil || stats.TotalBuilds != 0 {
+		t.Errorf("Stats() after Compact = %+v, %v", stats, err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 
 	bolt "go.etcd.io/bbolt"
@@ -15,6 +16,7 @@ const (
 	RunStatusFailed  = "failed"
 	RunStatusSkipped = "skipped"
 	RunStatusIgnored = "ignored"
+	RunStatusTimeout = "timeout" // Failed because a build timeout expired
 )
 
 // RunStats aggregates per-run port outcomes.
@@ -76,7 +78,7 @@ func (db *DB) GetRun(runID string) (*RunRecord, error) {
 	}
 
 	var rec RunRecord
-	err := db.db.View(func(tx *bolt.Tx) error {
+	err := db.view(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(BucketBuildRuns))
 		if bucket == nil {
 			return &DatabaseError{Op: "get bucket", Bucket: BucketBuildRuns, Err: ErrBucketNotFound}
@@ -100,7 +102,7 @@ func (db *DB) ActiveRun() (string, *RunRecord, error) {
 	var runID string
 	var rec *RunRecord
 
-	err := db.db.View(func(tx *bolt.Tx) error {
+	err := db.view(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(BucketBuildRuns))
 		if bucket == nil {
 			return &DatabaseError{Op: "get bucket", Bucket: BucketBuildRuns, Err: ErrBucketNotFound}
@@ -130,13 +132,53 @@ func (db *DB) ActiveRun() (string, *RunRecord, error) {
 	return runID, rec, nil
 }
 
+// RunEntry is a run record together with its run ID.
+type RunEntry struct {
+	ID string `json:"id"`
+	RunRecord
+}
+
+// ListRuns returns the most recent runs, newest first. A limit of 0
+// returns all runs.
+func (db *DB) ListRuns(limit int) ([]RunEntry, error) {
+	var runs []RunEntry
+
+	err := db.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketBuildRuns))
+		if bucket == nil {
+			return &DatabaseError{Op: "get bucket", Bucket: BucketBuildRuns, Err: ErrBucketNotFound}
+		}
+
+		return bucket.ForEach(func(k, v []byte) error {
+			entry := RunEntry{ID: string(k)}
+			if err := json.Unmarshal(v, &entry.RunRecord); err != nil {
+				return &RecordError{Op: "unmarshal run", UUID: string(k), Err: err}
+			}
+			runs = append(runs, entry)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Run IDs are random; order by start time
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].StartTime.After(runs[j].StartTime)
+	})
+	if limit > 0 && len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs, nil
+}
+
 // ClearActiveLocks marks all active runs (no end time) as aborted.
 // This is used by the cleanup command to clear stale build locks from
 // crashed or interrupted builds.
 func (db *DB) ClearActiveLocks() (int, error) {
 	cleared := 0
 
-	err := db.db.Update(func(tx *bolt.Tx) error {
+	err := db.update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(BucketBuildRuns))
 		if bucket == nil {
 			return &DatabaseError{Op: "get bucket", Bucket: BucketBuildRuns, Err: ErrBucketNotFound}
@@ -185,7 +227,7 @@ func (db *DB) PutRunPackage(runID string, pkg *RunPackageRecord) error {
 		return &RecordError{Op: "marshal run package", UUID: runID, Err: err}
 	}
 
-	return db.db.Update(func(tx *bolt.Tx) error {
+	return db.update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(BucketRunPackages))
 		if bucket == nil {
 			return &DatabaseError{Op: "get bucket", Bucket: BucketRunPackages, Err: ErrBucketNotFound}
@@ -203,7 +245,7 @@ func (db *DB) ListRunPackages(runID string) ([]RunPackageRecord, error) {
 	prefix := runPackagePrefix(runID)
 	var records []RunPackageRecord
 
-	err := db.db.View(func(tx *bolt.Tx) error {
+	err := db.view(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(BucketRunPackages))
 		if bucket == nil {
 			return &DatabaseError{Op: "get bucket", Bucket: BucketRunPackages, Err: ErrBucketNotFound}
@@ -242,7 +284,7 @@ func (db *DB) saveRunRecord(runID string, rec *RunRecord) error {
 		return &RecordError{Op: "marshal run", UUID: runID, Err: err}
 	}
 
-	return db.db.Update(func(tx *bolt.Tx) error {
+	return db.update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(BucketBuildRuns))
 		if bucket == nil {
 			return &DatabaseError{Op: "get bucket", Bucket: BucketBuildRuns, Err: ErrBucketNotFound}
@@ -252,7 +294,7 @@ func (db *DB) saveRunRecord(runID string, rec *RunRecord) error {
 }
 
 func (db *DB) updateRunRecord(runID string, mutate func(*RunRecord)) error {
-	return db.db.Update(func(tx *bolt.Tx) error {
+	return db.update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(BucketBuildRuns))
 		if bucket == nil {
 			return &DatabaseError{Op: "get bucket", Bucket: BucketBuildRuns, Err: ErrBucketNotFound}
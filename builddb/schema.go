@@ -0,0 +1,97 @@
+package builddb
+
+import (
+	"fmt"
+	"strconv"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// SchemaVersion is the database schema version this go-synth writes. New
+// databases are created at this version; older ones are brought up to it
+// by the migrations of the migration package.
+//
+// Databases created before schema versioning have no meta bucket and are
+// version 0.
+const SchemaVersion = 2
+
+// metaSchemaVersion is the meta bucket key holding the schema version as a
+// decimal string.
+var metaSchemaVersion = []byte("schema_version")
+
+// Migration upgrades the database schema from Version-1 to Version. Apply
+// runs inside the write transaction that records the new version, so a
+// failed migration leaves the database as it was.
+type Migration struct {
+	Version     int
+	Description string
+	Apply       func(tx *bolt.Tx) error
+}
+
+// readSchemaVersion returns the schema version recorded in tx, or 0 if
+// there is none.
+func readSchemaVersion(tx *bolt.Tx) (int, error) {
+	meta := tx.Bucket([]byte(BucketMeta))
+	if meta == nil {
+		return 0, nil
+	}
+	v := meta.Get(metaSchemaVersion)
+	if v == nil {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(string(v))
+	if err != nil {
+		return 0, fmt.Errorf("%w: schema version %q", ErrCorruptedData, v)
+	}
+	return version, nil
+}
+
+// writeSchemaVersion records version in tx.
+func writeSchemaVersion(tx *bolt.Tx, version int) error {
+	meta, err := tx.CreateBucketIfNotExists([]byte(BucketMeta))
+	if err != nil {
+		return &DatabaseError{Op: "create bucket", Bucket: BucketMeta, Err: err}
+	}
+	return meta.Put(metaSchemaVersion, []byte(strconv.Itoa(version)))
+}
+
+// SchemaVersion returns the schema version of the database.
+func (db *DB) SchemaVersion() (int, error) {
+	var version int
+	err := db.view(func(tx *bolt.Tx) error {
+		var err error
+		version, err = readSchemaVersion(tx)
+		return err
+	})
+	if err != nil {
+		return 0, &DatabaseError{Op: "read schema version", Bucket: BucketMeta, Err: err}
+	}
+	return version, nil
+}
+
+// ApplyMigration runs m and records m.Version as the schema version, in a
+// single transaction. The database must be at version m.Version-1.
+func (db *DB) ApplyMigration(m Migration) error {
+	err := db.update(func(tx *bolt.Tx) error {
+		version, err := readSchemaVersion(tx)
+		if err != nil {
+			return err
+		}
+		if version != m.Version-1 {
+			return fmt.Errorf("migration %d needs schema version %d, database is at %d", m.Version, m.Version-1, version)
+		}
+		if err := m.Apply(tx); err != nil {
+			return err
+		}
+		return writeSchemaVersion(tx, m.Version)
+	})
+	if err != nil {
+		return &DatabaseError{Op: fmt.Sprintf("migrate to schema %d", m.Version), Err: err}
+	}
+	return nil
+}
+
+// Path returns the path of the database file.
+func (db *DB) Path() string {
+	return db.path
+}
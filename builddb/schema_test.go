@@ -0,0 +1,102 @@
+package builddb
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// writeRawDB creates a database at path with bolt directly, bypassing
+// OpenDB, and runs fn in a write transaction.
+func writeRawDB(t *testing.T, path string, fn func(tx *bolt.Tx) error) {
+	t.Helper()
+	bdb, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatalf("bolt.Open failed: %v", err)
+	}
+	defer bdb.Close()
+	if err := bdb.Update(fn); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+}
+
+func TestSchemaVersion_New(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+
+	version, err := db.SchemaVersion()
+	if err != nil {
+		t.Fatalf("SchemaVersion failed: %v", err)
+	}
+	if version != SchemaVersion {
+		t.Errorf("new database at schema %d, want %d", version, SchemaVersion)
+	}
+}
+
+func TestSchemaVersion_Legacy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+	writeRawDB(t, path, func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucket([]byte(BucketBuilds))
+		return err
+	})
+
+	db, err := OpenDB(path)
+	if err != nil {
+		t.Fatalf("OpenDB failed: %v", err)
+	}
+	defer db.Close()
+
+	version, err := db.SchemaVersion()
+	if err != nil || version != 0 {
+		t.Fatalf("SchemaVersion = %d, %v; want 0 before migration", version, err)
+	}
+
+	// Migrations apply in order only
+	noop := func(*bolt.Tx) error { return nil }
+	if err := db.ApplyMigration(Migration{Version: 2, Apply: noop}); err == nil {
+		t.Error("ApplyMigration(2) on a version 0 database succeeded")
+	}
+	if err := db.ApplyMigration(Migration{Version: 1, Apply: noop}); err != nil {
+		t.Fatalf("ApplyMigration(1) failed: %v", err)
+	}
+	if version, _ := db.SchemaVersion(); version != 1 {
+		t.Errorf("SchemaVersion after migration = %d, want 1", version)
+	}
+
+	// A failed migration changes nothing
+	failing := Migration{Version: 2, Apply: func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket([]byte(BucketBuilds)); err != nil {
+			return err
+		}
+		return errors.New("boom")
+	}}
+	if err := db.ApplyMigration(failing); err == nil {
+		t.Error("failing migration succeeded")
+	}
+	if version, _ := db.SchemaVersion(); version != 1 {
+		t.Errorf("SchemaVersion after failed migration = %d, want 1", version)
+	}
+	verifyBucketsExist(t, db)
+}
+
+func TestOpenDB_NewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "newer.db")
+	writeRawDB(t, path, func(tx *bolt.Tx) error {
+		return writeSchemaVersion(tx, SchemaVersion+1)
+	})
+
+	db, err := OpenDB(path)
+	if err == nil {
+		db.Close()
+		t.Fatal("OpenDB of a newer schema succeeded")
+	}
+	if !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("OpenDB error = %v, want ErrSchemaTooNew", err)
+	}
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) || schemaErr.Found != SchemaVersion+1 {
+		t.Errorf("OpenDB error = %#v, want SchemaError with Found %d", err, SchemaVersion+1)
+	}
+}
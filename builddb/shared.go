@@ -0,0 +1,95 @@
+package builddb
+
+import (
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// sharedOpenTimeout bounds how long a shared DB waits for another process
+// to finish its transaction.
+const sharedOpenTimeout = 30 * time.Second
+
+// OpenDBShared opens the database at path like OpenDB, but holds the file
+// only for the duration of each transaction.
+//
+// bbolt locks the database file for as long as it is open, so a DB from
+// OpenDB keeps every other process out, even readers. A shared DB lets
+// several go-synth processes take turns: "status" and "monitor" can read
+// while a build is writing. Each transaction reopens the file, which costs
+// a little; concurrent transactions within the process share one opening.
+//
+// Shared DBs do not coordinate writers; the build base lock does that.
+func OpenDBShared(path string) (*DB, error) {
+	db, err := openDB(path, &bolt.Options{Timeout: sharedOpenTimeout})
+	if err != nil {
+		return nil, err
+	}
+	if err := db.db.Close(); err != nil {
+		return nil, &DatabaseError{Op: "close", Err: err}
+	}
+	db.db = nil
+	db.shared = true
+	return db, nil
+}
+
+// acquire returns the open bbolt handle, opening the file for a shared DB.
+// Every successful acquire must be paired with a release.
+func (db *DB) acquire() (*bolt.DB, error) {
+	if !db.shared {
+		if db.db == nil {
+			return nil, ErrDatabaseNotOpen
+		}
+		return db.db, nil
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		return nil, ErrDatabaseNotOpen
+	}
+	if db.users == 0 {
+		bdb, err := bolt.Open(db.path, 0600, &bolt.Options{Timeout: sharedOpenTimeout})
+		if err != nil {
+			return nil, &DatabaseError{Op: "open", Err: err}
+		}
+		db.db = bdb
+	}
+	db.users++
+	return db.db, nil
+}
+
+// release closes the file of a shared DB once no transaction uses it.
+func (db *DB) release() {
+	if !db.shared {
+		return
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.users--
+	if db.users == 0 {
+		db.db.Close()
+		db.db = nil
+	}
+}
+
+// view runs fn in a read transaction.
+func (db *DB) view(fn func(tx *bolt.Tx) error) error {
+	bdb, err := db.acquire()
+	if err != nil {
+		return err
+	}
+	defer db.release()
+	return bdb.View(fn)
+}
+
+// update runs fn in a write transaction.
+func (db *DB) update(fn func(tx *bolt.Tx) error) error {
+	bdb, err := db.acquire()
+	if err != nil {
+		return err
+	}
+	defer db.release()
+	return bdb.Update(fn)
+}
@@ -0,0 +1,67 @@
+package builddb
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// TestOpenDBShared opens one database twice, as two go-synth processes
+// would. bbolt's file lock belongs to the open file, so with OpenDB the
+// second open would wait for the first DB to be closed.
+func TestOpenDBShared(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "builds.db")
+
+	writer, err := OpenDBShared(path)
+	if err != nil {
+		t.Fatalf("OpenDBShared failed: %v", err)
+	}
+	defer writer.Close()
+	reader, err := OpenDBShared(path)
+	if err != nil {
+		t.Fatalf("second OpenDBShared failed: %v", err)
+	}
+	defer reader.Close()
+
+	if err := writer.SaveRecord(createTestRecord("uuid-1", "editors/vim", "9.1", "success")); err != nil {
+		t.Fatalf("SaveRecord failed: %v", err)
+	}
+	if _, err := reader.GetRecord("uuid-1"); err != nil {
+		t.Errorf("GetRecord through the second DB failed: %v", err)
+	}
+
+	// Concurrent transactions within one DB share the opened file
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			errs <- writer.UpdateCRC("editors/vim", uint32(i))
+		}()
+		go func() {
+			defer wg.Done()
+			_, _, err := reader.GetCRC("editors/vim")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("concurrent transaction failed: %v", err)
+		}
+	}
+
+	if _, err := writer.Compact(); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if _, err := reader.GetRecord("uuid-1"); err != nil {
+		t.Errorf("GetRecord after Compact failed: %v", err)
+	}
+
+	writer.Close()
+	if _, err := writer.GetRecord("uuid-1"); err == nil {
+		t.Error("GetRecord on a closed DB succeeded")
+	}
+}
@@ -0,0 +1,395 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go-synth/config"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// boolChoices are the DropDown options for boolean keys. The empty choice
+// leaves the key unset so it falls back to the global section or defaults.
+var boolChoices = []string{"", "yes", "no"}
+
+// configEditor is the tview state for `go-synth configure`.
+type configEditor struct {
+	app    *tview.Application
+	screen tcell.Screen // Optional injected screen (for testing)
+	pages  *tview.Pages
+	list   *tview.List
+	form   *tview.Form
+	status *tview.TextView
+	header *tview.TextView
+
+	file    *config.ConfigFile
+	section string                       // section shown in the form
+	pending map[string]map[string]string // section -> key -> edited value
+	dirty   bool
+	saved   bool
+}
+
+// DoConfigure implements the `go-synth configure` command, an interactive
+// editor for dsynth.ini profiles.
+//
+// Every key understood by the config loader can be edited per profile or in
+// the global section. Profiles can be added, removed and selected. Values are
+// validated before the file is written, and comments and unknown keys in the
+// existing file are preserved.
+//
+// Keys:
+//
+//	Tab/arrows  move between sections and fields
+//	Ctrl+S      validate and save
+//	Ctrl+N      add a profile
+//	Ctrl+D      delete the current profile
+//	Ctrl+P      make the current profile the selected profile
+//	Esc         quit (asks to confirm unsaved changes)
+func DoConfigure(cfg *config.Config, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("configure takes no arguments")
+	}
+
+	file, err := config.OpenConfigFile(cfg.ConfigPath)
+	if err != nil {
+		return err
+	}
+
+	ed := &configEditor{
+		file:    file,
+		section: config.GlobalSection,
+		pending: make(map[string]map[string]string),
+	}
+	if selected := file.SelectedProfile(); file.HasProfile(selected) {
+		ed.section = selected
+	}
+
+	if err := ed.run(); err != nil {
+		return err
+	}
+	if ed.saved {
+		fmt.Printf("✓ Configuration saved: %s\n", file.Path())
+	} else {
+		fmt.Println("Configuration not modified")
+	}
+	return nil
+}
+
+// run builds the layout and blocks until the editor exits.
+func (ed *configEditor) run() error {
+	ed.app = tview.NewApplication()
+	if ed.screen != nil {
+		ed.app.SetScreen(ed.screen)
+	}
+
+	ed.header = tview.NewTextView().SetDynamicColors(true)
+	ed.header.SetBorder(true).SetTitle(" go-synth configure ").SetTitleAlign(tview.AlignLeft)
+
+	ed.list = tview.NewList().ShowSecondaryText(false)
+	ed.list.SetBorder(true).SetTitle(" Sections ").SetTitleAlign(tview.AlignLeft)
+
+	ed.form = tview.NewForm()
+	ed.form.SetBorder(true).SetTitleAlign(tview.AlignLeft)
+
+	ed.status = tview.NewTextView().SetDynamicColors(true).SetWordWrap(true)
+	ed.status.SetBorder(true).SetTitle(" Status ").SetTitleAlign(tview.AlignLeft)
+
+	body := tview.NewFlex().
+		AddItem(ed.list, 28, 0, false).
+		AddItem(ed.form, 0, 1, true)
+
+	layout := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(ed.header, 4, 0, false).
+		AddItem(body, 0, 1, true).
+		AddItem(ed.status, 5, 0, false)
+
+	ed.pages = tview.NewPages().AddPage("main", layout, true, true)
+
+	ed.app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if name, _ := ed.pages.GetFrontPage(); name != "main" {
+			return event
+		}
+		switch event.Key() {
+		case tcell.KeyCtrlS:
+			ed.save()
+			return nil
+		case tcell.KeyCtrlN:
+			ed.promptAddProfile()
+			return nil
+		case tcell.KeyCtrlD:
+			ed.confirmDeleteProfile()
+			return nil
+		case tcell.KeyCtrlP:
+			ed.selectCurrentProfile()
+			return nil
+		case tcell.KeyEscape:
+			ed.quit()
+			return nil
+		}
+		return event
+	})
+
+	ed.refreshSections()
+	ed.showSection(ed.section)
+	ed.setStatus("[yellow]Ctrl+S[white] save  [yellow]Ctrl+N[white] new profile  [yellow]Ctrl+D[white] delete profile  [yellow]Ctrl+P[white] select profile  [yellow]Esc[white] quit")
+
+	return ed.app.SetRoot(ed.pages, true).EnableMouse(true).Run()
+}
+
+// refreshSections rebuilds the section list and header.
+func (ed *configEditor) refreshSections() {
+	ed.list.Clear()
+	sections := append([]string{config.GlobalSection}, ed.file.Profiles()...)
+	selected := ed.file.SelectedProfile()
+	for _, name := range sections {
+		label := name
+		if name == selected {
+			label += " *"
+		}
+		section := name
+		ed.list.AddItem(tview.Escape(label), "", 0, func() {
+			ed.showSection(section)
+			ed.app.SetFocus(ed.form)
+		})
+	}
+	for i, name := range sections {
+		if name == ed.section {
+			ed.list.SetCurrentItem(i)
+		}
+	}
+
+	if selected == "" {
+		selected = "(none)"
+	}
+	ed.header.SetText(fmt.Sprintf("[yellow]File:[white] %s\n[yellow]Selected profile:[white] %s",
+		tview.Escape(ed.file.Path()), tview.Escape(selected)))
+}
+
+// showSection rebuilds the form for a section, showing pending edits over
+// the values currently in the file.
+func (ed *configEditor) showSection(section string) {
+	ed.section = section
+	ed.form.Clear(true)
+	ed.form.SetTitle(tview.Escape(fmt.Sprintf(" [%s] ", section)))
+
+	for _, key := range config.KnownKeys {
+		key := key
+		value := ed.value(section, key.Name)
+
+		if key.Kind == config.KeyBool {
+			choices := boolChoices
+			current := boolChoiceIndex(value)
+			if current < 0 {
+				// Keep unrecognized values visible so they are not silently dropped
+				choices = append(append([]string(nil), boolChoices...), value)
+				current = len(choices) - 1
+			}
+			ed.form.AddDropDown(key.Name, choices, current, func(option string, _ int) {
+				ed.edit(section, key, option)
+			})
+			continue
+		}
+
+		ed.form.AddInputField(key.Name, value, 40, nil, func(text string) {
+			ed.edit(section, key, text)
+		})
+	}
+
+	ed.form.AddButton("Save", ed.save)
+	ed.form.AddButton("Quit", ed.quit)
+}
+
+// value returns the pending value for a key, or the value in the file.
+func (ed *configEditor) value(section, key string) string {
+	if v, ok := ed.pending[section][key]; ok {
+		return v
+	}
+	return ed.file.Get(section, key)
+}
+
+// edit records a pending value and reports validation problems immediately.
+func (ed *configEditor) edit(section string, key config.KeyInfo, value string) {
+	current := ed.value(section, key.Name)
+	if current == value {
+		return
+	}
+	if key.Kind == config.KeyBool && boolChoiceIndex(current) >= 0 && boolChoiceIndex(current) == boolChoiceIndex(value) {
+		return
+	}
+	if ed.pending[section] == nil {
+		ed.pending[section] = make(map[string]string)
+	}
+	ed.pending[section][key.Name] = value
+	ed.dirty = true
+
+	if err := config.ValidateKey(key.Name, value); err != nil {
+		ed.setStatus("[red]✗ " + tview.Escape(err.Error()))
+		return
+	}
+	if warning := config.PathWarning(key.Name, value); warning != "" {
+		ed.setStatus("[yellow]⚠ " + tview.Escape(warning))
+		return
+	}
+	ed.setStatus(fmt.Sprintf("%s: %s", key.Name, key.Description))
+}
+
+// save validates every pending value and writes the file.
+func (ed *configEditor) save() {
+	var errs []string
+	sections := make([]string, 0, len(ed.pending))
+	for section := range ed.pending {
+		sections = append(sections, section)
+	}
+	sort.Strings(sections)
+
+	for _, section := range sections {
+		for key, value := range ed.pending[section] {
+			if err := config.ValidateKey(key, value); err != nil {
+				errs = append(errs, fmt.Sprintf("[%s] %v", section, err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		ed.setStatus("[red]✗ Not saved:[white] " + tview.Escape(strings.Join(errs, "; ")))
+		return
+	}
+
+	for _, section := range sections {
+		for key, value := range ed.pending[section] {
+			if err := ed.file.Set(section, key, value); err != nil {
+				ed.setStatus("[red]✗ " + tview.Escape(err.Error()))
+				return
+			}
+		}
+	}
+
+	if err := ed.file.Save(); err != nil {
+		ed.setStatus("[red]✗ " + tview.Escape(err.Error()))
+		return
+	}
+
+	ed.pending = make(map[string]map[string]string)
+	ed.dirty = false
+	ed.saved = true
+	ed.setStatus("[green]✓ Saved " + tview.Escape(ed.file.Path()))
+}
+
+// promptAddProfile asks for a profile name and creates the section.
+func (ed *configEditor) promptAddProfile() {
+	input := tview.NewInputField().SetLabel("Profile name: ").SetFieldWidth(30)
+	input.SetDoneFunc(func(key tcell.Key) {
+		ed.pages.RemovePage("prompt")
+		if key != tcell.KeyEnter {
+			return
+		}
+		name := strings.TrimSpace(input.GetText())
+		if err := ed.file.AddProfile(name); err != nil {
+			ed.setStatus("[red]✗ " + tview.Escape(err.Error()))
+			return
+		}
+		if ed.file.SelectedProfile() == "" {
+			ed.file.SetSelectedProfile(name)
+		}
+		ed.dirty = true
+		ed.section = name
+		ed.refreshSections()
+		ed.showSection(name)
+		ed.app.SetFocus(ed.form)
+		ed.setStatus(fmt.Sprintf("Added profile %s (unsaved)", tview.Escape(name)))
+	})
+	input.SetBorder(true).SetTitle(" New profile ")
+
+	ed.pages.AddPage("prompt", centered(input, 50, 3), true, true)
+	ed.app.SetFocus(input)
+}
+
+// confirmDeleteProfile asks before removing the current profile section.
+func (ed *configEditor) confirmDeleteProfile() {
+	if ed.section == config.GlobalSection {
+		ed.setStatus("[red]✗ The global section cannot be deleted")
+		return
+	}
+	name := ed.section
+	ed.confirm(fmt.Sprintf("Delete profile %q?", name), func() {
+		if err := ed.file.RemoveProfile(name); err != nil {
+			ed.setStatus("[red]✗ " + tview.Escape(err.Error()))
+			return
+		}
+		delete(ed.pending, name)
+		ed.dirty = true
+		ed.section = config.GlobalSection
+		ed.refreshSections()
+		ed.showSection(config.GlobalSection)
+		ed.setStatus(fmt.Sprintf("Deleted profile %s (unsaved)", tview.Escape(name)))
+	})
+}
+
+// selectCurrentProfile sets profile_selected to the profile being edited.
+func (ed *configEditor) selectCurrentProfile() {
+	if err := ed.file.SetSelectedProfile(ed.section); err != nil {
+		ed.setStatus("[red]✗ Select a profile section first")
+		return
+	}
+	ed.dirty = true
+	ed.refreshSections()
+	ed.setStatus(fmt.Sprintf("profile_selected = %s (unsaved)", tview.Escape(ed.section)))
+}
+
+// quit exits, confirming first if there are unsaved changes.
+func (ed *configEditor) quit() {
+	if !ed.dirty {
+		ed.app.Stop()
+		return
+	}
+	ed.confirm("Discard unsaved changes?", ed.app.Stop)
+}
+
+// confirm shows a yes/no modal and runs onYes if confirmed.
+func (ed *configEditor) confirm(text string, onYes func()) {
+	modal := tview.NewModal().
+		SetText(text).
+		AddButtons([]string{"Yes", "No"}).
+		SetDoneFunc(func(_ int, label string) {
+			ed.pages.RemovePage("confirm")
+			ed.app.SetFocus(ed.form)
+			if label == "Yes" {
+				onYes()
+			}
+		})
+	ed.pages.AddPage("confirm", modal, true, true)
+}
+
+// setStatus replaces the status line text.
+func (ed *configEditor) setStatus(text string) {
+	ed.status.SetText(text)
+}
+
+// boolChoiceIndex maps a stored boolean value to its boolChoices index,
+// returning -1 for unrecognized values.
+func boolChoiceIndex(value string) int {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "":
+		return 0
+	case "yes", "on", "true", "1":
+		return 1
+	case "no", "off", "false", "0":
+		return 2
+	}
+	return -1
+}
+
+// centered wraps a primitive in a fixed-size box centered on the screen.
+func centered(p tview.Primitive, width, height int) tview.Primitive {
+	return tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(p, height, 1, true).
+			AddItem(nil, 0, 1, false), width, 1, true).
+		AddItem(nil, 0, 1, false)
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-synth/config"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// TestConfigEditor_EditAndSave drives the editor on a simulation screen and
+// verifies edits are written without losing unknown keys
+func TestConfigEditor_EditAndSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dsynth.ini")
+	content := "[Global Configuration]\nprofile_selected=LiveSystem\n\n[LiveSystem]\nNumber_of_builders=2\nTmpfs_workdir=on\nCustom_key=keep\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := config.OpenConfigFile(path)
+	if err != nil {
+		t.Fatalf("OpenConfigFile failed: %v", err)
+	}
+
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatalf("Failed to init simulation screen: %v", err)
+	}
+	screen.SetSize(120, 50)
+
+	ed := &configEditor{
+		file:    file,
+		section: "LiveSystem",
+		pending: make(map[string]map[string]string),
+		screen:  screen,
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- ed.run() }()
+	time.Sleep(200 * time.Millisecond)
+
+	jobs, _ := config.LookupKey("Max_jobs_per_builder")
+	builders, _ := config.LookupKey("Number_of_builders")
+	result := make(chan bool, 1)
+	ed.app.QueueUpdateDraw(func() {
+		// Loading the form must not mark equivalent boolean spellings as edits
+		clean := !ed.dirty
+
+		// Invalid values block saving
+		ed.edit("LiveSystem", builders, "0")
+		ed.save()
+		blocked := !ed.saved
+
+		ed.edit("LiveSystem", builders, "4")
+		ed.edit("LiveSystem", jobs, "3")
+		ed.save()
+		result <- clean && blocked && ed.saved && !ed.dirty
+	})
+
+	select {
+	case ok := <-result:
+		if !ok {
+			t.Error("unexpected editor state (dirty after load, invalid save accepted, or save failed)")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for editor update")
+	}
+
+	ed.app.QueueEvent(tcell.NewEventKey(tcell.KeyEscape, 0, 0))
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("editor returned error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("editor did not exit on Esc")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := string(data)
+	for _, want := range []string{"Custom_key", "Max_jobs_per_builder", "= 3", "= 4"} {
+		if !strings.Contains(saved, want) {
+			t.Errorf("saved config missing %q:\n%s", want, saved)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"go-synth/config"
+	"go-synth/log"
+)
+
+// DoLogs implements the `go-synth logs` command for port build logs.
+//
+// Usage:
+//
+//	go-synth logs PORT                 # Latest build log of PORT
+//	go-synth logs PORT --previous      # The build log before the latest
+//	go-synth logs PORT --build UUID    # The log of build UUID (or a prefix of it)
+//	go-synth logs PORT --list          # The kept build logs of PORT
+//
+// Earlier logs are stored compressed and are decompressed on the fly.
+func DoLogs(cfg *config.Config, args []string) error {
+	var port, buildID string
+	previous, list := false, false
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--build", "-b":
+			if i+1 >= len(args) {
+				return fmt.Errorf("%s requires a build UUID argument", args[i])
+			}
+			i++
+			buildID = args[i]
+		case "--previous":
+			previous = true
+		case "--list":
+			list = true
+		default:
+			if port != "" {
+				return fmt.Errorf("unknown logs argument: %s", args[i])
+			}
+			port = args[i]
+		}
+	}
+	if port == "" {
+		return fmt.Errorf("no port specified")
+	}
+	if buildID != "" && previous {
+		return fmt.Errorf("--build and --previous are mutually exclusive")
+	}
+
+	if list {
+		return listPortLogs(cfg, port)
+	}
+
+	var f log.LogFile
+	switch {
+	case buildID != "":
+		var err error
+		if f, err = log.FindPortLog(cfg, port, buildID); err != nil {
+			return err
+		}
+	case previous:
+		logs, err := log.PortLogHistory(cfg, port)
+		if err != nil {
+			return err
+		}
+		if len(logs) < 2 {
+			return fmt.Errorf("no earlier build log of %s", port)
+		}
+		f = logs[1]
+	default:
+		// The latest log, through the symlink, or as written without history
+		f = log.LogFile{Path: log.PackageLogPath(cfg, port)}
+	}
+
+	r, err := f.Open()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("log file not found: %s", f.Path)
+		}
+		return err
+	}
+	defer r.Close()
+	_, err = io.Copy(os.Stdout, r)
+	return err
+}
+
+// listPortLogs prints the kept build logs of port, newest first.
+func listPortLogs(cfg *config.Config, port string) error {
+	logs, err := log.PortLogHistory(cfg, port)
+	if err != nil {
+		return err
+	}
+	if len(logs) == 0 {
+		fmt.Printf("No build logs kept for %s\n", port)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BUILD\tWRITTEN\tSIZE\t")
+	for i, f := range logs {
+		note := ""
+		switch {
+		case i == 0:
+			note = "latest"
+		case f.Compressed:
+			note = "compressed"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", f.BuildID, f.ModTime.Format("2006-01-02 15:04:05"), f.Size, note)
+	}
+	return w.Flush()
+}
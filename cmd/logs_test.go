@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go-synth/config"
+	"go-synth/log"
+)
+
+func TestDoLogs(t *testing.T) {
+	cfg := &config.Config{LogsPath: t.TempDir(), LogHistory: 5}
+	for i, build := range []string{"a1b2c3d4-1111", "e5f6a7b8-2222", "c9d0e1f2-3333"} {
+		pl := log.NewPackageLogger(cfg, "editors/vim", build)
+		pl.WriteString("log of " + build + "\n")
+		pl.Close()
+		mtime := time.Now().Add(time.Duration(i-3) * time.Hour)
+		path := log.BuildLogPath(cfg, "editors/vim", build)
+		if _, err := os.Stat(path); err != nil {
+			path += ".gz"
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"editors/vim"}, "log of c9d0e1f2-3333\n"},
+		{[]string{"editors/vim", "--previous"}, "log of e5f6a7b8-2222\n"},
+		{[]string{"editors/vim", "--build", "a1b2c3d4"}, "log of a1b2c3d4-1111\n"},
+	}
+	for _, tt := range tests {
+		var err error
+		out := captureStdout(t, func() { err = DoLogs(cfg, tt.args) })
+		if err != nil || out != tt.want {
+			t.Errorf("DoLogs(%v) = %q, %v, want %q", tt.args, out, err, tt.want)
+		}
+	}
+
+	out := captureStdout(t, func() { DoLogs(cfg, []string{"editors/vim", "--list"}) })
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[1], "c9d0e1f2-3333") || !strings.Contains(lines[1], "latest") ||
+		!strings.Contains(lines[3], "compressed") {
+		t.Errorf("--list = %q", out)
+	}
+
+	if err := DoLogs(cfg, []string{"shells/bash", "--previous"}); err == nil {
+		t.Error("--previous of a port without history succeeded")
+	}
+	if err := DoLogs(cfg, []string{"editors/vim", "--build", "x", "--previous"}); err == nil {
+		t.Error("--build with --previous accepted")
+	}
+}
@@ -1,9 +1,13 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"go-synth/builddb"
@@ -17,6 +21,7 @@ import (
 // Usage:
 //
 //	go-synth monitor              # Watch active build from BuildDB (default)
+//	go-synth monitor --worker N   # Follow the build log of worker N
 //	go-synth monitor --file PATH  # Watch legacy monitor.dat file
 //	go-synth monitor export PATH  # Export current snapshot to file
 func DoMonitor(cfg *config.Config, args []string) error {
@@ -36,12 +41,27 @@ func DoMonitor(cfg *config.Config, args []string) error {
 		return doMonitorFile(args[1])
 	}
 
+	// Check for --worker flag
+	follow := -1
+	if len(args) > 0 && (args[0] == "--worker" || args[0] == "-w") {
+		if len(args) < 2 {
+			return fmt.Errorf("--worker requires a worker number")
+		}
+		n, err := strconv.Atoi(strings.TrimPrefix(strings.ToUpper(args[1]), "SL"))
+		if err != nil || n < 0 {
+			return fmt.Errorf("--worker: expected a worker number such as 0 or SL00, got %s", args[1])
+		}
+		follow = n
+	}
+
 	// Default: watch BuildDB
-	return doMonitorBuildDB(cfg)
+	return doMonitorBuildDB(cfg, follow)
 }
 
-// doMonitorBuildDB polls BuildDB's ActiveRunSnapshot() every second and displays stats
-func doMonitorBuildDB(cfg *config.Config) error {
+// doMonitorBuildDB polls BuildDB's active run every second and displays
+// its stats and worker table, or, when follow is a worker ID, follows the
+// build log of whatever that worker is building.
+func doMonitorBuildDB(cfg *config.Config, follow int) error {
 	// Open BuildDB
 	dbPath := cfg.Database.Path
 	if dbPath == "" {
@@ -49,13 +69,17 @@ func doMonitorBuildDB(cfg *config.Config) error {
 		dbPath = filepath.Join(cfg.BuildBase, "builds.db")
 	}
 
-	db, err := builddb.OpenDB(dbPath)
+	db, err := builddb.OpenDBShared(dbPath)
 	if err != nil {
 		return fmt.Errorf("failed to open builddb: %w", err)
 	}
 	defer db.Close()
 
-	fmt.Println("Monitoring active build (press Ctrl+C to exit)...")
+	if follow >= 0 {
+		fmt.Printf("Following the build log of worker SL%02d (press Ctrl+C to exit)...\n", follow)
+	} else {
+		fmt.Println("Monitoring active build (press Ctrl+C to exit)...")
+	}
 	fmt.Println()
 
 	ticker := time.NewTicker(1 * time.Second)
@@ -64,10 +88,9 @@ func doMonitorBuildDB(cfg *config.Config) error {
 	// Track if we've seen an active build
 	lastRunID := ""
 	noActiveBuildCount := 0
+	logs := &logFollower{worker: follow}
 
 	for {
-		// TODO: Replace with db.ActiveRunSnapshot() once Phase 3 BuildDB implementation is complete
-		// For now, use ActiveRun() and return placeholder data
 		runID, rec, err := db.ActiveRun()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error reading active run: %v\n", err)
@@ -77,21 +100,7 @@ func doMonitorBuildDB(cfg *config.Config) error {
 
 		var snapshot *stats.TopInfo
 		if rec != nil {
-			// Create placeholder TopInfo from RunRecord
-			// In Phase 3, this will be read from rec.LiveSnapshot JSON field
-			snapshot = &stats.TopInfo{
-				ActiveWorkers: 0, // Unknown without stats collector
-				MaxWorkers:    8, // Placeholder
-				DynMaxWorkers: 8,
-				Elapsed:       time.Since(rec.StartTime),
-				StartTime:     rec.StartTime,
-				Built:         rec.Stats.Success,
-				Failed:        rec.Stats.Failed,
-				Ignored:       rec.Stats.Ignored,
-				Skipped:       rec.Stats.Skipped,
-				Queued:        rec.Stats.Total,
-				Remaining:     rec.Stats.Total - (rec.Stats.Success + rec.Stats.Failed + rec.Stats.Ignored),
-			}
+			snapshot = runSnapshot(rec)
 		}
 
 		// No active build
@@ -102,6 +111,7 @@ func doMonitorBuildDB(cfg *config.Config) error {
 				fmt.Printf("No active build... (checked %d times)\r", noActiveBuildCount)
 			}
 			lastRunID = ""
+			logs.finish()
 			<-ticker.C
 			continue
 		}
@@ -109,25 +119,139 @@ func doMonitorBuildDB(cfg *config.Config) error {
 		// Active build found - reset counter
 		noActiveBuildCount = 0
 
-		// Print header if new build detected
-		if runID != lastRunID {
-			fmt.Printf("\n\n")
-			fmt.Printf("═══════════════════════════════════════════════════════════════════════\n")
-			fmt.Printf(" Build Run: %s\n", runID[:8])
-			fmt.Printf("═══════════════════════════════════════════════════════════════════════\n")
-			lastRunID = runID
+		if follow >= 0 {
+			if runID != lastRunID {
+				fmt.Printf("\n═══ Build Run: %s ═══\n", runID[:8])
+				lastRunID = runID
+			}
+			logs.update(snapshot.Workers)
+			<-ticker.C
+			continue
 		}
 
-		// Clear screen for cleaner display (optional - comment out if annoying)
-		// fmt.Print("\033[2J\033[H")
+		// Redraw the whole screen; the worker table does not fit a line
+		fmt.Print("\033[2J\033[H")
+		fmt.Printf("═══════════════════════════════════════════════════════════════════════\n")
+		fmt.Printf(" Build Run: %s\n", runID[:8])
+		fmt.Printf("═══════════════════════════════════════════════════════════════════════\n")
+		lastRunID = runID
 
 		// Display current stats
 		displaySnapshot(*snapshot)
+		displayWorkers(snapshot.Workers, time.Now())
 
 		<-ticker.C
 	}
 }
 
+// runSnapshot returns the live stats of an active run. Runs of go-synth
+// versions that did not record snapshots get totals from the run record.
+func runSnapshot(rec *builddb.RunRecord) *stats.TopInfo {
+	if rec.LiveSnapshot != "" {
+		var info stats.TopInfo
+		if err := json.Unmarshal([]byte(rec.LiveSnapshot), &info); err == nil {
+			return &info
+		}
+	}
+
+	return &stats.TopInfo{
+		Elapsed:   time.Since(rec.StartTime),
+		StartTime: rec.StartTime,
+		Built:     rec.Stats.Success,
+		Failed:    rec.Stats.Failed,
+		Ignored:   rec.Stats.Ignored,
+		Skipped:   rec.Stats.Skipped,
+		Queued:    rec.Stats.Total,
+		Remaining: rec.Stats.Total - (rec.Stats.Success + rec.Stats.Failed + rec.Stats.Ignored),
+	}
+}
+
+// displayWorkers prints the worker table, in the layout of dsynth's
+// ncurses display.
+func displayWorkers(workers []stats.WorkerSlot, now time.Time) {
+	if len(workers) == 0 {
+		return
+	}
+
+	fmt.Printf(" %-4s %-9s %-14s %s\n", "ID", "DURATION", "STATUS", "ORIGIN")
+	for _, w := range workers {
+		if w.Idle() {
+			fmt.Printf(" SL%02d %-9s %-14s\n", w.ID, "--:--:--", "idle")
+			continue
+		}
+		phase := w.Phase
+		if phase == "" {
+			phase = "starting"
+		}
+		origin := w.PortDir
+		if w.MemoryHeavy > 0 {
+			origin += fmt.Sprintf(" [memory-heavy ~%s]", config.FormatSize(w.MemoryHeavy))
+		}
+		fmt.Printf(" SL%02d %-9s %-14s %s\n", w.ID, stats.FormatDuration(now.Sub(w.Started)), phase, origin)
+	}
+	fmt.Println()
+}
+
+// logFollower copies the build log of one worker to stdout, moving on to
+// the next log whenever the worker starts another port.
+type logFollower struct {
+	worker  int
+	path    string // Log being followed, empty when the worker is idle
+	offset  int64  // Bytes of path already copied
+	idle    bool   // "idle" was reported
+	missing bool   // The worker was reported missing
+}
+
+// update follows the log of the worker's current port.
+func (f *logFollower) update(workers []stats.WorkerSlot) {
+	if f.worker >= len(workers) {
+		if !f.missing {
+			fmt.Printf("This build has no worker SL%02d (workers: %d)\n", f.worker, len(workers))
+			f.missing = true
+		}
+		return
+	}
+	f.missing = false
+
+	slot := workers[f.worker]
+	if slot.LogPath != f.path {
+		f.finish()
+		if slot.Idle() {
+			if !f.idle {
+				fmt.Printf("\n─── SL%02d idle ───\n", f.worker)
+				f.idle = true
+			}
+			return
+		}
+		f.path, f.offset, f.idle = slot.LogPath, 0, false
+		fmt.Printf("\n─── SL%02d: %s (%s) ───\n", f.worker, slot.PortDir, slot.LogPath)
+	}
+	f.copy()
+}
+
+// finish copies what is left of the current log and stops following it.
+func (f *logFollower) finish() {
+	if f.path != "" {
+		f.copy()
+		f.path, f.offset = "", 0
+	}
+}
+
+// copy writes the part of the log not yet copied.
+func (f *logFollower) copy() {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return // Not created yet
+	}
+	defer file.Close()
+
+	if info, err := file.Stat(); err == nil && info.Size() < f.offset {
+		f.offset = 0 // Recreated
+	}
+	n, _ := io.Copy(os.Stdout, io.NewSectionReader(file, f.offset, 1<<62))
+	f.offset += n
+}
+
 // displaySnapshot formats and prints a TopInfo snapshot to stdout
 func displaySnapshot(info stats.TopInfo) {
 	fmt.Printf("\r%-100s\r", "") // Clear line
@@ -198,7 +322,7 @@ func doMonitorExport(cfg *config.Config, exportPath string) error {
 		dbPath = filepath.Join(cfg.BuildBase, "builds.db")
 	}
 
-	db, err := builddb.OpenDB(dbPath)
+	db, err := builddb.OpenDBShared(dbPath)
 	if err != nil {
 		return fmt.Errorf("failed to open builddb: %w", err)
 	}
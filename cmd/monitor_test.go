@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-synth/builddb"
+	"go-synth/stats"
+)
+
+// captureStdout runs fn and returns what it wrote to stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+	fn()
+	w.Close()
+	return <-done
+}
+
+func TestRunSnapshot(t *testing.T) {
+	rec := &builddb.RunRecord{
+		StartTime:    time.Now(),
+		Stats:        builddb.RunStats{Total: 10, Success: 4},
+		LiveSnapshot: `{"Built":5,"Queued":12,"Workers":[{"ID":0,"PortDir":"editors/vim","Phase":"build"}]}`,
+	}
+	info := runSnapshot(rec)
+	if info.Built != 5 || info.Queued != 12 || len(info.Workers) != 1 || info.Workers[0].Phase != "build" {
+		t.Errorf("runSnapshot = %+v, want the live snapshot", info)
+	}
+
+	// Runs without a snapshot fall back to the run totals
+	rec.LiveSnapshot = ""
+	info = runSnapshot(rec)
+	if info.Built != 4 || info.Queued != 10 || info.Remaining != 6 {
+		t.Errorf("runSnapshot without snapshot = %+v, want run totals", info)
+	}
+}
+
+func TestDisplayWorkers(t *testing.T) {
+	now := time.Now()
+	out := captureStdout(t, func() {
+		displayWorkers([]stats.WorkerSlot{
+			{ID: 0, PortDir: "editors/vim", Phase: "build", Started: now.Add(-90 * time.Second)},
+			{ID: 1},
+			{ID: 2, PortDir: "www/chromium", Phase: "build", Started: now, MemoryHeavy: 12 << 30},
+		}, now)
+	})
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("worker table = %q, want a header and 3 rows", out)
+	}
+	if !strings.Contains(lines[1], "SL00") || !strings.Contains(lines[1], "00:01:30") ||
+		!strings.Contains(lines[1], "build") || !strings.Contains(lines[1], "editors/vim") {
+		t.Errorf("busy row = %q", lines[1])
+	}
+	if !strings.Contains(lines[2], "SL01") || !strings.Contains(lines[2], "idle") {
+		t.Errorf("idle row = %q", lines[2])
+	}
+	if strings.Contains(lines[1], "memory-heavy") || !strings.Contains(lines[3], "www/chromium [memory-heavy ~12G]") {
+		t.Errorf("memory-heavy mark in rows %q and %q", lines[1], lines[3])
+	}
+}
+
+func TestLogFollower(t *testing.T) {
+	dir := t.TempDir()
+	vimLog := filepath.Join(dir, "editors___vim.log")
+	bashLog := filepath.Join(dir, "shells___bash.log")
+	if err := os.WriteFile(vimLog, []byte("vim 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f := &logFollower{worker: 1}
+	vim := []stats.WorkerSlot{{ID: 0}, {ID: 1, PortDir: "editors/vim", LogPath: vimLog}}
+	bash := []stats.WorkerSlot{{ID: 0}, {ID: 1, PortDir: "shells/bash", LogPath: bashLog}}
+
+	out := captureStdout(t, func() { f.update(vim) })
+	if !strings.Contains(out, "SL01: editors/vim") || !strings.Contains(out, "vim 1\n") {
+		t.Errorf("first update = %q, want the header and the log so far", out)
+	}
+
+	// Output written before the worker moves on is not lost
+	appendFile(t, vimLog, "vim 2\n")
+	if err := os.WriteFile(bashLog, []byte("bash 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out = captureStdout(t, func() { f.update(bash) })
+	if !strings.Contains(out, "vim 2\n") || !strings.Contains(out, "SL01: shells/bash") || !strings.Contains(out, "bash 1\n") {
+		t.Errorf("switch update = %q, want the end of the vim log, then the bash log", out)
+	}
+	if strings.Contains(out, "vim 1") {
+		t.Errorf("switch update repeated old output: %q", out)
+	}
+
+	out = captureStdout(t, func() {
+		f.update([]stats.WorkerSlot{{ID: 0}, {ID: 1}})
+		f.update([]stats.WorkerSlot{{ID: 0}, {ID: 1}})
+	})
+	if strings.Count(out, "idle") != 1 {
+		t.Errorf("idle updates = %q, want idle reported once", out)
+	}
+
+	out = captureStdout(t, func() { (&logFollower{worker: 5}).update(vim) })
+	if !strings.Contains(out, "no worker SL05") {
+		t.Errorf("missing worker = %q", out)
+	}
+}
+
+func appendFile(t *testing.T, path, data string) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
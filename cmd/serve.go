@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"go-synth/builddb"
+	"go-synth/config"
+	"go-synth/web"
+)
+
+// DoServe implements the `go-synth serve` command: the read-only status
+// API and dashboard of package web, until interrupted.
+//
+// Usage:
+//
+//	go-synth serve                 # Listen on Http_listen (default 127.0.0.1:8080)
+//	go-synth serve --listen ADDR   # Listen on ADDR, e.g. :8080 for all interfaces
+//
+// Like monitor, serve opens the build database in shared mode and does not
+// take the build base lock, so it runs alongside builds.
+func DoServe(cfg *config.Config, args []string) error {
+	addr := cfg.HTTPListen
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--listen", "-l":
+			if i+1 >= len(args) {
+				return fmt.Errorf("%s requires an address argument", args[i])
+			}
+			i++
+			if err := config.ValidateKey("Http_listen", args[i]); err != nil {
+				return err
+			}
+			addr = args[i]
+		default:
+			return fmt.Errorf("unknown serve argument: %s", args[i])
+		}
+	}
+
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = filepath.Join(cfg.BuildBase, "builds.db")
+	}
+	db, err := builddb.OpenDBShared(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open builddb: %w", err)
+	}
+	defer db.Close()
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Requests end with ctx, so open log streams do not hold up shutdown
+	srv := &http.Server{
+		Handler:           web.New(cfg, db),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("Serving build status on http://%s/ (press Ctrl+C to exit)\n", ln.Addr())
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -5,7 +5,10 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"gopkg.in/ini.v1"
 )
@@ -13,7 +16,8 @@ import (
 // Config holds go-synth configuration
 type Config struct {
 	Profile    string
-	ConfigPath string
+	ConfigPath string   // System dsynth.ini (or the one under -C)
+	Files      []string // Config files actually loaded, lowest precedence first
 
 	BuildBase      string
 	DPortsPath     string
@@ -22,12 +26,27 @@ type Config struct {
 	DistFilesPath  string
 	OptionsPath    string
 	LogsPath       string
+	LogFormat      string // Log_format: text (default), json or both
+	LogHistory     int    // Log_history: earlier build logs kept per port
 	CCachePath     string
 	SystemPath     string
 
-	MaxWorkers int
-	MaxJobs    int
-	SlowStart  int
+	MaxWorkers    int
+	MaxJobs       int
+	JobsOverrides map[string]int // Per-port MaxJobs, keyed by origin or origin@flavor
+	AdaptiveJobs  bool           // Raise make jobs for builds started at the tail of a run
+	SlowStart     int
+
+	Limits          Limits            // Build_limits
+	LimitsOverrides map[string]string // Build_limits_overrides specs, keyed by origin or origin@flavor
+
+	Timeouts          Timeouts          // Build_timeouts
+	TimeoutsOverrides map[string]string // Build_timeouts_overrides specs, keyed by origin or origin@flavor
+
+	// Build pipeline; see Pipeline
+	Phases     []Phase                      // Build_phases, nil for DefaultPhases
+	PhaseHooks map[string]string            // Build_phase_hooks scripts, keyed by phase
+	PhaseEnv   map[string]map[string]string // Build_phase_env variables, keyed by phase
 
 	UseCCache    bool
 	UseUsrSrc    bool
@@ -40,20 +59,66 @@ type Config struct {
 	YesAll          bool
 	DevMode         bool
 	CheckPlist      bool
+	TestMode        bool // Run the test phase and resolve TEST_DEPENDS (go-synth test)
 	DisableUI       bool
 	DisableThrottle bool // Disable worker throttling based on system load/swap
 
+	// Memory-aware scheduling
+	Memory struct {
+		Target   int64 // Memory_target in bytes, 0 for no limit
+		Estimate int64 // Memory_default_estimate in bytes
+	}
+
 	// Migration settings
 	Migration struct {
 		AutoMigrate  bool // Default: true
 		BackupLegacy bool // Default: true
 	}
 
+	// Prebuilt package settings
+	Prebuilt struct {
+		Enabled    bool   // leverage_prebuilt
+		Repository string // Directory or URL of a pkg repository
+	}
+
+	// Previous versions of each package kept when pruning obsolete
+	// packages from the repository
+	KeepObsolete int // Keep_obsolete_packages
+
+	// Hook scripts run on the host, keyed by event (see HookEvent)
+	Hooks       map[string]string
+	HookTimeout time.Duration // Hook_timeout
+
 	// Database settings
 	Database struct {
-		Path       string // Default: ${BuildBase}/builds.db
-		AutoVacuum bool   // Default: true
-	}
+		Path        string // Default: ${BuildBase}/builds.db
+		AutoVacuum  bool   // Default: true
+		KeepRuns    int    // Database_keep_runs, 0 for no limit
+		KeepDays    int    // Database_keep_days, 0 for no limit
+		KeepBackups int    // Default: 5, 0 for no limit
+	}
+
+	// Notifications of run ends and port failures (see package notify)
+	Notify struct {
+		Events          []string // Default: run-end
+		NewFailuresOnly bool     // Notify_new_failures_only
+		Webhook         string   // Notify_webhook
+		WebhookTemplate string   // Notify_webhook_template
+		EmailTo         string   // Comma-separated addresses, empty for no mail
+		EmailFrom       string   // Default: go-synth@{hostname}
+		EmailTemplate   string   // Notify_email_template
+		SMTPServer      string   // Default: localhost:25
+	}
+
+	// Address builds serve metrics on, empty for none
+	MetricsListen string // Metrics_listen
+
+	// Address of the status API and dashboard (go-synth serve)
+	HTTPListen string // Http_listen
+
+	// sources records where each dsynth.ini key's effective value came
+	// from; keys without an entry hold their default.
+	sources map[string]string
 }
 
 var globalConfig *Config
@@ -68,7 +133,172 @@ func SetConfig(cfg *Config) {
 	globalConfig = cfg
 }
 
-// LoadConfig loads configuration from file
+// SourceDefault is reported by Source for values that were not set
+// explicitly.
+const SourceDefault = "default"
+
+// SetSource records where the effective value of a dsynth.ini key came
+// from, e.g. "dsynth.ini [LiveSystem]" or "command line (-S)".
+func (cfg *Config) SetSource(key, source string) {
+	if cfg.sources == nil {
+		cfg.sources = make(map[string]string)
+	}
+	cfg.sources[key] = source
+}
+
+// Source returns where the effective value of a dsynth.ini key came from.
+func (cfg *Config) Source(key string) string {
+	if src, ok := cfg.sources[key]; ok {
+		return src
+	}
+	return SourceDefault
+}
+
+// Value returns the effective value of a dsynth.ini key formatted as it
+// would appear in the file, or an empty string if the key is unset or
+// not backed by a Config field.
+func (cfg *Config) Value(key string) string {
+	switch key {
+	case "Directory_buildbase":
+		return cfg.BuildBase
+	case "Directory_portsdir":
+		return cfg.DPortsPath
+	case "Directory_repository":
+		return cfg.RepositoryPath
+	case "Directory_packages":
+		return cfg.PackagesPath
+	case "Directory_distfiles":
+		return cfg.DistFilesPath
+	case "Directory_options":
+		return cfg.OptionsPath
+	case "Directory_logs":
+		return cfg.LogsPath
+	case "Directory_ccache":
+		return cfg.CCachePath
+	case "Directory_system":
+		return cfg.SystemPath
+	case "Number_of_builders":
+		return strconv.Itoa(cfg.MaxWorkers)
+	case "Max_jobs_per_builder":
+		return strconv.Itoa(cfg.MaxJobs)
+	case "Max_jobs_overrides":
+		return FormatPortInts(cfg.JobsOverrides)
+	case "Adaptive_jobs":
+		return boolToYesNo(cfg.AdaptiveJobs)
+	case "Build_limits":
+		return cfg.Limits.String()
+	case "Build_limits_overrides":
+		return FormatPortSpecs(cfg.LimitsOverrides)
+	case "Build_timeouts":
+		return cfg.Timeouts.String()
+	case "Build_timeouts_overrides":
+		return FormatPortSpecs(cfg.TimeoutsOverrides)
+	case "Build_phases":
+		return FormatPhases(cfg.pipeline())
+	case "Build_phase_hooks":
+		return FormatPhaseHooks(cfg.PhaseHooks)
+	case "Build_phase_env":
+		return FormatPhaseEnv(cfg.PhaseEnv)
+	case "Memory_target":
+		return FormatSize(cfg.Memory.Target)
+	case "Memory_default_estimate":
+		return FormatSize(cfg.Memory.Estimate)
+	case "Tmpfs_workdir", "Tmpfs_localbase":
+		return boolToYesNo(cfg.UseTmpfs)
+	case "Display_with_ncurses":
+		return boolToYesNo(!cfg.DisableUI)
+	case "Disable_throttle":
+		return boolToYesNo(cfg.DisableThrottle)
+	case "leverage_prebuilt":
+		return boolToYesNo(cfg.Prebuilt.Enabled)
+	case "Prebuilt_repository":
+		return cfg.Prebuilt.Repository
+	case "Migration_auto_migrate":
+		return boolToYesNo(cfg.Migration.AutoMigrate)
+	case "Migration_backup_legacy":
+		return boolToYesNo(cfg.Migration.BackupLegacy)
+	case "Keep_obsolete_packages":
+		return strconv.Itoa(cfg.KeepObsolete)
+	case "Hook_run_start", "Hook_run_end", "Hook_port_start", "Hook_port_success", "Hook_port_failure", "Hook_port_skip":
+		return cfg.Hooks[HookEvent(key)]
+	case "Hook_timeout":
+		return cfg.HookTimeout.String()
+	case "Database_path":
+		return cfg.Database.Path
+	case "Database_auto_vacuum":
+		return boolToYesNo(cfg.Database.AutoVacuum)
+	case "Database_keep_runs":
+		return strconv.Itoa(cfg.Database.KeepRuns)
+	case "Database_keep_days":
+		return strconv.Itoa(cfg.Database.KeepDays)
+	case "Database_keep_backups":
+		return strconv.Itoa(cfg.Database.KeepBackups)
+	case "Log_format":
+		return cfg.LogFormat
+	case "Log_history":
+		return strconv.Itoa(cfg.LogHistory)
+	case "Notify_on":
+		return strings.Join(cfg.Notify.Events, " ")
+	case "Notify_new_failures_only":
+		return boolToYesNo(cfg.Notify.NewFailuresOnly)
+	case "Notify_webhook":
+		return cfg.Notify.Webhook
+	case "Notify_webhook_template":
+		return cfg.Notify.WebhookTemplate
+	case "Notify_email_to":
+		return cfg.Notify.EmailTo
+	case "Notify_email_from":
+		return cfg.Notify.EmailFrom
+	case "Notify_email_template":
+		return cfg.Notify.EmailTemplate
+	case "Notify_smtp_server":
+		return cfg.Notify.SMTPServer
+	case "Metrics_listen":
+		return cfg.MetricsListen
+	case "Http_listen":
+		return cfg.HTTPListen
+	}
+	return ""
+}
+
+// DefaultMemoryEstimate is the memory assumed for a build of a port with no
+// recorded peak, unless Memory_default_estimate says otherwise.
+const DefaultMemoryEstimate = 1 << 30
+
+// DefaultHTTPListen is the address go-synth serve listens on unless
+// Http_listen says otherwise. It is reachable from this host only.
+const DefaultHTTPListen = "127.0.0.1:8080"
+
+// EnvPrefix is prepended to an upper-cased dsynth.ini key to form the
+// environment variable overriding it, e.g. GOSYNTH_NUMBER_OF_BUILDERS.
+// GOSYNTH_PROFILE selects the profile and GOSYNTH_USER_CONFIG names the
+// per-user config file.
+const EnvPrefix = "GOSYNTH_"
+
+// UserConfigPath returns the per-user dsynth.ini layered over the system
+// file: $GOSYNTH_USER_CONFIG if set, otherwise go-synth/dsynth.ini under
+// the user's config directory. It returns an empty string if neither can
+// be determined.
+func UserConfigPath() string {
+	if path := os.Getenv(EnvPrefix + "USER_CONFIG"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "go-synth", "dsynth.ini")
+}
+
+// LoadConfig loads configuration from the layered sources, lowest
+// precedence first: built-in defaults, the system dsynth.ini, the per-user
+// dsynth.ini, then GOSYNTH_* environment variables. Command-line flags are
+// applied by the caller with Override. Within a file, profile settings
+// take precedence over the global section.
+//
+// configDir replaces /etc/dsynth as the location of the system file; when
+// it is given the per-user file is not consulted, so -C fully determines
+// the file-based configuration.
 func LoadConfig(configDir, profile string) (*Config, error) {
 	// Determine sensible defaults based on system resources
 	defaultWorkers := runtime.NumCPU()
@@ -87,66 +317,83 @@ func LoadConfig(configDir, profile string) (*Config, error) {
 		MaxWorkers: defaultWorkers,
 		MaxJobs:    1,
 	}
+	cfg.Limits.Nice = DefaultNice
+	cfg.Memory.Estimate = DefaultMemoryEstimate
+	cfg.HookTimeout = DefaultHookTimeout
 
-	// Determine config file path
+	// Determine config file paths
 	configFile := "/etc/dsynth/dsynth.ini"
 	if configDir != "" {
 		configFile = configDir + "/dsynth.ini"
 	}
 	cfg.ConfigPath = configFile
 
-	// Try to load config file
-	configFileExists := false
-	if _, err := os.Stat(configFile); err == nil {
-		configFileExists = true
-		iniFile, err := ini.Load(configFile)
-		if err != nil {
-			return nil, fmt.Errorf("failed to load config file: %w", err)
+	paths := []string{configFile}
+	if configDir == "" {
+		if userFile := UserConfigPath(); userFile != "" && userFile != configFile {
+			paths = append(paths, userFile)
 		}
+	}
 
-		// If no profile specified, read from global section
-		if cfg.Profile == "" || cfg.Profile == "default" {
-			globalSec := iniFile.Section("Global Configuration")
-			if globalSec == nil {
-				globalSec = iniFile.Section("global configuration")
-			}
-			if globalSec == nil {
-				globalSec = iniFile.Section("Global")
-			}
-
-			if globalSec != nil {
-				if key := globalSec.Key("profile_selected"); key != nil {
-					cfg.Profile = key.String()
-					if cfg.Profile != "" {
-						fmt.Printf("Auto-selected profile from config: %s\n", cfg.Profile)
-					}
+	// Load every config file that exists
+	var files []*ini.File
+	var loaded []string
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		iniFile, err := ini.Load(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config file %s: %w", path, err)
+		}
+		files = append(files, iniFile)
+		loaded = append(loaded, path)
+	}
+	cfg.Files = loaded
+
+	// If no profile specified, use GOSYNTH_PROFILE or the last file's
+	// profile_selected
+	if cfg.Profile == "" || cfg.Profile == "default" {
+		if env := os.Getenv(EnvPrefix + "PROFILE"); env != "" {
+			cfg.Profile = env
+		} else {
+			for _, iniFile := range files {
+				globalSec := findSection(iniFile, GlobalSection)
+				if globalSec != nil && globalSec.HasKey("profile_selected") {
+					cfg.Profile = globalSec.Key("profile_selected").String()
 				}
 			}
-		}
-
-		// Load the profile section
-		if cfg.Profile != "" {
-			profileSec := iniFile.Section(cfg.Profile)
-			if profileSec != nil {
-				cfg.loadFromSection(profileSec)
+			if cfg.Profile != "" && len(files) > 0 {
+				fmt.Printf("Auto-selected profile from config: %s\n", cfg.Profile)
 			}
 		}
+	}
 
-		// Also load from global section for any unset values
-		globalSec := iniFile.Section("Global Configuration")
-		if globalSec == nil {
-			globalSec = iniFile.Section("global configuration")
-		}
-		if globalSec != nil {
-			cfg.loadFromSection(globalSec)
+	for i, iniFile := range files {
+		// Global section first so the profile can override it
+		cfg.loadFromSection(findSection(iniFile, GlobalSection), loaded[i])
+		if cfg.Profile != "" {
+			cfg.loadFromSection(findSection(iniFile, cfg.Profile), loaded[i])
 		}
 	}
 
 	// Warn if no config file was found and using defaults
-	if !configFileExists {
+	if len(files) == 0 {
 		fmt.Fprintf(os.Stderr, "Warning: No config file found at %s\n", configFile)
 		fmt.Fprintf(os.Stderr, "Using defaults: %d workers (detected from CPU count)\n", cfg.MaxWorkers)
 		fmt.Fprintf(os.Stderr, "Run 'go-synth init' to create a config file, or override with config file settings.\n")
+	} else if cfg.Source("Display_with_ncurses") == SourceDefault {
+		// A config file without Display_with_ncurses has always meant
+		// running without the ncurses UI.
+		cfg.DisableUI = true
+	}
+
+	warnings, err := cfg.loadFromEnv(os.Environ())
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
 	}
 
 	// Apply defaults for unset paths
@@ -182,119 +429,236 @@ func LoadConfig(configDir, profile string) (*Config, error) {
 		cfg.CCachePath = cfg.BuildBase + "/ccache"
 	}
 
-	// Apply defaults for Migration settings (default to true)
-	// These are only false if explicitly set in config
-	if !cfg.Migration.AutoMigrate && !cfg.Migration.BackupLegacy {
-		// Neither was explicitly set, apply defaults
+	// Migration settings default to true unless explicitly set
+	if cfg.Source("Migration_auto_migrate") == SourceDefault {
 		cfg.Migration.AutoMigrate = true
+	}
+	if cfg.Source("Migration_backup_legacy") == SourceDefault {
 		cfg.Migration.BackupLegacy = true
-	} else if cfg.Migration.AutoMigrate && !cfg.Migration.BackupLegacy {
-		// AutoMigrate was set but BackupLegacy wasn't, default it
-		cfg.Migration.BackupLegacy = true
-	} else if !cfg.Migration.AutoMigrate && cfg.Migration.BackupLegacy {
-		// BackupLegacy was set but AutoMigrate wasn't, default it
-		cfg.Migration.AutoMigrate = true
 	}
 
 	// Apply defaults for Database settings
 	if cfg.Database.Path == "" {
 		cfg.Database.Path = cfg.BuildBase + "/builds.db"
 	}
-	cfg.Database.AutoVacuum = true // Always default to true for MVP
+	if cfg.Source("Database_auto_vacuum") == SourceDefault {
+		cfg.Database.AutoVacuum = true
+	}
+	if cfg.Source("Database_keep_backups") == SourceDefault {
+		cfg.Database.KeepBackups = 5
+	}
+	if cfg.LogFormat == "" {
+		cfg.LogFormat = LogFormatText
+	}
+	if cfg.Source("Log_history") == SourceDefault {
+		cfg.LogHistory = DefaultLogHistory
+	}
+	if cfg.Source("Notify_on") == SourceDefault {
+		cfg.Notify.Events = []string{NotifyRunEnd}
+	}
+	if cfg.Notify.SMTPServer == "" {
+		cfg.Notify.SMTPServer = DefaultSMTPServer
+	}
+	if cfg.HTTPListen == "" {
+		cfg.HTTPListen = DefaultHTTPListen
+	}
 
 	return cfg, nil
 }
 
-// loadFromSection loads config values from an INI section
-func (cfg *Config) loadFromSection(sec *ini.Section) {
+// loadFromSection loads config values from an INI section of the file at
+// path. Malformed values are skipped; ValidateFile reports them.
+func (cfg *Config) loadFromSection(sec *ini.Section, path string) {
 	// Skip if section is nil
 	if sec == nil {
 		return
 	}
 
-	// Directory paths
-	if key := sec.Key("Directory_buildbase"); key != nil && key.String() != "" {
-		cfg.BuildBase = key.String()
-	}
-	if key := sec.Key("Directory_portsdir"); key != nil && key.String() != "" {
-		cfg.DPortsPath = key.String()
-	}
-	if key := sec.Key("Directory_repository"); key != nil && key.String() != "" {
-		cfg.RepositoryPath = key.String()
-	}
-	if key := sec.Key("Directory_packages"); key != nil && key.String() != "" {
-		cfg.PackagesPath = key.String()
-	}
-	if key := sec.Key("Directory_distfiles"); key != nil && key.String() != "" {
-		cfg.DistFilesPath = key.String()
-	}
-	if key := sec.Key("Directory_options"); key != nil && key.String() != "" {
-		cfg.OptionsPath = key.String()
-	}
-	if key := sec.Key("Directory_logs"); key != nil && key.String() != "" {
-		cfg.LogsPath = key.String()
-	}
-	if key := sec.Key("Directory_ccache"); key != nil && key.String() != "" {
-		cfg.CCachePath = key.String()
+	src := fmt.Sprintf("%s [%s]", path, sec.Name())
+	for _, info := range KnownKeys {
+		if !sec.HasKey(info.Name) {
+			continue
+		}
+		_ = cfg.Override(info.Name, sec.Key(info.Name).String(), src)
 	}
-	if key := sec.Key("Directory_system"); key != nil && key.String() != "" {
-		cfg.SystemPath = key.String()
+
+	// Tmpfs_workdir and Tmpfs_localbase share UseTmpfs; either enables it
+	work := sec.HasKey("Tmpfs_workdir") && parseBool(sec.Key("Tmpfs_workdir").String())
+	local := sec.HasKey("Tmpfs_localbase") && parseBool(sec.Key("Tmpfs_localbase").String())
+	if work || local {
+		cfg.UseTmpfs = true
 	}
+}
 
-	// Worker settings
-	if key := sec.Key("Number_of_builders"); key != nil {
-		if n, err := key.Int(); err == nil && n > 0 {
-			cfg.MaxWorkers = n
+// loadFromEnv applies GOSYNTH_<KEY> overrides from environ. Malformed
+// values are errors: they usually come from CI definitions where a silent
+// fallback would go unnoticed. Unknown variables are only returned as
+// warnings, since GOSYNTH_* variables not meant for go-synth, such as those
+// of a parent go-synth's hooks, must not stop every command.
+func (cfg *Config) loadFromEnv(environ []string) ([]string, error) {
+	var warnings []string
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, EnvPrefix) {
+			continue
 		}
-	}
-	if key := sec.Key("Max_jobs_per_builder"); key != nil {
-		if n, err := key.Int(); err == nil && n > 0 {
-			cfg.MaxJobs = n
+		suffix := strings.TrimPrefix(name, EnvPrefix)
+		if suffix == "PROFILE" || suffix == "USER_CONFIG" || slices.Contains(HookVariables, suffix) {
+			continue
 		}
-	}
 
-	// Boolean options
-	if key := sec.Key("Tmpfs_workdir"); key != nil {
-		cfg.UseTmpfs = cfg.UseTmpfs || parseBool(key.String())
-	}
-	if key := sec.Key("Tmpfs_localbase"); key != nil {
-		cfg.UseTmpfs = cfg.UseTmpfs || parseBool(key.String())
-	}
-	if key := sec.Key("Display_with_ncurses"); key != nil {
-		cfg.DisableUI = !parseBool(key.String())
-	}
-	if key := sec.Key("Disable_throttle"); key != nil {
-		cfg.DisableThrottle = parseBool(key.String())
-	}
-	if key := sec.Key("leverage_prebuilt"); key != nil {
-		// TODO: Implement if needed
-		_ = key
-	}
+		key := ""
+		for _, info := range KnownKeys {
+			if strings.EqualFold(info.Name, suffix) {
+				key = info.Name
+				break
+			}
+		}
+		if key == "" {
+			if s := suggestKey(suffix); s != "" {
+				warnings = append(warnings, fmt.Sprintf("ignoring unknown environment override %s (did you mean %s%s?)", name, EnvPrefix, strings.ToUpper(s)))
+			} else {
+				warnings = append(warnings, fmt.Sprintf("ignoring unknown environment override %s", name))
+			}
+			continue
+		}
 
-	// Migration settings
-	if key := sec.Key("Migration_auto_migrate"); key != nil {
-		cfg.Migration.AutoMigrate = parseBool(key.String())
-	}
-	if key := sec.Key("Migration_backup_legacy"); key != nil {
-		cfg.Migration.BackupLegacy = parseBool(key.String())
+		if err := cfg.Override(key, value, "environment ("+name+")"); err != nil {
+			return nil, fmt.Errorf("invalid environment override %s: %w", name, err)
+		}
 	}
+	return warnings, nil
+}
 
-	// Database settings
-	if key := sec.Key("Database_path"); key != nil && key.String() != "" {
-		cfg.Database.Path = key.String()
-	}
-	if key := sec.Key("Database_auto_vacuum"); key != nil {
-		cfg.Database.AutoVacuum = parseBool(key.String())
-	}
+// Override sets a dsynth.ini key from a string value and records source as
+// its origin. The value is validated as in ValidateKey; an empty value
+// leaves the current setting untouched.
+func (cfg *Config) Override(key, value, source string) error {
+	if err := ValidateKey(key, value); err != nil {
+		return err
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	b, _ := parseStrictBool(value)
+	n, _ := strconv.Atoi(value)
+
+	switch key {
+	case "Directory_buildbase":
+		cfg.BuildBase = value
+	case "Directory_portsdir":
+		cfg.DPortsPath = value
+	case "Directory_repository":
+		cfg.RepositoryPath = value
+	case "Directory_packages":
+		cfg.PackagesPath = value
+	case "Directory_distfiles":
+		cfg.DistFilesPath = value
+	case "Directory_options":
+		cfg.OptionsPath = value
+	case "Directory_logs":
+		cfg.LogsPath = value
+	case "Directory_ccache":
+		cfg.CCachePath = value
+	case "Directory_system":
+		cfg.SystemPath = value
+	case "Number_of_builders":
+		cfg.MaxWorkers = n
+	case "Max_jobs_per_builder":
+		cfg.MaxJobs = n
+	case "Max_jobs_overrides":
+		cfg.JobsOverrides, _ = ParsePortInts(value)
+	case "Adaptive_jobs":
+		cfg.AdaptiveJobs = b
+	case "Build_limits":
+		cfg.Limits, _ = Limits{Nice: DefaultNice}.With(value)
+	case "Build_limits_overrides":
+		cfg.LimitsOverrides, _ = ParsePortLimits(value)
+	case "Build_timeouts":
+		cfg.Timeouts, _ = Timeouts{}.With(value)
+	case "Build_timeouts_overrides":
+		cfg.TimeoutsOverrides, _ = ParsePortTimeouts(value)
+	case "Build_phases":
+		cfg.Phases, _ = ParsePhases(value)
+	case "Build_phase_hooks":
+		cfg.PhaseHooks, _ = ParsePhaseHooks(value)
+	case "Build_phase_env":
+		cfg.PhaseEnv, _ = ParsePhaseEnv(value)
+	case "Memory_target":
+		cfg.Memory.Target, _ = ParseSize(value)
+	case "Memory_default_estimate":
+		cfg.Memory.Estimate, _ = ParseSize(value)
+	case "Tmpfs_workdir", "Tmpfs_localbase":
+		cfg.UseTmpfs = b
+	case "Display_with_ncurses":
+		cfg.DisableUI = !b
+	case "Disable_throttle":
+		cfg.DisableThrottle = b
+	case "leverage_prebuilt":
+		cfg.Prebuilt.Enabled = b
+	case "Prebuilt_repository":
+		cfg.Prebuilt.Repository = value
+	case "Migration_auto_migrate":
+		cfg.Migration.AutoMigrate = b
+	case "Migration_backup_legacy":
+		cfg.Migration.BackupLegacy = b
+	case "Keep_obsolete_packages":
+		cfg.KeepObsolete = n
+	case "Hook_run_start", "Hook_run_end", "Hook_port_start", "Hook_port_success", "Hook_port_failure", "Hook_port_skip":
+		if cfg.Hooks == nil {
+			cfg.Hooks = make(map[string]string)
+		}
+		cfg.Hooks[HookEvent(key)] = value
+	case "Hook_timeout":
+		cfg.HookTimeout, _ = time.ParseDuration(value)
+	case "Database_path":
+		cfg.Database.Path = value
+	case "Database_auto_vacuum":
+		cfg.Database.AutoVacuum = b
+	case "Database_keep_runs":
+		cfg.Database.KeepRuns = n
+	case "Database_keep_days":
+		cfg.Database.KeepDays = n
+	case "Database_keep_backups":
+		cfg.Database.KeepBackups = n
+	case "Log_format":
+		cfg.LogFormat = value
+	case "Log_history":
+		cfg.LogHistory = n
+	case "Notify_on":
+		cfg.Notify.Events, _ = ParseNotifyEvents(value)
+	case "Notify_new_failures_only":
+		cfg.Notify.NewFailuresOnly = b
+	case "Notify_webhook":
+		cfg.Notify.Webhook = value
+	case "Notify_webhook_template":
+		cfg.Notify.WebhookTemplate = value
+	case "Notify_email_to":
+		cfg.Notify.EmailTo = value
+	case "Notify_email_from":
+		cfg.Notify.EmailFrom = value
+	case "Notify_email_template":
+		cfg.Notify.EmailTemplate = value
+	case "Notify_smtp_server":
+		cfg.Notify.SMTPServer = value
+	case "Metrics_listen":
+		cfg.MetricsListen = value
+	case "Http_listen":
+		cfg.HTTPListen = value
+	}
+
+	cfg.SetSource(key, source)
+	return nil
 }
 
 func parseBool(s string) bool {
 	if b, err := strconv.ParseBool(s); err == nil {
 		return b
 	}
-	// Handle yes/no
-	s = s
-	return s == "yes" || s == "Yes" || s == "YES" || s == "1" || s == "on" || s == "On" || s == "ON"
+	b, _ := parseStrictBool(s)
+	return b
 }
 
 func boolToYesNo(b bool) string {
@@ -349,7 +713,12 @@ func SaveConfig(path string, cfg *Config) error {
 	setStr("Directory_system", cfg.SystemPath)
 
 	section.Key("Number_of_builders").SetValue(strconv.Itoa(cfg.MaxWorkers))
-	section.Key("Max_jobs_per_builder").SetValue(strconv.Itoa(cfg.MaxJobs))
+	// Left out unless set, so the ports default keeps applying
+	if cfg.Source("Max_jobs_per_builder") != SourceDefault {
+		section.Key("Max_jobs_per_builder").SetValue(strconv.Itoa(cfg.MaxJobs))
+	}
+
+	setStr("Memory_target", FormatSize(cfg.Memory.Target))
 
 	section.Key("Tmpfs_workdir").SetValue(boolToYesNo(cfg.UseTmpfs))
 	section.Key("Tmpfs_localbase").SetValue(boolToYesNo(cfg.UseTmpfs))
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 
 	"gopkg.in/ini.v1"
@@ -208,8 +209,7 @@ func TestConfig_GlobalFallback(t *testing.T) {
 	configFile := filepath.Join(tempDir, "dsynth.ini")
 
 	// Write test config with profile and global section
-	// Note: Current implementation has global override profile (not fallback)
-	// Profile sets some values, global section will override if present
+	// Global values apply for anything the profile does not set
 	configContent := `[Global Configuration]
 Directory_portsdir=/global/ports
 Number_of_builders=10
@@ -558,3 +558,155 @@ func TestSaveConfigWritesIni(t *testing.T) {
 		t.Fatalf("ConfigPath not updated, got %s want %s", cfg.ConfigPath, configPath)
 	}
 }
+
+func TestConfig_ProfileOverridesGlobal(t *testing.T) {
+	tempDir := t.TempDir()
+	configContent := `[Global Configuration]
+profile_selected=Live
+Number_of_builders=10
+
+[Live]
+Number_of_builders=3
+`
+	if err := os.WriteFile(filepath.Join(tempDir, "dsynth.ini"), []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	cfg, err := LoadConfig(tempDir, "")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.MaxWorkers != 3 {
+		t.Errorf("MaxWorkers = %d, want 3 (profile overrides global)", cfg.MaxWorkers)
+	}
+}
+
+func TestConfig_EnvOverrides(t *testing.T) {
+	tempDir := t.TempDir()
+	configContent := `[Global Configuration]
+profile_selected=Live
+
+[Live]
+Directory_packages=/ini/packages
+Number_of_builders=3
+Disable_throttle=no
+`
+	if err := os.WriteFile(filepath.Join(tempDir, "dsynth.ini"), []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	t.Setenv("GOSYNTH_DIRECTORY_PACKAGES", "/ci/packages")
+	t.Setenv("GOSYNTH_NUMBER_OF_BUILDERS", "7")
+	t.Setenv("GOSYNTH_DISABLE_THROTTLE", "yes")
+
+	cfg, err := LoadConfig(tempDir, "")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if cfg.PackagesPath != "/ci/packages" {
+		t.Errorf("PackagesPath = %q, want /ci/packages", cfg.PackagesPath)
+	}
+	if cfg.MaxWorkers != 7 {
+		t.Errorf("MaxWorkers = %d, want 7", cfg.MaxWorkers)
+	}
+	if !cfg.DisableThrottle {
+		t.Error("DisableThrottle = false, want true")
+	}
+	if got := cfg.Source("Number_of_builders"); got != "environment (GOSYNTH_NUMBER_OF_BUILDERS)" {
+		t.Errorf("Source(Number_of_builders) = %q", got)
+	}
+	// Derived defaults follow the overridden value
+	if cfg.RepositoryPath != "/build/synth/packages" {
+		t.Errorf("RepositoryPath = %q, want /build/synth/packages", cfg.RepositoryPath)
+	}
+}
+
+func TestConfig_InvalidEnvOverride(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"GOSYNTH_NUMBER_OF_BUILDERS", "abc", "not a number"},
+		{"GOSYNTH_DIRECTORY_LOGS", "relative", "must be absolute"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(tt.name, tt.value)
+			_, err := LoadConfig(t.TempDir(), "")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadConfig error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_UnknownEnvOverride(t *testing.T) {
+	cfg := &Config{}
+	warnings, err := cfg.loadFromEnv([]string{
+		"GOSYNTH_NUMBR_OF_BUILDERS=4",
+		"GOSYNTH_SOMETHING_ELSE=1",
+		"GOSYNTH_RUN_ID=run-1",
+		"GOSYNTH_NUMBER_OF_BUILDERS=6",
+	})
+	if err != nil {
+		t.Fatalf("unknown variables should not be errors: %v", err)
+	}
+	if len(warnings) != 2 || !strings.Contains(warnings[0], "did you mean GOSYNTH_NUMBER_OF_BUILDERS?") ||
+		!strings.Contains(warnings[1], "GOSYNTH_SOMETHING_ELSE") {
+		t.Errorf("warnings = %q", warnings)
+	}
+	if cfg.MaxWorkers != 6 {
+		t.Errorf("MaxWorkers = %d, want the known override applied", cfg.MaxWorkers)
+	}
+}
+
+func TestConfig_UserConfigLayer(t *testing.T) {
+	tempDir := t.TempDir()
+	userFile := filepath.Join(tempDir, "user.ini")
+	userContent := `[Global Configuration]
+Directory_logs=/home/me/logs
+`
+	if err := os.WriteFile(userFile, []byte(userContent), 0644); err != nil {
+		t.Fatalf("Failed to write user config: %v", err)
+	}
+	t.Setenv("GOSYNTH_USER_CONFIG", userFile)
+
+	// The user layer applies only when -C is not given
+	cfg, err := LoadConfig("", "")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.LogsPath != "/home/me/logs" {
+		t.Errorf("LogsPath = %q, want /home/me/logs", cfg.LogsPath)
+	}
+	if cfg.Source("Directory_logs") != userFile+" [Global Configuration]" {
+		t.Errorf("Source(Directory_logs) = %q", cfg.Source("Directory_logs"))
+	}
+
+	cfg, err = LoadConfig(t.TempDir(), "")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.LogsPath == "/home/me/logs" {
+		t.Error("user config applied despite explicit config directory")
+	}
+}
+
+func TestConfig_Override(t *testing.T) {
+	cfg := &Config{}
+	if err := cfg.Override("Max_jobs_per_builder", "4", "command line"); err != nil {
+		t.Fatalf("Override failed: %v", err)
+	}
+	if cfg.MaxJobs != 4 || cfg.Source("Max_jobs_per_builder") != "command line" {
+		t.Errorf("MaxJobs = %d, source = %q", cfg.MaxJobs, cfg.Source("Max_jobs_per_builder"))
+	}
+	if err := cfg.Override("Max_jobs_per_builder", "0", "command line"); err == nil {
+		t.Error("Override accepted 0 jobs")
+	}
+	if err := cfg.Override("No_such_key", "1", "command line"); err == nil {
+		t.Error("Override accepted an unknown key")
+	}
+}
@@ -0,0 +1,567 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+// GlobalSection is the dsynth.ini section holding profile_selected and
+// settings shared by every profile.
+const GlobalSection = "Global Configuration"
+
+// MaxBuilders is the upper bound accepted for Number_of_builders and
+// Max_jobs_per_builder.
+const MaxBuilders = 1024
+
+// KeyKind describes the type of value a dsynth.ini key holds.
+type KeyKind int
+
+const (
+	KeyPath         KeyKind = iota // Absolute filesystem path
+	KeyInt                         // Positive integer
+	KeyBool                        // yes/no boolean
+	KeyLocation                    // Absolute path or http(s):// or file:// URL
+	KeyPortInts                    // Space-separated category/port=N pairs
+	KeySize                        // Byte size with an optional K, M, G or T suffix
+	KeyLimits                      // Comma-separated name=value resource limits
+	KeyPortLimits                  // Space-separated category/port:name=value,... entries
+	KeyTimeouts                    // Comma-separated phase=duration timeouts
+	KeyPortTimeouts                // Space-separated category/port:phase=duration,... entries
+	KeyPhases                      // Space-separated phase[?condition] pipeline
+	KeyPhaseHooks                  // Space-separated phase:/path/to/script entries
+	KeyPhaseEnv                    // Space-separated phase:VAR=value entries
+	KeyDuration                    // Duration of at least 1s, e.g. 90s or 5m
+	KeyCount                       // Non-negative integer
+	KeyAddress                     // host:port address, e.g. 127.0.0.1:8080
+	KeyURL                         // http:// or https:// URL
+	KeyEmail                       // Email address, e.g. Builder <go-synth@example.org>
+	KeyEmails                      // Comma-separated email addresses
+	KeyNotifyEvents                // Space-separated notification events
+	KeyLogFormat                   // text, json or both
+)
+
+// KeyInfo describes a dsynth.ini key understood by loadFromSection.
+type KeyInfo struct {
+	Name        string
+	Kind        KeyKind
+	Description string
+}
+
+// KnownKeys lists every key loadFromSection understands, in the order they
+// are presented by the configure editor.
+var KnownKeys = []KeyInfo{
+	{"Directory_buildbase", KeyPath, "Base directory for workers and build state"},
+	{"Directory_portsdir", KeyPath, "Ports tree"},
+	{"Directory_repository", KeyPath, "Package repository"},
+	{"Directory_packages", KeyPath, "Built packages"},
+	{"Directory_distfiles", KeyPath, "Distfiles"},
+	{"Directory_options", KeyPath, "Saved port options"},
+	{"Directory_logs", KeyPath, "Build logs"},
+	{"Directory_ccache", KeyPath, "ccache directory"},
+	{"Directory_system", KeyPath, "System root used to populate workers"},
+	{"Number_of_builders", KeyInt, "Number of concurrent builders"},
+	{"Max_jobs_per_builder", KeyInt, "Make jobs per builder"},
+	{"Max_jobs_overrides", KeyPortInts, "Per-port make jobs, e.g. lang/rust=8 www/chromium=16"},
+	{"Adaptive_jobs", KeyBool, "Give more make jobs to the last builds of a run"},
+	{"Build_limits", KeyLimits, "Nice value and rlimits for builds, e.g. nice=10,cpu_time=4h,open_files=4096"},
+	{"Build_limits_overrides", KeyPortLimits, "Per-port limits, e.g. www/chromium:address_space=48G,cpu_time=8h"},
+	{"Build_timeouts", KeyTimeouts, "Phase and build timeouts, e.g. configure=30m,total=12h,inactivity=1h"},
+	{"Build_timeouts_overrides", KeyPortTimeouts, "Per-port timeouts, e.g. www/chromium:build=20h,total=24h"},
+	{"Build_phases", KeyPhases, "Build pipeline in run order; append ?dev, ?plist or ?test to run a phase only in that mode"},
+	{"Build_phase_hooks", KeyPhaseHooks, "Custom phases run as scripts inside the worker, e.g. lint:/xports/Tools/scripts/lint.sh"},
+	{"Build_phase_env", KeyPhaseEnv, "Per-phase environment variables, e.g. build:CCACHE_SLOPPINESS=time_macros"},
+	{"Memory_target", KeySize, "Memory the builds running at once may use, e.g. 48G"},
+	{"Memory_default_estimate", KeySize, "Memory assumed for ports without a recorded peak"},
+	{"Tmpfs_workdir", KeyBool, "Use tmpfs for port work directories"},
+	{"Tmpfs_localbase", KeyBool, "Use tmpfs for LOCALBASE"},
+	{"Display_with_ncurses", KeyBool, "Show the ncurses build UI"},
+	{"Disable_throttle", KeyBool, "Disable load/swap based worker throttling"},
+	{"leverage_prebuilt", KeyBool, "Use prebuilt packages when available"},
+	{"Prebuilt_repository", KeyLocation, "pkg repository (directory or URL) to take prebuilt packages from"},
+	{"Migration_auto_migrate", KeyBool, "Migrate legacy CRC data automatically"},
+	{"Migration_backup_legacy", KeyBool, "Back up legacy CRC data when migrating"},
+	{"Keep_obsolete_packages", KeyCount, "Previous versions of each package kept when pruning the repository"},
+	{"Hook_run_start", KeyPath, "Script run before a build run starts"},
+	{"Hook_port_start", KeyPath, "Script run before each port build"},
+	{"Hook_port_success", KeyPath, "Script run after a port builds successfully"},
+	{"Hook_port_failure", KeyPath, "Script run after a port build fails or times out"},
+	{"Hook_port_skip", KeyPath, "Script run for each port skipped after a dependency failed"},
+	{"Hook_run_end", KeyPath, "Script run after a build run ends"},
+	{"Hook_timeout", KeyDuration, "Longest a hook script may run, e.g. 5m"},
+	{"Database_path", KeyPath, "Build database file"},
+	{"Database_auto_vacuum", KeyBool, "Prune old build history and compact the build database at the end of each run"},
+	{"Database_keep_runs", KeyCount, "Build runs kept when pruning build history, 0 for no limit"},
+	{"Database_keep_days", KeyCount, "Days of build history kept when pruning, 0 for no limit"},
+	{"Database_keep_backups", KeyCount, "Build database backups kept by db backup, 0 for no limit"},
+	{"Log_history", KeyCount, "Earlier build logs kept per port, compressed, default 5"},
+	{"Log_format", KeyLogFormat, "Format of the build logs: text, json (09_log.jsonl) or both"},
+	{"Notify_on", KeyNotifyEvents, "Events to send notifications for: run-end, port-failure"},
+	{"Notify_new_failures_only", KeyBool, "Only notify about ports that did not fail in the previous run"},
+	{"Notify_webhook", KeyURL, "URL notifications are posted to as JSON"},
+	{"Notify_webhook_template", KeyPath, "Template for the webhook body, instead of the default JSON"},
+	{"Notify_email_to", KeyEmails, "Comma-separated addresses notifications are mailed to"},
+	{"Notify_email_from", KeyEmail, "Sender of notification mail, default go-synth@ this host"},
+	{"Notify_email_template", KeyPath, "Template for the notification mail body"},
+	{"Notify_smtp_server", KeyAddress, "SMTP server notification mail is sent through, default localhost:25"},
+	{"Metrics_listen", KeyAddress, "Address builds serve OpenMetrics on at /metrics, e.g. 127.0.0.1:9108; unset for none"},
+	{"Http_listen", KeyAddress, "Address go-synth serve listens on for the status API and dashboard, e.g. 127.0.0.1:8080"},
+}
+
+// LookupKey returns the KeyInfo for a known key name.
+func LookupKey(name string) (KeyInfo, bool) {
+	for _, k := range KnownKeys {
+		if k.Name == name {
+			return k, true
+		}
+	}
+	return KeyInfo{}, false
+}
+
+// ValidateKey checks that value is acceptable for the named key. An empty
+// value is always valid and means the key is unset.
+func ValidateKey(name, value string) error {
+	info, ok := LookupKey(name)
+	if !ok {
+		return fmt.Errorf("unknown key %s", name)
+	}
+
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	switch info.Kind {
+	case KeyPath:
+		if !filepath.IsAbs(value) {
+			return fmt.Errorf("%s: path must be absolute: %s", name, value)
+		}
+	case KeyInt:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s: not a number: %s", name, value)
+		}
+		if n < 1 || n > MaxBuilders {
+			return fmt.Errorf("%s: must be between 1 and %d, got %d", name, MaxBuilders, n)
+		}
+	case KeyCount:
+		if n, err := strconv.Atoi(value); err != nil || n < 0 {
+			return fmt.Errorf("%s: expected a number of 0 or more, got %s", name, value)
+		}
+	case KeyBool:
+		if _, ok := parseStrictBool(value); !ok {
+			return fmt.Errorf("%s: expected yes or no, got %s", name, value)
+		}
+	case KeyPortInts:
+		if _, err := ParsePortInts(value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	case KeyLimits:
+		if _, err := (Limits{}).With(value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	case KeyPortLimits:
+		if _, err := ParsePortLimits(value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	case KeyTimeouts:
+		if _, err := (Timeouts{}).With(value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	case KeyPortTimeouts:
+		if _, err := ParsePortTimeouts(value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	case KeyPhases:
+		if _, err := ParsePhases(value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	case KeyPhaseHooks:
+		if _, err := ParsePhaseHooks(value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	case KeyPhaseEnv:
+		if _, err := ParsePhaseEnv(value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	case KeyDuration:
+		d, err := time.ParseDuration(value)
+		if err != nil || d < time.Second {
+			return fmt.Errorf("%s: expected a duration of at least 1s such as 5m, got %s", name, value)
+		}
+	case KeyAddress:
+		_, port, err := net.SplitHostPort(value)
+		if n, perr := strconv.Atoi(port); err != nil || perr != nil || n < 0 || n > 65535 {
+			return fmt.Errorf("%s: expected host:port such as 127.0.0.1:8080, got %s", name, value)
+		}
+	case KeyURL:
+		u, err := url.Parse(value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%s: expected an http(s) URL, got %s", name, value)
+		}
+	case KeyEmail:
+		if _, err := mail.ParseAddress(value); err != nil {
+			return fmt.Errorf("%s: expected an email address, got %s", name, value)
+		}
+	case KeyEmails:
+		if _, err := mail.ParseAddressList(value); err != nil {
+			return fmt.Errorf("%s: expected comma-separated email addresses, got %s", name, value)
+		}
+	case KeyLogFormat:
+		if _, err := ParseLogFormat(value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	case KeyNotifyEvents:
+		if _, err := ParseNotifyEvents(value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	case KeySize:
+		if _, err := ParseSize(value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	case KeyLocation:
+		if filepath.IsAbs(value) {
+			break
+		}
+		u, err := url.Parse(value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "file") {
+			return fmt.Errorf("%s: expected an absolute path or http(s)/file URL, got %s", name, value)
+		}
+	}
+	return nil
+}
+
+// ParsePortInts parses a KeyPortInts value such as
+// "lang/rust=8 www/chromium@default=16" into a map keyed by port origin,
+// optionally with a flavor suffix.
+func ParsePortInts(value string) (map[string]int, error) {
+	m := make(map[string]int)
+	for _, field := range strings.Fields(value) {
+		port, num, ok := strings.Cut(field, "=")
+		if !ok || strings.Count(strings.SplitN(port, "@", 2)[0], "/") != 1 {
+			return nil, fmt.Errorf("expected category/port=N, got %s", field)
+		}
+		n, err := strconv.Atoi(num)
+		if err != nil || n < 1 || n > MaxBuilders {
+			return nil, fmt.Errorf("%s: count must be between 1 and %d", port, MaxBuilders)
+		}
+		m[port] = n
+	}
+	return m, nil
+}
+
+// FormatPortInts formats a map as parsed by ParsePortInts, sorted by port.
+func FormatPortInts(m map[string]int) string {
+	ports := make([]string, 0, len(m))
+	for port := range m {
+		ports = append(ports, port)
+	}
+	sort.Strings(ports)
+
+	fields := make([]string, len(ports))
+	for i, port := range ports {
+		fields[i] = port + "=" + strconv.Itoa(m[port])
+	}
+	return strings.Join(fields, " ")
+}
+
+// sizeUnits are the suffixes accepted by ParseSize, smallest first.
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"K", 1 << 10},
+	{"M", 1 << 20},
+	{"G", 1 << 30},
+	{"T", 1 << 40},
+}
+
+// ParseSize parses a KeySize value such as "48G" or "1.5G" into bytes.
+// Suffixes are binary (K is 1024) and may be followed by B; a bare number
+// is taken as bytes.
+func ParseSize(value string) (int64, error) {
+	s := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(value)), "B")
+	mult := int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			s = strings.TrimSuffix(s, u.suffix)
+			mult = u.bytes
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("expected a size such as 512M or 48G, got %s", value)
+	}
+	return int64(n * float64(mult)), nil
+}
+
+// FormatSize formats a byte count as accepted by ParseSize, using the
+// largest unit that keeps the value at least 1 and one decimal place at
+// most.
+func FormatSize(n int64) string {
+	if n <= 0 {
+		return ""
+	}
+	for i := len(sizeUnits) - 1; i >= 0; i-- {
+		u := sizeUnits[i]
+		if n >= u.bytes {
+			v := float64(n) / float64(u.bytes)
+			return strconv.FormatFloat(math.Round(v*10)/10, 'f', -1, 64) + u.suffix
+		}
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+// parsePortSpecs parses space-separated category/port:spec entries, as
+// used by the per-port override keys, checking each spec with check. The
+// result is keyed by port origin, optionally with a flavor suffix.
+func parsePortSpecs(value string, check func(spec string) error) (map[string]string, error) {
+	m := make(map[string]string)
+	for _, field := range strings.Fields(value) {
+		port, spec, ok := strings.Cut(field, ":")
+		if !ok || strings.Count(strings.SplitN(port, "@", 2)[0], "/") != 1 {
+			return nil, fmt.Errorf("expected category/port:name=value,..., got %s", field)
+		}
+		if err := check(spec); err != nil {
+			return nil, fmt.Errorf("%s: %w", port, err)
+		}
+		m[port] = spec
+	}
+	return m, nil
+}
+
+// FormatPortSpecs formats a map as parsed by ParsePortLimits or
+// ParsePortTimeouts, sorted by port.
+func FormatPortSpecs(m map[string]string) string {
+	ports := make([]string, 0, len(m))
+	for port := range m {
+		ports = append(ports, port)
+	}
+	sort.Strings(ports)
+
+	fields := make([]string, len(ports))
+	for i, port := range ports {
+		fields[i] = port + ":" + m[port]
+	}
+	return strings.Join(fields, " ")
+}
+
+// PathWarning returns a non-fatal warning for path keys whose directory does
+// not exist yet, or an empty string.
+func PathWarning(name, value string) string {
+	info, ok := LookupKey(name)
+	if !ok || info.Kind != KeyPath || value == "" || !filepath.IsAbs(value) {
+		return ""
+	}
+
+	check := value
+	if name == "Database_path" {
+		check = filepath.Dir(value)
+	}
+	if _, err := os.Stat(check); err != nil {
+		return fmt.Sprintf("%s does not exist yet: %s", name, check)
+	}
+	return ""
+}
+
+// parseStrictBool parses the boolean spellings accepted in dsynth.ini,
+// reporting false for anything else.
+func parseStrictBool(s string) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "yes", "on", "true", "1":
+		return true, true
+	case "no", "off", "false", "0":
+		return false, true
+	}
+	return false, false
+}
+
+// ConfigFile is an editable dsynth.ini. Unlike SaveConfig, which writes a
+// fresh file, edits made through ConfigFile keep existing comments, section
+// order and keys go-synth does not understand.
+type ConfigFile struct {
+	path string
+	file *ini.File
+}
+
+// OpenConfigFile loads a dsynth.ini for editing. A missing file yields an
+// empty ConfigFile that is created on Save.
+func OpenConfigFile(path string) (*ConfigFile, error) {
+	if path == "" {
+		path = "/etc/dsynth/dsynth.ini"
+	}
+
+	cf := &ConfigFile{path: path}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		cf.file = ini.Empty()
+		return cf, nil
+	}
+
+	file, err := ini.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config file: %w", err)
+	}
+	cf.file = file
+	return cf, nil
+}
+
+// Path returns the file the configuration is saved to.
+func (cf *ConfigFile) Path() string {
+	return cf.path
+}
+
+// Profiles returns the profile section names in file order, excluding the
+// global section.
+func (cf *ConfigFile) Profiles() []string {
+	var profiles []string
+	for _, name := range cf.file.SectionStrings() {
+		if name == ini.DefaultSection || strings.EqualFold(name, GlobalSection) {
+			continue
+		}
+		profiles = append(profiles, name)
+	}
+	return profiles
+}
+
+// HasProfile reports whether a profile section exists.
+func (cf *ConfigFile) HasProfile(name string) bool {
+	for _, p := range cf.Profiles() {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SelectedProfile returns the profile_selected value of the global section.
+func (cf *ConfigFile) SelectedProfile() string {
+	return cf.Get(GlobalSection, "profile_selected")
+}
+
+// SetSelectedProfile sets profile_selected, which must name an existing
+// profile section.
+func (cf *ConfigFile) SetSelectedProfile(name string) error {
+	if !cf.HasProfile(name) {
+		return fmt.Errorf("profile %q does not exist", name)
+	}
+	cf.globalSection().Key("profile_selected").SetValue(name)
+	return nil
+}
+
+// AddProfile creates an empty profile section.
+func (cf *ConfigFile) AddProfile(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.ContainsAny(name, "[]") {
+		return fmt.Errorf("invalid profile name %q", name)
+	}
+	if strings.EqualFold(name, GlobalSection) || name == ini.DefaultSection {
+		return fmt.Errorf("profile name %q is reserved", name)
+	}
+	if cf.HasProfile(name) {
+		return fmt.Errorf("profile %q already exists", name)
+	}
+	_, err := cf.file.NewSection(name)
+	return err
+}
+
+// RemoveProfile deletes a profile section. If it was selected,
+// profile_selected is cleared.
+func (cf *ConfigFile) RemoveProfile(name string) error {
+	if !cf.HasProfile(name) {
+		return fmt.Errorf("profile %q does not exist", name)
+	}
+	cf.file.DeleteSection(name)
+	if cf.SelectedProfile() == name {
+		cf.globalSection().DeleteKey("profile_selected")
+	}
+	return nil
+}
+
+// Get returns the value of key in section, or an empty string if unset.
+func (cf *ConfigFile) Get(section, key string) string {
+	sec := cf.lookupSection(section)
+	if sec == nil || !sec.HasKey(key) {
+		return ""
+	}
+	return sec.Key(key).String()
+}
+
+// Set validates and stores a known key in section. An empty value removes
+// the key so the setting falls back to the global section or defaults.
+func (cf *ConfigFile) Set(section, key, value string) error {
+	if err := ValidateKey(key, value); err != nil {
+		return err
+	}
+
+	sec := cf.lookupSection(section)
+	if sec == nil && strings.EqualFold(section, GlobalSection) {
+		sec = cf.globalSection()
+	}
+	if sec == nil {
+		return fmt.Errorf("section %q does not exist", section)
+	}
+
+	value = strings.TrimSpace(value)
+	if value == "" {
+		sec.DeleteKey(key)
+		return nil
+	}
+	sec.Key(key).SetValue(value)
+	return nil
+}
+
+// Save writes the configuration atomically.
+func (cf *ConfigFile) Save() error {
+	if err := os.MkdirAll(filepath.Dir(cf.path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	tmpPath := cf.path + ".tmp"
+	if err := cf.file.SaveTo(tmpPath); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, cf.path); err != nil {
+		return fmt.Errorf("failed to finalize config: %w", err)
+	}
+	return nil
+}
+
+// lookupSection finds a section by name without creating it. The global
+// section is matched case-insensitively, as in LoadConfig.
+func (cf *ConfigFile) lookupSection(name string) *ini.Section {
+	if strings.EqualFold(name, GlobalSection) {
+		for _, sec := range cf.file.Sections() {
+			if strings.EqualFold(sec.Name(), GlobalSection) {
+				return sec
+			}
+		}
+		return nil
+	}
+	sec, err := cf.file.GetSection(name)
+	if err != nil {
+		return nil
+	}
+	return sec
+}
+
+// globalSection returns the global section, creating it if needed.
+func (cf *ConfigFile) globalSection() *ini.Section {
+	if sec := cf.lookupSection(GlobalSection); sec != nil {
+		return sec
+	}
+	sec, _ := cf.file.NewSection(GlobalSection)
+	return sec
+}
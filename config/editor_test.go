@@ -0,0 +1,213 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const editorTestIni = `; dsynth configuration
+[Global Configuration]
+profile_selected= LiveSystem
+
+; Live system profile
+[LiveSystem]
+# Keep in sync with the build host
+Number_of_builders= 4
+Directory_portsdir= /usr/dports
+Custom_upstream_key= keep-me
+`
+
+func writeEditorTestIni(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "dsynth.ini")
+	if err := os.WriteFile(path, []byte(editorTestIni), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+	return path
+}
+
+func TestConfigFile_PreservesCommentsAndUnknownKeys(t *testing.T) {
+	path := writeEditorTestIni(t)
+
+	cf, err := OpenConfigFile(path)
+	if err != nil {
+		t.Fatalf("OpenConfigFile failed: %v", err)
+	}
+	if err := cf.Set("LiveSystem", "Max_jobs_per_builder", "2"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := cf.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(data)
+	for _, want := range []string{
+		"; dsynth configuration",
+		"; Live system profile",
+		"# Keep in sync with the build host",
+		"Custom_upstream_key",
+		"keep-me",
+		"Max_jobs_per_builder",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("saved config missing %q:\n%s", want, content)
+		}
+	}
+
+	cfg, err := LoadConfig(filepath.Dir(path), "")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.MaxWorkers != 4 || cfg.MaxJobs != 2 {
+		t.Errorf("MaxWorkers=%d MaxJobs=%d, want 4 and 2", cfg.MaxWorkers, cfg.MaxJobs)
+	}
+}
+
+func TestConfigFile_Profiles(t *testing.T) {
+	cf, err := OpenConfigFile(writeEditorTestIni(t))
+	if err != nil {
+		t.Fatalf("OpenConfigFile failed: %v", err)
+	}
+
+	if got := cf.Profiles(); len(got) != 1 || got[0] != "LiveSystem" {
+		t.Fatalf("Profiles() = %v, want [LiveSystem]", got)
+	}
+	if cf.SelectedProfile() != "LiveSystem" {
+		t.Errorf("SelectedProfile() = %q", cf.SelectedProfile())
+	}
+
+	if err := cf.AddProfile("Release"); err != nil {
+		t.Fatalf("AddProfile failed: %v", err)
+	}
+	if err := cf.AddProfile("Release"); err == nil {
+		t.Error("expected error adding duplicate profile")
+	}
+	if err := cf.AddProfile("Global Configuration"); err == nil {
+		t.Error("expected error adding reserved profile name")
+	}
+	if err := cf.SetSelectedProfile("Missing"); err == nil {
+		t.Error("expected error selecting missing profile")
+	}
+	if err := cf.SetSelectedProfile("Release"); err != nil {
+		t.Fatalf("SetSelectedProfile failed: %v", err)
+	}
+
+	if err := cf.RemoveProfile("Release"); err != nil {
+		t.Fatalf("RemoveProfile failed: %v", err)
+	}
+	if cf.SelectedProfile() != "" {
+		t.Errorf("profile_selected should be cleared, got %q", cf.SelectedProfile())
+	}
+	if cf.HasProfile("Release") {
+		t.Error("Release should have been removed")
+	}
+}
+
+func TestConfigFile_SetValidatesAndUnsets(t *testing.T) {
+	cf, err := OpenConfigFile(filepath.Join(t.TempDir(), "missing", "dsynth.ini"))
+	if err != nil {
+		t.Fatalf("OpenConfigFile failed: %v", err)
+	}
+
+	if err := cf.Set(GlobalSection, "Number_of_builders", "8"); err != nil {
+		t.Fatalf("Set on new global section failed: %v", err)
+	}
+	if err := cf.Set(GlobalSection, "Number_of_builders", ""); err != nil {
+		t.Fatalf("Set empty failed: %v", err)
+	}
+	if got := cf.Get(GlobalSection, "Number_of_builders"); got != "" {
+		t.Errorf("expected key removed, got %q", got)
+	}
+	if err := cf.Set("NoSuchProfile", "Tmpfs_workdir", "yes"); err == nil {
+		t.Error("expected error for missing section")
+	}
+	if err := cf.Save(); err != nil {
+		t.Fatalf("Save should create parent directories: %v", err)
+	}
+}
+
+func TestValidateKey(t *testing.T) {
+	tests := []struct {
+		key     string
+		value   string
+		wantErr bool
+	}{
+		{"Directory_buildbase", "/build/synth", false},
+		{"Directory_buildbase", "build/synth", true},
+		{"Database_path", "/build/synth/builds.db", false},
+		{"Number_of_builders", "8", false},
+		{"Number_of_builders", "0", true},
+		{"Number_of_builders", "many", true},
+		{"Max_jobs_per_builder", "2048", true},
+		{"Tmpfs_workdir", "yes", false},
+		{"Tmpfs_localbase", "maybe", true},
+		{"Display_with_ncurses", "", false},
+		{"Memory_target", "48G", false},
+		{"Memory_target", "0", true},
+		{"Memory_default_estimate", "lots", true},
+		{"Keep_obsolete_packages", "0", false},
+		{"Keep_obsolete_packages", "-1", true},
+		{"Http_listen", "127.0.0.1:8080", false},
+		{"Http_listen", ":8080", false},
+		{"Http_listen", "[::1]:8080", false},
+		{"Http_listen", "8080", true},
+		{"Http_listen", "localhost:http", true},
+		{"Log_history", "0", false},
+		{"Log_history", "-1", true},
+		{"Log_format", "json", false},
+		{"Log_format", "both", false},
+		{"Log_format", "xml", true},
+		{"Notify_on", "run-end port-failure", false},
+		{"Notify_on", "run-end,port-failure", false},
+		{"Notify_on", "port-success", true},
+		{"Notify_webhook", "https://chat.example.org/hooks/builds", false},
+		{"Notify_webhook", "/tmp/hook", true},
+		{"Notify_email_to", "builds@example.org, Ops <ops@example.org>", false},
+		{"Notify_email_to", "builds at example.org", true},
+		{"Notify_email_from", "go-synth@builder1.example.org", false},
+		{"Notify_email_from", "a@example.org, b@example.org", true},
+		{"No_such_key", "yes", true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateKey(tt.key, tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateKey(%s, %q) error = %v, wantErr %v", tt.key, tt.value, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+	}{
+		{"1024", 1024},
+		{"512M", 512 << 20},
+		{"48G", 48 << 30},
+		{"48gb", 48 << 30},
+		{"1.5G", 3 << 29},
+		{"2T", 2 << 40},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d", tt.value, got, err, tt.want)
+		}
+		if back, _ := ParseSize(FormatSize(got)); back != got {
+			t.Errorf("FormatSize(%d) = %q does not round-trip", got, FormatSize(got))
+		}
+	}
+
+	for _, value := range []string{"", "G", "-1G", "12Q"} {
+		if _, err := ParseSize(value); err == nil {
+			t.Errorf("ParseSize(%q) succeeded, want error", value)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"strings"
+	"time"
+)
+
+// DefaultHookTimeout is how long a hook script may run unless Hook_timeout
+// says otherwise.
+const DefaultHookTimeout = 5 * time.Minute
+
+// HookKeys are the dsynth.ini keys naming hook scripts, in the order the
+// events occur during a run.
+var HookKeys = []string{
+	"Hook_run_start",
+	"Hook_port_start",
+	"Hook_port_success",
+	"Hook_port_failure",
+	"Hook_port_skip",
+	"Hook_run_end",
+}
+
+// HookVariables are the GOSYNTH_* variables set for hook scripts. They are
+// not configuration overrides, so a hook can run go-synth itself.
+var HookVariables = []string{
+	"HOOK", "RUN_ID", "PORT", "VERSION", "STATUS", "PHASE", "LOG", "PACKAGE",
+	"LOGS_DIR", "SUCCESS", "FAILED", "SKIPPED", "IGNORED", "DURATION",
+}
+
+// HookEvent returns the event name for a hook key, e.g. "port-success" for
+// Hook_port_success.
+func HookEvent(key string) string {
+	return strings.ReplaceAll(strings.TrimPrefix(key, "Hook_"), "_", "-")
+}
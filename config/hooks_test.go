@@ -0,0 +1,64 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfig_Hooks(t *testing.T) {
+	tempDir := t.TempDir()
+	script := filepath.Join(tempDir, "notify")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\n"), 0644); err != nil {
+		t.Fatalf("Failed to write hook script: %v", err)
+	}
+	configContent := `[Global Configuration]
+profile_selected=Live
+
+[Live]
+Hook_port_success=` + script + `
+Hook_run_end=` + script + `
+Hook_timeout=90s
+`
+	if err := os.WriteFile(filepath.Join(tempDir, "dsynth.ini"), []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	// Variables set for hooks are not overrides, so hooks can run go-synth
+	t.Setenv("GOSYNTH_RUN_ID", "run-1")
+	t.Setenv("GOSYNTH_HOOK", "run-end")
+
+	cfg, err := LoadConfig(tempDir, "")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.Hooks["port-success"] != script || cfg.Hooks["run-end"] != script || len(cfg.Hooks) != 2 {
+		t.Errorf("Hooks = %v", cfg.Hooks)
+	}
+	if cfg.HookTimeout != 90*time.Second || cfg.Value("Hook_timeout") != "1m30s" {
+		t.Errorf("HookTimeout = %v", cfg.HookTimeout)
+	}
+
+	issue, ok := findIssue(cfg.Validate(), "Hook_port_success")
+	if !ok || !strings.Contains(issue.Message, "not executable") {
+		t.Errorf("Validate() did not report the non-executable hook: %+v", issue)
+	}
+
+	for _, value := range []string{"0s", "500ms", "soon"} {
+		if err := ValidateKey("Hook_timeout", value); err == nil {
+			t.Errorf("ValidateKey(Hook_timeout, %q) succeeded, want error", value)
+		}
+	}
+}
+
+func TestConfig_DefaultHookTimeout(t *testing.T) {
+	cfg, err := LoadConfig(t.TempDir(), "")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.HookTimeout != DefaultHookTimeout {
+		t.Errorf("HookTimeout = %v, want %v", cfg.HookTimeout, DefaultHookTimeout)
+	}
+}
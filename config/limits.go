@@ -0,0 +1,117 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultNice is the nice value builds run at unless Build_limits or -N
+// say otherwise, as in dsynth.
+const DefaultNice = 10
+
+// Limits are the scheduling priority and resource limits applied to build
+// processes. Zero values leave the corresponding limit unchanged.
+type Limits struct {
+	Nice         int           // Scheduling priority adjustment, -20 to 20
+	CPUTime      time.Duration // RLIMIT_CPU
+	FileSize     int64         // RLIMIT_FSIZE in bytes
+	OpenFiles    int           // RLIMIT_NOFILE
+	AddressSpace int64         // RLIMIT_AS in bytes
+}
+
+// With returns l with the limits named in spec replaced. spec is a
+// comma-separated list of name=value pairs:
+//
+//	nice=15,cpu_time=4h,file_size=8G,open_files=4096,address_space=32G
+//
+// Sizes accept the suffixes understood by ParseSize.
+func (l Limits) With(spec string) (Limits, error) {
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return l, fmt.Errorf("expected name=value, got %s", field)
+		}
+
+		var err error
+		switch name {
+		case "nice":
+			l.Nice, err = strconv.Atoi(value)
+			if err == nil && (l.Nice < -20 || l.Nice > 20) {
+				err = fmt.Errorf("must be between -20 and 20")
+			}
+		case "cpu_time":
+			l.CPUTime, err = time.ParseDuration(value)
+			if err == nil && l.CPUTime < time.Second {
+				err = fmt.Errorf("must be at least 1s")
+			}
+		case "file_size":
+			l.FileSize, err = ParseSize(value)
+		case "open_files":
+			l.OpenFiles, err = strconv.Atoi(value)
+			if err == nil && l.OpenFiles < 1 {
+				err = fmt.Errorf("must be positive")
+			}
+		case "address_space":
+			l.AddressSpace, err = ParseSize(value)
+		default:
+			return l, fmt.Errorf("unknown limit %s (expected nice, cpu_time, file_size, open_files or address_space)", name)
+		}
+		if err != nil {
+			return l, fmt.Errorf("%s: invalid value %s: %v", name, value, err)
+		}
+	}
+	return l, nil
+}
+
+// String formats l as accepted by With, omitting unset rlimits. The nice
+// value is always included, since Build_limits starts from DefaultNice.
+func (l Limits) String() string {
+	fields := []string{"nice=" + strconv.Itoa(l.Nice)}
+	if l.CPUTime > 0 {
+		fields = append(fields, "cpu_time="+l.CPUTime.String())
+	}
+	if l.FileSize > 0 {
+		fields = append(fields, "file_size="+FormatSize(l.FileSize))
+	}
+	if l.OpenFiles > 0 {
+		fields = append(fields, "open_files="+strconv.Itoa(l.OpenFiles))
+	}
+	if l.AddressSpace > 0 {
+		fields = append(fields, "address_space="+FormatSize(l.AddressSpace))
+	}
+	return strings.Join(fields, ",")
+}
+
+// ParsePortLimits parses a KeyPortLimits value such as
+// "www/chromium:address_space=48G,cpu_time=8h lang/rust@default:nice=5"
+// into limit specs keyed by port origin, optionally with a flavor suffix.
+func ParsePortLimits(value string) (map[string]string, error) {
+	return parsePortSpecs(value, func(spec string) error {
+		_, err := Limits{}.With(spec)
+		return err
+	})
+}
+
+// LimitsFor returns the limits for a build of portDir with the given
+// flavor: Build_limits, overlaid by the port's Build_limits_overrides
+// entry and then by its flavor-specific entry.
+func (cfg *Config) LimitsFor(portDir, flavor string) Limits {
+	l := cfg.Limits
+	keys := []string{portDir}
+	if flavor != "" {
+		keys = append(keys, portDir+"@"+flavor)
+	}
+	for _, key := range keys {
+		if spec, ok := cfg.LimitsOverrides[key]; ok {
+			// Specs were validated when loaded
+			l, _ = l.With(spec)
+		}
+	}
+	return l
+}
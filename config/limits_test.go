@@ -0,0 +1,60 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimits_With(t *testing.T) {
+	l, err := Limits{Nice: DefaultNice}.With("cpu_time=4h, file_size=8G,open_files=4096,address_space=32G")
+	if err != nil {
+		t.Fatalf("With() failed: %v", err)
+	}
+	want := Limits{
+		Nice:         DefaultNice,
+		CPUTime:      4 * time.Hour,
+		FileSize:     8 << 30,
+		OpenFiles:    4096,
+		AddressSpace: 32 << 30,
+	}
+	if l != want {
+		t.Errorf("With() = %+v, want %+v", l, want)
+	}
+
+	back, err := Limits{}.With(l.String())
+	if err != nil || back != l {
+		t.Errorf("String() = %q does not round-trip: %+v, %v", l.String(), back, err)
+	}
+
+	for _, spec := range []string{"nice=21", "cpu_time=0s", "open_files=0", "file_size=big", "stack=1M", "nice"} {
+		if _, err := (Limits{}).With(spec); err == nil {
+			t.Errorf("With(%q) succeeded, want error", spec)
+		}
+	}
+}
+
+func TestConfig_LimitsFor(t *testing.T) {
+	cfg := &Config{}
+	if err := cfg.Override("Build_limits", "open_files=4096", "test"); err != nil {
+		t.Fatalf("Override(Build_limits) failed: %v", err)
+	}
+	if err := cfg.Override("Build_limits_overrides", "www/chromium:address_space=48G,nice=15 devel/py-six@py311:open_files=8192", "test"); err != nil {
+		t.Fatalf("Override(Build_limits_overrides) failed: %v", err)
+	}
+	if err := cfg.Override("Build_limits_overrides", "www/chromium", "test"); err == nil {
+		t.Error("Override accepted an entry without limits")
+	}
+
+	if got := cfg.LimitsFor("editors/vim", ""); got != (Limits{Nice: DefaultNice, OpenFiles: 4096}) {
+		t.Errorf("LimitsFor(editors/vim) = %+v, want Build_limits with the default nice", got)
+	}
+	if got := cfg.LimitsFor("www/chromium", ""); got != (Limits{Nice: 15, OpenFiles: 4096, AddressSpace: 48 << 30}) {
+		t.Errorf("LimitsFor(www/chromium) = %+v", got)
+	}
+	if got := cfg.LimitsFor("devel/py-six", "py311"); got.OpenFiles != 8192 {
+		t.Errorf("LimitsFor(devel/py-six@py311).OpenFiles = %d, want 8192", got.OpenFiles)
+	}
+	if got := cfg.LimitsFor("devel/py-six", "py39"); got.OpenFiles != 4096 {
+		t.Errorf("LimitsFor(devel/py-six@py39).OpenFiles = %d, want 4096", got.OpenFiles)
+	}
+}
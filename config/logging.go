@@ -0,0 +1,33 @@
+package config
+
+import "fmt"
+
+// Log formats, as set with Log_format
+const (
+	LogFormatText = "text" // 00_last_results.log and 07_debug.log
+	LogFormatJSON = "json" // 09_log.jsonl only
+	LogFormatBoth = "both" // Text and JSON
+)
+
+// DefaultLogHistory is how many earlier build logs are kept per port
+// unless Log_history says otherwise.
+const DefaultLogHistory = 5
+
+// ParseLogFormat checks a Log_format value.
+func ParseLogFormat(value string) (string, error) {
+	switch value {
+	case LogFormatText, LogFormatJSON, LogFormatBoth:
+		return value, nil
+	}
+	return "", fmt.Errorf("unknown log format %q (expected %s, %s or %s)", value, LogFormatText, LogFormatJSON, LogFormatBoth)
+}
+
+// TextLog reports whether messages go to the text logs.
+func (cfg *Config) TextLog() bool {
+	return cfg.LogFormat != LogFormatJSON
+}
+
+// JSONLog reports whether messages go to the structured JSON log.
+func (cfg *Config) JSONLog() bool {
+	return cfg.LogFormat == LogFormatJSON || cfg.LogFormat == LogFormatBoth
+}
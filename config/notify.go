@@ -0,0 +1,31 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Notification events, as named in Notify_on
+const (
+	NotifyRunEnd      = "run-end"
+	NotifyPortFailure = "port-failure"
+)
+
+// DefaultSMTPServer is the server notification mail goes through unless
+// Notify_smtp_server says otherwise.
+const DefaultSMTPServer = "localhost:25"
+
+// ParseNotifyEvents parses a Notify_on value such as
+// "run-end port-failure". Events may be separated by spaces or commas.
+func ParseNotifyEvents(value string) ([]string, error) {
+	var events []string
+	for _, event := range strings.FieldsFunc(value, func(r rune) bool { return r == ' ' || r == ',' }) {
+		switch event {
+		case NotifyRunEnd, NotifyPortFailure:
+			events = append(events, event)
+		default:
+			return nil, fmt.Errorf("unknown notification event %q (expected %s or %s)", event, NotifyRunEnd, NotifyPortFailure)
+		}
+	}
+	return events, nil
+}
@@ -0,0 +1,202 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultPhases is the build pipeline used unless Build_phases says
+// otherwise.
+const DefaultPhases = "install-pkgs check-sanity fetch-depends fetch checksum " +
+	"extract-depends extract patch-depends patch build-depends lib-depends " +
+	"configure build run-depends stage test?test check-plist?plist package"
+
+// BuiltinPhases are the phases go-synth implements itself. Any other phase
+// in Build_phases must be defined by Build_phase_hooks.
+var BuiltinPhases = []string{
+	"install-pkgs", "check-sanity",
+	"fetch-depends", "fetch", "checksum",
+	"extract-depends", "extract",
+	"patch-depends", "patch",
+	"build-depends", "lib-depends", "configure", "build",
+	"run-depends", "stage", "test", "check-plist", "package",
+}
+
+// phaseConditions maps the conditions a Build_phases entry may carry to
+// the run modes that enable them.
+var phaseConditions = map[string]func(cfg *Config) bool{
+	"dev":   func(cfg *Config) bool { return cfg.DevMode },    // -D
+	"plist": func(cfg *Config) bool { return cfg.CheckPlist }, // -P
+	"test":  func(cfg *Config) bool { return cfg.TestMode },   // go-synth test
+}
+
+// Phase is one step of the build pipeline. A phase with a Condition only
+// runs when that run mode is on, or off if Negate is set.
+type Phase struct {
+	Name      string
+	Condition string
+	Negate    bool
+}
+
+// String formats p as it appears in Build_phases, e.g. "check-plist?dev".
+func (p Phase) String() string {
+	switch {
+	case p.Condition == "":
+		return p.Name
+	case p.Negate:
+		return p.Name + "?!" + p.Condition
+	default:
+		return p.Name + "?" + p.Condition
+	}
+}
+
+// ParsePhases parses a KeyPhases value: space-separated phase names in run
+// order, each optionally followed by ?condition or ?!condition, where the
+// condition is dev (-D), plist (-P) or test (go-synth test):
+//
+//	install-pkgs fetch checksum extract patch configure build stage check-plist?dev package
+func ParsePhases(value string) ([]Phase, error) {
+	var phases []Phase
+	seen := make(map[string]bool)
+	for _, field := range strings.Fields(value) {
+		name, cond, hasCond := strings.Cut(field, "?")
+		p := Phase{Name: name}
+		if hasCond {
+			p.Condition = strings.TrimPrefix(cond, "!")
+			p.Negate = p.Condition != cond
+			if _, ok := phaseConditions[p.Condition]; !ok {
+				return nil, fmt.Errorf("%s: unknown condition %q (expected dev, plist or test)", name, p.Condition)
+			}
+		}
+		if !validTimeoutName(name) {
+			return nil, fmt.Errorf("invalid phase name %q (expected lower-case letters and dashes)", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("phase %s is listed twice", name)
+		}
+		seen[name] = true
+		phases = append(phases, p)
+	}
+	if len(phases) == 0 {
+		return nil, fmt.Errorf("no phases listed")
+	}
+	return phases, nil
+}
+
+// FormatPhases formats phases as accepted by ParsePhases.
+func FormatPhases(phases []Phase) string {
+	fields := make([]string, len(phases))
+	for i, p := range phases {
+		fields[i] = p.String()
+	}
+	return strings.Join(fields, " ")
+}
+
+// ParsePhaseHooks parses a KeyPhaseHooks value such as
+// "lint:/xports/Tools/scripts/lint.sh" into script paths keyed by phase
+// name. Scripts run with /bin/sh inside the worker, so paths are as seen
+// from there.
+func ParsePhaseHooks(value string) (map[string]string, error) {
+	m := make(map[string]string)
+	for _, field := range strings.Fields(value) {
+		name, script, ok := strings.Cut(field, ":")
+		if !ok || !validTimeoutName(name) || !strings.HasPrefix(script, "/") {
+			return nil, fmt.Errorf("expected phase:/path/to/script, got %s", field)
+		}
+		if isBuiltinPhase(name) {
+			return nil, fmt.Errorf("%s is a built-in phase and cannot be redefined", name)
+		}
+		m[name] = script
+	}
+	return m, nil
+}
+
+// ParsePhaseEnv parses a KeyPhaseEnv value of space-separated
+// phase:VAR=value entries, such as "build:CCACHE_SLOPPINESS=time_macros
+// lint:STRICT=yes", into variables keyed by phase name. A phase may appear
+// in several entries; values cannot contain spaces.
+func ParsePhaseEnv(value string) (map[string]map[string]string, error) {
+	m := make(map[string]map[string]string)
+	for _, field := range strings.Fields(value) {
+		name, assign, ok := strings.Cut(field, ":")
+		if !ok || !validTimeoutName(name) {
+			return nil, fmt.Errorf("expected phase:VAR=value, got %s", field)
+		}
+		v, val, ok := strings.Cut(assign, "=")
+		if !ok || !validEnvName(v) {
+			return nil, fmt.Errorf("expected phase:VAR=value, got %s", field)
+		}
+		if m[name] == nil {
+			m[name] = make(map[string]string)
+		}
+		m[name][v] = val
+	}
+	return m, nil
+}
+
+// FormatPhaseHooks formats a map as parsed by ParsePhaseHooks, sorted by
+// phase.
+func FormatPhaseHooks(m map[string]string) string {
+	var fields []string
+	for _, name := range sortedKeys(m) {
+		fields = append(fields, name+":"+m[name])
+	}
+	return strings.Join(fields, " ")
+}
+
+// FormatPhaseEnv formats a map as parsed by ParsePhaseEnv, sorted by phase
+// and variable.
+func FormatPhaseEnv(m map[string]map[string]string) string {
+	var fields []string
+	for _, name := range sortedKeys(m) {
+		for _, v := range sortedKeys(m[name]) {
+			fields = append(fields, name+":"+v+"="+m[name][v])
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+// validEnvName reports whether name can be an environment variable name.
+func validEnvName(name string) bool {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+	for _, r := range name {
+		if (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+func isBuiltinPhase(name string) bool {
+	for _, b := range BuiltinPhases {
+		if b == name {
+			return true
+		}
+	}
+	return false
+}
+
+// pipeline returns Build_phases, or the default pipeline if it is unset.
+func (cfg *Config) pipeline() []Phase {
+	if len(cfg.Phases) > 0 {
+		return cfg.Phases
+	}
+	// DefaultPhases is known to parse
+	phases, _ := ParsePhases(DefaultPhases)
+	return phases
+}
+
+// Pipeline returns the names of the phases a build runs, in order, leaving
+// out phases whose condition does not hold for this run.
+func (cfg *Config) Pipeline() []string {
+	var names []string
+	for _, p := range cfg.pipeline() {
+		if p.Condition != "" && phaseConditions[p.Condition](cfg) == p.Negate {
+			continue
+		}
+		names = append(names, p.Name)
+	}
+	return names
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParsePhases(t *testing.T) {
+	phases, err := ParsePhases(DefaultPhases)
+	if err != nil {
+		t.Fatalf("ParsePhases(DefaultPhases) failed: %v", err)
+	}
+	if got := FormatPhases(phases); got != DefaultPhases {
+		t.Errorf("FormatPhases() = %q, want %q", got, DefaultPhases)
+	}
+	for _, p := range phases {
+		if !isBuiltinPhase(p.Name) {
+			t.Errorf("default phase %s is not built in", p.Name)
+		}
+	}
+
+	phases, err = ParsePhases("build lint?!dev check-plist?dev")
+	if err != nil {
+		t.Fatalf("ParsePhases() failed: %v", err)
+	}
+	want := []Phase{{Name: "build"}, {Name: "lint", Condition: "dev", Negate: true}, {Name: "check-plist", Condition: "dev"}}
+	if !slices.Equal(phases, want) {
+		t.Errorf("ParsePhases() = %+v, want %+v", phases, want)
+	}
+
+	for _, value := range []string{"", "build build", "build?nightly", "Build", "build?"} {
+		if _, err := ParsePhases(value); err == nil {
+			t.Errorf("ParsePhases(%q) succeeded, want error", value)
+		}
+	}
+}
+
+func TestConfig_Pipeline(t *testing.T) {
+	cfg := &Config{}
+	def := cfg.Pipeline()
+	if slices.Contains(def, "test") || slices.Contains(def, "check-plist") {
+		t.Errorf("default pipeline runs conditional phases: %v", def)
+	}
+	if len(def) != 16 || def[0] != "install-pkgs" || def[len(def)-1] != "package" {
+		t.Errorf("default pipeline = %v", def)
+	}
+
+	cfg.TestMode = true
+	cfg.CheckPlist = true
+	full := cfg.Pipeline()
+	if i := slices.Index(full, "test"); i < 0 || full[i-1] != "stage" || full[i+1] != "check-plist" {
+		t.Errorf("test mode pipeline = %v, want test between stage and check-plist", full)
+	}
+
+	if err := cfg.Override("Build_phases", "build lint?!dev check-plist?dev package", "test"); err != nil {
+		t.Fatalf("Override(Build_phases) failed: %v", err)
+	}
+	if got := strings.Join(cfg.Pipeline(), " "); got != "build lint package" {
+		t.Errorf("Pipeline() = %q outside dev mode", got)
+	}
+	cfg.DevMode = true
+	if got := strings.Join(cfg.Pipeline(), " "); got != "build check-plist package" {
+		t.Errorf("Pipeline() = %q in dev mode", got)
+	}
+}
+
+func TestParsePhaseHooksAndEnv(t *testing.T) {
+	hooks, err := ParsePhaseHooks("lint:/xports/Tools/scripts/lint.sh sbom:/usr/local/libexec/sbom")
+	if err != nil {
+		t.Fatalf("ParsePhaseHooks() failed: %v", err)
+	}
+	if hooks["lint"] != "/xports/Tools/scripts/lint.sh" || len(hooks) != 2 {
+		t.Errorf("ParsePhaseHooks() = %v", hooks)
+	}
+	if got := FormatPhaseHooks(hooks); got != "lint:/xports/Tools/scripts/lint.sh sbom:/usr/local/libexec/sbom" {
+		t.Errorf("FormatPhaseHooks() = %q", got)
+	}
+	for _, value := range []string{"lint", "lint:relative.sh", "build:/bin/true"} {
+		if _, err := ParsePhaseHooks(value); err == nil {
+			t.Errorf("ParsePhaseHooks(%q) succeeded, want error", value)
+		}
+	}
+
+	env, err := ParsePhaseEnv("build:CFLAGS=-O2,-g build:V=1 lint:STRICT=")
+	if err != nil {
+		t.Fatalf("ParsePhaseEnv() failed: %v", err)
+	}
+	if env["build"]["CFLAGS"] != "-O2,-g" || env["build"]["V"] != "1" || env["lint"]["STRICT"] != "" {
+		t.Errorf("ParsePhaseEnv() = %v", env)
+	}
+	if got := FormatPhaseEnv(env); got != "build:CFLAGS=-O2,-g build:V=1 lint:STRICT=" {
+		t.Errorf("FormatPhaseEnv() = %q", got)
+	}
+	for _, value := range []string{"build", "build:V", "build:1V=x", ":V=1"} {
+		if _, err := ParsePhaseEnv(value); err == nil {
+			t.Errorf("ParsePhaseEnv(%q) succeeded, want error", value)
+		}
+	}
+}
+
+func TestValidate_Pipeline(t *testing.T) {
+	cfg := &Config{}
+	for key, value := range map[string]string{
+		"Build_phases":      "build lint stage",
+		"Build_phase_hooks": "sbom:/usr/local/libexec/sbom",
+		"Build_phase_env":   "sbom:FORMAT=spdx",
+	} {
+		if err := cfg.Override(key, value, "test"); err != nil {
+			t.Fatalf("Override(%s) failed: %v", key, err)
+		}
+	}
+
+	var messages []string
+	for _, issue := range cfg.Validate() {
+		if strings.HasPrefix(issue.Key, "Build_phase") {
+			messages = append(messages, issue.String())
+		}
+	}
+	got := strings.Join(messages, "\n")
+	for _, want := range []string{
+		"phase lint is neither built in nor defined by Build_phase_hooks",
+		"has no package phase",
+		"phase sbom is not listed in Build_phases and never runs",
+		"Build_phase_env: phase sbom is not listed",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Validate() issues do not mention %q:\n%s", want, got)
+		}
+	}
+}
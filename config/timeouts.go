@@ -0,0 +1,125 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Timeouts bound how long a build may run. Zero values disable the
+// corresponding timeout.
+type Timeouts struct {
+	Phases     map[string]time.Duration // Per-phase command timeout, keyed by phase name
+	Total      time.Duration            // Whole build, all phases together
+	Inactivity time.Duration            // Longest stretch without build log output
+}
+
+// With returns t with the timeouts named in spec replaced. spec is a
+// comma-separated list of name=duration pairs, where name is a build phase
+// such as configure or build, total, or inactivity:
+//
+//	configure=30m,build=6h,total=12h,inactivity=1h
+//
+// A duration of 0 disables a timeout set by an earlier layer.
+func (t Timeouts) With(spec string) (Timeouts, error) {
+	phases := make(map[string]time.Duration, len(t.Phases))
+	for phase, d := range t.Phases {
+		phases[phase] = d
+	}
+	t.Phases = phases
+
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(field, "=")
+		if !ok || !validTimeoutName(name) {
+			return t, fmt.Errorf("expected phase=duration, got %s", field)
+		}
+
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 || (d > 0 && d < time.Second) {
+			return t, fmt.Errorf("%s: expected a duration of at least 1s such as 30m, got %s", name, value)
+		}
+
+		switch name {
+		case "total":
+			t.Total = d
+		case "inactivity":
+			t.Inactivity = d
+		default:
+			if d == 0 {
+				delete(t.Phases, name)
+			} else {
+				t.Phases[name] = d
+			}
+		}
+	}
+	return t, nil
+}
+
+// String formats t as accepted by With: phases sorted by name, then total
+// and inactivity.
+func (t Timeouts) String() string {
+	phases := make([]string, 0, len(t.Phases))
+	for phase := range t.Phases {
+		phases = append(phases, phase)
+	}
+	sort.Strings(phases)
+
+	var fields []string
+	for _, phase := range phases {
+		fields = append(fields, phase+"="+t.Phases[phase].String())
+	}
+	if t.Total > 0 {
+		fields = append(fields, "total="+t.Total.String())
+	}
+	if t.Inactivity > 0 {
+		fields = append(fields, "inactivity="+t.Inactivity.String())
+	}
+	return strings.Join(fields, ",")
+}
+
+// validTimeoutName reports whether name can be a phase name: lower-case
+// letters separated by dashes.
+func validTimeoutName(name string) bool {
+	if name == "" || name[0] == '-' || name[len(name)-1] == '-' {
+		return false
+	}
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// ParsePortTimeouts parses a KeyPortTimeouts value such as
+// "www/chromium:build=20h,total=24h lang/rust@default:inactivity=2h" into
+// timeout specs keyed by port origin, optionally with a flavor suffix.
+func ParsePortTimeouts(value string) (map[string]string, error) {
+	return parsePortSpecs(value, func(spec string) error {
+		_, err := Timeouts{}.With(spec)
+		return err
+	})
+}
+
+// TimeoutsFor returns the timeouts for a build of portDir with the given
+// flavor: Build_timeouts, overlaid by the port's Build_timeouts_overrides
+// entry and then by its flavor-specific entry.
+func (cfg *Config) TimeoutsFor(portDir, flavor string) Timeouts {
+	t := cfg.Timeouts
+	keys := []string{portDir}
+	if flavor != "" {
+		keys = append(keys, portDir+"@"+flavor)
+	}
+	for _, key := range keys {
+		if spec, ok := cfg.TimeoutsOverrides[key]; ok {
+			// Specs were validated when loaded
+			t, _ = t.With(spec)
+		}
+	}
+	return t
+}
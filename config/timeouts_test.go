@@ -0,0 +1,60 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeouts_With(t *testing.T) {
+	to, err := Timeouts{}.With("configure=30m,build=6h,total=12h,inactivity=1h")
+	if err != nil {
+		t.Fatalf("With() failed: %v", err)
+	}
+	if to.Phases["configure"] != 30*time.Minute || to.Phases["build"] != 6*time.Hour {
+		t.Errorf("Phases = %v", to.Phases)
+	}
+	if to.Total != 12*time.Hour || to.Inactivity != time.Hour {
+		t.Errorf("Total = %v, Inactivity = %v", to.Total, to.Inactivity)
+	}
+
+	back, err := Timeouts{}.With(to.String())
+	if err != nil || back.String() != to.String() {
+		t.Errorf("String() = %q does not round-trip: %q, %v", to.String(), back.String(), err)
+	}
+
+	// Overlaying must not modify the original's phase map
+	cleared, _ := to.With("configure=0")
+	if _, ok := cleared.Phases["configure"]; ok {
+		t.Error("configure=0 did not clear the phase timeout")
+	}
+	if to.Phases["configure"] != 30*time.Minute {
+		t.Error("With() modified the receiver's phases")
+	}
+
+	for _, spec := range []string{"build", "build=forever", "build=-1h", "build=10ms", "Build=1h", "-x=1h"} {
+		if _, err := (Timeouts{}).With(spec); err == nil {
+			t.Errorf("With(%q) succeeded, want error", spec)
+		}
+	}
+}
+
+func TestConfig_TimeoutsFor(t *testing.T) {
+	cfg := &Config{}
+	if err := cfg.Override("Build_timeouts", "configure=30m,inactivity=1h", "test"); err != nil {
+		t.Fatalf("Override(Build_timeouts) failed: %v", err)
+	}
+	if err := cfg.Override("Build_timeouts_overrides", "www/chromium:build=20h,inactivity=2h www/chromium@wayland:total=30h", "test"); err != nil {
+		t.Fatalf("Override(Build_timeouts_overrides) failed: %v", err)
+	}
+
+	vim := cfg.TimeoutsFor("editors/vim", "")
+	if vim.Inactivity != time.Hour || vim.Phases["build"] != 0 {
+		t.Errorf("TimeoutsFor(editors/vim) = %+v", vim)
+	}
+
+	chromium := cfg.TimeoutsFor("www/chromium", "wayland")
+	if chromium.Phases["configure"] != 30*time.Minute || chromium.Phases["build"] != 20*time.Hour ||
+		chromium.Inactivity != 2*time.Hour || chromium.Total != 30*time.Hour {
+		t.Errorf("TimeoutsFor(www/chromium@wayland) = %+v", chromium)
+	}
+}
@@ -0,0 +1,358 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"gopkg.in/ini.v1"
+)
+
+// Severity classifies a validation Issue.
+type Severity int
+
+const (
+	SeverityWarning Severity = iota // Suspicious but usable
+	SeverityError                   // The setting is ignored or unusable
+)
+
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// Issue is a single problem found while validating a configuration.
+type Issue struct {
+	Severity Severity
+	File     string // Config file, empty for issues about the effective configuration
+	Section  string
+	Key      string
+	Message  string
+}
+
+func (i Issue) String() string {
+	var b strings.Builder
+	if i.Section != "" {
+		fmt.Fprintf(&b, "[%s] ", i.Section)
+	}
+	if i.Key != "" {
+		fmt.Fprintf(&b, "%s: ", i.Key)
+	}
+	b.WriteString(i.Message)
+	return b.String()
+}
+
+// HasErrors reports whether any issue has SeverityError.
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateFile checks a dsynth.ini for keys LoadConfig would ignore or
+// misread: unknown or misspelled keys, malformed values and settings that
+// conflict with each other. A missing file yields no issues.
+func ValidateFile(path string) ([]Issue, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	file, err := ini.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config file: %w", err)
+	}
+
+	var issues []Issue
+	add := func(sev Severity, section, key, format string, args ...interface{}) {
+		issues = append(issues, Issue{
+			Severity: sev,
+			File:     path,
+			Section:  section,
+			Key:      key,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	for _, sec := range file.Sections() {
+		name := sec.Name()
+		global := strings.EqualFold(name, GlobalSection)
+
+		for _, key := range sec.Keys() {
+			k := key.Name()
+			switch {
+			case name == ini.DefaultSection:
+				add(SeverityError, "", k, "key outside of any section is ignored")
+				continue
+			case k == "profile_selected":
+				if !global {
+					add(SeverityWarning, name, k, "only honored in [%s]", GlobalSection)
+				}
+				continue
+			}
+
+			if _, ok := LookupKey(k); !ok {
+				if s := suggestKey(k); s != "" {
+					add(SeverityError, name, k, "unknown key (did you mean %s?)", s)
+				} else {
+					add(SeverityError, name, k, "unknown key")
+				}
+				continue
+			}
+
+			if err := ValidateKey(k, key.String()); err != nil {
+				// ValidateKey prefixes the key name; Issue adds it again.
+				add(SeverityError, name, k, "%s", strings.TrimPrefix(err.Error(), k+": "))
+				continue
+			}
+		}
+
+		// Tmpfs_workdir and Tmpfs_localbase both map to UseTmpfs, so
+		// different values cannot both be honored.
+		if sec.HasKey("Tmpfs_workdir") && sec.HasKey("Tmpfs_localbase") {
+			work, _ := parseStrictBool(sec.Key("Tmpfs_workdir").String())
+			local, _ := parseStrictBool(sec.Key("Tmpfs_localbase").String())
+			if work != local {
+				add(SeverityWarning, name, "Tmpfs_localbase",
+					"differs from Tmpfs_workdir; go-synth uses tmpfs for both when either is enabled")
+			}
+		}
+	}
+
+	if global := findSection(file, GlobalSection); global != nil && global.HasKey("profile_selected") {
+		selected := global.Key("profile_selected").String()
+		if selected != "" && findSection(file, selected) == nil {
+			add(SeverityError, global.Name(), "profile_selected",
+				"profile %q has no section; only global settings apply", selected)
+		}
+	}
+
+	return issues, nil
+}
+
+// Validate checks the effective configuration for problems that no single
+// key reveals: missing directories, paths nested inside each other and
+// unsupported combinations of settings.
+func (cfg *Config) Validate() []Issue {
+	var issues []Issue
+	add := func(sev Severity, key, format string, args ...interface{}) {
+		issues = append(issues, Issue{
+			Severity: sev,
+			Key:      key,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	// Directories that must exist before a build can start
+	required := []string{"Directory_portsdir"}
+	if cfg.SystemPath != "" {
+		required = append(required, "Directory_system")
+	}
+	for _, key := range required {
+		if _, err := os.Stat(cfg.Value(key)); err != nil {
+			add(SeverityError, key, "directory does not exist: %s", cfg.Value(key))
+		}
+	}
+
+	// Directories go-synth creates on demand
+	for _, key := range []string{
+		"Directory_buildbase", "Directory_repository", "Directory_packages",
+		"Directory_distfiles", "Directory_options", "Directory_logs",
+	} {
+		if PathWarning(key, cfg.Value(key)) != "" {
+			add(SeverityWarning, key, "directory does not exist yet and will be created: %s", cfg.Value(key))
+		}
+	}
+
+	for _, info := range KnownKeys {
+		if info.Kind != KeyPath {
+			continue
+		}
+		if v := cfg.Value(info.Name); v != "" && !filepath.IsAbs(v) {
+			add(SeverityError, info.Name, "path must be absolute: %s", v)
+		}
+	}
+
+	// Output directories must not live inside the ports tree, where
+	// port updates would clobber them.
+	for _, key := range []string{
+		"Directory_buildbase", "Directory_repository", "Directory_packages",
+		"Directory_distfiles", "Directory_options", "Directory_logs",
+		"Directory_ccache",
+	} {
+		if pathWithin(cfg.Value(key), cfg.DPortsPath) {
+			add(SeverityError, key, "%s is inside Directory_portsdir (%s)", cfg.Value(key), cfg.DPortsPath)
+		}
+	}
+
+	// Directories with distinct roles must not overlap. Repository and
+	// packages share a directory by default, so only that pair may match.
+	distinct := []string{
+		"Directory_packages", "Directory_distfiles", "Directory_options",
+		"Directory_logs", "Directory_ccache",
+	}
+	for i, a := range distinct {
+		for _, b := range distinct[i+1:] {
+			pa, pb := cfg.Value(a), cfg.Value(b)
+			switch {
+			case pa == "" || pb == "":
+			case filepath.Clean(pa) == filepath.Clean(pb):
+				add(SeverityError, b, "same directory as %s: %s", a, pb)
+			case pathWithin(pb, pa):
+				add(SeverityError, b, "%s is inside %s (%s)", pb, a, pa)
+			case pathWithin(pa, pb):
+				add(SeverityError, a, "%s is inside %s (%s)", pa, b, pb)
+			}
+		}
+	}
+
+	if cfg.Database.Path != "" && pathWithin(cfg.Database.Path, cfg.DPortsPath) {
+		add(SeverityError, "Database_path", "%s is inside Directory_portsdir (%s)", cfg.Database.Path, cfg.DPortsPath)
+	}
+
+	// Unsupported combinations
+	if cfg.MaxWorkers > MaxBuilders {
+		add(SeverityError, "Number_of_builders", "must be at most %d, got %d", MaxBuilders, cfg.MaxWorkers)
+	}
+	if cfg.SlowStart > cfg.MaxWorkers {
+		add(SeverityWarning, "Number_of_builders", "slow start (%d) exceeds the number of builders (%d)", cfg.SlowStart, cfg.MaxWorkers)
+	}
+	if limit := 4 * runtime.NumCPU(); cfg.MaxWorkers*cfg.MaxJobs > limit {
+		add(SeverityWarning, "Max_jobs_per_builder",
+			"%d builders x %d jobs oversubscribes %d CPUs", cfg.MaxWorkers, cfg.MaxJobs, runtime.NumCPU())
+	}
+	if cfg.Memory.Target > 0 && cfg.Memory.Estimate > cfg.Memory.Target {
+		add(SeverityWarning, "Memory_target", "%s is below Memory_default_estimate (%s); ports without history will build one at a time",
+			FormatSize(cfg.Memory.Target), FormatSize(cfg.Memory.Estimate))
+	}
+	listed := make(map[string]bool)
+	for _, p := range cfg.pipeline() {
+		listed[p.Name] = true
+		if _, ok := cfg.PhaseHooks[p.Name]; !ok && !isBuiltinPhase(p.Name) {
+			add(SeverityError, "Build_phases", "phase %s is neither built in nor defined by Build_phase_hooks", p.Name)
+		}
+	}
+	if !listed["package"] {
+		add(SeverityWarning, "Build_phases", "has no package phase; builds will not produce packages")
+	}
+	for _, name := range sortedKeys(cfg.PhaseHooks) {
+		if !listed[name] {
+			add(SeverityWarning, "Build_phase_hooks", "phase %s is not listed in Build_phases and never runs", name)
+		}
+	}
+	for _, name := range sortedKeys(cfg.PhaseEnv) {
+		if !listed[name] {
+			add(SeverityWarning, "Build_phase_env", "phase %s is not listed in Build_phases", name)
+		}
+	}
+	for _, key := range HookKeys {
+		script := cfg.Value(key)
+		if script == "" {
+			continue
+		}
+		if info, err := os.Stat(script); err != nil {
+			add(SeverityError, key, "script does not exist: %s", script)
+		} else if info.IsDir() || info.Mode()&0111 == 0 {
+			add(SeverityError, key, "script is not executable: %s", script)
+		}
+	}
+	if cfg.Prebuilt.Enabled {
+		repo := cfg.Prebuilt.Repository
+		switch {
+		case repo == "":
+			add(SeverityError, "leverage_prebuilt", "requires Prebuilt_repository")
+		case filepath.IsAbs(repo):
+			if _, err := os.Stat(repo); err != nil {
+				add(SeverityError, "Prebuilt_repository", "directory does not exist: %s", repo)
+			}
+		}
+	}
+	if cfg.UseVKernel {
+		add(SeverityError, "", "vkernel builders are not supported")
+	}
+
+	return issues
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// pathWithin reports whether path is strictly inside dir.
+func pathWithin(path, dir string) bool {
+	if path == "" || dir == "" {
+		return false
+	}
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(path))
+	if err != nil || rel == "." {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// findSection looks up a section without creating it. The global section is
+// matched case-insensitively, as in LoadConfig.
+func findSection(file *ini.File, name string) *ini.Section {
+	for _, sec := range file.Sections() {
+		if sec.Name() == name || (strings.EqualFold(name, GlobalSection) && strings.EqualFold(sec.Name(), name)) {
+			return sec
+		}
+	}
+	return nil
+}
+
+// suggestKey returns the known key closest to name, or an empty string if
+// none is plausibly what was meant.
+func suggestKey(name string) string {
+	lower := strings.ToLower(name)
+	best, bestDist := "", 4
+	for _, candidate := range append([]string{"profile_selected"}, knownKeyNames()...) {
+		d := editDistance(lower, strings.ToLower(candidate))
+		if d < bestDist {
+			best, bestDist = candidate, d
+		}
+	}
+	return best
+}
+
+func knownKeyNames() []string {
+	names := make([]string, len(KnownKeys))
+	for i, k := range KnownKeys {
+		names[i] = k.Name
+	}
+	return names
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
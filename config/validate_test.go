@@ -0,0 +1,208 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeINI(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "dsynth.ini")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	return path
+}
+
+func findIssue(issues []Issue, key string) (Issue, bool) {
+	for _, issue := range issues {
+		if issue.Key == key {
+			return issue, true
+		}
+	}
+	return Issue{}, false
+}
+
+func TestValidateFile(t *testing.T) {
+	path := writeINI(t, `[Global Configuration]
+profile_selected=Live
+
+[Live]
+Numbr_of_builders=4
+Number_of_builders=abc
+Directory_packages=relative/path
+Database_auto_vacuum=no
+Tmpfs_workdir=yes
+Tmpfs_localbase=no
+Directory_logs=/build/logs
+`)
+
+	issues, err := ValidateFile(path)
+	if err != nil {
+		t.Fatalf("ValidateFile failed: %v", err)
+	}
+
+	tests := []struct {
+		key      string
+		severity Severity
+		contains string
+	}{
+		{"Numbr_of_builders", SeverityError, "did you mean Number_of_builders?"},
+		{"Number_of_builders", SeverityError, "not a number"},
+		{"Directory_packages", SeverityError, "must be absolute"},
+		{"Tmpfs_localbase", SeverityWarning, "differs from Tmpfs_workdir"},
+	}
+	for _, tt := range tests {
+		issue, ok := findIssue(issues, tt.key)
+		if !ok {
+			t.Errorf("no issue reported for %s", tt.key)
+			continue
+		}
+		if issue.Severity != tt.severity {
+			t.Errorf("%s: severity = %v, want %v", tt.key, issue.Severity, tt.severity)
+		}
+		if !strings.Contains(issue.Message, tt.contains) {
+			t.Errorf("%s: message = %q, want it to contain %q", tt.key, issue.Message, tt.contains)
+		}
+		if issue.Section != "Live" {
+			t.Errorf("%s: section = %q, want Live", tt.key, issue.Section)
+		}
+	}
+
+	for _, key := range []string{"Directory_logs", "Database_auto_vacuum"} {
+		if _, ok := findIssue(issues, key); ok {
+			t.Errorf("valid key %s reported as an issue", key)
+		}
+	}
+	if _, ok := findIssue(issues, "profile_selected"); ok {
+		t.Error("profile_selected naming an existing profile reported as an issue")
+	}
+}
+
+func TestValidateFile_MissingProfile(t *testing.T) {
+	path := writeINI(t, "[Global Configuration]\nprofile_selected=Gone\n")
+
+	issues, err := ValidateFile(path)
+	if err != nil {
+		t.Fatalf("ValidateFile failed: %v", err)
+	}
+	issue, ok := findIssue(issues, "profile_selected")
+	if !ok || issue.Severity != SeverityError {
+		t.Fatalf("issues = %v, want error for profile_selected", issues)
+	}
+}
+
+func TestValidateFile_NoFile(t *testing.T) {
+	issues, err := ValidateFile(filepath.Join(t.TempDir(), "missing.ini"))
+	if err != nil {
+		t.Fatalf("ValidateFile failed: %v", err)
+	}
+	if len(issues) != 0 {
+		t.Errorf("issues = %v, want none", issues)
+	}
+}
+
+func TestSuggestKey(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"number_of_builders", "Number_of_builders"},
+		{"Directory_package", "Directory_packages"},
+		{"Max_job_per_builder", "Max_jobs_per_builder"},
+		{"profile_select", "profile_selected"},
+		{"completely_unrelated", ""},
+	}
+	for _, tt := range tests {
+		if got := suggestKey(tt.input); got != tt.want {
+			t.Errorf("suggestKey(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tmpDir := t.TempDir()
+	ports := filepath.Join(tmpDir, "ports")
+	if err := os.MkdirAll(ports, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	base := func() *Config {
+		return &Config{
+			BuildBase:      tmpDir,
+			DPortsPath:     ports,
+			RepositoryPath: filepath.Join(tmpDir, "packages"),
+			PackagesPath:   filepath.Join(tmpDir, "packages"),
+			DistFilesPath:  filepath.Join(tmpDir, "distfiles"),
+			OptionsPath:    filepath.Join(tmpDir, "options"),
+			LogsPath:       filepath.Join(tmpDir, "logs"),
+			CCachePath:     filepath.Join(tmpDir, "ccache"),
+			MaxWorkers:     1,
+			MaxJobs:        1,
+		}
+	}
+
+	if issues := base().Validate(); HasErrors(issues) {
+		t.Errorf("valid config reported errors: %v", issues)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		key    string
+	}{
+		{"packages inside ports", func(c *Config) { c.PackagesPath = filepath.Join(ports, "packages") }, "Directory_packages"},
+		{"logs equal distfiles", func(c *Config) { c.LogsPath = c.DistFilesPath }, "Directory_logs"},
+		{"ccache inside logs", func(c *Config) { c.CCachePath = filepath.Join(c.LogsPath, "ccache") }, "Directory_ccache"},
+		{"missing ports tree", func(c *Config) { c.DPortsPath = filepath.Join(tmpDir, "nope") }, "Directory_portsdir"},
+		{"missing system root", func(c *Config) { c.SystemPath = filepath.Join(tmpDir, "nope") }, "Directory_system"},
+		{"relative database", func(c *Config) { c.Database.Path = "builds.db" }, "Database_path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base()
+			tt.modify(cfg)
+			found := false
+			for _, issue := range cfg.Validate() {
+				if issue.Key == tt.key && issue.Severity == SeverityError {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("Validate() = %v, want error for %s", cfg.Validate(), tt.key)
+			}
+		})
+	}
+}
+
+func TestConfig_Source(t *testing.T) {
+	tmpDir := t.TempDir()
+	content := `[Global Configuration]
+profile_selected=Live
+
+[Live]
+Number_of_builders=3
+Directory_logs=/var/log/synth
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "dsynth.ini"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(tmpDir, "")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	want := filepath.Join(tmpDir, "dsynth.ini") + " [Live]"
+	if got := cfg.Source("Number_of_builders"); got != want {
+		t.Errorf("Source(Number_of_builders) = %q, want %q", got, want)
+	}
+	if got := cfg.Value("Directory_logs"); got != "/var/log/synth" {
+		t.Errorf("Value(Directory_logs) = %q, want /var/log/synth", got)
+	}
+	if got := cfg.Source("Directory_buildbase"); got != SourceDefault {
+		t.Errorf("Source(Directory_buildbase) = %q, want %q", got, SourceDefault)
+	}
+}
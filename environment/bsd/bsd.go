@@ -59,9 +59,14 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 )
 
+// helperWaitDelay is how long a worker helper asked to stop gets to kill
+// and reap the command's processes before it is killed itself.
+const helperWaitDelay = 10 * time.Second
+
 // NewBSDEnvironment creates a new BSD environment instance.
 //
 // This constructor is registered with the environment package to handle
@@ -316,7 +321,8 @@ func (e *BSDEnvironment) Setup(workerID int, cfg *config.Config, logger log.Libr
 // Context and timeout behavior:
 //   - Parent context cancellation always takes precedence
 //   - If cmd.Timeout > 0: Creates derived context with timeout
-//   - Uses exec.CommandContext for automatic process cleanup on cancellation
+//   - On cancellation the worker helper gets SIGTERM and reaps the command's
+//     descendants before exiting; it is killed after helperWaitDelay
 //   - Timeout errors are wrapped in ErrExecutionFailed
 //
 // Environment variables:
@@ -392,15 +398,35 @@ func (e *BSDEnvironment) Execute(ctx context.Context, cmd *environment.ExecComma
 	}
 
 	// Build worker helper arguments
-	// Format: go-synth --worker-helper --chroot=<path> --workdir=<dir> --timeout=<duration> -- <command> <args...>
+	// Format: go-synth --worker-helper --chroot=<path> --workdir=<dir> [limits] -- <command> <args...>
+	//
+	// cmd.Timeout is enforced here through execCtx rather than passed as
+	// --timeout, so a timed out command is always reported as a timeout and
+	// not as the exit code of the killed command.
 	args := []string{
 		"--worker-helper",
 		"--chroot=" + e.baseDir,
 		"--workdir=" + cmd.WorkDir,
 	}
 
-	if cmd.Timeout > 0 {
-		args = append(args, "--timeout="+cmd.Timeout.String())
+	// Nice value and resource limits, applied by the helper before it
+	// runs the command
+	if l := cmd.Limits; l != (config.Limits{}) {
+		if l.Nice != 0 {
+			args = append(args, fmt.Sprintf("--nice=%d", l.Nice))
+		}
+		if l.CPUTime > 0 {
+			args = append(args, "--cpu-time="+l.CPUTime.String())
+		}
+		if l.FileSize > 0 {
+			args = append(args, fmt.Sprintf("--file-size=%d", l.FileSize))
+		}
+		if l.OpenFiles > 0 {
+			args = append(args, fmt.Sprintf("--open-files=%d", l.OpenFiles))
+		}
+		if l.AddressSpace > 0 {
+			args = append(args, fmt.Sprintf("--address-space=%d", l.AddressSpace))
+		}
 	}
 
 	// Add separator and actual command
@@ -409,9 +435,14 @@ func (e *BSDEnvironment) Execute(ctx context.Context, cmd *environment.ExecComma
 	args = append(args, cmd.Args...)
 
 	// Create command with context support
-	// CommandContext ensures the helper process is killed if context is cancelled
-	// The helper's reaper status ensures all descendants are killed too
+	// When the context is done the helper gets SIGTERM, on which it kills
+	// and reaps all descendants of the command before exiting. Only a
+	// helper that does not exit within helperWaitDelay is killed outright.
 	execCmd := exec.CommandContext(execCtx, selfPath, args...)
+	execCmd.Cancel = func() error {
+		return execCmd.Process.Signal(syscall.SIGTERM)
+	}
+	execCmd.WaitDelay = helperWaitDelay
 
 	// Set working directory from host perspective
 	// The chroot command itself runs from root, and the command inside
@@ -480,6 +511,13 @@ func (e *BSDEnvironment) Execute(ctx context.Context, cmd *environment.ExecComma
 		Duration: duration,
 	}
 
+	// ru_maxrss is reported in kilobytes. For the helper's reaped
+	// descendants it is the peak of the largest single process, not the
+	// sum of processes that ran side by side
+	if ru, ok := execCmd.ProcessState.SysUsage().(*syscall.Rusage); ok {
+		result.MaxRSS = int64(ru.Maxrss) * 1024
+	}
+
 	// Handle errors with proper semantics
 	// CRITICAL: Non-zero exit code is NOT an error from Execute's perspective
 	if err != nil {
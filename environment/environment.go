@@ -177,6 +177,10 @@ type ExecCommand struct {
 	// Zero means no timeout.
 	// Context cancellation takes precedence.
 	Timeout time.Duration
+
+	// Limits are the nice value and resource limits the command runs
+	// with. Zero values leave the inherited settings unchanged.
+	Limits config.Limits
 }
 
 // ExecResult contains the result of command execution.
@@ -189,6 +193,11 @@ type ExecResult struct {
 	// Duration is how long the command took to execute.
 	Duration time.Duration
 
+	// MaxRSS is the largest resident set size, in bytes, reached by the
+	// command or any single one of its descendants. Processes running
+	// concurrently are not added up. Zero if the backend cannot measure it.
+	MaxRSS int64
+
 	// Error is set if command execution failed.
 	// This is different from non-zero exit code:
 	//   - err != nil: failed to execute command (e.g., chroot failed)
@@ -0,0 +1,100 @@
+package environment
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"go-synth/config"
+)
+
+// limitsHelperEnv makes the test binary act as a process that applies
+// limits and reports them, so the test process itself is not restricted.
+const limitsHelperEnv = "GOSYNTH_TEST_LIMITS_HELPER"
+
+func TestApplyLimits(t *testing.T) {
+	if os.Getenv(limitsHelperEnv) != "" {
+		return
+	}
+
+	// setpriority(2) sets an absolute nice value, and lowering the test's
+	// own niceness back to 5 would need privileges
+	prio, err := syscall.Getpriority(syscall.PRIO_PROCESS, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nice := 20 - prio; nice != 0 { // getpriority(2) returns 20-nice on Linux
+		t.Skipf("test runs at nice %d", nice)
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestApplyLimitsHelper$")
+	cmd.Env = append(os.Environ(), limitsHelperEnv+"=1")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("helper failed: %v\n%s", err, out)
+	}
+
+	want := []string{
+		"nice 5",
+		"cpu 3600 3600",
+		fmt.Sprintf("fsize %d %d", int64(8<<20), int64(8<<20)),
+		"nofile 64 64",
+		fmt.Sprintf("as %d %d", int64(4<<30), int64(4<<30)),
+	}
+	for _, line := range want {
+		if !strings.Contains(string(out), line+"\n") {
+			t.Errorf("helper output missing %q:\n%s", line, out)
+		}
+	}
+}
+
+// TestApplyLimitsHelper runs in the child started by TestApplyLimits.
+func TestApplyLimitsHelper(t *testing.T) {
+	if os.Getenv(limitsHelperEnv) == "" {
+		t.Skip("only run as a helper process")
+	}
+
+	err := ApplyLimits(config.Limits{
+		Nice:         5,
+		CPUTime:      time.Hour,
+		FileSize:     8 << 20,
+		OpenFiles:    64,
+		AddressSpace: 4 << 30,
+	})
+	if err != nil {
+		t.Fatalf("ApplyLimits failed: %v", err)
+	}
+
+	prio, err := syscall.Getpriority(syscall.PRIO_PROCESS, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Printf("nice %d\n", 20-prio)
+
+	for _, r := range []struct {
+		name     string
+		resource int
+	}{
+		{"cpu", syscall.RLIMIT_CPU},
+		{"fsize", syscall.RLIMIT_FSIZE},
+		{"nofile", syscall.RLIMIT_NOFILE},
+		{"as", syscall.RLIMIT_AS},
+	} {
+		var rlim syscall.Rlimit
+		if err := syscall.Getrlimit(r.resource, &rlim); err != nil {
+			t.Fatal(err)
+		}
+		fmt.Printf("%s %d %d\n", r.name, rlim.Cur, rlim.Max)
+	}
+}
+
+func TestApplyLimits_Unset(t *testing.T) {
+	// Zero limits must not touch the (test) process
+	if err := ApplyLimits(config.Limits{}); err != nil {
+		t.Errorf("ApplyLimits with no limits failed: %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+//go:build unix
+
+package environment
+
+import (
+	"fmt"
+	"syscall"
+
+	"go-synth/config"
+)
+
+// ApplyLimits sets the nice value and resource limits of the calling
+// process, which its children then inherit. The worker helper calls it
+// before running a build command. Resource limits set both the soft and
+// hard limit, so the command cannot raise them again.
+func ApplyLimits(l config.Limits) error {
+	if l.Nice != 0 {
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, 0, l.Nice); err != nil {
+			return fmt.Errorf("failed to set nice value %d: %w", l.Nice, err)
+		}
+	}
+
+	rlimits := []struct {
+		name     string
+		resource int
+		value    int64
+	}{
+		{"cpu_time", syscall.RLIMIT_CPU, int64(l.CPUTime.Seconds())},
+		{"file_size", syscall.RLIMIT_FSIZE, l.FileSize},
+		{"open_files", syscall.RLIMIT_NOFILE, int64(l.OpenFiles)},
+		{"address_space", syscall.RLIMIT_AS, l.AddressSpace},
+	}
+	for _, r := range rlimits {
+		if r.value <= 0 {
+			continue
+		}
+		var rlim syscall.Rlimit
+		setRlimitValue(&rlim.Cur, r.value)
+		setRlimitValue(&rlim.Max, r.value)
+		if err := syscall.Setrlimit(r.resource, &rlim); err != nil {
+			return fmt.Errorf("failed to set %s limit to %d: %w", r.name, r.value, err)
+		}
+	}
+	return nil
+}
+
+// setRlimitValue stores v in an Rlimit field, which is uint64 on Linux and
+// int64 on the BSDs.
+func setRlimitValue[T int64 | uint64](field *T, v int64) {
+	*field = T(v)
+}
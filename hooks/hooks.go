@@ -0,0 +1,149 @@
+// Package hooks runs the site-specific scripts configured with the Hook_*
+// keys of dsynth.ini when a build run or a port build starts and ends.
+//
+// Hooks run on the host, not inside a worker, and receive the details of
+// the event in GOSYNTH_* environment variables (see Env). A hook that
+// fails or runs longer than Hook_timeout is logged; it never stops the
+// build.
+//
+// Example usage:
+//
+//	hooks.Run(ctx, cfg, hooks.PortSuccess, hooks.Env{
+//	    RunID:   runID,
+//	    Port:    "editors/vim",
+//	    Version: "9.1.0470",
+//	    Status:  "success",
+//	}, logger)
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"go-synth/config"
+)
+
+// Hook events, named as in the Hook_* keys
+const (
+	RunStart    = "run-start"
+	RunEnd      = "run-end"
+	PortStart   = "port-start"
+	PortSuccess = "port-success"
+	PortFailure = "port-failure"
+	PortSkip    = "port-skip"
+)
+
+// Env holds the event details passed to a hook. Port fields are empty for
+// run events, and the counts are only set for run-end.
+type Env struct {
+	RunID   string // GOSYNTH_RUN_ID
+	Port    string // GOSYNTH_PORT: port origin, with @flavor if flavored
+	Version string // GOSYNTH_VERSION
+	Status  string // GOSYNTH_STATUS: the port's run status, or for run-end success, failed or aborted
+	Phase   string // GOSYNTH_PHASE: the phase a port failed in
+	Log     string // GOSYNTH_LOG: the port's build log
+	Package string // GOSYNTH_PACKAGE: the package file, for port-success
+
+	// run-end only
+	Success  int           // GOSYNTH_SUCCESS
+	Failed   int           // GOSYNTH_FAILED
+	Skipped  int           // GOSYNTH_SKIPPED
+	Ignored  int           // GOSYNTH_IGNORED
+	Duration time.Duration // GOSYNTH_DURATION, in whole seconds
+}
+
+// environ returns the GOSYNTH_* variables for event, leaving out empty
+// port fields.
+func (e Env) environ(event string, cfg *config.Config) []string {
+	vars := []string{
+		config.EnvPrefix + "HOOK=" + event,
+		config.EnvPrefix + "PROFILE=" + cfg.Profile,
+		config.EnvPrefix + "RUN_ID=" + e.RunID,
+		config.EnvPrefix + "LOGS_DIR=" + cfg.LogsPath,
+	}
+	add := func(name, value string) {
+		if value != "" {
+			vars = append(vars, config.EnvPrefix+name+"="+value)
+		}
+	}
+	add("PORT", e.Port)
+	add("VERSION", e.Version)
+	add("STATUS", e.Status)
+	add("PHASE", e.Phase)
+	add("LOG", e.Log)
+	add("PACKAGE", e.Package)
+
+	if event == RunEnd {
+		add("SUCCESS", strconv.Itoa(e.Success))
+		add("FAILED", strconv.Itoa(e.Failed))
+		add("SKIPPED", strconv.Itoa(e.Skipped))
+		add("IGNORED", strconv.Itoa(e.Ignored))
+		add("DURATION", strconv.Itoa(int(e.Duration.Seconds())))
+	}
+	return vars
+}
+
+// Run runs the hook configured for event, if any, and waits for it to exit
+// or for Hook_timeout to pass. Failures are logged as warnings and
+// reported in the returned error, which callers may ignore.
+func Run(ctx context.Context, cfg *config.Config, event string, env Env, logger interface {
+	Info(format string, args ...any)
+	Warn(format string, args ...any)
+}) error {
+	script := cfg.Hooks[event]
+	if script == "" {
+		return nil
+	}
+
+	timeout := cfg.HookTimeout
+	if timeout <= 0 {
+		timeout = config.DefaultHookTimeout
+	}
+	hookCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(hookCtx, script)
+	cmd.Env = append(os.Environ(), env.environ(event, cfg)...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	// Kill the script's whole process group on timeout, so children
+	// holding the output open don't outlive it
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = 5 * time.Second
+
+	start := time.Now()
+	err := cmd.Run()
+	if errors.Is(hookCtx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+	if err != nil {
+		err = fmt.Errorf("hook %s (%s) failed: %w", event, script, err)
+		if out := lastLine(output.String()); out != "" {
+			logger.Warn("%v: %s", err, out)
+		} else {
+			logger.Warn("%v", err)
+		}
+		return err
+	}
+
+	logger.Info("Hook %s (%s) completed in %s", event, script, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// lastLine returns the last non-empty line of output.
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
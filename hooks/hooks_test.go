@@ -0,0 +1,123 @@
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go-synth/config"
+)
+
+// recordingLogger collects warnings for inspection
+type recordingLogger struct {
+	warnings []string
+}
+
+func (l *recordingLogger) Info(format string, args ...any) {}
+
+func (l *recordingLogger) Warn(format string, args ...any) {
+	l.warnings = append(l.warnings, fmt.Sprintf(format, args...))
+}
+
+func writeScript(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "hook.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0755); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	return path
+}
+
+func TestRun_Environment(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "env")
+	script := writeScript(t, "env | grep ^GOSYNTH_ | sort > "+out+"\n")
+	cfg := &config.Config{
+		Profile:  "LiveSystem",
+		LogsPath: "/build/logs",
+		Hooks:    map[string]string{PortFailure: script},
+	}
+
+	logger := &recordingLogger{}
+	err := Run(context.Background(), cfg, PortFailure, Env{
+		RunID:   "run-1",
+		Port:    "editors/vim",
+		Version: "9.1.0470",
+		Status:  "failed",
+		Phase:   "configure",
+		Log:     "/build/logs/editors___vim.log",
+	}, logger)
+	if err != nil {
+		t.Fatalf("Run() failed: %v (%v)", err, logger.warnings)
+	}
+
+	content, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("hook did not run: %v", err)
+	}
+	for _, want := range []string{
+		"GOSYNTH_HOOK=port-failure",
+		"GOSYNTH_PROFILE=LiveSystem",
+		"GOSYNTH_RUN_ID=run-1",
+		"GOSYNTH_PORT=editors/vim",
+		"GOSYNTH_VERSION=9.1.0470",
+		"GOSYNTH_STATUS=failed",
+		"GOSYNTH_PHASE=configure",
+		"GOSYNTH_LOG=/build/logs/editors___vim.log",
+		"GOSYNTH_LOGS_DIR=/build/logs",
+	} {
+		if !strings.Contains(string(content), want+"\n") {
+			t.Errorf("hook environment lacks %s:\n%s", want, content)
+		}
+	}
+	if strings.Contains(string(content), "GOSYNTH_PACKAGE") || strings.Contains(string(content), "GOSYNTH_SUCCESS") {
+		t.Errorf("hook environment has variables for other events:\n%s", content)
+	}
+}
+
+func TestRun_Failures(t *testing.T) {
+	cfg := &config.Config{
+		Hooks: map[string]string{
+			RunStart: writeScript(t, "echo snapshot failed >&2\nexit 3\n"),
+			RunEnd:   writeScript(t, "sleep 10\n"),
+		},
+		HookTimeout: 200 * time.Millisecond,
+	}
+
+	logger := &recordingLogger{}
+	if err := Run(context.Background(), cfg, RunStart, Env{}, logger); err == nil {
+		t.Error("Run() succeeded for a failing hook")
+	}
+	if len(logger.warnings) != 1 || !strings.Contains(logger.warnings[0], "exit status 3: snapshot failed") {
+		t.Errorf("warnings = %q", logger.warnings)
+	}
+
+	start := time.Now()
+	if err := Run(context.Background(), cfg, RunEnd, Env{}, logger); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Run() = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("timed out hook ran for %s", elapsed)
+	}
+
+	// Events without a hook do nothing
+	if err := Run(context.Background(), cfg, PortSkip, Env{}, logger); err != nil {
+		t.Errorf("Run() for an unconfigured event = %v", err)
+	}
+}
+
+// TestEnv_ReservedVariables verifies config does not mistake hook
+// variables for configuration overrides.
+func TestEnv_ReservedVariables(t *testing.T) {
+	env := Env{RunID: "r", Port: "p", Version: "v", Status: "s", Phase: "ph", Log: "l", Package: "pk"}
+	for _, v := range env.environ(RunEnd, &config.Config{}) {
+		name, _, _ := strings.Cut(strings.TrimPrefix(v, config.EnvPrefix), "=")
+		if name != "PROFILE" && !slices.Contains(config.HookVariables, name) {
+			t.Errorf("%s%s is not listed in config.HookVariables", config.EnvPrefix, name)
+		}
+	}
+}
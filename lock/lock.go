@@ -0,0 +1,231 @@
+// Package lock provides the advisory lock that gives one go-synth process
+// at a time the use of a build base: its worker slots (SL00, SL01, ...),
+// its logs and the build database.
+//
+// The lock is a flock(2) on {BuildBase}/go-synth.lock. The file also
+// records the holder (PID, host, command and start time), so a refused
+// command can say who is building, and a lock left behind by a crashed
+// process can be recognized: the kernel drops the flock when its holder
+// dies, and the recorded PID must no longer be alive.
+//
+// Commands that only read (status, monitor, logs) do not take the lock.
+//
+// Example usage:
+//
+//	l, err := lock.Acquire(cfg.BuildBase)
+//	var held *lock.HeldError
+//	if errors.As(err, &held) {
+//	    log.Fatalf("build base busy: %v", held.Holder)
+//	}
+//	defer l.Release()
+package lock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// FileName is the name of the lock file in the build base.
+const FileName = "go-synth.lock"
+
+// Holder describes the process holding a lock.
+type Holder struct {
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	Command string    `json:"command"`
+	Started time.Time `json:"started"`
+}
+
+// String formats h for messages, e.g.
+// "PID 4242 on builder1 (go-synth build editors/vim) since 2025-01-01 12:00:00".
+func (h *Holder) String() string {
+	return fmt.Sprintf("PID %d on %s (%s) since %s",
+		h.PID, h.Host, h.Command, h.Started.Local().Format("2006-01-02 15:04:05"))
+}
+
+// HeldError is returned by Acquire when another process holds the lock.
+type HeldError struct {
+	Path   string  // Lock file
+	Holder *Holder // nil if the holder has not recorded itself yet
+
+	// Unlocked is set when the flock was free and the holder only counts
+	// as live because its recorded PID exists, as on file systems without
+	// flock support. After a reboot the PID may belong to another process.
+	Unlocked bool
+}
+
+// Error implements the error interface
+func (e *HeldError) Error() string {
+	dir := filepath.Dir(e.Path)
+	if e.Holder == nil {
+		return fmt.Sprintf("build base %s is locked by another go-synth", dir)
+	}
+	host, _ := os.Hostname()
+	if e.Holder.Host != host {
+		return fmt.Sprintf("build base %s is locked by %s; if that process is gone, remove %s",
+			dir, e.Holder, e.Path)
+	}
+	if e.Unlocked {
+		return fmt.Sprintf("build base %s is locked by %s; if that PID is not go-synth, remove %s",
+			dir, e.Holder, e.Path)
+	}
+	return fmt.Sprintf("build base %s is locked by %s", dir, e.Holder)
+}
+
+// Lock is a held build base lock.
+type Lock struct {
+	file *os.File
+
+	// Stale is the holder recorded by a process that died without
+	// releasing the lock, if any.
+	Stale *Holder
+}
+
+// Acquire takes the lock on the build base dir without waiting. If another
+// live process holds it, Acquire returns a *HeldError.
+//
+// A lock recorded by a process on another host cannot be checked, since
+// flock is not reliable across network file systems; it is treated as
+// held until its file is removed.
+func Acquire(dir string) (*Lock, error) {
+	path := filepath.Join(dir, FileName)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open lock file: %w", err)
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		holder, _ := readHolder(f)
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, &HeldError{Path: path, Holder: holder}
+		}
+		return nil, fmt.Errorf("lock %s: %w", path, err)
+	}
+
+	// The flock is ours; whoever recorded itself must be gone, unless the
+	// file system does not support flock
+	l := &Lock{file: f}
+	if prev, _ := readHolder(f); prev != nil {
+		host, _ := os.Hostname()
+		if prev.Host != host {
+			l.unlock()
+			return nil, &HeldError{Path: path, Holder: prev}
+		}
+		if prev.PID != os.Getpid() && alive(prev.PID) {
+			l.unlock()
+			return nil, &HeldError{Path: path, Holder: prev, Unlocked: true}
+		}
+		l.Stale = prev
+	}
+
+	if err := l.record(); err != nil {
+		l.unlock()
+		return nil, fmt.Errorf("record lock holder: %w", err)
+	}
+	return l, nil
+}
+
+// Release clears the recorded holder and drops the lock. The file stays,
+// so that processes waiting on it keep locking the same file.
+func (l *Lock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	err := l.file.Truncate(0)
+	if uerr := l.unlock(); err == nil {
+		err = uerr
+	}
+	return err
+}
+
+// Read returns the holder of the lock on dir, or nil if it is not held.
+// It does not take the lock.
+func Read(dir string) (*Holder, error) {
+	f, err := os.Open(filepath.Join(dir, FileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	holder, err := readHolder(f)
+	if err != nil || holder == nil {
+		return nil, err
+	}
+
+	// A shared flock succeeds only if nobody holds the lock
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_SH|syscall.LOCK_NB); err == nil {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		host, _ := os.Hostname()
+		if holder.Host == host && !alive(holder.PID) {
+			return nil, nil // stale
+		}
+	}
+	return holder, nil
+}
+
+// record writes the current process as the holder.
+func (l *Lock) record() error {
+	host, _ := os.Hostname()
+	data, err := json.Marshal(&Holder{
+		PID:     os.Getpid(),
+		Host:    host,
+		Command: strings.Join(os.Args, " "),
+		Started: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	if err := l.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := l.file.WriteAt(append(data, '\n'), 0); err != nil {
+		return err
+	}
+	return l.file.Sync()
+}
+
+// unlock drops the flock and closes the file.
+func (l *Lock) unlock() error {
+	err := syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	if cerr := l.file.Close(); err == nil {
+		err = cerr
+	}
+	l.file = nil
+	return err
+}
+
+// readHolder parses the holder recorded in f; nil if none is.
+func readHolder(f *os.File) (*Holder, error) {
+	data, err := io.ReadAll(io.NewSectionReader(f, 0, 1<<16))
+	if err != nil {
+		return nil, err
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return nil, nil
+	}
+	var holder Holder
+	if err := json.Unmarshal(data, &holder); err != nil {
+		return nil, fmt.Errorf("parse lock file: %w", err)
+	}
+	return &holder, nil
+}
+
+// alive reports whether a process with the given PID exists.
+func alive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
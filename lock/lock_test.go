@@ -0,0 +1,139 @@
+package lock
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeHolder records holder in dir's lock file without locking it.
+func writeHolder(t *testing.T, dir string, holder Holder) {
+	t.Helper()
+	data, err := json.Marshal(holder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, FileName), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// deadPID returns the PID of a process that has exited.
+func deadPID(t *testing.T) int {
+	t.Helper()
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("cannot run true: %v", err)
+	}
+	return cmd.Process.Pid
+}
+
+func TestAcquire(t *testing.T) {
+	dir := t.TempDir()
+
+	l, err := Acquire(dir)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if l.Stale != nil {
+		t.Errorf("fresh lock reports stale holder %v", l.Stale)
+	}
+
+	holder, err := Read(dir)
+	if err != nil || holder == nil {
+		t.Fatalf("Read = %v, %v; want the holder", holder, err)
+	}
+	if holder.PID != os.Getpid() {
+		t.Errorf("holder PID = %d, want %d", holder.PID, os.Getpid())
+	}
+
+	// flock locks belong to the open file, so a second Acquire conflicts
+	// even within one process
+	_, err = Acquire(dir)
+	var held *HeldError
+	if !errors.As(err, &held) {
+		t.Fatalf("second Acquire error = %v, want HeldError", err)
+	}
+	if held.Holder == nil || held.Holder.PID != os.Getpid() {
+		t.Errorf("HeldError holder = %v", held.Holder)
+	}
+
+	if err := l.Release(); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if holder, err := Read(dir); err != nil || holder != nil {
+		t.Errorf("Read after Release = %v, %v; want nil", holder, err)
+	}
+
+	l, err = Acquire(dir)
+	if err != nil {
+		t.Fatalf("Acquire after Release failed: %v", err)
+	}
+	l.Release()
+}
+
+func TestAcquire_Stale(t *testing.T) {
+	dir := t.TempDir()
+	host, _ := os.Hostname()
+	stale := Holder{PID: deadPID(t), Host: host, Command: "go-synth build", Started: time.Now().Add(-time.Hour)}
+	writeHolder(t, dir, stale)
+
+	if holder, err := Read(dir); err != nil || holder != nil {
+		t.Errorf("Read of a stale lock = %v, %v; want nil", holder, err)
+	}
+
+	l, err := Acquire(dir)
+	if err != nil {
+		t.Fatalf("Acquire over a stale lock failed: %v", err)
+	}
+	defer l.Release()
+	if l.Stale == nil || l.Stale.PID != stale.PID {
+		t.Errorf("Stale = %v, want PID %d", l.Stale, stale.PID)
+	}
+}
+
+func TestAcquire_LiveHolder(t *testing.T) {
+	host, _ := os.Hostname()
+
+	// A live process recorded without a flock (file system without flock
+	// support), and a holder on another host that cannot be checked
+	sleep := exec.Command("sleep", "30")
+	if err := sleep.Start(); err != nil {
+		t.Skipf("cannot run sleep: %v", err)
+	}
+	defer func() {
+		sleep.Process.Kill()
+		sleep.Wait()
+	}()
+
+	tests := []struct {
+		name   string
+		holder Holder
+	}{
+		{"live PID", Holder{PID: sleep.Process.Pid, Host: host, Command: "go-synth build"}},
+		{"other host", Holder{PID: deadPID(t), Host: host + "-elsewhere", Command: "go-synth build"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeHolder(t, dir, tt.holder)
+
+			_, err := Acquire(dir)
+			var held *HeldError
+			if !errors.As(err, &held) {
+				t.Fatalf("Acquire error = %v, want HeldError", err)
+			}
+			if held.Holder == nil || held.Holder.PID != tt.holder.PID {
+				t.Errorf("HeldError holder = %v, want PID %d", held.Holder, tt.holder.PID)
+			}
+			if !strings.Contains(held.Error(), "remove") {
+				t.Errorf("HeldError = %q, want a hint to remove the lock file", held.Error())
+			}
+		})
+	}
+}
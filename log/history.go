@@ -0,0 +1,242 @@
+package log
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go-synth/config"
+)
+
+// Per-build logs of a port are kept in ports/category___name/ in the logs
+// directory, one per build UUID. PackageLogPath is a symlink to the latest,
+// so readers that only want the latest log need not know about history.
+// Earlier logs are gzipped, and Log_history of them are kept.
+
+// PortLogDir returns the directory holding the per-build logs of portDir
+func PortLogDir(cfg *config.Config, portDir string) string {
+	return filepath.Join(cfg.LogsPath, "ports", strings.ReplaceAll(portDir, "/", "___"))
+}
+
+// BuildLogPath returns the log of build buildID of portDir, as written
+// during the build. Once a later build starts, it is compressed to the
+// same path with a .gz suffix.
+func BuildLogPath(cfg *config.Config, portDir, buildID string) string {
+	return filepath.Join(PortLogDir(cfg, portDir), buildID+".log")
+}
+
+// LogFile is a kept build log of a port.
+type LogFile struct {
+	BuildID    string
+	Path       string
+	Compressed bool
+	ModTime    time.Time // When the build last wrote to it
+	Size       int64     // On disk, compressed or not
+}
+
+// PortLogHistory returns the kept build logs of portDir, newest first.
+func PortLogHistory(cfg *config.Config, portDir string) ([]LogFile, error) {
+	dir := PortLogDir(cfg, portDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var logs []LogFile
+	for _, e := range entries {
+		name := e.Name()
+		f := LogFile{Path: filepath.Join(dir, name)}
+		switch {
+		case strings.HasSuffix(name, ".log.gz"):
+			f.BuildID = strings.TrimSuffix(name, ".log.gz")
+			f.Compressed = true
+		case strings.HasSuffix(name, ".log"):
+			f.BuildID = strings.TrimSuffix(name, ".log")
+		default:
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		f.ModTime = info.ModTime()
+		f.Size = info.Size()
+		logs = append(logs, f)
+	}
+
+	sort.Slice(logs, func(i, j int) bool {
+		if !logs[i].ModTime.Equal(logs[j].ModTime) {
+			return logs[i].ModTime.After(logs[j].ModTime)
+		}
+		return logs[i].BuildID > logs[j].BuildID
+	})
+	return logs, nil
+}
+
+// FindPortLog returns the kept log of portDir whose build UUID is or starts
+// with buildID, so the short UUIDs shown in the build logs work too.
+func FindPortLog(cfg *config.Config, portDir, buildID string) (LogFile, error) {
+	logs, err := PortLogHistory(cfg, portDir)
+	if err != nil {
+		return LogFile{}, err
+	}
+
+	var found []LogFile
+	for _, f := range logs {
+		if f.BuildID == buildID {
+			return f, nil
+		}
+		if strings.HasPrefix(f.BuildID, buildID) {
+			found = append(found, f)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return LogFile{}, fmt.Errorf("no log of build %s of %s", buildID, portDir)
+	case 1:
+		return found[0], nil
+	}
+	return LogFile{}, fmt.Errorf("build %s of %s is ambiguous, %d logs match", buildID, portDir, len(found))
+}
+
+// Open opens the log for reading, decompressing it if needed.
+func (f LogFile) Open() (io.ReadCloser, error) {
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	if !f.Compressed {
+		return file, nil
+	}
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", f.Path, err)
+	}
+	return &gzipReadCloser{Reader: zr, file: file}, nil
+}
+
+type gzipReadCloser struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (r *gzipReadCloser) Close() error {
+	return errors.Join(r.Reader.Close(), r.file.Close())
+}
+
+// createBuildLog creates the log of build buildID of portDir, points the
+// latest symlink at it, and compresses and prunes the earlier logs.
+func createBuildLog(cfg *config.Config, portDir, buildID string) (*os.File, error) {
+	dir := PortLogDir(cfg, portDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	// A log from before history was kept becomes the first earlier log
+	latest := PackageLogPath(cfg, portDir)
+	if info, err := os.Lstat(latest); err == nil && info.Mode().IsRegular() {
+		legacy := filepath.Join(dir, "legacy-"+info.ModTime().Format("20060102T150405")+".log")
+		if err := os.Rename(latest, legacy); err != nil {
+			return nil, err
+		}
+	}
+
+	path := BuildLogPath(cfg, portDir, buildID)
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Replace the symlink atomically, so readers always find a log
+	target, err := filepath.Rel(filepath.Dir(latest), path)
+	if err != nil {
+		target = path
+	}
+	tmp := latest + ".tmp"
+	os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err == nil {
+		err = os.Rename(tmp, latest)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to link latest log of %s: %v\n", portDir, err)
+	}
+
+	if err := rotatePortLogs(cfg, portDir, buildID); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to rotate logs of %s: %v\n", portDir, err)
+	}
+	return file, nil
+}
+
+// rotatePortLogs compresses the logs of portDir other than that of build
+// current, and removes all but the newest Log_history of them.
+func rotatePortLogs(cfg *config.Config, portDir, current string) error {
+	logs, err := PortLogHistory(cfg, portDir)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	kept := 0
+	for _, f := range logs {
+		if f.BuildID == current {
+			continue
+		}
+		if kept >= cfg.LogHistory {
+			if err := os.Remove(f.Path); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		kept++
+		if !f.Compressed {
+			if err := compressLog(f.Path, f.ModTime); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// compressLog replaces the log at path with a gzipped copy at path.gz,
+// keeping its modification time so history stays in build order.
+func compressLog(path string, modTime time.Time) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	gzPath := path + ".gz"
+	out, err := os.Create(gzPath)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	zw.Name = filepath.Base(path)
+	zw.ModTime = modTime
+	_, err = io.Copy(zw, in)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chtimes(gzPath, modTime, modTime)
+	}
+	if err != nil {
+		os.Remove(gzPath)
+		return fmt.Errorf("compress %s: %w", path, err)
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,135 @@
+package log
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-synth/config"
+)
+
+// writeBuildLog writes a build log of portDir the way a build does, and
+// backdates it by age so builds sort in the order they are written.
+func writeBuildLog(t *testing.T, cfg *config.Config, portDir, buildID, content string, age time.Duration) {
+	t.Helper()
+	pl := NewPackageLogger(cfg, portDir, buildID)
+	pl.WriteString(content)
+	pl.Close()
+
+	mtime := time.Now().Add(-age)
+	if err := os.Chtimes(BuildLogPath(cfg, portDir, buildID), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readLogFile(t *testing.T, f LogFile) string {
+	t.Helper()
+	r, err := f.Open()
+	if err != nil {
+		t.Fatalf("Open %s failed: %v", f.Path, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Read %s failed: %v", f.Path, err)
+	}
+	return string(data)
+}
+
+func TestPackageLogger_History(t *testing.T) {
+	cfg := &config.Config{LogsPath: t.TempDir(), LogHistory: 2}
+
+	writeBuildLog(t, cfg, "editors/vim", "build-1", "first\n", 4*time.Hour)
+	writeBuildLog(t, cfg, "editors/vim", "build-2", "second\n", 3*time.Hour)
+	writeBuildLog(t, cfg, "editors/vim", "build-3", "third\n", 2*time.Hour)
+	writeBuildLog(t, cfg, "editors/vim", "build-4", "fourth\n", time.Hour)
+
+	// The latest symlink follows the newest build
+	latest, err := os.ReadFile(PackageLogPath(cfg, "editors/vim"))
+	if err != nil || string(latest) != "fourth\n" {
+		t.Errorf("latest log = %q, %v, want the fourth build", latest, err)
+	}
+	if info, err := os.Lstat(PackageLogPath(cfg, "editors/vim")); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("latest log is not a symlink: %v", err)
+	}
+
+	logs, err := PortLogHistory(cfg, "editors/vim")
+	if err != nil {
+		t.Fatalf("PortLogHistory failed: %v", err)
+	}
+	var ids []string
+	for _, f := range logs {
+		ids = append(ids, f.BuildID)
+	}
+	if strings.Join(ids, " ") != "build-4 build-3 build-2" {
+		t.Fatalf("history = %v, want the latest and 2 earlier builds", ids)
+	}
+	if logs[0].Compressed || !logs[1].Compressed || !logs[2].Compressed {
+		t.Errorf("only the earlier logs should be compressed: %+v", logs)
+	}
+	if got := readLogFile(t, logs[1]); got != "third\n" {
+		t.Errorf("decompressed log = %q, want third", got)
+	}
+}
+
+func TestPackageLogger_LegacyLog(t *testing.T) {
+	cfg := &config.Config{LogsPath: t.TempDir(), LogHistory: 5}
+	if err := os.WriteFile(PackageLogPath(cfg, "shells/bash"), []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	writeBuildLog(t, cfg, "shells/bash", "build-1", "new\n", 0)
+
+	logs, err := PortLogHistory(cfg, "shells/bash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 2 || !strings.HasPrefix(logs[1].BuildID, "legacy-") {
+		t.Fatalf("history = %+v, want the new log and the legacy one", logs)
+	}
+	if got := readLogFile(t, logs[1]); got != "old\n" {
+		t.Errorf("legacy log = %q", got)
+	}
+}
+
+func TestFindPortLog(t *testing.T) {
+	cfg := &config.Config{LogsPath: t.TempDir(), LogHistory: 5}
+	writeBuildLog(t, cfg, "devel/git", "a1b2c3d4-0000", "one\n", 2*time.Hour)
+	writeBuildLog(t, cfg, "devel/git", "a1b2ffff-0000", "two\n", time.Hour)
+
+	f, err := FindPortLog(cfg, "devel/git", "a1b2c3")
+	if err != nil {
+		t.Fatalf("FindPortLog by prefix failed: %v", err)
+	}
+	if got := readLogFile(t, f); got != "one\n" {
+		t.Errorf("found log = %q, want the first build", got)
+	}
+
+	if _, err := FindPortLog(cfg, "devel/git", "a1b2"); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("ambiguous prefix = %v", err)
+	}
+	if _, err := FindPortLog(cfg, "devel/git", "ffff"); err == nil {
+		t.Error("unknown build found")
+	}
+	if logs, err := PortLogHistory(cfg, "devel/none"); err != nil || logs != nil {
+		t.Errorf("history of a never built port = %v, %v", logs, err)
+	}
+}
+
+func TestNewPackageLogger_WithoutBuildID(t *testing.T) {
+	cfg := &config.Config{LogsPath: t.TempDir(), LogHistory: 5}
+	writeBuildLog(t, cfg, "www/nginx", "build-1", "kept\n", 0)
+
+	// Writing without a build UUID must not clobber the kept log
+	pl := NewPackageLogger(cfg, "www/nginx", "")
+	pl.WriteString("plain\n")
+	pl.Close()
+
+	data, err := os.ReadFile(filepath.Join(PortLogDir(cfg, "www/nginx"), "build-1.log"))
+	if err != nil || string(data) != "kept\n" {
+		t.Errorf("kept log = %q, %v", data, err)
+	}
+}
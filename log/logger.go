@@ -1,6 +1,7 @@
 package log
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -25,14 +26,44 @@ type Logger struct {
 	abnormalFile *os.File
 	obsoleteFile *os.File
 	debugFile    *os.File
+	testFile     *os.File
+	jsonFile     *os.File // 09_log.jsonl, nil unless Log_format is json or both
+	runID        string
+	opened       bool // See Open
 	mu           sync.Mutex
 }
 
+// JSONLogName is the structured log written when Log_format is json or both.
+const JSONLogName = "09_log.jsonl"
+
+// Log levels of Entry
+const (
+	LevelDebug = "debug"
+	LevelInfo  = "info"
+	LevelWarn  = "warn"
+	LevelError = "error"
+)
+
+// Entry is a line of the structured JSON log. Port builds carry their
+// context; Event tells build outcomes apart from plain messages.
+type Entry struct {
+	Time    time.Time `json:"ts"`
+	Level   string    `json:"level"`
+	Event   string    `json:"event,omitempty"` // success, failed, timeout, skipped, ignored, abnormal, test, summary
+	RunID   string    `json:"run_id,omitempty"`
+	BuildID string    `json:"build_id,omitempty"` // Build UUID
+	Port    string    `json:"port,omitempty"`
+	Worker  *int      `json:"worker,omitempty"`
+	Phase   string    `json:"phase,omitempty"`
+	Message string    `json:"msg"`
+}
+
 // LogContext provides metadata for contextual logging
 type LogContext struct {
 	BuildID  string // Build UUID (full or short)
 	PortDir  string // Port directory (e.g., "editors/vim")
 	WorkerID int    // Worker ID (0-based)
+	Phase    string // Current build phase, see ContextLogger.SetPhase
 }
 
 // ContextLogger wraps Logger with context metadata for enriched log entries
@@ -41,62 +72,91 @@ type ContextLogger struct {
 	ctx    LogContext
 }
 
-// NewLogger creates a new logger
+// NewLogger creates a new logger and opens its log files, replacing the
+// logs of the previous run.
 func NewLogger(cfg *config.Config) (*Logger, error) {
+	l := NewDeferredLogger(cfg)
+	if err := l.Open(); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// NewDeferredLogger creates a logger that leaves the log files alone until
+// Open is called, so commands that do not build can share the logs
+// directory with a running build. Messages before Open are dropped.
+func NewDeferredLogger(cfg *config.Config) *Logger {
+	return &Logger{cfg: cfg}
+}
+
+// Open creates the log files, replacing those of the previous run. It does
+// nothing if they are already open.
+func (l *Logger) Open() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.opened {
+		return nil
+	}
+
+	cfg := l.cfg
+
 	// Ensure logs directory exists
 	if err := os.MkdirAll(cfg.LogsPath, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create logs directory: %w", err)
+		return fmt.Errorf("failed to create logs directory: %w", err)
 	}
 
-	l := &Logger{cfg: cfg}
+	create := func(name string) (*os.File, error) {
+		return os.Create(filepath.Join(cfg.LogsPath, name))
+	}
 
 	// Open all log files
 	var err error
 
-	l.resultsFile, err = os.Create(filepath.Join(cfg.LogsPath, "00_last_results.log"))
-	if err != nil {
-		return nil, err
+	// The message logs follow Log_format; the lists are always written
+	if cfg.TextLog() {
+		if l.resultsFile, err = create("00_last_results.log"); err != nil {
+			return err
+		}
+		if l.debugFile, err = create("07_debug.log"); err != nil {
+			return err
+		}
 	}
 
-	l.successFile, err = os.Create(filepath.Join(cfg.LogsPath, "01_success_list.log"))
-	if err != nil {
-		return nil, err
+	if cfg.JSONLog() {
+		if l.jsonFile, err = create(JSONLogName); err != nil {
+			return err
+		}
 	}
 
-	l.failureFile, err = os.Create(filepath.Join(cfg.LogsPath, "02_failure_list.log"))
-	if err != nil {
-		return nil, err
+	if l.successFile, err = create("01_success_list.log"); err != nil {
+		return err
 	}
-
-	l.ignoredFile, err = os.Create(filepath.Join(cfg.LogsPath, "03_ignored_list.log"))
-	if err != nil {
-		return nil, err
+	if l.failureFile, err = create("02_failure_list.log"); err != nil {
+		return err
 	}
-
-	l.skippedFile, err = os.Create(filepath.Join(cfg.LogsPath, "04_skipped_list.log"))
-	if err != nil {
-		return nil, err
+	if l.ignoredFile, err = create("03_ignored_list.log"); err != nil {
+		return err
 	}
-
-	l.abnormalFile, err = os.Create(filepath.Join(cfg.LogsPath, "05_abnormal_command_output.log"))
-	if err != nil {
-		return nil, err
+	if l.skippedFile, err = create("04_skipped_list.log"); err != nil {
+		return err
 	}
-
-	l.obsoleteFile, err = os.Create(filepath.Join(cfg.LogsPath, "06_obsolete_packages.log"))
-	if err != nil {
-		return nil, err
+	if l.abnormalFile, err = create("05_abnormal_command_output.log"); err != nil {
+		return err
 	}
-
-	l.debugFile, err = os.Create(filepath.Join(cfg.LogsPath, "07_debug.log"))
-	if err != nil {
-		return nil, err
+	if l.obsoleteFile, err = create("06_obsolete_packages.log"); err != nil {
+		return err
+	}
+	if l.testFile, err = create("08_test_summary.log"); err != nil {
+		return err
 	}
 
 	// Write headers
 	l.writeHeaders()
+	l.opened = true
 
-	return l, nil
+	return nil
 }
 
 // Close closes all log files
@@ -128,14 +188,60 @@ func (l *Logger) Close() {
 	if l.debugFile != nil {
 		l.debugFile.Close()
 	}
+	if l.testFile != nil {
+		l.testFile.Close()
+	}
+	if l.jsonFile != nil {
+		l.jsonFile.Close()
+	}
+}
+
+// SetRunID sets the build run ID recorded in the structured log.
+func (l *Logger) SetRunID(runID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.runID = runID
+}
+
+// write writes line to the text logs among files that are open, and e to
+// the structured log if enabled. Callers hold l.mu.
+func (l *Logger) write(e Entry, line string, files ...*os.File) {
+	for _, f := range files {
+		if f != nil {
+			f.WriteString(line)
+			f.Sync()
+		}
+	}
+
+	if l.jsonFile == nil {
+		return
+	}
+	e.Time = time.Now()
+	e.RunID = l.runID
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	l.jsonFile.Write(append(data, '\n'))
+}
+
+// writeList appends line to the list log f, if the logs are open. Callers
+// hold l.mu.
+func writeList(f *os.File, line string) {
+	if f != nil {
+		f.WriteString(line)
+		f.Sync()
+	}
 }
 
 // writeHeaders writes initial headers to log files
 func (l *Logger) writeHeaders() {
 	timestamp := time.Now().Format(time.RFC3339)
 
-	fmt.Fprintf(l.resultsFile, "go-synth build log - %s\n", timestamp)
-	fmt.Fprintf(l.resultsFile, "%s\n\n", strings.Repeat("=", 70))
+	if l.resultsFile != nil {
+		fmt.Fprintf(l.resultsFile, "go-synth build log - %s\n", timestamp)
+		fmt.Fprintf(l.resultsFile, "%s\n\n", strings.Repeat("=", 70))
+	}
 
 	fmt.Fprintf(l.successFile, "Successful builds - %s\n\n", timestamp)
 	fmt.Fprintf(l.failureFile, "Failed builds - %s\n\n", timestamp)
@@ -143,7 +249,10 @@ func (l *Logger) writeHeaders() {
 	fmt.Fprintf(l.skippedFile, "Skipped packages - %s\n\n", timestamp)
 	fmt.Fprintf(l.abnormalFile, "Abnormal output - %s\n\n", timestamp)
 	fmt.Fprintf(l.obsoleteFile, "Obsolete packages - %s\n\n", timestamp)
-	fmt.Fprintf(l.debugFile, "Debug log - %s\n\n", timestamp)
+	if l.debugFile != nil {
+		fmt.Fprintf(l.debugFile, "Debug log - %s\n\n", timestamp)
+	}
+	fmt.Fprintf(l.testFile, "Test results - %s\n\n", timestamp)
 }
 
 // Success logs a successful build
@@ -154,11 +263,8 @@ func (l *Logger) Success(portDir string) {
 	timestamp := time.Now().Format("15:04:05")
 	msg := fmt.Sprintf("[%s] SUCCESS: %s\n", timestamp, portDir)
 
-	l.resultsFile.WriteString(msg)
-	l.successFile.WriteString(portDir + "\n")
-
-	l.resultsFile.Sync()
-	l.successFile.Sync()
+	l.write(Entry{Level: LevelInfo, Event: "success", Port: portDir, Message: "build succeeded"}, msg, l.resultsFile)
+	writeList(l.successFile, portDir+"\n")
 }
 
 // Failed logs a failed build
@@ -169,11 +275,50 @@ func (l *Logger) Failed(portDir, phase string) {
 	timestamp := time.Now().Format("15:04:05")
 	msg := fmt.Sprintf("[%s] FAILED: %s (phase: %s)\n", timestamp, portDir, phase)
 
-	l.resultsFile.WriteString(msg)
-	l.failureFile.WriteString(fmt.Sprintf("%s (phase: %s)\n", portDir, phase))
+	l.write(Entry{Level: LevelError, Event: "failed", Port: portDir, Phase: phase, Message: "build failed"}, msg, l.resultsFile)
+	writeList(l.failureFile, fmt.Sprintf("%s (phase: %s)\n", portDir, phase))
+}
 
-	l.resultsFile.Sync()
-	l.failureFile.Sync()
+// TimedOut logs a build killed by a timeout. It is listed with the
+// failures, noting which timeout expired.
+func (l *Logger) TimedOut(portDir, phase, reason string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	timestamp := time.Now().Format("15:04:05")
+	msg := fmt.Sprintf("[%s] TIMEOUT: %s (phase: %s, %s)\n", timestamp, portDir, phase, reason)
+
+	l.write(Entry{Level: LevelError, Event: "timeout", Port: portDir, Phase: phase, Message: "build timed out: " + reason}, msg, l.resultsFile)
+	writeList(l.failureFile, fmt.Sprintf("%s (phase: %s, timeout: %s)\n", portDir, phase, reason))
+}
+
+// TestResult logs the outcome of a port's test phase in test mode. Test
+// results are kept apart from build results: a port whose tests fail still
+// counts as built.
+func (l *Logger) TestResult(portDir string, passed bool, duration time.Duration, detail string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	timestamp := time.Now().Format("15:04:05")
+	if passed {
+		l.write(Entry{Level: LevelInfo, Event: "test", Port: portDir, Phase: "test", Message: "tests passed"},
+			fmt.Sprintf("[%s] TEST PASSED: %s\n", timestamp, portDir), l.resultsFile)
+		writeList(l.testFile, fmt.Sprintf("PASS %s (%s)\n", portDir, duration.Round(time.Second)))
+	} else {
+		l.write(Entry{Level: LevelWarn, Event: "test", Port: portDir, Phase: "test", Message: "tests failed: " + detail},
+			fmt.Sprintf("[%s] TEST FAILED: %s (%s)\n", timestamp, portDir, detail), l.resultsFile)
+		writeList(l.testFile, fmt.Sprintf("FAIL %s (%s): %s\n", portDir, duration.Round(time.Second), detail))
+	}
+}
+
+// WriteTestSummary appends the pass and fail totals to the test summary log
+func (l *Logger) WriteTestSummary(passed, failed int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	writeList(l.testFile, fmt.Sprintf("\n%s\nTests run:         %d\nPassed:            %d\nFailed:            %d\n%s\n",
+		strings.Repeat("=", 70), passed+failed, passed, failed, strings.Repeat("=", 70)))
+	l.write(Entry{Level: LevelInfo, Event: "summary", Message: fmt.Sprintf("tests run: %d, passed: %d, failed: %d", passed+failed, passed, failed)}, "")
 }
 
 // Skipped logs a skipped package
@@ -184,11 +329,8 @@ func (l *Logger) Skipped(portDir string) {
 	timestamp := time.Now().Format("15:04:05")
 	msg := fmt.Sprintf("[%s] SKIPPED: %s\n", timestamp, portDir)
 
-	l.resultsFile.WriteString(msg)
-	l.skippedFile.WriteString(portDir + "\n")
-
-	l.resultsFile.Sync()
-	l.skippedFile.Sync()
+	l.write(Entry{Level: LevelInfo, Event: "skipped", Port: portDir, Message: "skipped"}, msg, l.resultsFile)
+	writeList(l.skippedFile, portDir+"\n")
 }
 
 // Ignored logs an ignored package
@@ -199,11 +341,8 @@ func (l *Logger) Ignored(portDir, reason string) {
 	timestamp := time.Now().Format("15:04:05")
 	msg := fmt.Sprintf("[%s] IGNORED: %s (%s)\n", timestamp, portDir, reason)
 
-	l.resultsFile.WriteString(msg)
-	l.ignoredFile.WriteString(fmt.Sprintf("%s: %s\n", portDir, reason))
-
-	l.resultsFile.Sync()
-	l.ignoredFile.Sync()
+	l.write(Entry{Level: LevelInfo, Event: "ignored", Port: portDir, Message: reason}, msg, l.resultsFile)
+	writeList(l.ignoredFile, fmt.Sprintf("%s: %s\n", portDir, reason))
 }
 
 // Abnormal logs abnormal command output
@@ -214,8 +353,7 @@ func (l *Logger) Abnormal(portDir, output string) {
 	timestamp := time.Now().Format("15:04:05")
 	msg := fmt.Sprintf("[%s] ABNORMAL: %s\n%s\n\n", timestamp, portDir, output)
 
-	l.abnormalFile.WriteString(msg)
-	l.abnormalFile.Sync()
+	l.write(Entry{Level: LevelWarn, Event: "abnormal", Port: portDir, Message: output}, msg, l.abnormalFile)
 }
 
 // Obsolete logs an obsolete package
@@ -223,8 +361,15 @@ func (l *Logger) Obsolete(pkgFile string) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	l.obsoleteFile.WriteString(pkgFile + "\n")
-	l.obsoleteFile.Sync()
+	writeList(l.obsoleteFile, pkgFile+"\n")
+}
+
+// Orphaned logs a package no port in the tree produces any more
+func (l *Logger) Orphaned(pkgFile string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	writeList(l.obsoleteFile, pkgFile+" (orphaned)\n")
 }
 
 // Debug logs debug information
@@ -239,8 +384,7 @@ func (l *Logger) Debug(format string, args ...any) {
 
 	timestamp := time.Now().Format("15:04:05")
 	msg := fmt.Sprintf(format, args...)
-	l.debugFile.WriteString(fmt.Sprintf("[%s] %s\n", timestamp, msg))
-	l.debugFile.Sync()
+	l.write(Entry{Level: LevelDebug, Message: msg}, fmt.Sprintf("[%s] %s\n", timestamp, msg), l.debugFile)
 }
 
 // Error logs an error message
@@ -252,11 +396,7 @@ func (l *Logger) Error(format string, args ...any) {
 	msg := fmt.Sprintf(format, args...)
 	errMsg := fmt.Sprintf("[%s] ERROR: %s\n", timestamp, msg)
 
-	l.resultsFile.WriteString(errMsg)
-	l.debugFile.WriteString(errMsg)
-
-	l.resultsFile.Sync()
-	l.debugFile.Sync()
+	l.write(Entry{Level: LevelError, Message: msg}, errMsg, l.resultsFile, l.debugFile)
 }
 
 // Warn logs a warning message (non-fatal issues)
@@ -268,11 +408,7 @@ func (l *Logger) Warn(format string, args ...any) {
 	msg := fmt.Sprintf(format, args...)
 	warnMsg := fmt.Sprintf("[%s] WARN: %s\n", timestamp, msg)
 
-	l.resultsFile.WriteString(warnMsg)
-	l.debugFile.WriteString(warnMsg)
-
-	l.resultsFile.Sync()
-	l.debugFile.Sync()
+	l.write(Entry{Level: LevelWarn, Message: msg}, warnMsg, l.resultsFile, l.debugFile)
 }
 
 // Info logs an informational message
@@ -282,8 +418,7 @@ func (l *Logger) Info(format string, args ...any) {
 
 	timestamp := time.Now().Format("15:04:05")
 	msg := fmt.Sprintf(format, args...)
-	l.resultsFile.WriteString(fmt.Sprintf("[%s] INFO: %s\n", timestamp, msg))
-	l.resultsFile.Sync()
+	l.write(Entry{Level: LevelInfo, Message: msg}, fmt.Sprintf("[%s] INFO: %s\n", timestamp, msg), l.resultsFile)
 }
 
 // InfoTerminal logs an informational message to both files and terminal (stderr).
@@ -298,8 +433,7 @@ func (l *Logger) InfoTerminal(format string, args ...any) {
 	logMsg := fmt.Sprintf("[%s] INFO: %s\n", timestamp, msg)
 
 	// Write to log file
-	l.resultsFile.WriteString(logMsg)
-	l.resultsFile.Sync()
+	l.write(Entry{Level: LevelInfo, Message: msg}, logMsg, l.resultsFile)
 
 	// Also write to terminal (stderr for visibility even when stdout is redirected)
 	fmt.Fprintf(os.Stderr, "%s\n", msg)
@@ -310,6 +444,13 @@ func (l *Logger) WriteSummary(total, success, failed, skipped, ignored int, dura
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
+	l.write(Entry{Level: LevelInfo, Event: "summary", Message: fmt.Sprintf(
+		"total: %d, success: %d, failed: %d, skipped: %d, ignored: %d, duration: %s",
+		total, success, failed, skipped, ignored, duration)}, "")
+	if l.resultsFile == nil {
+		return
+	}
+
 	fmt.Fprintf(l.resultsFile, "\n%s\n", strings.Repeat("=", 70))
 	fmt.Fprintf(l.resultsFile, "BUILD SUMMARY\n")
 	fmt.Fprintf(l.resultsFile, "%s\n", strings.Repeat("=", 70))
@@ -343,6 +484,26 @@ func (l *Logger) WithContext(ctx LogContext) *ContextLogger {
 	}
 }
 
+// SetPhase records the phase the port's build is in, for the structured
+// log. The text prefix does not show it.
+func (cl *ContextLogger) SetPhase(phase string) {
+	cl.ctx.Phase = phase
+}
+
+// entry returns a structured log entry carrying the context.
+func (cl *ContextLogger) entry(level, event, msg string) Entry {
+	worker := cl.ctx.WorkerID
+	return Entry{
+		Level:   level,
+		Event:   event,
+		BuildID: cl.ctx.BuildID,
+		Port:    cl.ctx.PortDir,
+		Worker:  &worker,
+		Phase:   cl.ctx.Phase,
+		Message: msg,
+	}
+}
+
 // formatPrefix creates a log prefix with context metadata
 func (cl *ContextLogger) formatPrefix() string {
 	shortUUID := cl.ctx.BuildID
@@ -364,11 +525,8 @@ func (cl *ContextLogger) Success(msg string) {
 	timestamp := time.Now().Format("15:04:05")
 	fullMsg := fmt.Sprintf("[%s] %sSUCCESS: %s\n", timestamp, prefix, msg)
 
-	cl.logger.resultsFile.WriteString(fullMsg)
-	cl.logger.successFile.WriteString(cl.ctx.PortDir + "\n")
-
-	cl.logger.resultsFile.Sync()
-	cl.logger.successFile.Sync()
+	cl.logger.write(cl.entry(LevelInfo, "success", msg), fullMsg, cl.logger.resultsFile)
+	writeList(cl.logger.successFile, cl.ctx.PortDir+"\n")
 }
 
 // Failed logs a failed build with context
@@ -381,12 +539,10 @@ func (cl *ContextLogger) Failed(phase, msg string) {
 	fullMsg := fmt.Sprintf("[%s] %sFAILED: %s (phase: %s)\n",
 		timestamp, prefix, msg, phase)
 
-	cl.logger.resultsFile.WriteString(fullMsg)
-	cl.logger.failureFile.WriteString(fmt.Sprintf("%s (phase: %s)\n",
-		cl.ctx.PortDir, phase))
-
-	cl.logger.resultsFile.Sync()
-	cl.logger.failureFile.Sync()
+	e := cl.entry(LevelError, "failed", msg)
+	e.Phase = phase
+	cl.logger.write(e, fullMsg, cl.logger.resultsFile)
+	writeList(cl.logger.failureFile, fmt.Sprintf("%s (phase: %s)\n", cl.ctx.PortDir, phase))
 }
 
 // Info logs an informational message with context
@@ -399,8 +555,7 @@ func (cl *ContextLogger) Info(format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
 	fullMsg := fmt.Sprintf("[%s] %sINFO: %s\n", timestamp, prefix, msg)
 
-	cl.logger.resultsFile.WriteString(fullMsg)
-	cl.logger.resultsFile.Sync()
+	cl.logger.write(cl.entry(LevelInfo, "", msg), fullMsg, cl.logger.resultsFile)
 }
 
 // Error logs an error message with context
@@ -413,11 +568,7 @@ func (cl *ContextLogger) Error(format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
 	fullMsg := fmt.Sprintf("[%s] %sERROR: %s\n", timestamp, prefix, msg)
 
-	cl.logger.resultsFile.WriteString(fullMsg)
-	cl.logger.debugFile.WriteString(fullMsg)
-
-	cl.logger.resultsFile.Sync()
-	cl.logger.debugFile.Sync()
+	cl.logger.write(cl.entry(LevelError, "", msg), fullMsg, cl.logger.resultsFile, cl.logger.debugFile)
 }
 
 // Debug logs debug information with context
@@ -435,8 +586,7 @@ func (cl *ContextLogger) Debug(format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
 	fullMsg := fmt.Sprintf("[%s] %sDEBUG: %s\n", timestamp, prefix, msg)
 
-	cl.logger.debugFile.WriteString(fullMsg)
-	cl.logger.debugFile.Sync()
+	cl.logger.write(cl.entry(LevelDebug, "", msg), fullMsg, cl.logger.debugFile)
 }
 
 // Warn logs a warning message with context
@@ -449,9 +599,5 @@ func (cl *ContextLogger) Warn(format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
 	fullMsg := fmt.Sprintf("[%s] %sWARN: %s\n", timestamp, prefix, msg)
 
-	cl.logger.resultsFile.WriteString(fullMsg)
-	cl.logger.debugFile.WriteString(fullMsg)
-
-	cl.logger.resultsFile.Sync()
-	cl.logger.debugFile.Sync()
+	cl.logger.write(cl.entry(LevelWarn, "", msg), fullMsg, cl.logger.resultsFile, cl.logger.debugFile)
 }
@@ -1,6 +1,8 @@
 package log
 
 import (
+	"bufio"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"strings"
@@ -37,6 +39,7 @@ func TestNewLogger(t *testing.T) {
 		"05_abnormal_command_output.log",
 		"06_obsolete_packages.log",
 		"07_debug.log",
+		"08_test_summary.log",
 	}
 
 	for _, filename := range expectedFiles {
@@ -132,6 +135,77 @@ func TestLogger_Failed(t *testing.T) {
 	}
 }
 
+func TestLogger_TimedOut(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		LogsPath: filepath.Join(tempDir, "logs"),
+	}
+
+	logger, err := NewLogger(cfg)
+	if err != nil {
+		t.Fatalf("NewLogger failed: %v", err)
+	}
+	defer logger.Close()
+
+	logger.TimedOut("www/nginx", "configure", "no output for 30m0s")
+
+	content, err := os.ReadFile(filepath.Join(cfg.LogsPath, "02_failure_list.log"))
+	if err != nil {
+		t.Fatalf("Failed to read failure log: %v", err)
+	}
+	if !strings.Contains(string(content), "www/nginx (phase: configure, timeout: no output for 30m0s)") {
+		t.Errorf("Failure log does not list the timeout:\n%s", content)
+	}
+
+	content, err = os.ReadFile(filepath.Join(cfg.LogsPath, "00_last_results.log"))
+	if err != nil {
+		t.Fatalf("Failed to read results log: %v", err)
+	}
+	if !strings.Contains(string(content), "TIMEOUT: www/nginx") {
+		t.Error("Results log does not contain TIMEOUT")
+	}
+}
+
+func TestLogger_TestResult(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		LogsPath: filepath.Join(tempDir, "logs"),
+	}
+
+	logger, err := NewLogger(cfg)
+	if err != nil {
+		t.Fatalf("NewLogger failed: %v", err)
+	}
+	defer logger.Close()
+
+	logger.TestResult("devel/py-six", true, 90*time.Second, "")
+	logger.TestResult("www/nginx", false, 5*time.Second, "phase failed with exit code 1")
+	logger.WriteTestSummary(1, 1)
+
+	content, err := os.ReadFile(filepath.Join(cfg.LogsPath, "08_test_summary.log"))
+	if err != nil {
+		t.Fatalf("Failed to read test summary log: %v", err)
+	}
+	for _, want := range []string{
+		"PASS devel/py-six (1m30s)",
+		"FAIL www/nginx (5s): phase failed with exit code 1",
+		"Tests run:         2",
+		"Failed:            1",
+	} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("Test summary log does not contain %q:\n%s", want, content)
+		}
+	}
+
+	content, err = os.ReadFile(filepath.Join(cfg.LogsPath, "02_failure_list.log"))
+	if err != nil {
+		t.Fatalf("Failed to read failure log: %v", err)
+	}
+	if strings.Contains(string(content), "www/nginx") {
+		t.Error("Test failure was listed as a build failure")
+	}
+}
+
 func TestLogger_Skipped(t *testing.T) {
 	tempDir := t.TempDir()
 	cfg := &config.Config{
@@ -527,3 +601,153 @@ func TestLogger_Warn(t *testing.T) {
 		t.Error("Warn with formatting did not work correctly")
 	}
 }
+
+// readJSONLog returns the entries of the structured log in logsPath.
+func readJSONLog(t *testing.T, logsPath string) []Entry {
+	t.Helper()
+
+	file, err := os.Open(filepath.Join(logsPath, JSONLogName))
+	if err != nil {
+		t.Fatalf("Failed to open JSON log: %v", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("Malformed JSON log line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestLogger_JSONFormat(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		LogsPath:  filepath.Join(tempDir, "logs"),
+		LogFormat: config.LogFormatJSON,
+	}
+
+	logger, err := NewLogger(cfg)
+	if err != nil {
+		t.Fatalf("NewLogger failed: %v", err)
+	}
+	logger.SetRunID("run-1")
+
+	ctxLogger := logger.WithContext(LogContext{
+		BuildID:  "a1b2c3d4-e5f6-7890-abcd-ef1234567890",
+		PortDir:  "editors/vim",
+		WorkerID: 0,
+	})
+	ctxLogger.SetPhase("build")
+	ctxLogger.Info("Starting phase: %s", "build")
+	ctxLogger.Failed("build", "exit status 1")
+	logger.Warn("disk %d%% full", 90)
+	logger.Close()
+
+	// The text message logs are replaced, the lists are kept
+	for _, name := range []string{"00_last_results.log", "07_debug.log"} {
+		if _, err := os.Stat(filepath.Join(cfg.LogsPath, name)); !os.IsNotExist(err) {
+			t.Errorf("%s written with Log_format=json", name)
+		}
+	}
+	failures, err := os.ReadFile(filepath.Join(cfg.LogsPath, "02_failure_list.log"))
+	if err != nil || !strings.Contains(string(failures), "editors/vim (phase: build)") {
+		t.Errorf("failure list = %q, %v", failures, err)
+	}
+
+	entries := readJSONLog(t, cfg.LogsPath)
+	if len(entries) != 3 {
+		t.Fatalf("JSON log has %d entries, want 3: %+v", len(entries), entries)
+	}
+
+	info := entries[0]
+	if info.Level != LevelInfo || info.RunID != "run-1" || info.BuildID != "a1b2c3d4-e5f6-7890-abcd-ef1234567890" ||
+		info.Port != "editors/vim" || info.Worker == nil || *info.Worker != 0 || info.Phase != "build" ||
+		info.Message != "Starting phase: build" || info.Time.IsZero() {
+		t.Errorf("context entry = %+v", info)
+	}
+	if failed := entries[1]; failed.Level != LevelError || failed.Event != "failed" || failed.Phase != "build" || failed.Message != "exit status 1" {
+		t.Errorf("failed entry = %+v", failed)
+	}
+	if warn := entries[2]; warn.Level != LevelWarn || warn.Port != "" || warn.Worker != nil || warn.Message != "disk 90% full" {
+		t.Errorf("plain entry = %+v", warn)
+	}
+}
+
+func TestLogger_BothFormats(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		LogsPath:  filepath.Join(tempDir, "logs"),
+		LogFormat: config.LogFormatBoth,
+	}
+
+	logger, err := NewLogger(cfg)
+	if err != nil {
+		t.Fatalf("NewLogger failed: %v", err)
+	}
+	logger.Success("shells/bash")
+	logger.WriteSummary(1, 1, 0, 0, 0, time.Minute)
+	logger.Close()
+
+	results, err := os.ReadFile(filepath.Join(cfg.LogsPath, "00_last_results.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(results), "SUCCESS: shells/bash") || !strings.Contains(string(results), "BUILD SUMMARY") {
+		t.Errorf("text log = %q", results)
+	}
+
+	entries := readJSONLog(t, cfg.LogsPath)
+	if len(entries) != 2 || entries[0].Event != "success" || entries[0].Port != "shells/bash" || entries[1].Event != "summary" {
+		t.Errorf("JSON log = %+v", entries)
+	}
+}
+
+func TestNewLogger_TextOnly(t *testing.T) {
+	cfg := &config.Config{LogsPath: t.TempDir()}
+
+	logger, err := NewLogger(cfg)
+	if err != nil {
+		t.Fatalf("NewLogger failed: %v", err)
+	}
+	logger.Info("hello")
+	logger.Close()
+
+	if _, err := os.Stat(filepath.Join(cfg.LogsPath, JSONLogName)); !os.IsNotExist(err) {
+		t.Errorf("%s written with the default Log_format", JSONLogName)
+	}
+}
+
+func TestNewDeferredLogger(t *testing.T) {
+	cfg := &config.Config{LogsPath: t.TempDir()}
+	results := filepath.Join(cfg.LogsPath, "00_last_results.log")
+	if err := os.WriteFile(results, []byte("running build\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Until Open, the logs of the running build are left alone
+	logger := NewDeferredLogger(cfg)
+	logger.Info("hello")
+	logger.Success("editors/vim")
+	if data, _ := os.ReadFile(results); string(data) != "running build\n" {
+		t.Errorf("results log = %q before Open", data)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.LogsPath, "01_success_list.log")); !os.IsNotExist(err) {
+		t.Error("success list created before Open")
+	}
+
+	if err := logger.Open(); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	logger.Success("shells/bash")
+	logger.Close()
+
+	data, err := os.ReadFile(filepath.Join(cfg.LogsPath, "01_success_list.log"))
+	if err != nil || !strings.Contains(string(data), "shells/bash") || strings.Contains(string(data), "editors/vim") {
+		t.Errorf("success list = %q, %v", data, err)
+	}
+}
@@ -13,32 +13,50 @@ import (
 
 // PackageLogger logs build output for a specific package
 type PackageLogger struct {
-	cfg     *config.Config
-	portDir string
-	file    *os.File
-	mu      sync.Mutex
+	cfg        *config.Config
+	portDir    string
+	file       *os.File
+	lastOutput time.Time // Last phase start or build output, for the inactivity watchdog
+	mu         sync.Mutex
 }
 
-// NewPackageLogger creates a new package logger
-func NewPackageLogger(cfg *config.Config, portDir string) *PackageLogger {
+// PackageLogPath returns the build log file of portDir. For builds with a
+// UUID it is a symlink to the latest build's log (see BuildLogPath).
+func PackageLogPath(cfg *config.Config, portDir string) string {
 	// Convert category/name to category___name format
 	logFileName := strings.ReplaceAll(portDir, "/", "___") + ".log"
-	logFile := filepath.Join(cfg.LogsPath, logFileName)
-	
-	file, err := os.Create(logFile)
+	return filepath.Join(cfg.LogsPath, logFileName)
+}
+
+// NewPackageLogger creates a new package logger for build buildID of
+// portDir. The log is kept per build UUID, and earlier logs of the port are
+// compressed and pruned to Log_history. Without a buildID, the log is
+// written to PackageLogPath itself and replaces the previous one.
+func NewPackageLogger(cfg *config.Config, portDir, buildID string) *PackageLogger {
+	var file *os.File
+	var err error
+	if buildID != "" {
+		file, err = createBuildLog(cfg, portDir, buildID)
+	} else {
+		path := PackageLogPath(cfg, portDir)
+		os.Remove(path) // Do not write through the latest symlink
+		file, err = os.Create(path)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: failed to create package log: %v\n", err)
 		return &PackageLogger{
-			cfg:     cfg,
-			portDir: portDir,
-			file:    nil,
+			cfg:        cfg,
+			portDir:    portDir,
+			file:       nil,
+			lastOutput: time.Now(),
 		}
 	}
 
 	return &PackageLogger{
-		cfg:     cfg,
-		portDir: portDir,
-		file:    file,
+		cfg:        cfg,
+		portDir:    portDir,
+		file:       file,
+		lastOutput: time.Now(),
 	}
 }
 
@@ -73,6 +91,8 @@ func (pl *PackageLogger) WritePhase(phase string) {
 	pl.mu.Lock()
 	defer pl.mu.Unlock()
 
+	pl.lastOutput = time.Now()
+
 	if pl.file == nil {
 		return
 	}
@@ -90,6 +110,8 @@ func (pl *PackageLogger) Write(output []byte) {
 	pl.mu.Lock()
 	defer pl.mu.Unlock()
 
+	pl.lastOutput = time.Now()
+
 	if pl.file == nil {
 		return
 	}
@@ -98,6 +120,14 @@ func (pl *PackageLogger) Write(output []byte) {
 	pl.file.Sync()
 }
 
+// LastOutput returns when build output was last written or a phase last
+// started.
+func (pl *PackageLogger) LastOutput() time.Time {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	return pl.lastOutput
+}
+
 // WriteString writes a string to the log
 func (pl *PackageLogger) WriteString(s string) {
 	pl.mu.Lock()
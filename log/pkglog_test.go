@@ -20,7 +20,7 @@ func TestNewPackageLogger(t *testing.T) {
 	os.MkdirAll(cfg.LogsPath, 0755)
 
 	portDir := "devel/git"
-	pl := NewPackageLogger(cfg, portDir)
+	pl := NewPackageLogger(cfg, portDir, "")
 	defer pl.Close()
 
 	// Verify log file was created
@@ -38,7 +38,7 @@ func TestPackageLogger_WriteHeader(t *testing.T) {
 	os.MkdirAll(cfg.LogsPath, 0755)
 
 	portDir := "www/nginx"
-	pl := NewPackageLogger(cfg, portDir)
+	pl := NewPackageLogger(cfg, portDir, "")
 	defer pl.Close()
 
 	pl.WriteHeader()
@@ -70,7 +70,7 @@ func TestPackageLogger_WritePhase(t *testing.T) {
 	os.MkdirAll(cfg.LogsPath, 0755)
 
 	portDir := "editors/vim"
-	pl := NewPackageLogger(cfg, portDir)
+	pl := NewPackageLogger(cfg, portDir, "")
 	defer pl.Close()
 
 	phase := "configure"
@@ -100,7 +100,7 @@ func TestPackageLogger_Write(t *testing.T) {
 	os.MkdirAll(cfg.LogsPath, 0755)
 
 	portDir := "lang/python"
-	pl := NewPackageLogger(cfg, portDir)
+	pl := NewPackageLogger(cfg, portDir, "")
 	defer pl.Close()
 
 	output := []byte("Build output line 1\nBuild output line 2\n")
@@ -118,6 +118,33 @@ func TestPackageLogger_Write(t *testing.T) {
 	}
 }
 
+func TestPackageLogger_LastOutput(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		LogsPath: filepath.Join(tempDir, "logs"),
+	}
+	os.MkdirAll(cfg.LogsPath, 0755)
+
+	pl := NewPackageLogger(cfg, "lang/python", "")
+	defer pl.Close()
+
+	created := pl.LastOutput()
+	if created.IsZero() {
+		t.Fatal("LastOutput() is zero for a new logger")
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	pl.WriteString("Make jobs: 4\n")
+	if !pl.LastOutput().Equal(created) {
+		t.Error("WriteString counted as build output")
+	}
+
+	pl.Write([]byte("compiling...\n"))
+	if !pl.LastOutput().After(created) {
+		t.Error("Write did not update LastOutput()")
+	}
+}
+
 func TestPackageLogger_WriteString(t *testing.T) {
 	tempDir := t.TempDir()
 	cfg := &config.Config{
@@ -126,7 +153,7 @@ func TestPackageLogger_WriteString(t *testing.T) {
 	os.MkdirAll(cfg.LogsPath, 0755)
 
 	portDir := "databases/postgresql"
-	pl := NewPackageLogger(cfg, portDir)
+	pl := NewPackageLogger(cfg, portDir, "")
 	defer pl.Close()
 
 	msg := "Configuration complete\n"
@@ -152,7 +179,7 @@ func TestPackageLogger_WriteCommand(t *testing.T) {
 	os.MkdirAll(cfg.LogsPath, 0755)
 
 	portDir := "net/curl"
-	pl := NewPackageLogger(cfg, portDir)
+	pl := NewPackageLogger(cfg, portDir, "")
 	defer pl.Close()
 
 	cmd := "./configure --prefix=/usr/local"
@@ -182,7 +209,7 @@ func TestPackageLogger_WriteWarning(t *testing.T) {
 	os.MkdirAll(cfg.LogsPath, 0755)
 
 	portDir := "security/openssl"
-	pl := NewPackageLogger(cfg, portDir)
+	pl := NewPackageLogger(cfg, portDir, "")
 	defer pl.Close()
 
 	warning := "Deprecated function used"
@@ -212,7 +239,7 @@ func TestPackageLogger_WriteError(t *testing.T) {
 	os.MkdirAll(cfg.LogsPath, 0755)
 
 	portDir := "multimedia/ffmpeg"
-	pl := NewPackageLogger(cfg, portDir)
+	pl := NewPackageLogger(cfg, portDir, "")
 	defer pl.Close()
 
 	errMsg := "Compilation failed"
@@ -242,7 +269,7 @@ func TestPackageLogger_WriteSuccess(t *testing.T) {
 	os.MkdirAll(cfg.LogsPath, 0755)
 
 	portDir := "shells/bash"
-	pl := NewPackageLogger(cfg, portDir)
+	pl := NewPackageLogger(cfg, portDir, "")
 	defer pl.Close()
 
 	duration := 2 * time.Minute
@@ -275,7 +302,7 @@ func TestPackageLogger_WriteFailure(t *testing.T) {
 	os.MkdirAll(cfg.LogsPath, 0755)
 
 	portDir := "x11/xorg"
-	pl := NewPackageLogger(cfg, portDir)
+	pl := NewPackageLogger(cfg, portDir, "")
 	defer pl.Close()
 
 	duration := 5 * time.Minute
@@ -312,7 +339,7 @@ func TestPackageLogger_Close(t *testing.T) {
 	os.MkdirAll(cfg.LogsPath, 0755)
 
 	portDir := "devel/make"
-	pl := NewPackageLogger(cfg, portDir)
+	pl := NewPackageLogger(cfg, portDir, "")
 
 	// Close should not panic
 	pl.Close()
@@ -360,7 +387,7 @@ func TestPackageLogger_FileNameConversion(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.portDir, func(t *testing.T) {
-			pl := NewPackageLogger(cfg, tt.portDir)
+			pl := NewPackageLogger(cfg, tt.portDir, "")
 			defer pl.Close()
 
 			pl.WriteString("test\n")
@@ -24,6 +24,8 @@ func ListLogs(cfg *config.Config) {
 	fmt.Println("  05 or abnormal - 05_abnormal_command_output.log")
 	fmt.Println("  06 or obsolete - 06_obsolete_packages.log")
 	fmt.Println("  07 or debug    - 07_debug.log")
+	fmt.Println("  08 or test     - 08_test_summary.log")
+	fmt.Println("  09 or json     - 09_log.jsonl (Log_format json or both)")
 	fmt.Println()
 	fmt.Println("Package logs:")
 	fmt.Println("  Use category/portname to view package-specific log")
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"bufio"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -40,7 +43,9 @@ func main() {
 	devMode := flag.Bool("D", false, "Developer mode")
 	checkPlist := flag.Bool("P", false, "Check plist")
 	disableUI := flag.Bool("S", false, "Disable ncurses UI")
-	niceVal := flag.Int("N", 10, "Nice value for builds")
+	var overrides stringList
+	flag.Var(&overrides, "o", "Override a config key (Key=value, repeatable)")
+	niceVal := flag.Int("N", config.DefaultNice, "Nice value for builds")
 
 	flag.Usage = usage
 	flag.Parse()
@@ -81,11 +86,48 @@ func main() {
 		cfg.CheckPlist = true
 	}
 	if *disableUI {
-		cfg.DisableUI = true
+		cfg.Override("Display_with_ncurses", "no", "command line (-S)")
+	}
+	// -N only overrides Build_limits when given explicitly
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name != "N" {
+			return
+		}
+		limits := fmt.Sprintf("%s,nice=%d", cfg.Value("Build_limits"), *niceVal)
+		if err := cfg.Override("Build_limits", limits, "command line (-N)"); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -N: %v\n", err)
+			os.Exit(1)
+		}
+	})
+	if *memTarget > 0 {
+		cfg.Override("Memory_target", fmt.Sprintf("%dG", *memTarget), "command line (-m)")
+	}
+	for _, o := range overrides {
+		key, value, ok := strings.Cut(o, "=")
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: -o expects Key=value, got %q\n", o)
+			os.Exit(1)
+		}
+		if err := cfg.Override(strings.TrimSpace(key), value, "command line (-o)"); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -o %s: %v\n", o, err)
+			os.Exit(1)
+		}
+	}
+	// Don't let misspelled or malformed settings go unnoticed; "config
+	// check" reports these in full.
+	if command != "config" {
+		for _, path := range cfg.Files {
+			issues, err := config.ValidateFile(path)
+			if err != nil {
+				continue
+			}
+			for _, issue := range issues {
+				if issue.Severity == config.SeverityError {
+					fmt.Fprintf(os.Stderr, "Warning: %s: %s (ignored)\n", path, issue)
+				}
+			}
+		}
 	}
-	// Skip unsupported config options for now
-	_ = memTarget
-	_ = niceVal
 
 	// Execute command
 	switch command {
@@ -96,7 +138,13 @@ func main() {
 	case "cleanup":
 		doCleanup(cfg)
 	case "configure":
-		doConfigure(cfg)
+		doConfigure(cfg, commandArgs)
+	case "config":
+		doConfig(cfg, commandArgs)
+	case "config-options":
+		doConfigOptions(cfg, commandArgs)
+	case "options":
+		doOptions(cfg, commandArgs)
 	case "upgrade-system":
 		doUpgradeSystem(cfg)
 	case "prepare-system":
@@ -107,10 +155,12 @@ func main() {
 		doPurgeDistfiles(cfg)
 	case "reset-db":
 		doResetDB(cfg)
+	case "db":
+		doDB(cfg, commandArgs)
 	case "verify":
 		doVerify(cfg)
 	case "status-everything":
-		doStatusEverything(cfg)
+		doStatusEverything(cfg, commandArgs)
 	case "everything":
 		doEverything(cfg)
 	case "version":
@@ -132,6 +182,8 @@ func main() {
 		doLogs(cfg, commandArgs)
 	case "monitor":
 		doMonitor(cfg, commandArgs)
+	case "serve":
+		doServe(cfg, commandArgs)
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", command)
 		usage()
@@ -139,6 +191,16 @@ func main() {
 	}
 }
 
+// stringList collects a repeatable string flag.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
 // askYN prompts the user for yes/no confirmation.
 // This is a CLI-specific function and should not be moved to a library package.
 func askYN(prompt string, defaultYes bool) bool {
@@ -175,6 +237,7 @@ func usage() {
 	fmt.Println("  -P            Check plist")
 	fmt.Println("  -S            Disable ncurses")
 	fmt.Println("  -N val        Nice value")
+	fmt.Println("  -o Key=value  Override a dsynth.ini key (repeatable)")
 	fmt.Println()
 	fmt.Println("Build Commands:")
 	fmt.Println("  init                     Initialize configuration")
@@ -184,23 +247,37 @@ func usage() {
 	fmt.Println("  upgrade-system           Build all installed packages")
 	fmt.Println("  prepare-system           Build for system upgrade")
 	fmt.Println("  force [ports...]         Force rebuild specified ports")
+	fmt.Println("  test [ports...]          Build and run port tests")
 	fmt.Println("  fetch-only [ports...]    Download distfiles only")
 	fmt.Println()
 	fmt.Println("Maintenance Commands:")
 	fmt.Println("  status [ports...]        Show port build status")
-	fmt.Println("  status-everything        Status of entire ports tree")
+	fmt.Println("  status-everything        Status of entire ports tree (--json, --top N, --prune)")
 	fmt.Println("  cleanup                  Clean up stale mounts and logs")
-	fmt.Println("  configure                Configure go-synth")
+	fmt.Println("  configure                Edit dsynth.ini profiles interactively")
+	fmt.Println("  config check             Validate config and show effective settings")
+	fmt.Println("  config-options port      Configure port options (--set OPT=on|off, --reset)")
+	fmt.Println("  options diff             List ports whose saved options differ from defaults")
 	fmt.Println("  rebuild-repository       Rebuild package repository")
 	fmt.Println("  purge-distfiles          Remove obsolete distfiles")
 	fmt.Println("  reset-db                 Reset CRC database")
+	fmt.Println("  db prune                 Delete build history past the retention settings")
+	fmt.Println("  db compact               Reclaim unused space in the build database")
+	fmt.Println("  db backup                Back up the build database (safe during builds)")
+	fmt.Println("  db export [FILE]         Dump the build database as JSON lines")
+	fmt.Println("  db import FILE           Merge a dump into the build database")
 	fmt.Println("  verify                   Verify package integrity")
-	fmt.Println("  logs [port]              View build logs")
+	fmt.Println("  logs PORT                View the latest build log of PORT")
+	fmt.Println("  logs PORT --previous     View the build log before the latest")
+	fmt.Println("  logs PORT --build UUID   View the log of an earlier build")
+	fmt.Println("  logs PORT --list         List the kept build logs of PORT")
 	fmt.Println()
 	fmt.Println("Monitoring Commands:")
-	fmt.Println("  monitor                  Watch active build stats (from BuildDB)")
+	fmt.Println("  monitor                  Watch active build stats and workers (from BuildDB)")
+	fmt.Println("  monitor --worker N       Follow the build log of worker N")
 	fmt.Println("  monitor --file PATH      Watch legacy monitor.dat file")
 	fmt.Println("  monitor export PATH      Export snapshot to dsynth-format file")
+	fmt.Println("  serve [--listen ADDR]    Serve the status API and web dashboard")
 	fmt.Println()
 }
 
@@ -333,9 +410,13 @@ func doStatus(cfg *config.Config, portList []string) {
 		fmt.Println("=== Build Database Status ===")
 		fmt.Printf("Database:      %s\n", result.Stats.DatabasePath)
 		fmt.Printf("Size:          %s\n", formatBytes(result.Stats.DatabaseSize))
+		fmt.Printf("Schema:        %d\n", result.Stats.Schema)
 		fmt.Printf("Total builds:  %d\n", result.Stats.TotalBuilds)
 		fmt.Printf("Unique ports:  %d\n", result.Stats.TotalPorts)
 		fmt.Printf("CRC entries:   %d\n", result.Stats.TotalCRCs)
+		if holder, err := svc.LockHolder(); err == nil && holder != nil {
+			fmt.Printf("Locked by:     %s\n", holder)
+		}
 		return
 	}
 
@@ -437,9 +518,256 @@ func doCleanup(cfg *config.Config) {
 	fmt.Println("\n✓ Cleanup complete")
 }
 
-func doConfigure(cfg *config.Config) {
-	fmt.Println("Configure not yet implemented")
-	// TODO: Implement interactive configuration
+func doConfigure(cfg *config.Config, args []string) {
+	if err := cmd.DoConfigure(cfg, args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func doConfig(cfg *config.Config, args []string) {
+	if len(args) == 0 || args[0] != "check" {
+		fmt.Println("Usage: go-synth config check")
+		os.Exit(1)
+	}
+
+	var issues []config.Issue
+	for _, path := range cfg.Files {
+		fileIssues, err := config.ValidateFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		issues = append(issues, fileIssues...)
+	}
+	issues = append(issues, cfg.Validate()...)
+
+	if len(cfg.Files) == 0 {
+		fmt.Printf("Config file: %s (not found, using defaults)\n", cfg.ConfigPath)
+	}
+	for _, path := range cfg.Files {
+		fmt.Printf("Config file: %s\n", path)
+	}
+	if cfg.Profile != "" {
+		fmt.Printf("Profile:     %s\n", cfg.Profile)
+	}
+	fmt.Println()
+
+	fmt.Println("Effective configuration:")
+	for _, key := range config.KnownKeys {
+		value := cfg.Value(key.Name)
+		if value == "" {
+			value = "-"
+		}
+		fmt.Printf("  %-24s %-32s %s\n", key.Name, value, cfg.Source(key.Name))
+	}
+	fmt.Println()
+
+	if len(issues) == 0 {
+		fmt.Println("✓ No problems found")
+		return
+	}
+
+	errorCount := 0
+	for _, issue := range issues {
+		if issue.Severity == config.SeverityError {
+			errorCount++
+		}
+		if issue.File != "" && len(cfg.Files) > 1 {
+			fmt.Printf("  %-8s %s: %s\n", issue.Severity, issue.File, issue)
+		} else {
+			fmt.Printf("  %-8s %s\n", issue.Severity, issue)
+		}
+	}
+	fmt.Printf("\n%d error(s), %d warning(s)\n", errorCount, len(issues)-errorCount)
+
+	if config.HasErrors(issues) {
+		os.Exit(1)
+	}
+}
+
+func doConfigOptions(cfg *config.Config, args []string) {
+	opts := service.PortOptionsOptions{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--set" || arg == "-set":
+			if i+1 >= len(args) {
+				fmt.Fprintln(os.Stderr, "--set requires an OPT=on|off argument")
+				os.Exit(1)
+			}
+			i++
+			opts.Settings = append(opts.Settings, args[i])
+		case strings.HasPrefix(arg, "--set="):
+			opts.Settings = append(opts.Settings, strings.TrimPrefix(arg, "--set="))
+		case arg == "--reset":
+			opts.Reset = true
+		case strings.HasPrefix(arg, "-"):
+			fmt.Fprintf(os.Stderr, "Unknown config-options flag: %s\n", arg)
+			os.Exit(1)
+		case opts.PortSpec == "":
+			opts.PortSpec = arg
+		default:
+			fmt.Fprintf(os.Stderr, "Unexpected argument: %s\n", arg)
+			os.Exit(1)
+		}
+	}
+
+	if opts.PortSpec == "" {
+		fmt.Println("Usage: go-synth config-options <category/port> [--set OPT=on|off ...] [--reset]")
+		os.Exit(1)
+	}
+
+	svc, err := service.NewService(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize service: %v\n", err)
+		os.Exit(1)
+	}
+	defer svc.Close()
+
+	// Non-interactive: apply settings and save in one step
+	interactive := len(opts.Settings) == 0 && !cfg.YesAll
+	opts.Save = !interactive
+
+	result, err := svc.ConfigurePortOptions(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(result.Options.All()) == 0 {
+		fmt.Printf("%s has no configurable options\n", result.Options.PortDir)
+		return
+	}
+
+	if !interactive {
+		printPortOptions(result.Options, result.Selected)
+		fmt.Printf("\n✓ Options saved: %s\n", result.Path)
+		return
+	}
+
+	if result.Saved == nil {
+		fmt.Println("No saved options (using port defaults)")
+	} else {
+		fmt.Printf("Saved options: %s\n", result.Path)
+	}
+
+	selected := result.Selected
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Println()
+		printPortOptions(result.Options, selected)
+		fmt.Print("\nToggle option (number or name), Enter to save, q to quit: ")
+
+		line, readErr := reader.ReadString('\n')
+		input := strings.TrimSpace(line)
+		if readErr != nil && input == "" {
+			fmt.Println("\nCancelled")
+			return
+		}
+
+		switch strings.ToLower(input) {
+		case "q", "quit":
+			fmt.Println("Cancelled")
+			return
+		case "":
+			if err := svc.SavePortOptions(result.Options, selected); err != nil {
+				fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+				continue
+			}
+			fmt.Printf("✓ Options saved: %s\n", result.Path)
+			return
+		}
+
+		name := strings.ToUpper(input)
+		all := result.Options.All()
+		if n, err := strconv.Atoi(input); err == nil && n >= 1 && n <= len(all) {
+			name = all[n-1]
+		}
+		if err := result.Options.Apply(selected, name, !selected[name]); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+		}
+	}
+}
+
+// printPortOptions prints a numbered list of a port's options grouped as
+// declared in the Makefile, marking enabled options and non-default values.
+func printPortOptions(defs *pkg.PortOptions, selected pkg.OptionSet) {
+	fmt.Printf("Options for %s (%s):\n", defs.PortDir, defs.PkgName)
+
+	index := make(map[string]int)
+	for i, name := range defs.All() {
+		index[name] = i + 1
+	}
+
+	printOption := func(name string) {
+		mark := " "
+		if selected[name] {
+			mark = "x"
+		}
+		changed := ""
+		if selected[name] != defs.Defaults[name] {
+			changed = " *"
+		}
+		fmt.Printf("  %2d [%s] %-16s %s%s\n", index[name], mark, name, defs.Descriptions[name], changed)
+	}
+
+	for _, name := range defs.Define {
+		printOption(name)
+	}
+	for _, g := range defs.Groups {
+		fmt.Printf("  %s %s:\n", g.Kind, g.Name)
+		for _, name := range g.Options {
+			printOption(name)
+		}
+	}
+}
+
+func doOptions(cfg *config.Config, args []string) {
+	if len(args) == 0 || args[0] != "diff" {
+		fmt.Println("Usage: go-synth options diff")
+		os.Exit(1)
+	}
+
+	svc, err := service.NewService(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize service: %v\n", err)
+		os.Exit(1)
+	}
+	defer svc.Close()
+
+	result, err := svc.DiffOptions()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to diff options: %v\n", err)
+		os.Exit(1)
+	}
+
+	if result.Checked == 0 {
+		fmt.Printf("No saved port options in %s\n", cfg.OptionsPath)
+		return
+	}
+
+	if len(result.Ports) == 0 {
+		fmt.Printf("All %d port(s) with saved options match their defaults\n", result.Checked)
+		return
+	}
+
+	for _, diff := range result.Ports {
+		if diff.Err != nil {
+			fmt.Printf("%-40s ⚠ %v\n", diff.PortDir, diff.Err)
+			continue
+		}
+		var changes []string
+		for _, name := range diff.Enabled {
+			changes = append(changes, "+"+name)
+		}
+		for _, name := range diff.Disabled {
+			changes = append(changes, "-"+name)
+		}
+		fmt.Printf("%-40s %s\n", diff.PortDir, strings.Join(changes, " "))
+	}
+
+	fmt.Printf("\n%d of %d port(s) differ from defaults\n", len(result.Ports), result.Checked)
 }
 
 func doUpgradeSystem(cfg *config.Config) {
@@ -490,6 +818,11 @@ func doResetDB(cfg *config.Config) {
 		return
 	}
 
+	if err := svc.Lock(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Confirm destructive operation (unless -y flag)
 	if !cfg.YesAll {
 		fmt.Printf("⚠️  WARNING: This will delete the build database\n")
@@ -525,15 +858,228 @@ func doResetDB(cfg *config.Config) {
 	}
 }
 
+func doDB(cfg *config.Config, args []string) {
+	const dbUsage = "Usage: go-synth db prune|compact|backup|export [FILE]|import FILE"
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, dbUsage)
+		os.Exit(1)
+	}
+	sub, args := args[0], args[1:]
+	switch {
+	case (sub == "prune" || sub == "compact" || sub == "backup") && len(args) == 0:
+	case sub == "export" && len(args) <= 1:
+	case sub == "import" && len(args) == 1:
+	default:
+		fmt.Fprintln(os.Stderr, dbUsage)
+		os.Exit(1)
+	}
+
+	svc, err := service.NewService(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize service: %v\n", err)
+		os.Exit(1)
+	}
+	defer svc.Close()
+
+	switch sub {
+	case "prune":
+		if cfg.Database.KeepRuns == 0 && cfg.Database.KeepDays == 0 {
+			fmt.Println("No retention configured (Database_keep_runs, Database_keep_days); nothing to prune")
+			return
+		}
+		result, err := svc.PruneDatabase()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Deleted %d run(s) with %d package record(s), %d build record(s) and %d index entries\n",
+			result.Runs, result.RunPackages, result.Builds, result.IndexKeys)
+		if result.Total() > 0 {
+			fmt.Println("Run 'go-synth db compact' to reclaim the space")
+		}
+
+	case "compact":
+		result, err := svc.CompactDatabase()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Build database compacted: %s -> %s\n", formatBytes(result.SizeBefore), formatBytes(result.SizeAfter))
+
+	case "backup":
+		path, err := svc.BackupDatabase()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Build database backed up to %s\n", path)
+
+	case "export":
+		// The dump goes to stdout unless a file is named, so report on stderr
+		out := os.Stdout
+		if len(args) == 1 && args[0] != "-" {
+			f, err := os.Create(args[0])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			out = f
+		}
+		counts, err := svc.ExportDatabase(out)
+		if err == nil && out != os.Stdout {
+			err = out.Close()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "✓ Exported %d entries\n", counts.Total())
+
+	case "import":
+		in := os.Stdin
+		if args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			in = f
+		}
+		counts, backupPath, err := svc.ImportDatabase(in)
+		if backupPath != "" {
+			fmt.Printf("Build database backed up to %s\n", backupPath)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		for _, bucket := range counts.Buckets() {
+			fmt.Printf("  %-14s %8d\n", bucket, counts[bucket])
+		}
+		fmt.Printf("✓ Imported %d entries\n", counts.Total())
+	}
+}
+
 func doVerify(cfg *config.Config) {
 	fmt.Println("Verifying packages...")
 	// TODO: Implement package verification
 	fmt.Println("Package verification not yet implemented")
 }
 
-func doStatusEverything(cfg *config.Config) {
-	fmt.Println("Status everything not yet implemented")
-	// TODO: Implement status-everything
+func doStatusEverything(cfg *config.Config, args []string) {
+	prune, asJSON := false, false
+	opts := service.EverythingOptions{Top: 10}
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--prune" || arg == "-prune":
+			prune = true
+		case arg == "--json" || arg == "-json":
+			asJSON = true
+		case arg == "--top" || arg == "-top":
+			if i+1 >= len(args) {
+				fmt.Fprintln(os.Stderr, "--top requires a number")
+				os.Exit(1)
+			}
+			i++
+			n, err := strconv.Atoi(args[i])
+			if err != nil || n < 0 {
+				fmt.Fprintf(os.Stderr, "--top: expected a number, got %s\n", args[i])
+				os.Exit(1)
+			}
+			opts.Top = n
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown status-everything argument: %s\n", arg)
+			os.Exit(1)
+		}
+	}
+	if prune && asJSON {
+		fmt.Fprintln(os.Stderr, "--prune cannot be combined with --json")
+		os.Exit(1)
+	}
+
+	svc, err := service.NewService(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize service: %v\n", err)
+		os.Exit(1)
+	}
+	defer svc.Close()
+
+	if !asJSON {
+		fmt.Println("Resolving entire ports tree...")
+	}
+	result, err := svc.StatusEverything(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to get status: %v\n", err)
+		os.Exit(1)
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(result); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to encode status: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Printf("\n=== Ports Tree Status (%d ports, %d packages) ===\n", result.Ports, result.Packages)
+	fmt.Printf("%-20s %10s %12s %8s %7s\n", "Category", "Up-to-date", "Needs build", "Ignored", "Broken")
+	for _, cs := range result.Categories {
+		fmt.Printf("%-20s %10d %12d %8d %7d\n", cs.Category, cs.UpToDate, cs.NeedsBuild, cs.Ignored, cs.Broken)
+	}
+	t := result.Totals
+	fmt.Printf("%-20s %10d %12d %8d %7d\n", "Total", t.UpToDate, t.NeedsBuild, t.Ignored, t.Broken)
+
+	if len(result.TopRebuilds) > 0 {
+		fmt.Println("\nPorts needing a build with the most dependents:")
+		for _, r := range result.TopRebuilds {
+			fmt.Printf("  %-40s %-16s %6d dependents\n", r.PortDir, r.Version, r.Dependents)
+		}
+	}
+
+	if len(result.Broken) > 0 {
+		fmt.Println("\nBroken ports:")
+		for _, portDir := range result.Broken {
+			fmt.Printf("  %s\n", portDir)
+		}
+	}
+
+	report := result.Repository.Report
+	fmt.Printf("\n=== Repository: %s ===\n", filepath.Join(cfg.PackagesPath, "All"))
+	for _, rp := range report.Obsolete {
+		fmt.Printf("  obsolete  %s\n", rp.File)
+	}
+	for _, rp := range report.Orphaned {
+		fmt.Printf("  orphaned  %s\n", rp.File)
+	}
+	fmt.Printf("%d obsolete and %d orphaned package(s), %s\n",
+		len(report.Obsolete), len(report.Orphaned), formatBytes(report.Size()))
+	if len(report.Kept) > 0 {
+		fmt.Printf("%d previous version(s) kept (Keep_obsolete_packages=%d)\n", len(report.Kept), cfg.KeepObsolete)
+	}
+
+	if len(report.Files()) == 0 {
+		return
+	}
+	if !prune {
+		fmt.Println("Run 'go-synth status-everything --prune' to remove them")
+		return
+	}
+	if !cfg.YesAll && !askYN(fmt.Sprintf("Remove %d package(s)?", len(report.Files())), false) {
+		fmt.Println("Cancelled")
+		return
+	}
+
+	err = svc.PruneObsolete(result.Repository)
+	fmt.Printf("✓ Removed %d package(s), freed %s\n", len(result.Repository.Removed), formatBytes(result.Repository.Freed))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to remove some packages: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Run 'go-synth rebuild-repository' to update the repository catalog")
 }
 
 func doEverything(cfg *config.Config) {
@@ -558,6 +1104,9 @@ func doBuild(cfg *config.Config, portList []string, justBuild bool, testMode boo
 		return
 	}
 
+	// Set before planning so the plan includes TEST_DEPENDS
+	cfg.TestMode = testMode
+
 	// Create service
 	svc, err := service.NewService(cfg)
 	if err != nil {
@@ -566,6 +1115,12 @@ func doBuild(cfg *config.Config, portList []string, justBuild bool, testMode boo
 	}
 	defer svc.Close()
 
+	// Fail before planning if another go-synth is using the build base
+	if err := svc.Lock(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Check for migration and prompt user if needed (unless auto-migrate is on)
 	if !cfg.Migration.AutoMigrate {
 		migStatus, err := svc.CheckMigrationStatus()
@@ -674,6 +1229,10 @@ func doBuild(cfg *config.Config, portList []string, justBuild bool, testMode boo
 	fmt.Printf("  ✗ Failed:        %d\n", result.Stats.Failed)
 	fmt.Printf("  - Skipped:       %d\n", result.Stats.Skipped)
 	fmt.Printf("  - Ignored:       %d\n", result.Stats.Ignored)
+	if testMode {
+		fmt.Printf("  Tests passed:    %d\n", result.Stats.TestsPassed)
+		fmt.Printf("  Tests failed:    %d\n", result.Stats.TestsFailed)
+	}
 	fmt.Printf("  Duration:        %s\n\n", result.Stats.Duration)
 
 	// Also update repo if not just-build mode
@@ -691,28 +1250,11 @@ func doFetchOnly(cfg *config.Config, portList []string) {
 	// TODO: Implement fetch-only
 }
 
-func doLogs(cfg *config.Config, portList []string) {
-	if len(portList) == 0 {
-		fmt.Println("No port specified")
-		return
-	}
-
-	port := portList[0]
-	logFile := filepath.Join(cfg.LogsPath, strings.ReplaceAll(port, "/", "___")+".log")
-
-	if !util.FileExists(logFile) {
-		fmt.Printf("Log file not found: %s\n", logFile)
-		return
-	}
-
-	// Display the log file
-	content, err := os.ReadFile(logFile)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading log: %v\n", err)
+func doLogs(cfg *config.Config, args []string) {
+	if err := cmd.DoLogs(cfg, args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-
-	fmt.Print(string(content))
 }
 
 func doMonitor(cfg *config.Config, args []string) {
@@ -721,3 +1263,10 @@ func doMonitor(cfg *config.Config, args []string) {
 		os.Exit(1)
 	}
 }
+
+func doServe(cfg *config.Config, args []string) {
+	if err := cmd.DoServe(cfg, args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -1,5 +1,6 @@
 // Package migration provides utilities for migrating from legacy file-based
-// CRC storage to the new BuildDB format.
+// CRC storage to the new BuildDB format, and the BuildDB schema migrations
+// (see Schema and OpenDB).
 //
 // The legacy format is a plain text file located at ${BuildBase}/crc_index
 // with lines in the format: portdir:crc32_hex
@@ -0,0 +1,138 @@
+package migration
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"go-synth/builddb"
+)
+
+// Schema lists the build database migrations in order. Entry i upgrades
+// schema version i to i+1; the last one reaches builddb.SchemaVersion.
+//
+// Migrations are never edited or removed once released, only appended.
+// When appending one, bump builddb.SchemaVersion.
+var Schema = []builddb.Migration{
+	{
+		Version:     1,
+		Description: "create the build run and CRC buckets",
+		Apply:       createBuckets,
+	},
+	{
+		Version:     2,
+		Description: "rebuild the package index from build records",
+		Apply:       reindexPackages,
+	},
+}
+
+// createBuckets creates the buckets databases of early go-synth versions
+// may lack. OpenDB creates them too; this records that they exist.
+func createBuckets(tx *bolt.Tx) error {
+	for _, name := range []string{
+		builddb.BucketBuilds,
+		builddb.BucketPackages,
+		builddb.BucketBuildRuns,
+		builddb.BucketRunPackages,
+		builddb.BucketCRCIndex,
+	} {
+		if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+			return fmt.Errorf("create bucket %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// reindexPackages points each portdir@version index entry at the latest
+// successful build record of that version. Index entries written before
+// run tracking may point at records that no longer exist, or be missing.
+func reindexPackages(tx *bolt.Tx) error {
+	builds := tx.Bucket([]byte(builddb.BucketBuilds))
+
+	latest := make(map[string]builddb.BuildRecord)
+	err := builds.ForEach(func(k, v []byte) error {
+		var rec builddb.BuildRecord
+		if err := json.Unmarshal(v, &rec); err != nil {
+			return fmt.Errorf("build record %s: %w", k, err)
+		}
+		if rec.Status != "success" {
+			return nil
+		}
+		key := rec.PortDir + "@" + rec.Version
+		if cur, ok := latest[key]; !ok || rec.StartTime.After(cur.StartTime) {
+			latest[key] = rec
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tx.DeleteBucket([]byte(builddb.BucketPackages)); err != nil {
+		return err
+	}
+	packages, err := tx.CreateBucket([]byte(builddb.BucketPackages))
+	if err != nil {
+		return err
+	}
+	for key, rec := range latest {
+		if err := packages.Put([]byte(key), []byte(rec.UUID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrateSchema brings db up to builddb.SchemaVersion. Before the first
+// migration the database is backed up next to itself (see
+// builddb.BackupPath); each migration then runs in its own transaction.
+//
+// Returns the number of migrations applied.
+func MigrateSchema(db *builddb.DB, logger interface {
+	Info(format string, args ...any)
+	Warn(format string, args ...any)
+}) (int, error) {
+	version, err := db.SchemaVersion()
+	if err != nil {
+		return 0, err
+	}
+	if version >= builddb.SchemaVersion {
+		return 0, nil
+	}
+
+	backupPath := builddb.BackupPath(db.Path(), time.Now())
+	if _, err := db.Backup(backupPath); err != nil {
+		return 0, fmt.Errorf("back up database before migrating: %w", err)
+	}
+	logger.Info("Migrating build database from schema %d to %d (backup: %s)", version, builddb.SchemaVersion, backupPath)
+
+	applied := 0
+	for _, m := range Schema[version:] {
+		if err := db.ApplyMigration(m); err != nil {
+			return applied, fmt.Errorf("schema migration %d (%s): %w", m.Version, m.Description, err)
+		}
+		logger.Info("Applied schema migration %d: %s", m.Version, m.Description)
+		applied++
+	}
+	return applied, nil
+}
+
+// OpenDB opens the build database at path in shared mode (see
+// builddb.OpenDBShared) and migrates it to the current schema. Databases
+// of a newer go-synth are refused; see builddb.ErrSchemaTooNew.
+func OpenDB(path string, logger interface {
+	Info(format string, args ...any)
+	Warn(format string, args ...any)
+}) (*builddb.DB, error) {
+	db, err := builddb.OpenDBShared(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := MigrateSchema(db, logger); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
@@ -0,0 +1,134 @@
+package migration_test
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"go-synth/builddb"
+	"go-synth/migration"
+)
+
+// TestSchema_Ordered checks that the registry climbs one version at a time
+// up to builddb.SchemaVersion.
+func TestSchema_Ordered(t *testing.T) {
+	for i, m := range migration.Schema {
+		if m.Version != i+1 {
+			t.Errorf("Schema[%d].Version = %d, want %d", i, m.Version, i+1)
+		}
+		if m.Description == "" || m.Apply == nil {
+			t.Errorf("Schema[%d] lacks a description or Apply", i)
+		}
+	}
+	if len(migration.Schema) != builddb.SchemaVersion {
+		t.Errorf("%d migrations, builddb.SchemaVersion is %d", len(migration.Schema), builddb.SchemaVersion)
+	}
+}
+
+// TestOpenDB_MigratesLegacy opens a database written before schema
+// versioning, with a stale package index.
+func TestOpenDB_MigratesLegacy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "builds.db")
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	records := []builddb.BuildRecord{
+		{UUID: "old", PortDir: "editors/vim", Version: "9.1", Status: "success", StartTime: start},
+		{UUID: "new", PortDir: "editors/vim", Version: "9.1", Status: "success", StartTime: start.Add(time.Hour)},
+		{UUID: "bad", PortDir: "editors/vim", Version: "9.1", Status: "failed", StartTime: start.Add(2 * time.Hour)},
+	}
+	bdb, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatalf("bolt.Open failed: %v", err)
+	}
+	err = bdb.Update(func(tx *bolt.Tx) error {
+		builds, err := tx.CreateBucket([]byte(builddb.BucketBuilds))
+		if err != nil {
+			return err
+		}
+		for _, rec := range records {
+			data, _ := json.Marshal(rec)
+			if err := builds.Put([]byte(rec.UUID), data); err != nil {
+				return err
+			}
+		}
+		packages, err := tx.CreateBucket([]byte(builddb.BucketPackages))
+		if err != nil {
+			return err
+		}
+		return packages.Put([]byte("editors/vim@9.1"), []byte("gone"))
+	})
+	bdb.Close()
+	if err != nil {
+		t.Fatalf("writing legacy database failed: %v", err)
+	}
+
+	db, err := migration.OpenDB(path, testLogger{t})
+	if err != nil {
+		t.Fatalf("OpenDB() failed: %v", err)
+	}
+	defer db.Close()
+
+	if version, err := db.SchemaVersion(); err != nil || version != builddb.SchemaVersion {
+		t.Errorf("SchemaVersion() = %d, %v; want %d", version, err, builddb.SchemaVersion)
+	}
+	rec, err := db.LatestFor("editors/vim", "9.1")
+	if err != nil || rec == nil || rec.UUID != "new" {
+		t.Errorf("LatestFor() after migration = %v, %v; want build new", rec, err)
+	}
+	if backups, _ := builddb.ListBackups(path); len(backups) != 1 {
+		t.Errorf("%d backups taken before migrating, want 1", len(backups))
+	}
+
+	// Already current: nothing to do, no further backup
+	if n, err := migration.MigrateSchema(db, testLogger{t}); err != nil || n != 0 {
+		t.Errorf("MigrateSchema() on a current database = %d, %v", n, err)
+	}
+	if backups, _ := builddb.ListBackups(path); len(backups) != 1 {
+		t.Errorf("%d backups after a no-op migration, want 1", len(backups))
+	}
+}
+
+// TestOpenDB_Current checks that new databases need no migration.
+func TestOpenDB_Current(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "builds.db")
+	db, err := migration.OpenDB(path, testLogger{t})
+	if err != nil {
+		t.Fatalf("OpenDB() failed: %v", err)
+	}
+	defer db.Close()
+
+	if backups, _ := builddb.ListBackups(path); len(backups) != 0 {
+		t.Errorf("new database backed up: %v", backups)
+	}
+}
+
+// TestOpenDB_Newer checks that a database of a newer go-synth is refused.
+func TestOpenDB_Newer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "builds.db")
+	bdb, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatalf("bolt.Open failed: %v", err)
+	}
+	err = bdb.Update(func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucket([]byte(builddb.BucketMeta))
+		if err != nil {
+			return err
+		}
+		return meta.Put([]byte("schema_version"), []byte("999"))
+	})
+	bdb.Close()
+	if err != nil {
+		t.Fatalf("writing database failed: %v", err)
+	}
+
+	if db, err := migration.OpenDB(path, testLogger{t}); !errors.Is(err, builddb.ErrSchemaTooNew) {
+		if db != nil {
+			db.Close()
+		}
+		t.Errorf("OpenDB() error = %v, want ErrSchemaTooNew", err)
+	}
+}
@@ -0,0 +1,535 @@
+// Package notify sends notifications when a build run ends and when a port
+// fails to build, to a JSON webhook and by email, as configured with the
+// Notify_* keys of dsynth.ini.
+//
+// Unlike hooks, which run site scripts on the host, notifications go out
+// to other systems: a chat or incident webhook, or a mailing list. Both
+// channels get the same Event, either as is (JSON for the webhook, a
+// built-in text body for mail) or rendered through a text/template given
+// with Notify_webhook_template or Notify_email_template.
+//
+// With Notify_new_failures_only, ports that also failed in the previous
+// finished run are left out, so a broken port is reported once rather than
+// on every run until it is fixed.
+//
+// Example usage:
+//
+//	n := notify.New(cfg, db, runID, logger)
+//	n.QueuePortFailure("editors/vim", "9.1.0470", builddb.RunStatusFailed, "build")
+//	...
+//	n.Drain()
+//	n.RunEnded("failed", stats, time.Since(start))
+//
+// Delivery failures are logged as warnings; they never stop the build.
+package notify
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"go-synth/builddb"
+	"go-synth/config"
+	"go-synth/log"
+)
+
+const (
+	webhookTimeout = 10 * time.Second
+	smtpTimeout    = 10 * time.Second
+
+	// excerptLines is how much of the end of a failed port's log goes
+	// into a notification, and maxFailures how many ports get an excerpt.
+	excerptLines = 20
+	maxFailures  = 10
+
+	// queueSize is how many port-failure notifications may wait for
+	// delivery; more are dropped rather than holding up the workers.
+	queueSize = 64
+)
+
+// Event is what a notification reports, and the data its templates see.
+type Event struct {
+	Kind     string           `json:"event"` // config.NotifyRunEnd or config.NotifyPortFailure
+	Profile  string           `json:"profile"`
+	Host     string           `json:"host"`
+	RunID    string           `json:"run_id"`
+	Status   string           `json:"status"` // run-end: success, failed or aborted; port-failure: failed or timeout
+	Time     time.Time        `json:"time"`
+	Duration time.Duration    `json:"-"`                          // run-end only
+	Seconds  int64            `json:"duration_seconds,omitempty"` // Duration in whole seconds
+	Stats    builddb.RunStats `json:"stats"`                      // run-end only
+
+	// Failed ports, new failures first. For port-failure, the one port;
+	// for run-end, all failures of the run, with excerpts for the first
+	// few.
+	Failures    []Failure `json:"failures"`
+	NewFailures int       `json:"new_failures"`
+}
+
+// Failure describes a port that failed to build.
+type Failure struct {
+	Port    string   `json:"port"`
+	Version string   `json:"version"`
+	Status  string   `json:"status"` // failed or timeout
+	Phase   string   `json:"phase"`
+	Log     string   `json:"log"`
+	New     bool     `json:"new"` // Did not fail in the previous run
+	Excerpt []string `json:"excerpt,omitempty"`
+}
+
+// Notifier sends the notifications of one build run.
+type Notifier struct {
+	cfg    *config.Config
+	db     *builddb.DB
+	runID  string
+	logger interface {
+		Warn(format string, args ...any)
+	}
+
+	prevOnce   sync.Once
+	prevFailed map[string]bool // Ports that failed in the previous run, nil if there was none
+
+	queueMu   sync.Mutex
+	queue     chan portFailure // Port failures waiting for delivery, nil until the first
+	drained   bool             // Drain was called; later failures are dropped
+	delivered sync.WaitGroup
+}
+
+// portFailure is a queued PortFailed call.
+type portFailure struct {
+	portDir, version, status, phase string
+}
+
+// New creates the notifier of build run runID. db is used to compare
+// failures with the previous run and may be nil, in which case every
+// failure counts as new.
+func New(cfg *config.Config, db *builddb.DB, runID string, logger interface {
+	Warn(format string, args ...any)
+}) *Notifier {
+	return &Notifier{cfg: cfg, db: db, runID: runID, logger: logger}
+}
+
+// enabled reports whether event notifications go anywhere.
+func (n *Notifier) enabled(event string) bool {
+	if n.cfg.Notify.Webhook == "" && n.cfg.Notify.EmailTo == "" {
+		return false
+	}
+	return slices.Contains(n.cfg.Notify.Events, event)
+}
+
+// PortFailed notifies that portDir failed in phase, with status failed or
+// timeout.
+func (n *Notifier) PortFailed(portDir, version, status, phase string) error {
+	if !n.enabled(config.NotifyPortFailure) {
+		return nil
+	}
+	f := n.failure(portDir, version, status, phase)
+	if n.cfg.Notify.NewFailuresOnly && !f.New {
+		return nil
+	}
+	f.Excerpt = excerpt(f.Log)
+
+	ev := n.event(config.NotifyPortFailure, status)
+	ev.Failures = []Failure{f}
+	if f.New {
+		ev.NewFailures = 1
+	}
+	return n.send(ev)
+}
+
+// QueuePortFailure queues the PortFailed notification of portDir for
+// delivery in the background, so a slow webhook or mail server does not
+// hold up the worker that reports it. When the queue is full the
+// notification is dropped with a warning. Call Drain before the run ends.
+func (n *Notifier) QueuePortFailure(portDir, version, status, phase string) {
+	if !n.enabled(config.NotifyPortFailure) {
+		return
+	}
+
+	n.queueMu.Lock()
+	defer n.queueMu.Unlock()
+	if n.drained {
+		n.logger.Warn("Notification: run ended, dropping failure of %s", portDir)
+		return
+	}
+	if n.queue == nil {
+		n.queue = make(chan portFailure, queueSize)
+		n.delivered.Add(1)
+		go n.deliver()
+	}
+	select {
+	case n.queue <- portFailure{portDir, version, status, phase}:
+	default:
+		n.logger.Warn("Notification: %d failures waiting, dropping failure of %s", queueSize, portDir)
+	}
+}
+
+// deliver sends the queued port failures until Drain closes the queue.
+func (n *Notifier) deliver() {
+	defer n.delivered.Done()
+	for f := range n.queue {
+		_ = n.PortFailed(f.portDir, f.version, f.status, f.phase)
+	}
+}
+
+// Drain waits until the port failures queued so far are delivered. Each
+// delivery is bounded by the webhook and mail timeouts.
+func (n *Notifier) Drain() {
+	n.queueMu.Lock()
+	if !n.drained && n.queue != nil {
+		close(n.queue)
+	}
+	n.drained = true
+	n.queueMu.Unlock()
+
+	n.delivered.Wait()
+}
+
+// RunEnded notifies that the run ended with status success, failed or
+// aborted. The failures are read from the run's packages in the build
+// database.
+func (n *Notifier) RunEnded(status string, stats builddb.RunStats, duration time.Duration) error {
+	if !n.enabled(config.NotifyRunEnd) {
+		return nil
+	}
+
+	ev := n.event(config.NotifyRunEnd, status)
+	ev.Stats = stats
+	ev.Duration = duration
+	ev.Seconds = int64(duration / time.Second)
+	if n.db != nil {
+		pkgs, err := n.db.ListRunPackages(n.runID)
+		if err != nil {
+			n.logger.Warn("Notification: failed to list packages of run %s: %v", n.runID, err)
+		}
+		for _, p := range pkgs {
+			if p.Status == builddb.RunStatusFailed || p.Status == builddb.RunStatusTimeout {
+				ev.Failures = append(ev.Failures, n.failure(p.PortDir, p.Version, p.Status, p.LastPhase))
+			}
+		}
+	}
+	sort.SliceStable(ev.Failures, func(i, j int) bool {
+		if ev.Failures[i].New != ev.Failures[j].New {
+			return ev.Failures[i].New
+		}
+		return ev.Failures[i].Port < ev.Failures[j].Port
+	})
+	for i := range ev.Failures {
+		if ev.Failures[i].New {
+			ev.NewFailures++
+		}
+		if i < maxFailures {
+			ev.Failures[i].Excerpt = excerpt(ev.Failures[i].Log)
+		}
+	}
+
+	if n.cfg.Notify.NewFailuresOnly && ev.NewFailures == 0 {
+		return nil
+	}
+	return n.send(ev)
+}
+
+func (n *Notifier) event(kind, status string) Event {
+	host, _ := os.Hostname()
+	return Event{
+		Kind:    kind,
+		Profile: n.cfg.Profile,
+		Host:    host,
+		RunID:   n.runID,
+		Status:  status,
+		Time:    time.Now(),
+	}
+}
+
+func (n *Notifier) failure(portDir, version, status, phase string) Failure {
+	return Failure{
+		Port:    portDir,
+		Version: version,
+		Status:  status,
+		Phase:   phase,
+		Log:     log.PackageLogPath(n.cfg, portDir),
+		New:     !n.previousFailures()[portDir],
+	}
+}
+
+// previousFailures returns the ports that failed in the last finished run
+// before this one. Aborted runs are passed over, since the ports they did
+// not get to say nothing about whether a failure is new.
+func (n *Notifier) previousFailures() map[string]bool {
+	n.prevOnce.Do(func() {
+		if n.db == nil {
+			return
+		}
+		runs, err := n.db.ListRuns(0)
+		if err != nil {
+			n.logger.Warn("Notification: failed to list previous runs: %v", err)
+			return
+		}
+		var start time.Time
+		for _, r := range runs {
+			if r.ID == n.runID {
+				start = r.StartTime
+			}
+		}
+		for _, r := range runs { // Newest first
+			if r.ID == n.runID || r.Aborted || r.EndTime.IsZero() ||
+				(!start.IsZero() && !r.StartTime.Before(start)) {
+				continue
+			}
+			pkgs, err := n.db.ListRunPackages(r.ID)
+			if err != nil {
+				n.logger.Warn("Notification: failed to list packages of run %s: %v", r.ID, err)
+				return
+			}
+			n.prevFailed = make(map[string]bool)
+			for _, p := range pkgs {
+				if p.Status == builddb.RunStatusFailed || p.Status == builddb.RunStatusTimeout {
+					n.prevFailed[p.PortDir] = true
+				}
+			}
+			return
+		}
+	})
+	return n.prevFailed
+}
+
+// excerpt returns the last lines of the log at path, or nil if it cannot
+// be read.
+func excerpt(path string) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if len(lines) > excerptLines {
+			lines = lines[1:]
+		}
+	}
+	return lines
+}
+
+// send delivers ev on every configured channel.
+func (n *Notifier) send(ev Event) error {
+	var errs []error
+	if n.cfg.Notify.Webhook != "" {
+		if err := n.sendWebhook(ev); err != nil {
+			n.logger.Warn("Notification: webhook %s: %v", n.cfg.Notify.Webhook, err)
+			errs = append(errs, err)
+		}
+	}
+	if n.cfg.Notify.EmailTo != "" {
+		if err := n.sendEmail(ev); err != nil {
+			n.logger.Warn("Notification: mail to %s: %v", n.cfg.Notify.EmailTo, err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (n *Notifier) sendWebhook(ev Event) error {
+	var body []byte
+	if n.cfg.Notify.WebhookTemplate != "" {
+		text, err := render(n.cfg.Notify.WebhookTemplate, "", ev)
+		if err != nil {
+			return err
+		}
+		body = []byte(text)
+	} else {
+		var err error
+		if body, err = json.Marshal(ev); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.cfg.Notify.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-synth")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected response %s", resp.Status)
+	}
+	return nil
+}
+
+func (n *Notifier) sendEmail(ev Event) error {
+	to, err := mail.ParseAddressList(n.cfg.Notify.EmailTo)
+	if err != nil {
+		return err
+	}
+	from := &mail.Address{Address: "go-synth@" + ev.Host}
+	if n.cfg.Notify.EmailFrom != "" {
+		if from, err = mail.ParseAddress(n.cfg.Notify.EmailFrom); err != nil {
+			return err
+		}
+	}
+
+	body, err := render(n.cfg.Notify.EmailTemplate, defaultEmailTemplate, ev)
+	if err != nil {
+		return err
+	}
+
+	rcpts := make([]string, len(to))
+	toHeader := make([]string, len(to))
+	for i, a := range to {
+		rcpts[i] = a.Address
+		toHeader[i] = a.String()
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(toHeader, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject(ev))
+	fmt.Fprintf(&msg, "Date: %s\r\n", ev.Time.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+
+	return sendMail(n.cfg.Notify.SMTPServer, smtpTimeout, from.Address, rcpts, msg.Bytes())
+}
+
+// sendMail delivers msg through the SMTP server at addr like
+// smtp.SendMail, but gives up after timeout rather than hold up the build
+// on an unresponsive server.
+func sendMail(addr string, timeout time.Duration, from string, to []string, msg []byte) error {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// subject returns the mail subject line of ev.
+func subject(ev Event) string {
+	profile := ""
+	if ev.Profile != "" && ev.Profile != "default" {
+		profile = " (" + ev.Profile + ")"
+	}
+	if ev.Kind == config.NotifyPortFailure && len(ev.Failures) > 0 {
+		f := ev.Failures[0]
+		return fmt.Sprintf("[go-synth] %s %s on %s%s", f.Port, f.Status, ev.Host, profile)
+	}
+	return fmt.Sprintf("[go-synth] Build run %s on %s%s: %d built, %d failed",
+		ev.Status, ev.Host, profile, ev.Stats.Success, ev.Stats.Failed)
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"duration": func(d time.Duration) string {
+		return d.Round(time.Second).String()
+	},
+	"join": strings.Join,
+}
+
+// render executes the template in file path, or text if path is empty.
+func render(path, text string, ev Event) (string, error) {
+	name := "notification"
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		name, text = path, string(data)
+	}
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, ev); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+const defaultEmailTemplate = `{{if eq .Kind "run-end" -}}
+Build run {{.RunID}} on {{.Host}} ended: {{.Status}} after {{duration .Duration}}.
+
+  Total:   {{.Stats.Total}}
+  Built:   {{.Stats.Success}}
+  Failed:  {{.Stats.Failed}}{{if .NewFailures}} ({{.NewFailures}} new){{end}}
+  Skipped: {{.Stats.Skipped}}
+  Ignored: {{.Stats.Ignored}}
+{{- else -}}
+A port failed in build run {{.RunID}} on {{.Host}}.
+{{- end}}
+{{range .Failures}}
+{{.Port}} {{.Version}}: {{.Status}}{{if .Phase}} in {{.Phase}}{{end}}{{if .New}} (new){{end}}
+  Log: {{.Log}}
+{{- if .Excerpt}}
+
+  {{join .Excerpt "\n  "}}
+{{- end}}
+{{end}}`
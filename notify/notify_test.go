@@ -0,0 +1,467 @@
+package notify
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-synth/builddb"
+	"go-synth/config"
+)
+
+type testLogger struct {
+	t *testing.T
+}
+
+func (l testLogger) Warn(format string, args ...any) {
+	l.t.Logf("WARN: "+format, args...)
+}
+
+// webhookServer records the bodies posted to it.
+type webhookServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	bodies []string
+}
+
+func newWebhookServer(t *testing.T) *webhookServer {
+	s := &webhookServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.bodies = append(s.bodies, string(body))
+		s.mu.Unlock()
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *webhookServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.bodies...)
+}
+
+// smtpMessage is a message received by smtpServer.
+type smtpMessage struct {
+	from string
+	to   []string
+	data string
+}
+
+// smtpServer is a minimal SMTP server accepting any message.
+type smtpServer struct {
+	ln       net.Listener
+	mu       sync.Mutex
+	messages []smtpMessage
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpServer{ln: ln}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *smtpServer) addr() string {
+	return s.ln.Addr().String()
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	reply("220 localhost ESMTP test")
+	var msg smtpMessage
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimRight(line, "\r\n")
+		switch verb := strings.ToUpper(strings.SplitN(cmd, " ", 2)[0]); verb {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "MAIL":
+			msg = smtpMessage{from: strings.TrimSuffix(strings.TrimPrefix(cmd[5:], "FROM:<"), ">")}
+			reply("250 OK")
+		case "RCPT":
+			msg.to = append(msg.to, strings.TrimSuffix(strings.TrimPrefix(cmd[5:], "TO:<"), ">"))
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(line, "."))
+			}
+			msg.data = data.String()
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func (s *smtpServer) received() []smtpMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]smtpMessage(nil), s.messages...)
+}
+
+// setupRuns opens a build database with a finished previous run in which
+// lang/ruby failed, and a current run in which lang/ruby and editors/vim
+// failed and shells/bash built.
+func setupRuns(t *testing.T, cfg *config.Config) (*builddb.DB, string) {
+	t.Helper()
+
+	db, err := builddb.OpenDB(filepath.Join(t.TempDir(), "builds.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	start := time.Now().Add(-2 * time.Hour)
+	if err := db.StartRun("prev", start); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.PutRunPackage("prev", &builddb.RunPackageRecord{PortDir: "lang/ruby", Version: "3.3", Status: builddb.RunStatusFailed}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.FinishRun("prev", builddb.RunStats{Total: 1, Failed: 1}, start.Add(time.Hour), false); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.StartRun("cur", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []*builddb.RunPackageRecord{
+		{PortDir: "lang/ruby", Version: "3.3", Status: builddb.RunStatusFailed, LastPhase: "configure"},
+		{PortDir: "editors/vim", Version: "9.1", Status: builddb.RunStatusTimeout, LastPhase: "build"},
+		{PortDir: "shells/bash", Version: "5.2", Status: builddb.RunStatusSuccess},
+	} {
+		if err := db.PutRunPackage("cur", p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	log := "line 1\nline 2\ncc: error: vim.c: oops\n"
+	if err := os.WriteFile(filepath.Join(cfg.LogsPath, "editors___vim.log"), []byte(log), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return db, "cur"
+}
+
+func testConfig(t *testing.T) *config.Config {
+	cfg := &config.Config{Profile: "LiveSystem", LogsPath: t.TempDir()}
+	cfg.Notify.Events = []string{config.NotifyRunEnd, config.NotifyPortFailure}
+	return cfg
+}
+
+func TestRunEnded_Webhook(t *testing.T) {
+	hook := newWebhookServer(t)
+	cfg := testConfig(t)
+	cfg.Notify.Webhook = hook.URL
+	db, runID := setupRuns(t, cfg)
+
+	stats := builddb.RunStats{Total: 3, Success: 1, Failed: 2}
+	if err := New(cfg, db, runID, testLogger{t}).RunEnded("failed", stats, 90*time.Second); err != nil {
+		t.Fatalf("RunEnded failed: %v", err)
+	}
+
+	bodies := hook.received()
+	if len(bodies) != 1 {
+		t.Fatalf("webhook received %d posts, want 1", len(bodies))
+	}
+	var ev Event
+	if err := json.Unmarshal([]byte(bodies[0]), &ev); err != nil {
+		t.Fatalf("webhook body is not an Event: %v\n%s", err, bodies[0])
+	}
+	if ev.Kind != config.NotifyRunEnd || ev.RunID != runID || ev.Status != "failed" || ev.Stats != stats || ev.Seconds != 90 {
+		t.Errorf("event = %+v", ev)
+	}
+	if len(ev.Failures) != 2 || ev.NewFailures != 1 {
+		t.Fatalf("failures = %+v, want 2 with 1 new", ev.Failures)
+	}
+	vim, ruby := ev.Failures[0], ev.Failures[1]
+	if vim.Port != "editors/vim" || !vim.New || vim.Status != builddb.RunStatusTimeout || vim.Phase != "build" {
+		t.Errorf("first failure = %+v, want the new editors/vim timeout", vim)
+	}
+	if len(vim.Excerpt) != 3 || vim.Excerpt[2] != "cc: error: vim.c: oops" {
+		t.Errorf("vim excerpt = %q", vim.Excerpt)
+	}
+	if ruby.Port != "lang/ruby" || ruby.New {
+		t.Errorf("second failure = %+v, want the old lang/ruby failure", ruby)
+	}
+}
+
+func TestRunEnded_NewFailuresOnly(t *testing.T) {
+	hook := newWebhookServer(t)
+	cfg := testConfig(t)
+	cfg.Notify.Webhook = hook.URL
+	cfg.Notify.NewFailuresOnly = true
+	db, runID := setupRuns(t, cfg)
+
+	n := New(cfg, db, runID, testLogger{t})
+	for _, port := range []string{"lang/ruby", "editors/vim"} {
+		if err := n.PortFailed(port, "1.0", builddb.RunStatusFailed, "build"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := n.RunEnded("failed", builddb.RunStats{Total: 3, Failed: 2}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	// lang/ruby failed in the previous run too
+	bodies := hook.received()
+	if len(bodies) != 2 {
+		t.Fatalf("webhook received %d posts, want 2", len(bodies))
+	}
+	if !strings.Contains(bodies[0], `"port":"editors/vim"`) || strings.Contains(bodies[0], "lang/ruby") {
+		t.Errorf("port-failure post = %s, want editors/vim only", bodies[0])
+	}
+
+	// A run whose failures are all old sends nothing
+	if err := db.PutRunPackage(runID, &builddb.RunPackageRecord{PortDir: "editors/vim", Version: "9.1", Status: builddb.RunStatusSuccess}); err != nil {
+		t.Fatal(err)
+	}
+	if err := New(cfg, db, runID, testLogger{t}).RunEnded("failed", builddb.RunStats{Total: 3, Failed: 1}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(hook.received()); got != 2 {
+		t.Errorf("webhook received %d posts, want no new one", got)
+	}
+}
+
+func TestWebhookTemplate(t *testing.T) {
+	hook := newWebhookServer(t)
+	cfg := testConfig(t)
+	cfg.Notify.Webhook = hook.URL
+	cfg.Notify.WebhookTemplate = filepath.Join(t.TempDir(), "chat.tmpl")
+	tmpl := `{"text": {{json (printf "%s: %d failed on %s" .Status .Stats.Failed .Host)}}}`
+	if err := os.WriteFile(cfg.Notify.WebhookTemplate, []byte(tmpl), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := New(cfg, nil, "run", testLogger{t}).RunEnded("failed", builddb.RunStats{Failed: 4}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	bodies := hook.received()
+	if len(bodies) != 1 {
+		t.Fatalf("webhook received %d posts, want 1", len(bodies))
+	}
+	var body struct{ Text string }
+	if err := json.Unmarshal([]byte(bodies[0]), &body); err != nil {
+		t.Fatalf("templated body is not JSON: %v\n%s", err, bodies[0])
+	}
+	if !strings.HasPrefix(body.Text, "failed: 4 failed on ") {
+		t.Errorf("text = %q", body.Text)
+	}
+}
+
+func TestWebhookError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(t)
+	cfg.Notify.Webhook = srv.URL
+	err := New(cfg, nil, "run", testLogger{t}).PortFailed("editors/vim", "9.1", builddb.RunStatusFailed, "build")
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("PortFailed = %v, want the 500 response", err)
+	}
+}
+
+func TestQueuePortFailure(t *testing.T) {
+	// A webhook that answers only once released
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var ports []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		var ev Event
+		json.NewDecoder(r.Body).Decode(&ev)
+		mu.Lock()
+		for _, f := range ev.Failures {
+			ports = append(ports, f.Port)
+		}
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(t)
+	cfg.Notify.Webhook = srv.URL
+	n := New(cfg, nil, "run", testLogger{t})
+
+	start := time.Now()
+	n.QueuePortFailure("lang/ruby", "3.3", builddb.RunStatusFailed, "configure")
+	n.QueuePortFailure("editors/vim", "9.1", builddb.RunStatusTimeout, "build")
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("QueuePortFailure blocked for %v on a slow webhook", elapsed)
+	}
+
+	close(release)
+	n.Drain()
+	mu.Lock()
+	got := strings.Join(ports, " ")
+	mu.Unlock()
+	if got != "lang/ruby editors/vim" {
+		t.Errorf("delivered failures = %q, want both, in order", got)
+	}
+
+	// Failures after the end of the run are dropped
+	n.QueuePortFailure("shells/bash", "5.2", builddb.RunStatusFailed, "build")
+	n.Drain()
+	if len(ports) != 2 {
+		t.Errorf("failure queued after Drain was delivered: %v", ports)
+	}
+}
+
+func TestSendMail_Timeout(t *testing.T) {
+	// A server that accepts the connection but never greets
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	start := time.Now()
+	err = sendMail(ln.Addr().String(), 200*time.Millisecond, "go-synth@localhost", []string{"root@localhost"}, []byte("Subject: test\r\n\r\n"))
+	if err == nil {
+		t.Fatal("sendMail to a silent server succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("sendMail gave up after %v", elapsed)
+	}
+}
+
+func TestPortFailed_Email(t *testing.T) {
+	smtpd := newSMTPServer(t)
+	cfg := testConfig(t)
+	cfg.Notify.EmailTo = "builds@example.org, Ops <ops@example.org>"
+	cfg.Notify.EmailFrom = "Builder <go-synth@builder.example.org>"
+	cfg.Notify.SMTPServer = smtpd.addr()
+	db, runID := setupRuns(t, cfg)
+
+	if err := New(cfg, db, runID, testLogger{t}).PortFailed("editors/vim", "9.1", builddb.RunStatusTimeout, "build"); err != nil {
+		t.Fatalf("PortFailed failed: %v", err)
+	}
+
+	msgs := smtpd.received()
+	if len(msgs) != 1 {
+		t.Fatalf("SMTP server received %d messages, want 1", len(msgs))
+	}
+	msg := msgs[0]
+	if msg.from != "go-synth@builder.example.org" {
+		t.Errorf("MAIL FROM = %q", msg.from)
+	}
+	if strings.Join(msg.to, ",") != "builds@example.org,ops@example.org" {
+		t.Errorf("RCPT TO = %q", msg.to)
+	}
+	for _, want := range []string{
+		"Subject: [go-synth] editors/vim timeout on ",
+		"(LiveSystem)",
+		"editors/vim 9.1: timeout in build (new)",
+		"cc: error: vim.c: oops",
+	} {
+		if !strings.Contains(msg.data, want) {
+			t.Errorf("message does not contain %q:\n%s", want, msg.data)
+		}
+	}
+}
+
+func TestRunEnded_EmailTemplate(t *testing.T) {
+	smtpd := newSMTPServer(t)
+	cfg := testConfig(t)
+	cfg.Notify.EmailTo = "builds@example.org"
+	cfg.Notify.SMTPServer = smtpd.addr()
+	cfg.Notify.EmailTemplate = filepath.Join(t.TempDir(), "mail.tmpl")
+	tmpl := "{{.Stats.Success}} built in {{duration .Duration}}{{range .Failures}}, {{.Port}}{{end}}\n"
+	if err := os.WriteFile(cfg.Notify.EmailTemplate, []byte(tmpl), 0644); err != nil {
+		t.Fatal(err)
+	}
+	db, runID := setupRuns(t, cfg)
+
+	if err := New(cfg, db, runID, testLogger{t}).RunEnded("failed", builddb.RunStats{Success: 1, Failed: 2}, 61500*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	msgs := smtpd.received()
+	if len(msgs) != 1 {
+		t.Fatalf("SMTP server received %d messages, want 1", len(msgs))
+	}
+	if !strings.Contains(msgs[0].data, "\r\n\r\n1 built in 1m2s, editors/vim, lang/ruby\r\n") {
+		t.Errorf("message body = %q", msgs[0].data)
+	}
+	if !strings.HasPrefix(msgs[0].from, "go-synth@") {
+		t.Errorf("default sender = %q", msgs[0].from)
+	}
+}
+
+func TestNotifyOn(t *testing.T) {
+	hook := newWebhookServer(t)
+	cfg := testConfig(t)
+	cfg.Notify.Webhook = hook.URL
+	cfg.Notify.Events = []string{config.NotifyRunEnd}
+
+	n := New(cfg, nil, "run", testLogger{t})
+	if err := n.PortFailed("editors/vim", "9.1", builddb.RunStatusFailed, "build"); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(hook.received()); got != 0 {
+		t.Errorf("port failure sent with Notify_on run-end only")
+	}
+
+	// Nothing configured, nothing sent
+	cfg.Notify.Webhook = ""
+	if err := n.RunEnded("success", builddb.RunStats{}, time.Minute); err != nil {
+		t.Errorf("RunEnded without channels = %v", err)
+	}
+}
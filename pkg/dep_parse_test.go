@@ -32,3 +32,31 @@ func TestParseDependencyStringSkipsNonexistent(t *testing.T) {
 		t.Fatalf("expected only editors/vim, got %+v", deps)
 	}
 }
+
+func TestDependencyStringsTestMode(t *testing.T) {
+	p := &Package{RunDeps: "py39-six>0:devel/py-six", TestDeps: "py39-pytest>0:devel/py-pytest"}
+	registry := NewBuildStateRegistry()
+	registry.AddFlags(p, PkgFManualSel)
+
+	cfg := &config.Config{}
+	for _, d := range dependencyStrings(p, cfg, registry) {
+		if d.depType == DepTypeTest {
+			t.Fatalf("TEST_DEPENDS resolved outside test mode")
+		}
+	}
+
+	cfg.TestMode = true
+	deps := dependencyStrings(p, cfg, registry)
+	last := deps[len(deps)-1]
+	if last.depType != DepTypeTest || last.depStr != p.TestDeps {
+		t.Fatalf("expected TEST_DEPENDS in test mode, got %+v", deps)
+	}
+
+	// Dependencies pulled in by the graph are not tested
+	dep := &Package{TestDeps: p.TestDeps}
+	for _, d := range dependencyStrings(dep, cfg, registry) {
+		if d.depType == DepTypeTest {
+			t.Fatalf("TEST_DEPENDS resolved for a port not named on the command line")
+		}
+	}
+}
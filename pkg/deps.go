@@ -52,19 +52,7 @@ func resolveDependencies(packages []*Package, cfg *config.Config, registry *Buil
 
 		for _, pkg := range currentBatch {
 			// Parse and queue all dependency types
-			deps := []struct {
-				depStr  string
-				depType DepType
-			}{
-				{pkg.FetchDeps, DepTypeFetch},
-				{pkg.ExtractDeps, DepTypeExtract},
-				{pkg.PatchDeps, DepTypePatch},
-				{pkg.BuildDeps, DepTypeBuild},
-				{pkg.LibDeps, DepTypeLib},
-				{pkg.RunDeps, DepTypeRun},
-			}
-
-			for _, d := range deps {
+			for _, d := range dependencyStrings(pkg, cfg, registry) {
 				if d.depStr == "" {
 					continue
 				}
@@ -139,7 +127,7 @@ func resolveDependencies(packages []*Package, cfg *config.Config, registry *Buil
 
 	// Phase 2: Build the dependency graph
 	logger.Info("Building dependency graph...")
-	if err := buildDependencyGraph(packages, cfg, pkgRegistry, logger); err != nil {
+	if err := buildDependencyGraph(packages, cfg, registry, pkgRegistry, logger); err != nil {
 		return err
 	}
 
@@ -239,14 +227,14 @@ func parseDependencyString(depStr string, cfg *config.Config) []depOrigin {
 }
 
 // buildDependencyGraph creates the IDependOn and DependsOnMe links
-func buildDependencyGraph(packages []*Package, cfg *config.Config, pkgRegistry *PackageRegistry, logger interface {
+func buildDependencyGraph(packages []*Package, cfg *config.Config, registry *BuildStateRegistry, pkgRegistry *PackageRegistry, logger interface {
 	Info(format string, args ...any)
 	Warn(format string, args ...any)
 }) error {
 	// Process all packages
 	count := 0
 	for _, pkg := range packages {
-		if err := linkPackageDependencies(pkg, cfg, pkgRegistry, logger); err != nil {
+		if err := linkPackageDependencies(pkg, cfg, registry, pkgRegistry, logger); err != nil {
 			return err
 		}
 		count++
@@ -265,13 +253,18 @@ func buildDependencyGraph(packages []*Package, cfg *config.Config, pkgRegistry *
 	return nil
 }
 
-func linkPackageDependencies(pkg *Package, cfg *config.Config, pkgRegistry *PackageRegistry, logger interface {
-	Warn(format string, args ...any)
-}) error {
-	deps := []struct {
-		depStr  string
-		depType DepType
-	}{
+// depString is a raw dependency specification and the type of dependency
+// it describes.
+type depString struct {
+	depStr  string
+	depType DepType
+}
+
+// dependencyStrings returns pkg's dependency specifications in DepType
+// order. TEST_DEPENDS is only included in test mode, and only for ports
+// named on the command line, which are the only ones tested.
+func dependencyStrings(pkg *Package, cfg *config.Config, registry *BuildStateRegistry) []depString {
+	deps := []depString{
 		{pkg.FetchDeps, DepTypeFetch},
 		{pkg.ExtractDeps, DepTypeExtract},
 		{pkg.PatchDeps, DepTypePatch},
@@ -279,8 +272,16 @@ func linkPackageDependencies(pkg *Package, cfg *config.Config, pkgRegistry *Pack
 		{pkg.LibDeps, DepTypeLib},
 		{pkg.RunDeps, DepTypeRun},
 	}
+	if cfg.TestMode && registry.HasFlags(pkg, PkgFManualSel) {
+		deps = append(deps, depString{pkg.TestDeps, DepTypeTest})
+	}
+	return deps
+}
 
-	for _, d := range deps {
+func linkPackageDependencies(pkg *Package, cfg *config.Config, registry *BuildStateRegistry, pkgRegistry *PackageRegistry, logger interface {
+	Warn(format string, args ...any)
+}) error {
+	for _, d := range dependencyStrings(pkg, cfg, registry) {
 		if d.depStr == "" {
 			continue
 		}
@@ -2,6 +2,7 @@ package pkg
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
 	"go-synth/config"
@@ -124,6 +125,59 @@ func TestIntegration_SimpleWorkflow(t *testing.T) {
 	t.Logf("Build order verified: %d packages in correct dependency order", len(buildOrder))
 }
 
+// TestIntegration_TestDependsOnlyForRequestedPorts verifies that test mode
+// resolves the TEST_DEPENDS of the ports named on the command line, but not
+// those of their dependencies.
+func TestIntegration_TestDependsOnlyForRequestedPorts(t *testing.T) {
+	dir := t.TempDir()
+	fixture := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	restore := setTestQuerier(newTestFixtureQuerier(map[string]string{
+		"devel/py-requests": fixture("requests.txt", "@@go-synth@@ PKGNAME\npy39-requests-2.31.0\n"+
+			"@@go-synth@@ RUN_DEPENDS\npy39-urllib3>0:net/py-urllib3\n"+
+			"@@go-synth@@ TEST_DEPENDS\npy39-pytest>0:devel/py-pytest\n"),
+		"net/py-urllib3": fixture("urllib3.txt", "@@go-synth@@ PKGNAME\npy39-urllib3-2.2.1\n"+
+			"@@go-synth@@ TEST_DEPENDS\npy39-hypothesis>0:devel/py-hypothesis\n"),
+		"devel/py-pytest":     fixture("pytest.txt", "@@go-synth@@ PKGNAME\npy39-pytest-8.1.1\n"),
+		"devel/py-hypothesis": fixture("hypothesis.txt", "@@go-synth@@ PKGNAME\npy39-hypothesis-6.99.0\n"),
+	}))
+	defer restore()
+
+	cfg := &config.Config{DPortsPath: "/usr/ports", MaxWorkers: 2, TestMode: true}
+	pkgRegistry := NewPackageRegistry()
+	bsRegistry := NewBuildStateRegistry()
+
+	packages, err := ParsePortList([]string{"devel/py-requests"}, cfg, bsRegistry, pkgRegistry, log.NoOpLogger{})
+	if err != nil {
+		t.Fatalf("ParsePortList failed: %v", err)
+	}
+	if err := ResolveDependencies(packages, cfg, bsRegistry, pkgRegistry, log.NoOpLogger{}); err != nil {
+		t.Fatalf("ResolveDependencies failed: %v", err)
+	}
+
+	if pkgRegistry.Find("devel/py-pytest") == nil {
+		t.Error("TEST_DEPENDS of the requested port not resolved")
+	}
+	if pkgRegistry.Find("devel/py-hypothesis") != nil {
+		t.Error("TEST_DEPENDS of a dependency resolved")
+	}
+
+	urllib3 := pkgRegistry.Find("net/py-urllib3")
+	if urllib3 == nil {
+		t.Fatal("RUN_DEPENDS of the requested port not resolved")
+	}
+	for _, link := range urllib3.IDependOn {
+		if link.DepType == DepTypeTest {
+			t.Errorf("net/py-urllib3 linked to test dependency %s", link.Pkg.PortDir)
+		}
+	}
+}
+
 // TestIntegration_SharedDependencies tests that shared dependencies appear only once
 // in the dependency graph and build order.
 func TestIntegration_SharedDependencies(t *testing.T) {
@@ -0,0 +1,186 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go-synth/config"
+)
+
+// packageExts are the package file extensions pkg has used, newest first.
+var packageExts = []string{".pkg", ".tzst", ".txz", ".tbz", ".tgz", ".tar"}
+
+// RepoPackage is a package file in the repository's All directory.
+type RepoPackage struct {
+	File    string    `json:"file"`    // e.g., "vim-9.0.1234.pkg"
+	Name    string    `json:"name"`    // package name without version, e.g., "vim"
+	Version string    `json:"version"` // e.g., "9.0.1234"
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+}
+
+// ObsoleteReport lists the package files in the repository that the
+// current ports tree no longer produces.
+type ObsoleteReport struct {
+	Obsolete []RepoPackage `json:"obsolete"` // Previous versions of current packages
+	Orphaned []RepoPackage `json:"orphaned"` // Packages no current port produces (complete scans only)
+	Kept     []RepoPackage `json:"kept"`     // Previous versions retained by Keep_obsolete_packages
+}
+
+// Files returns the obsolete and orphaned packages, the files a prune
+// removes.
+func (r *ObsoleteReport) Files() []RepoPackage {
+	return append(append([]RepoPackage(nil), r.Obsolete...), r.Orphaned...)
+}
+
+// Size returns the total size of the obsolete and orphaned packages.
+func (r *ObsoleteReport) Size() int64 {
+	var size int64
+	for _, rp := range r.Files() {
+		size += rp.Size
+	}
+	return size
+}
+
+// splitPackageFile splits a package file name such as "vim-9.1.0470.pkg"
+// into package name and version. Package versions never contain a dash,
+// so the version starts after the last one.
+func splitPackageFile(file string) (name, version string, ok bool) {
+	stem := ""
+	for _, ext := range packageExts {
+		if strings.HasSuffix(file, ext) {
+			stem = strings.TrimSuffix(file, ext)
+			break
+		}
+	}
+	i := strings.LastIndex(stem, "-")
+	if i <= 0 || i == len(stem)-1 {
+		return "", "", false
+	}
+	return stem[:i], stem[i+1:], true
+}
+
+// FindObsoletePackages compares the repository's All directory against
+// the package files of packages. Once the current version of a package in
+// packages is in the repository, its other versions are obsolete, except
+// for the Keep_obsolete_packages most recent ones, which are reported as
+// kept. Until then they are left alone, so the repository keeps a usable
+// version of every package.
+//
+// Only a complete scan, where packages holds the whole resolved ports
+// tree, can tell that a package belongs to no port; package files of
+// unknown names are then reported as orphaned, otherwise ignored. A
+// package that did not resolve to a package file makes the scan
+// incomplete, since it may produce any of them, and so does a port whose
+// non-default FLAVORS are missing from packages, since each flavor
+// produces a package of its own.
+func FindObsoletePackages(cfg *config.Config, packages []*Package, complete bool) (*ObsoleteReport, error) {
+	current := make(map[string]bool) // package files
+	names := make(map[string]bool)   // package names
+	portDirs := make(map[string]bool)
+	for _, p := range packages {
+		portDirs[p.PortDir] = true
+		if p.PkgFile == "" {
+			complete = false
+			continue
+		}
+		current[p.PkgFile] = true
+		if name, _, ok := splitPackageFile(p.PkgFile); ok {
+			names[name] = true
+		}
+	}
+	for _, p := range packages {
+		if len(p.Flavors) < 2 {
+			continue
+		}
+		origin, _, _ := strings.Cut(p.PortDir, "@")
+		for _, flavor := range p.Flavors[1:] {
+			if !portDirs[origin+"@"+flavor] {
+				complete = false
+			}
+		}
+	}
+
+	dir := filepath.Join(cfg.PackagesPath, "All")
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return &ObsoleteReport{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repository: %w", err)
+	}
+
+	report := &ObsoleteReport{}
+	previous := make(map[string][]RepoPackage)
+	built := make(map[string]bool) // names whose current version is in the repository
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		name, version, ok := splitPackageFile(entry.Name())
+		if current[entry.Name()] {
+			built[name] = true
+			continue
+		}
+		if !ok {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue // removed since ReadDir
+		}
+		rp := RepoPackage{
+			File:    entry.Name(),
+			Name:    name,
+			Version: version,
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		}
+		switch {
+		case names[name]:
+			previous[name] = append(previous[name], rp)
+		case complete:
+			report.Orphaned = append(report.Orphaned, rp)
+		}
+	}
+
+	for name, versions := range previous {
+		if !built[name] {
+			continue
+		}
+		// Newest first, so the versions kept are the most recent builds
+		sort.Slice(versions, func(i, j int) bool {
+			return versions[i].ModTime.After(versions[j].ModTime)
+		})
+		keep := min(cfg.KeepObsolete, len(versions))
+		report.Kept = append(report.Kept, versions[:keep]...)
+		report.Obsolete = append(report.Obsolete, versions[keep:]...)
+	}
+
+	for _, list := range [][]RepoPackage{report.Obsolete, report.Orphaned, report.Kept} {
+		sort.Slice(list, func(i, j int) bool { return list[i].File < list[j].File })
+	}
+	return report, nil
+}
+
+// PruneObsoletePackages removes the obsolete and orphaned packages of
+// report from the repository. It returns the packages removed; failures
+// to remove the others are joined in the error.
+func PruneObsoletePackages(cfg *config.Config, report *ObsoleteReport) ([]RepoPackage, error) {
+	dir := filepath.Join(cfg.PackagesPath, "All")
+	var removed []RepoPackage
+	var errs []error
+	for _, rp := range report.Files() {
+		if err := os.Remove(filepath.Join(dir, rp.File)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+			continue
+		}
+		removed = append(removed, rp)
+	}
+	return removed, errors.Join(errs...)
+}
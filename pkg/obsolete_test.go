@@ -0,0 +1,210 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"go-synth/config"
+	"go-synth/log"
+)
+
+// writeRepoPackages creates package files in the All directory of a
+// repository, each one hour older than the previous.
+func writeRepoPackages(t *testing.T, files ...string) *config.Config {
+	t.Helper()
+	cfg := &config.Config{PackagesPath: t.TempDir()}
+	dir := filepath.Join(cfg.PackagesPath, "All")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Now()
+	for _, file := range files {
+		path := filepath.Join(dir, file)
+		if err := os.WriteFile(path, []byte(file), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+		mtime = mtime.Add(-time.Hour)
+	}
+	return cfg
+}
+
+func repoFiles(list []RepoPackage) []string {
+	var files []string
+	for _, rp := range list {
+		files = append(files, rp.File)
+	}
+	return files
+}
+
+func TestFindObsoletePackages(t *testing.T) {
+	cfg := writeRepoPackages(t,
+		"vim-9.1.0470.pkg",
+		"vim-9.1.0200.pkg",
+		"vim-9.0.1234.pkg",
+		"vim-9.0.1000.txz",
+		"py311-setuptools-69.0.pkg",
+		"py39-setuptools-63.1.pkg",
+		"gone-1.0.pkg",
+		"packagesite.pkg",
+	)
+	packages := []*Package{
+		{PortDir: "editors/vim", PkgFile: "vim-9.1.0470.pkg"},
+		{PortDir: "devel/py-setuptools@py311", PkgFile: "py311-setuptools-69.0.pkg"},
+	}
+
+	report, err := FindObsoletePackages(cfg, packages, false)
+	if err != nil {
+		t.Fatalf("FindObsoletePackages() failed: %v", err)
+	}
+	if got, want := repoFiles(report.Obsolete), []string{"vim-9.0.1000.txz", "vim-9.0.1234.pkg", "vim-9.1.0200.pkg"}; !slices.Equal(got, want) {
+		t.Errorf("Obsolete = %v, want %v", got, want)
+	}
+	if len(report.Orphaned) != 0 {
+		t.Errorf("Orphaned = %v for a partial scan", repoFiles(report.Orphaned))
+	}
+
+	// A complete scan knows nothing produces the other files; the most
+	// recent previous version is kept
+	cfg.KeepObsolete = 1
+	report, err = FindObsoletePackages(cfg, packages, true)
+	if err != nil {
+		t.Fatalf("FindObsoletePackages() failed: %v", err)
+	}
+	if got, want := repoFiles(report.Kept), []string{"vim-9.1.0200.pkg"}; !slices.Equal(got, want) {
+		t.Errorf("Kept = %v, want %v", got, want)
+	}
+	if got, want := repoFiles(report.Orphaned), []string{"gone-1.0.pkg", "py39-setuptools-63.1.pkg"}; !slices.Equal(got, want) {
+		t.Errorf("Orphaned = %v, want %v", got, want)
+	}
+
+	removed, err := PruneObsoletePackages(cfg, report)
+	if err != nil {
+		t.Fatalf("PruneObsoletePackages() failed: %v", err)
+	}
+	if len(removed) != 4 {
+		t.Errorf("removed %v, want 4 packages", repoFiles(removed))
+	}
+	entries, _ := os.ReadDir(filepath.Join(cfg.PackagesPath, "All"))
+	var left []string
+	for _, e := range entries {
+		left = append(left, e.Name())
+	}
+	want := []string{"packagesite.pkg", "py311-setuptools-69.0.pkg", "vim-9.1.0200.pkg", "vim-9.1.0470.pkg"}
+	if !slices.Equal(left, want) {
+		t.Errorf("repository holds %v after pruning, want %v", left, want)
+	}
+}
+
+func TestFindObsoletePackages_NoRepository(t *testing.T) {
+	cfg := &config.Config{PackagesPath: filepath.Join(t.TempDir(), "missing")}
+	report, err := FindObsoletePackages(cfg, nil, true)
+	if err != nil || len(report.Files()) != 0 {
+		t.Errorf("FindObsoletePackages() = %+v, %v for a missing repository", report, err)
+	}
+}
+
+func TestFindObsoletePackages_CurrentNotBuilt(t *testing.T) {
+	// vim 9.1 is not built yet, so 9.0 is the only vim there is
+	cfg := writeRepoPackages(t, "vim-9.0.1234.pkg", "gone-1.0.pkg")
+	packages := []*Package{
+		{PortDir: "editors/vim", PkgFile: "vim-9.1.0470.pkg"},
+		{PortDir: "devel/broken"}, // did not resolve
+	}
+
+	report, err := FindObsoletePackages(cfg, packages, true)
+	if err != nil {
+		t.Fatalf("FindObsoletePackages() failed: %v", err)
+	}
+	if files := report.Files(); len(files) != 0 || len(report.Kept) != 0 {
+		t.Errorf("Files() = %v, Kept = %v, want nothing to prune", repoFiles(files), repoFiles(report.Kept))
+	}
+
+	removed, err := PruneObsoletePackages(cfg, report)
+	if err != nil || len(removed) != 0 {
+		t.Errorf("PruneObsoletePackages() removed %v, %v", repoFiles(removed), err)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.PackagesPath, "All", "vim-9.0.1234.pkg")); err != nil {
+		t.Errorf("only vim package removed: %v", err)
+	}
+}
+
+func TestFindObsoletePackages_Flavors(t *testing.T) {
+	cfg := writeRepoPackages(t, "py311-setuptools-69.5.1.pkg", "py39-setuptools-69.5.1.pkg", "gone-1.0.pkg")
+	setuptools := &Package{PortDir: "devel/py-setuptools", PkgFile: "py311-setuptools-69.5.1.pkg", Flavors: []string{"py311", "py39"}}
+
+	// Without the py39 flavor, its package looks like it belongs to no port
+	report, err := FindObsoletePackages(cfg, []*Package{setuptools}, true)
+	if err != nil {
+		t.Fatalf("FindObsoletePackages() failed: %v", err)
+	}
+	if files := report.Files(); len(files) != 0 {
+		t.Errorf("Files() = %v, want nothing to prune while flavors are missing", repoFiles(files))
+	}
+
+	py39 := &Package{PortDir: "devel/py-setuptools@py39", Flavor: "py39", PkgFile: "py39-setuptools-69.5.1.pkg", Flavors: setuptools.Flavors}
+	report, err = FindObsoletePackages(cfg, []*Package{setuptools, py39}, true)
+	if err != nil {
+		t.Fatalf("FindObsoletePackages() failed: %v", err)
+	}
+	if got := repoFiles(report.Orphaned); !slices.Equal(got, []string{"gone-1.0.pkg"}) {
+		t.Errorf("Orphaned = %v, want only gone-1.0.pkg", got)
+	}
+}
+
+func TestParseFlavors_SurvivePrune(t *testing.T) {
+	dir := t.TempDir()
+	fixture := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	restore := setTestQuerier(newTestFixtureQuerier(map[string]string{
+		"devel/py-setuptools": fixture("py311.txt", "@@go-synth@@ PKGFILE\npy311-setuptools-69.5.1.pkg\n"+
+			"@@go-synth@@ FLAVORS\npy311 py39\n"),
+		"devel/py-setuptools@py39": fixture("py39.txt", "@@go-synth@@ PKGFILE\npy39-setuptools-69.5.1.pkg\n"+
+			"@@go-synth@@ FLAVORS\npy311 py39\n"),
+	}))
+	defer restore()
+
+	cfg := writeRepoPackages(t, "py311-setuptools-69.5.1.pkg", "py39-setuptools-69.5.1.pkg", "gone-1.0.pkg")
+	cfg.DPortsPath = "/usr/ports"
+	cfg.MaxWorkers = 2
+	pkgRegistry := NewPackageRegistry()
+	registry := NewBuildStateRegistry()
+
+	packages, err := ParsePortList([]string{"devel/py-setuptools"}, cfg, registry, pkgRegistry, log.NoOpLogger{})
+	if err != nil {
+		t.Fatalf("ParsePortList failed: %v", err)
+	}
+	flavored, err := ParseFlavors(packages, cfg, registry, pkgRegistry, log.NoOpLogger{})
+	if err != nil {
+		t.Fatalf("ParseFlavors failed: %v", err)
+	}
+	if len(flavored) != 1 || flavored[0].PortDir != "devel/py-setuptools@py39" {
+		t.Fatalf("ParseFlavors = %v, want devel/py-setuptools@py39", flavored)
+	}
+
+	report, err := FindObsoletePackages(cfg, append(packages, flavored...), true)
+	if err != nil {
+		t.Fatalf("FindObsoletePackages() failed: %v", err)
+	}
+	if _, err := PruneObsoletePackages(cfg, report); err != nil {
+		t.Fatalf("PruneObsoletePackages() failed: %v", err)
+	}
+	for _, file := range []string{"py311-setuptools-69.5.1.pkg", "py39-setuptools-69.5.1.pkg"} {
+		if _, err := os.Stat(filepath.Join(cfg.PackagesPath, "All", file)); err != nil {
+			t.Errorf("%s pruned: %v", file, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(cfg.PackagesPath, "All", "gone-1.0.pkg")); !os.IsNotExist(err) {
+		t.Errorf("orphaned gone-1.0.pkg not pruned: %v", err)
+	}
+}
@@ -0,0 +1,482 @@
+package pkg
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go-synth/config"
+)
+
+// Option errors returned when validating option selections.
+var (
+	// ErrUnknownOption is returned when a setting names an option the port
+	// does not define.
+	ErrUnknownOption = errors.New("unknown port option")
+
+	// ErrInvalidOptions is returned when a selection violates the port's
+	// option group constraints (e.g., two options in an OPTIONS_SINGLE group).
+	ErrInvalidOptions = errors.New("invalid option selection")
+)
+
+// optionsFileName is the name of the file written in each port's options
+// directory by 'make config', relative to PORT_DBDIR.
+const optionsFileName = "options"
+
+// OptionGroupKind identifies how the options in a group may be combined.
+// The kinds correspond to the ports framework OPTIONS_* group variables.
+type OptionGroupKind int
+
+const (
+	// OptionGroupAny allows any combination (OPTIONS_GROUP).
+	OptionGroupAny OptionGroupKind = iota
+
+	// OptionGroupSingle requires exactly one option (OPTIONS_SINGLE).
+	OptionGroupSingle
+
+	// OptionGroupRadio allows at most one option (OPTIONS_RADIO).
+	OptionGroupRadio
+
+	// OptionGroupMulti requires at least one option (OPTIONS_MULTI).
+	OptionGroupMulti
+)
+
+// optionGroupVars maps each group kind to its ports framework variable name.
+var optionGroupVars = []struct {
+	kind OptionGroupKind
+	name string
+}{
+	{OptionGroupAny, "OPTIONS_GROUP"},
+	{OptionGroupSingle, "OPTIONS_SINGLE"},
+	{OptionGroupRadio, "OPTIONS_RADIO"},
+	{OptionGroupMulti, "OPTIONS_MULTI"},
+}
+
+// String returns the ports framework variable name for the group kind.
+func (k OptionGroupKind) String() string {
+	for _, g := range optionGroupVars {
+		if g.kind == k {
+			return g.name
+		}
+	}
+	return fmt.Sprintf("UNKNOWN(%d)", int(k))
+}
+
+// OptionGroup is a named set of options with a combination constraint.
+type OptionGroup struct {
+	Name    string          // e.g., "GUI" from OPTIONS_SINGLE=GUI
+	Kind    OptionGroupKind // combination constraint
+	Options []string        // members from OPTIONS_SINGLE_GUI
+}
+
+// PortOptions describes the configurable options of a port as declared by
+// its Makefile.
+type PortOptions struct {
+	PortDir      string            // e.g., "editors/vim"
+	PkgName      string            // e.g., "vim-9.1.0470"
+	Define       []string          // standalone options (OPTIONS_DEFINE, arch-specific ones included)
+	Groups       []OptionGroup     // grouped options
+	Defaults     map[string]bool   // options enabled by default (OPTIONS_DEFAULT)
+	Descriptions map[string]string // option descriptions (<OPT>_DESC)
+	Effective    OptionSet         // selection a build uses (PORT_OPTIONS), saved options included
+}
+
+// OptionSet maps option names to their enabled state.
+type OptionSet map[string]bool
+
+// Enabled returns the names of enabled options in sorted order.
+func (s OptionSet) Enabled() []string {
+	var names []string
+	for name, on := range s {
+		if on {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SavedOptions holds the contents of a port's options file.
+type SavedOptions struct {
+	PkgName string   // _OPTIONS_READ
+	Set     []string // OPTIONS_FILE_SET
+	Unset   []string // OPTIONS_FILE_UNSET
+}
+
+// QueryPortOptions queries a port's option definitions from its Makefile.
+//
+// The options offered are those of COMPLETE_OPTIONS_LIST, which the ports
+// framework derives from OPTIONS_DEFINE, the groups and their per-arch
+// variants, less OPTIONS_EXCLUDE and OPTIONS_SLAVE. The definitions are
+// only used to order and group them. The query runs in two passes: the
+// first retrieves the option and group lists, the second retrieves group
+// members and option descriptions whose variable names depend on the first.
+func QueryPortOptions(portSpec string, cfg *config.Config) (*PortOptions, error) {
+	first := []string{VarPkgName, "OPTIONS_DEFINE", "OPTIONS_DEFAULT", "COMPLETE_OPTIONS_LIST", "PORT_OPTIONS"}
+	for _, g := range optionGroupVars {
+		first = append(first, g.name)
+	}
+
+	vars, err := QueryPortVars(portSpec, first, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	complete := make(map[string]bool)
+	for _, o := range vars.Words("COMPLETE_OPTIONS_LIST") {
+		complete[o] = true
+	}
+	offered := func(names []string) []string {
+		var kept []string
+		for _, n := range names {
+			if complete[n] {
+				kept = append(kept, n)
+			}
+		}
+		return kept
+	}
+
+	category, name, _ := parsePortSpec(portSpec, cfg)
+	opts := &PortOptions{
+		PortDir:      category + "/" + name,
+		PkgName:      vars.Get(VarPkgName),
+		Define:       offered(vars.Words("OPTIONS_DEFINE")),
+		Defaults:     make(map[string]bool),
+		Descriptions: make(map[string]string),
+		Effective:    make(OptionSet),
+	}
+	for _, o := range offered(vars.Words("OPTIONS_DEFAULT")) {
+		opts.Defaults[o] = true
+	}
+
+	var second []string
+	for _, g := range optionGroupVars {
+		for _, groupName := range vars.Words(g.name) {
+			opts.Groups = append(opts.Groups, OptionGroup{Name: groupName, Kind: g.kind})
+			second = append(second, g.name+"_"+groupName)
+		}
+	}
+
+	if len(opts.Groups) > 0 {
+		members, err := QueryPortVars(portSpec, second, cfg)
+		if err != nil {
+			return nil, err
+		}
+		for i := range opts.Groups {
+			opts.Groups[i].Options = offered(members.Words(second[i]))
+		}
+	}
+
+	// Options offered outside OPTIONS_DEFINE and the groups, such as
+	// OPTIONS_DEFINE_<ARCH>, are standalone
+	listed := make(map[string]bool)
+	for _, o := range opts.All() {
+		listed[o] = true
+	}
+	for _, o := range vars.Words("COMPLETE_OPTIONS_LIST") {
+		if !listed[o] {
+			opts.Define = append(opts.Define, o)
+		}
+	}
+
+	all := opts.All()
+	enabled := make(map[string]bool)
+	for _, o := range vars.Words("PORT_OPTIONS") {
+		enabled[o] = true
+	}
+	for _, o := range all {
+		opts.Effective[o] = enabled[o]
+	}
+	if len(all) == 0 {
+		return opts, nil
+	}
+
+	descVars := make([]string, len(all))
+	for i, o := range all {
+		descVars[i] = o + "_DESC"
+	}
+	descs, err := QueryPortVars(portSpec, descVars, cfg)
+	if err != nil {
+		return nil, err
+	}
+	for i, o := range all {
+		if d := descs.Get(descVars[i]); d != "" {
+			opts.Descriptions[o] = d
+		}
+	}
+
+	return opts, nil
+}
+
+// All returns every option the port defines, standalone options first,
+// followed by group members in declaration order.
+func (o *PortOptions) All() []string {
+	seen := make(map[string]bool)
+	var all []string
+	add := func(names []string) {
+		for _, n := range names {
+			if !seen[n] {
+				seen[n] = true
+				all = append(all, n)
+			}
+		}
+	}
+	add(o.Define)
+	for _, g := range o.Groups {
+		add(g.Options)
+	}
+	return all
+}
+
+// Has reports whether the port defines the named option.
+func (o *PortOptions) Has(name string) bool {
+	for _, n := range o.All() {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultSet returns the selection produced by OPTIONS_DEFAULT alone.
+func (o *PortOptions) DefaultSet() OptionSet {
+	set := make(OptionSet)
+	for _, n := range o.All() {
+		set[n] = o.Defaults[n]
+	}
+	return set
+}
+
+// Resolve returns the effective selection for the port: defaults overridden
+// by any saved settings. Saved entries for options the port no longer
+// defines are ignored, matching the ports framework.
+func (o *PortOptions) Resolve(saved *SavedOptions) OptionSet {
+	set := o.DefaultSet()
+	if saved == nil {
+		return set
+	}
+	for _, n := range saved.Set {
+		if _, ok := set[n]; ok {
+			set[n] = true
+		}
+	}
+	for _, n := range saved.Unset {
+		if _, ok := set[n]; ok {
+			set[n] = false
+		}
+	}
+	return set
+}
+
+// Apply enables or disables an option in set. Enabling a member of an
+// OPTIONS_SINGLE or OPTIONS_RADIO group disables the other members.
+func (o *PortOptions) Apply(set OptionSet, name string, on bool) error {
+	if !o.Has(name) {
+		return fmt.Errorf("%w: %s (port %s)", ErrUnknownOption, name, o.PortDir)
+	}
+
+	set[name] = on
+	if !on {
+		return nil
+	}
+
+	for _, g := range o.Groups {
+		if g.Kind != OptionGroupSingle && g.Kind != OptionGroupRadio {
+			continue
+		}
+		if !containsString(g.Options, name) {
+			continue
+		}
+		for _, member := range g.Options {
+			if member != name {
+				set[member] = false
+			}
+		}
+	}
+	return nil
+}
+
+// Validate checks set against the port's group constraints.
+func (o *PortOptions) Validate(set OptionSet) error {
+	for _, g := range o.Groups {
+		count := 0
+		for _, member := range g.Options {
+			if set[member] {
+				count++
+			}
+		}
+
+		switch {
+		case g.Kind == OptionGroupSingle && count != 1:
+			return fmt.Errorf("%w: %s group %s requires exactly one of %s (got %d)",
+				ErrInvalidOptions, g.Kind, g.Name, strings.Join(g.Options, ", "), count)
+		case g.Kind == OptionGroupRadio && count > 1:
+			return fmt.Errorf("%w: %s group %s allows at most one of %s (got %d)",
+				ErrInvalidOptions, g.Kind, g.Name, strings.Join(g.Options, ", "), count)
+		case g.Kind == OptionGroupMulti && count < 1:
+			return fmt.Errorf("%w: %s group %s requires at least one of %s",
+				ErrInvalidOptions, g.Kind, g.Name, strings.Join(g.Options, ", "))
+		}
+	}
+	return nil
+}
+
+// ParseOptionSetting parses a "NAME=on" style setting. Accepted values are
+// on/off, yes/no, true/false and 1/0 (case-insensitive).
+func ParseOptionSetting(s string) (string, bool, error) {
+	name, value, ok := strings.Cut(s, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return "", false, fmt.Errorf("invalid option setting %q (expected NAME=on|off)", s)
+	}
+
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "on", "yes":
+		return name, true, nil
+	case "off", "no":
+		return name, false, nil
+	}
+	on, err := strconv.ParseBool(value)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid value for option %s: %q (expected on or off)", name, value)
+	}
+	return name, on, nil
+}
+
+// OptionsName returns the options directory name for a port, following the
+// ports framework OPTIONS_NAME convention (category_name, flavor ignored).
+func OptionsName(portDir string) string {
+	origin, _, _ := strings.Cut(portDir, "@")
+	return strings.Replace(origin, "/", "_", 1)
+}
+
+// OptionsFilePath returns the path of a port's saved options file under
+// cfg.OptionsPath, which is mounted as PORT_DBDIR inside workers.
+func OptionsFilePath(cfg *config.Config, portDir string) string {
+	return filepath.Join(cfg.OptionsPath, OptionsName(portDir), optionsFileName)
+}
+
+// ReadSavedOptions reads a port's saved options file. It returns nil and no
+// error if the port has no saved options.
+func ReadSavedOptions(cfg *config.Config, portDir string) (*SavedOptions, error) {
+	return readOptionsFile(OptionsFilePath(cfg, portDir))
+}
+
+// readOptionsFile parses an options file written by 'make config' or
+// WriteSavedOptions.
+func readOptionsFile(path string) (*SavedOptions, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open options file: %w", err)
+	}
+	defer f.Close()
+
+	saved := &SavedOptions{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "OPTIONS_FILE_SET+="):
+			saved.Set = append(saved.Set, strings.Fields(strings.TrimPrefix(line, "OPTIONS_FILE_SET+="))...)
+		case strings.HasPrefix(line, "OPTIONS_FILE_UNSET+="):
+			saved.Unset = append(saved.Unset, strings.Fields(strings.TrimPrefix(line, "OPTIONS_FILE_UNSET+="))...)
+		case strings.HasPrefix(line, "_OPTIONS_READ="):
+			saved.PkgName = strings.TrimPrefix(line, "_OPTIONS_READ=")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read options file: %w", err)
+	}
+
+	return saved, nil
+}
+
+// WriteSavedOptions writes a port's options file in the format produced by
+// 'make config', recording every defined option as set or unset.
+func WriteSavedOptions(cfg *config.Config, opts *PortOptions, set OptionSet) error {
+	path := OptionsFilePath(cfg, opts.PortDir)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create options directory: %w", err)
+	}
+
+	all := opts.All()
+	sorted := append([]string(nil), all...)
+	sort.Strings(sorted)
+
+	var b strings.Builder
+	b.WriteString("# This file is auto-generated by 'make config'.\n")
+	fmt.Fprintf(&b, "# Options for %s\n", opts.PkgName)
+	fmt.Fprintf(&b, "_OPTIONS_READ=%s\n", opts.PkgName)
+	fmt.Fprintf(&b, "_FILE_COMPLETE_OPTIONS_LIST=%s\n", strings.Join(sorted, " "))
+	for _, name := range sorted {
+		if set[name] {
+			fmt.Fprintf(&b, "OPTIONS_FILE_SET+=%s\n", name)
+		} else {
+			fmt.Fprintf(&b, "OPTIONS_FILE_UNSET+=%s\n", name)
+		}
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write options file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to finalize options file: %w", err)
+	}
+	return nil
+}
+
+// SavedOptionPorts returns the port directories (e.g., "editors/vim") that
+// have an options file under cfg.OptionsPath, in sorted order.
+func SavedOptionPorts(cfg *config.Config) ([]string, error) {
+	entries, err := os.ReadDir(cfg.OptionsPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read options directory: %w", err)
+	}
+
+	var ports []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		// Categories never contain underscores, so the first one separates
+		// category from port name (e.g., "x11-wm_i3" -> "x11-wm/i3")
+		category, name, ok := strings.Cut(entry.Name(), "_")
+		if !ok || category == "" || name == "" {
+			continue
+		}
+		path := filepath.Join(cfg.OptionsPath, entry.Name(), optionsFileName)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		ports = append(ports, category+"/"+name)
+	}
+	sort.Strings(ports)
+	return ports, nil
+}
+
+// containsString reports whether list contains s.
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,213 @@
+package pkg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go-synth/config"
+)
+
+// setupOptionsTest installs a fixture querier for editors/vim option
+// definitions and returns a config with a temporary OptionsPath
+func setupOptionsTest(t *testing.T) *config.Config {
+	t.Helper()
+
+	restore := setTestQuerier(newTestFixtureQuerier(map[string]string{
+		"editors/vim": "testdata/options/editors__vim.txt",
+	}))
+	t.Cleanup(restore)
+
+	return &config.Config{
+		DPortsPath:  "/usr/ports",
+		OptionsPath: t.TempDir(),
+	}
+}
+
+func TestQueryPortOptions(t *testing.T) {
+	cfg := setupOptionsTest(t)
+
+	opts, err := QueryPortOptions("editors/vim", cfg)
+	if err != nil {
+		t.Fatalf("QueryPortOptions failed: %v", err)
+	}
+
+	if opts.PkgName != "vim-9.1.0470" {
+		t.Errorf("PkgName = %q, want vim-9.1.0470", opts.PkgName)
+	}
+
+	// DEBUG is excluded and SIMD arch-specific: the options offered are
+	// those of COMPLETE_OPTIONS_LIST
+	want := []string{"CTAGS_BASE", "NLS", "XTERM_SAVE", "SIMD", "CONSOLE", "GTK3", "X11", "LUA", "PYTHON"}
+	if got := opts.All(); !reflect.DeepEqual(got, want) {
+		t.Errorf("All() = %v, want %v", got, want)
+	}
+
+	// PORT_OPTIONS entries the port does not offer are ignored
+	if got := opts.Effective.Enabled(); !reflect.DeepEqual(got, []string{"CONSOLE", "NLS"}) {
+		t.Errorf("Effective = %v, want CONSOLE and NLS", got)
+	}
+	if len(opts.Effective) != len(want) {
+		t.Errorf("Effective has %d options, want %d", len(opts.Effective), len(want))
+	}
+
+	if len(opts.Groups) != 2 || opts.Groups[0].Kind != OptionGroupSingle || opts.Groups[1].Kind != OptionGroupMulti {
+		t.Errorf("unexpected groups: %+v", opts.Groups)
+	}
+
+	if opts.Descriptions["NLS"] != "Native Language Support" {
+		t.Errorf("NLS description = %q", opts.Descriptions["NLS"])
+	}
+	if _, ok := opts.Descriptions["X11"]; ok {
+		t.Error("expected no description for X11")
+	}
+}
+
+func TestPortOptions_ApplyAndValidate(t *testing.T) {
+	cfg := setupOptionsTest(t)
+
+	opts, err := QueryPortOptions("editors/vim", cfg)
+	if err != nil {
+		t.Fatalf("QueryPortOptions failed: %v", err)
+	}
+
+	set := opts.DefaultSet()
+
+	// Defaults leave the LANGS multi group empty
+	if err := opts.Validate(set); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("expected ErrInvalidOptions for empty multi group, got %v", err)
+	}
+
+	if err := opts.Apply(set, "PYTHON", true); err != nil {
+		t.Fatalf("Apply PYTHON failed: %v", err)
+	}
+	if err := opts.Validate(set); err != nil {
+		t.Errorf("Validate failed: %v", err)
+	}
+
+	// Enabling a single-group member disables its siblings
+	if err := opts.Apply(set, "GTK3", true); err != nil {
+		t.Fatalf("Apply GTK3 failed: %v", err)
+	}
+	if set["CONSOLE"] || !set["GTK3"] {
+		t.Errorf("expected GTK3 to replace CONSOLE, got %v", set.Enabled())
+	}
+
+	// Disabling the only single-group member is invalid
+	if err := opts.Apply(set, "GTK3", false); err != nil {
+		t.Fatalf("Apply GTK3 off failed: %v", err)
+	}
+	if err := opts.Validate(set); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("expected ErrInvalidOptions for empty single group, got %v", err)
+	}
+
+	if err := opts.Apply(set, "BOGUS", true); !errors.Is(err, ErrUnknownOption) {
+		t.Errorf("expected ErrUnknownOption, got %v", err)
+	}
+}
+
+func TestSavedOptions_RoundTrip(t *testing.T) {
+	cfg := setupOptionsTest(t)
+
+	opts, err := QueryPortOptions("editors/vim", cfg)
+	if err != nil {
+		t.Fatalf("QueryPortOptions failed: %v", err)
+	}
+
+	saved, err := ReadSavedOptions(cfg, "editors/vim")
+	if err != nil || saved != nil {
+		t.Fatalf("expected no saved options, got %+v, %v", saved, err)
+	}
+
+	set := opts.DefaultSet()
+	set["NLS"] = false
+	set["LUA"] = true
+	if err := WriteSavedOptions(cfg, opts, set); err != nil {
+		t.Fatalf("WriteSavedOptions failed: %v", err)
+	}
+
+	path := filepath.Join(cfg.OptionsPath, "editors_vim", "options")
+	if OptionsFilePath(cfg, "editors/vim@python39") != path {
+		t.Errorf("OptionsFilePath = %s, want %s", OptionsFilePath(cfg, "editors/vim@python39"), path)
+	}
+
+	saved, err = ReadSavedOptions(cfg, "editors/vim")
+	if err != nil {
+		t.Fatalf("ReadSavedOptions failed: %v", err)
+	}
+	if saved.PkgName != "vim-9.1.0470" {
+		t.Errorf("PkgName = %q", saved.PkgName)
+	}
+	if got := opts.Resolve(saved); !reflect.DeepEqual(got, set) {
+		t.Errorf("Resolve = %v, want %v", got.Enabled(), set.Enabled())
+	}
+
+	ports, err := SavedOptionPorts(cfg)
+	if err != nil {
+		t.Fatalf("SavedOptionPorts failed: %v", err)
+	}
+	if !reflect.DeepEqual(ports, []string{"editors/vim"}) {
+		t.Errorf("SavedOptionPorts = %v", ports)
+	}
+}
+
+func TestReadSavedOptions_MakeConfigFormat(t *testing.T) {
+	cfg := &config.Config{OptionsPath: t.TempDir()}
+
+	dir := filepath.Join(cfg.OptionsPath, "x11-wm_i3")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := "# This file is auto-generated by 'make config'.\n" +
+		"# Options for i3-4.23\n" +
+		"_OPTIONS_READ=i3-4.23\n" +
+		"_FILE_COMPLETE_OPTIONS_LIST=DOCS MANPAGES\n" +
+		"OPTIONS_FILE_SET+=DOCS\n" +
+		"OPTIONS_FILE_UNSET+=MANPAGES\n"
+	if err := os.WriteFile(filepath.Join(dir, "options"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := ReadSavedOptions(cfg, "x11-wm/i3")
+	if err != nil {
+		t.Fatalf("ReadSavedOptions failed: %v", err)
+	}
+	if !reflect.DeepEqual(saved.Set, []string{"DOCS"}) || !reflect.DeepEqual(saved.Unset, []string{"MANPAGES"}) {
+		t.Errorf("unexpected saved options: %+v", saved)
+	}
+
+	ports, _ := SavedOptionPorts(cfg)
+	if !reflect.DeepEqual(ports, []string{"x11-wm/i3"}) {
+		t.Errorf("SavedOptionPorts = %v", ports)
+	}
+}
+
+func TestParseOptionSetting(t *testing.T) {
+	tests := []struct {
+		in      string
+		name    string
+		on      bool
+		wantErr bool
+	}{
+		{"NLS=on", "NLS", true, false},
+		{"NLS=OFF", "NLS", false, false},
+		{"DOCS=yes", "DOCS", true, false},
+		{"DOCS=0", "DOCS", false, false},
+		{"DOCS", "", false, true},
+		{"=on", "", false, true},
+		{"DOCS=maybe", "", false, true},
+	}
+
+	for _, tt := range tests {
+		name, on, err := ParseOptionSetting(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseOptionSetting(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if name != tt.name || on != tt.on {
+			t.Errorf("ParseOptionSetting(%q) = %s, %v; want %s, %v", tt.in, name, on, tt.name, tt.on)
+		}
+	}
+}
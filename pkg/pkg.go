@@ -51,6 +51,7 @@ package pkg
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -190,7 +191,8 @@ func (f PackageFlags) String() string {
 
 // DepType represents the type of dependency relationship between packages.
 // BSD ports support six distinct dependency types, each controlling when
-// a dependency is required during the build process.
+// a dependency is required during the build process. A seventh, TEST,
+// is only resolved in test mode.
 //
 // Values match the original C dsynth implementation for compatibility.
 type DepType int
@@ -220,6 +222,10 @@ const (
 	// DepTypeRun indicates a runtime dependency not needed during build.
 	// Used for programs/libraries only needed when the package runs.
 	DepTypeRun DepType = 6
+
+	// DepTypeTest indicates a dependency required by the test phase. It has
+	// no C dsynth counterpart and is only resolved in test mode.
+	DepTypeTest DepType = 7
 )
 
 // String returns the string representation of the dependency type.
@@ -237,6 +243,8 @@ func (d DepType) String() string {
 		return "LIB"
 	case DepTypeRun:
 		return "RUN"
+	case DepTypeTest:
+		return "TEST"
 	default:
 		return fmt.Sprintf("UNKNOWN(%d)", d)
 	}
@@ -244,7 +252,7 @@ func (d DepType) String() string {
 
 // Valid reports whether the dependency type is valid.
 func (d DepType) Valid() bool {
-	return d >= DepTypeFetch && d <= DepTypeRun
+	return d >= DepTypeFetch && d <= DepTypeTest
 }
 
 // Package represents immutable metadata about a BSD port. It contains only
@@ -263,7 +271,9 @@ func (d DepType) Valid() bool {
 //
 // Six string fields (FetchDeps, ExtractDeps, PatchDeps, BuildDeps, LibDeps,
 // RunDeps) contain raw dependency specifications as returned by the port's
-// Makefile. These are parsed during dependency resolution.
+// Makefile. These are parsed during dependency resolution. TestDeps is also
+// collected, but only resolved in test mode, for the ports named on the
+// command line.
 //
 // # Dependency Graph
 //
@@ -276,12 +286,13 @@ func (d DepType) Valid() bool {
 // is complete. During resolution, access is coordinated via PackageRegistry.
 type Package struct {
 	// Identification - uniquely identifies this package
-	PortDir  string // e.g., "editors/vim" - unique identifier
-	Category string // e.g., "editors"
-	Name     string // e.g., "vim"
-	Flavor   string // e.g., "" or "python" for flavored ports
-	Version  string // e.g., "9.0.1234"
-	PkgFile  string // e.g., "vim-9.0.1234.pkg" - package filename
+	PortDir  string   // e.g., "editors/vim" - unique identifier
+	Category string   // e.g., "editors"
+	Name     string   // e.g., "vim"
+	Flavor   string   // e.g., "" or "python" for flavored ports
+	Flavors  []string // FLAVORS of the port, the default first; each produces its own package
+	Version  string   // e.g., "9.0.1234"
+	PkgFile  string   // e.g., "vim-9.0.1234.pkg" - package filename
 
 	// Dependencies - raw dependency strings from Makefile
 	FetchDeps   string // FETCH_DEPENDS
@@ -290,6 +301,10 @@ type Package struct {
 	BuildDeps   string // BUILD_DEPENDS
 	LibDeps     string // LIB_DEPENDS
 	RunDeps     string // RUN_DEPENDS
+	TestDeps    string // TEST_DEPENDS, resolved only for tested ports
+
+	// Build hints
+	MakeJobsUnsafe bool // MAKE_JOBS_UNSAFE or DISABLE_MAKE_JOBS: make runs serially
 
 	// Dependency graph - populated during resolution
 	IDependOn   []*PkgLink // Forward edges: packages I depend on
@@ -503,6 +518,36 @@ func ParsePortList(portList []string, cfg *config.Config, registry *BuildStateRe
 	return packages, nil
 }
 
+// ParseFlavors parses the non-default FLAVORS of the unflavored ports in
+// packages that pkgRegistry does not hold yet, as ParsePortList does. Each
+// flavor produces a package of its own, so a scan of the whole tree needs
+// them all; resolve the returned packages together with packages. Flavors
+// that fail to parse are logged and left out.
+func ParseFlavors(packages []*Package, cfg *config.Config, registry *BuildStateRegistry, pkgRegistry *PackageRegistry, logger interface {
+	Warn(format string, args ...any)
+}) ([]*Package, error) {
+	var specs []string
+	for _, p := range packages {
+		if p.Flavor != "" || len(p.Flavors) < 2 {
+			continue
+		}
+		for _, flavor := range p.Flavors[1:] {
+			if spec := p.PortDir + "@" + flavor; pkgRegistry.Find(spec) == nil {
+				specs = append(specs, spec)
+			}
+		}
+	}
+	if len(specs) == 0 {
+		return nil, nil
+	}
+
+	flavored, err := ParsePortList(specs, cfg, registry, pkgRegistry, logger)
+	if errors.Is(err, ErrNoValidPorts) {
+		return nil, nil
+	}
+	return flavored, err
+}
+
 // parsePortSpec parses a port specification into category/name/flavor
 func parsePortSpec(spec string, cfg *config.Config) (category, name, flavor string) {
 	// Handle absolute paths
@@ -570,7 +615,18 @@ func getPackageInfo(category, name, flavor string, cfg *config.Config) (*Package
 // The querier can be swapped in tests to use fixtures instead of real make commands.
 // Returns: flags to set, ignoreReason, error
 func queryMakefile(pkg *Package, portPath string, cfg *config.Config) (PackageFlags, string, error) {
-	return portsQuerier.QueryMakefile(pkg, portPath, cfg)
+	vars, err := portsQuerier.QueryVars(PortQuery{
+		PortDir:  pkg.PortDir,
+		PortPath: portPath,
+		Flavor:   pkg.Flavor,
+		Vars:     metadataVars,
+	}, cfg)
+	if err != nil {
+		return 0, "", err
+	}
+
+	flags, ignoreReason := applyPortVars(pkg, vars)
+	return flags, ignoreReason, nil
 }
 
 // ResolveDependencies builds the complete dependency graph for a set of packages
@@ -643,6 +699,9 @@ func ResolveDependencies(packages []*Package, cfg *config.Config, registry *Buil
 //   - Ignored packages are marked PkgFNoBuildIgnore
 //   - Packages with unchanged CRCs are marked PkgFSuccess|PkgFPackaged
 //   - Packages with CRC errors default to "needs rebuild" for safety
+//   - With leverage_prebuilt, packages needing a build whose origin, version
+//     and options match the Prebuilt_repository catalog are imported into
+//     PackagesPath/All and marked PkgFSuccess|PkgFPackaged instead
 //
 // This function is typically called after dependency resolution and before
 // starting the build process:
@@ -680,6 +739,11 @@ func MarkPackagesNeedingBuild(packages []*Package, cfg *config.Config, registry
 	needBuild := 0
 	checked := 0
 
+	// Packages needing a build, with their port CRC when known, for
+	// leverage_prebuilt to replace with prebuilt packages
+	var pending []*Package
+	pendingCRC := make(map[*Package]uint32)
+
 	for _, pkg := range packages {
 		checked++
 
@@ -708,6 +772,7 @@ func MarkPackagesNeedingBuild(packages []*Package, cfg *config.Config, registry
 			// On error computing CRC, rebuild to be safe
 			logger.Info("  %s: needs rebuild (CRC computation error: %v)", pkg.PortDir, err)
 			needBuild++
+			pending = append(pending, pkg)
 			continue
 		}
 
@@ -716,6 +781,7 @@ func MarkPackagesNeedingBuild(packages []*Package, cfg *config.Config, registry
 			// On database error, rebuild to be safe
 			logger.Info("  %s: needs rebuild (DB error: %v)", pkg.PortDir, err)
 			needBuild++
+			pending = append(pending, pkg)
 			continue
 		}
 
@@ -738,6 +804,8 @@ func MarkPackagesNeedingBuild(packages []*Package, cfg *config.Config, registry
 				}
 			}
 			needBuild++
+			pending = append(pending, pkg)
+			pendingCRC[pkg] = currentCRC
 			logger.Info("  %s: needs rebuild", pkg.PortDir)
 		} else {
 			// CRC matches, but verify package file actually exists
@@ -747,6 +815,8 @@ func MarkPackagesNeedingBuild(packages []*Package, cfg *config.Config, registry
 					// Package file missing despite CRC match - rebuild needed
 					logger.Info("  %s: needs rebuild (package file missing)", pkg.PortDir)
 					needBuild++
+					pending = append(pending, pkg)
+					pendingCRC[pkg] = currentCRC
 					continue
 				}
 			}
@@ -760,6 +830,26 @@ func MarkPackagesNeedingBuild(packages []*Package, cfg *config.Config, registry
 		}
 	}
 
+	if cfg.Prebuilt.Enabled && len(pending) > 0 {
+		catalog, err := LoadPrebuiltCatalog(cfg.Prebuilt.Repository)
+		if err != nil {
+			logger.Warn("  Prebuilt packages unavailable: %v", err)
+		} else {
+			logger.Info("  Checking %d packages against prebuilt repository %s (%d packages)",
+				len(pending), catalog.Source, catalog.Len())
+			imported := leveragePrebuilt(pending, catalog, cfg, registry, logger)
+			for _, pkg := range imported {
+				if crc, ok := pendingCRC[pkg]; ok {
+					if err := buildDB.UpdateCRC(pkg.PortDir, crc); err != nil {
+						logger.Warn("  %s: failed to update CRC: %v", pkg.PortDir, err)
+					}
+				}
+			}
+			needBuild -= len(imported)
+			logger.Info("  %d packages taken from prebuilt repository", len(imported))
+		}
+	}
+
 	logger.Info("  Checked %d packages", checked)
 	logger.Info("  %d packages need building", needBuild)
 	logger.Info("  %d packages are up-to-date", checked-needBuild)
@@ -147,6 +147,7 @@ func TestDepType_String(t *testing.T) {
 		{DepTypeBuild, "BUILD"},
 		{DepTypeLib, "LIB"},
 		{DepTypeRun, "RUN"},
+		{DepTypeTest, "TEST"},
 		{DepType(0), "UNKNOWN(0)"},
 		{DepType(99), "UNKNOWN(99)"},
 	}
@@ -174,7 +175,8 @@ func TestDepType_Valid(t *testing.T) {
 		{DepTypeBuild, true},
 		{DepTypeLib, true},
 		{DepTypeRun, true},
-		{DepType(7), false},
+		{DepTypeTest, true},
+		{DepType(8), false},
 		{DepType(99), false},
 		{DepType(-1), false},
 	}
@@ -19,47 +19,130 @@ var portsQuerier PortsQuerier = &realPortsQuerier{}
 // This is set to true in tests that use fixtures.
 var skipPortDirCheck = false
 
+// Make variables queried from port Makefiles. These are the variables used to
+// populate a Package; callers may query any other variable by name.
+const (
+	VarPkgName        = "PKGNAME"
+	VarPkgVersion     = "PKGVERSION"
+	VarPkgFile        = "PKGFILE"
+	VarFetchDepends   = "FETCH_DEPENDS"
+	VarExtractDepends = "EXTRACT_DEPENDS"
+	VarPatchDepends   = "PATCH_DEPENDS"
+	VarBuildDepends   = "BUILD_DEPENDS"
+	VarLibDepends     = "LIB_DEPENDS"
+	VarRunDepends     = "RUN_DEPENDS"
+	VarTestDepends    = "TEST_DEPENDS"
+	VarIgnore         = "IGNORE"
+	VarFlavors        = "FLAVORS"
+
+	// Ports that cannot build with parallel make jobs set one of these
+	VarMakeJobsUnsafe  = "MAKE_JOBS_UNSAFE"
+	VarDisableMakeJobs = "DISABLE_MAKE_JOBS"
+)
+
+// metadataVars is the set of variables required to populate a Package.
+var metadataVars = []string{
+	VarPkgName,
+	VarPkgVersion,
+	VarPkgFile,
+	VarFetchDepends,
+	VarExtractDepends,
+	VarPatchDepends,
+	VarBuildDepends,
+	VarLibDepends,
+	VarRunDepends,
+	VarTestDepends,
+	VarIgnore,
+	VarFlavors,
+	VarMakeJobsUnsafe,
+	VarDisableMakeJobs,
+}
+
+// queryMarker prefixes the header line emitted before each variable value in
+// query output. Each value runs from the line after its header up to the next
+// header, so values may span multiple lines and may appear in any order.
+//
+//	@@go-synth@@ PKGNAME
+//	vim-9.1.0470
+//	@@go-synth@@ IGNORE
+const queryMarker = "@@go-synth@@"
+
+// PortQuery describes a request for make variables from a single port.
+type PortQuery struct {
+	PortDir  string   // e.g., "editors/vim" or "editors/vim@python39"
+	PortPath string   // e.g., "/usr/dports/editors/vim"
+	Flavor   string   // e.g., "" or "python39"
+	Vars     []string // variable names to query, e.g., VarPkgName
+}
+
+// PortVars holds the values of queried make variables keyed by variable name.
+// Variables that are unset in the Makefile have an empty value.
+type PortVars map[string]string
+
+// Get returns the value of the named variable with surrounding whitespace removed.
+func (v PortVars) Get(name string) string {
+	return strings.TrimSpace(v[name])
+}
+
+// Words returns the value of the named variable split on whitespace.
+// This is convenient for list variables such as OPTIONS_DEFINE.
+func (v PortVars) Words(name string) []string {
+	return strings.Fields(v[name])
+}
+
+// Has reports whether the named variable was present in the query output.
+func (v PortVars) Has(name string) bool {
+	_, ok := v[name]
+	return ok
+}
+
 // PortsQuerier defines the interface for querying port metadata from the ports tree.
 // This abstraction allows tests to use fixtures instead of requiring a real ports tree.
 type PortsQuerier interface {
-	// QueryMakefile extracts metadata from a port's Makefile by querying make variables.
-	// It returns the package flags and ignore reason (if any).
-	QueryMakefile(pkg *Package, portPath string, cfg *config.Config) (PackageFlags, string, error)
+	// QueryVars extracts the requested variables from a port's Makefile.
+	// Every variable listed in q.Vars is present in the result.
+	QueryVars(q PortQuery, cfg *config.Config) (PortVars, error)
 }
 
-// realPortsQuerier implements PortsQuerier by executing actual make commands.
-// This is the production implementation used on BSD systems with a ports tree.
-type realPortsQuerier struct{}
-
-// QueryMakefile implements PortsQuerier for real ports tree queries using make.
-func (r *realPortsQuerier) QueryMakefile(pkg *Package, portPath string, cfg *config.Config) (PackageFlags, string, error) {
-	// Build make command to extract variables
-	vars := []string{
-		"PKGNAME",
-		"PKGVERSION",
-		"PKGFILE",
-		"FETCH_DEPENDS",
-		"EXTRACT_DEPENDS",
-		"PATCH_DEPENDS",
-		"BUILD_DEPENDS",
-		"LIB_DEPENDS",
-		"RUN_DEPENDS",
-		"IGNORE",
+// QueryPortVars queries arbitrary make variables for a port specification
+// such as "editors/vim" or "editors/vim@python39".
+//
+// Returns a PortNotFoundError if the port directory does not exist.
+func QueryPortVars(portSpec string, vars []string, cfg *config.Config) (PortVars, error) {
+	category, name, flavor := parsePortSpec(portSpec, cfg)
+	if category == "" || name == "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSpec, portSpec)
 	}
 
-	// Use make -V to query variables
-	args := []string{
-		"-C", portPath,
+	portDir := category + "/" + name
+	if flavor != "" {
+		portDir += "@" + flavor
 	}
+	portPath := filepath.Join(cfg.DPortsPath, category, name)
 
-	// Add flavor if specified
-	if pkg.Flavor != "" {
-		args = append(args, "FLAVOR="+pkg.Flavor)
+	if !skipPortDirCheck {
+		if _, err := os.Stat(portPath); os.IsNotExist(err) {
+			return nil, &PortNotFoundError{PortSpec: portDir, Path: portPath}
+		}
 	}
 
-	// Query all variables in one go
-	for _, v := range vars {
-		args = append(args, "-V", v)
+	return portsQuerier.QueryVars(PortQuery{
+		PortDir:  portDir,
+		PortPath: portPath,
+		Flavor:   flavor,
+		Vars:     vars,
+	}, cfg)
+}
+
+// realPortsQuerier implements PortsQuerier by executing actual make commands.
+// This is the production implementation used on BSD systems with a ports tree.
+type realPortsQuerier struct{}
+
+// QueryVars implements PortsQuerier for real ports tree queries using make.
+func (r *realPortsQuerier) QueryVars(q PortQuery, cfg *config.Config) (PortVars, error) {
+	args, err := queryArgs(q, cfg.OptionsPath)
+	if err != nil {
+		return nil, err
 	}
 
 	cmd := exec.Command("make", args...)
@@ -68,10 +151,68 @@ func (r *realPortsQuerier) QueryMakefile(pkg *Package, portPath string, cfg *con
 	cmd.Stderr = os.Stderr
 
 	if err := cmd.Run(); err != nil {
-		return 0, "", fmt.Errorf("make query failed: %w", err)
+		return nil, fmt.Errorf("make query failed: %w", err)
+	}
+
+	vars, err := parseQueryOutput(out.String())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range q.Vars {
+		if !vars.Has(name) {
+			return nil, fmt.Errorf("make query output missing variable %s", name)
+		}
 	}
 
-	return parseQueryOutput(pkg, out.String())
+	return vars, nil
+}
+
+// queryArgs builds the make arguments for a variable query. Each variable is
+// preceded by a header line produced by expanding a constant expression, so
+// the output can be split without relying on line counts.
+//
+// The saved options in optionsPath are used as PORT_DBDIR, as they are in
+// the workers, so the query sees the port as it will be built.
+func queryArgs(q PortQuery, optionsPath string) ([]string, error) {
+	args := []string{"-C", q.PortPath}
+
+	if optionsPath != "" {
+		args = append(args, "PORT_DBDIR="+optionsPath)
+	}
+
+	// Add flavor if specified
+	if q.Flavor != "" {
+		args = append(args, "FLAVOR="+q.Flavor)
+	}
+
+	for _, name := range q.Vars {
+		if !validVarName(name) {
+			return nil, fmt.Errorf("invalid make variable name: %q", name)
+		}
+		args = append(args,
+			"-V", "${:U"+queryMarker+" "+name+"}",
+			"-V", name,
+		)
+	}
+
+	return args, nil
+}
+
+// validVarName reports whether name is safe to embed in a make expression.
+func validVarName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case c == '_' || c == '.':
+		default:
+			return false
+		}
+	}
+	return true
 }
 
 // testFixtureQuerier implements PortsQuerier by loading data from test fixtures.
@@ -88,46 +229,82 @@ func newTestFixtureQuerier(fixtures map[string]string) *testFixtureQuerier {
 	}
 }
 
-// QueryMakefile implements PortsQuerier for test fixtures.
-func (t *testFixtureQuerier) QueryMakefile(pkg *Package, portPath string, cfg *config.Config) (PackageFlags, string, error) {
+// QueryVars implements PortsQuerier for test fixtures. Variables requested but
+// absent from the fixture are returned empty, as make does for unset variables.
+func (t *testFixtureQuerier) QueryVars(q PortQuery, cfg *config.Config) (PortVars, error) {
 	// Get fixture path for this port
-	fixturePath, ok := t.fixtures[pkg.PortDir]
+	fixturePath, ok := t.fixtures[q.PortDir]
 	if !ok {
 		// Port not found in fixtures - simulate port not found error
-		return PkgFNotFound, "", &PortNotFoundError{
-			PortSpec: pkg.PortDir,
-			Path:     portPath,
+		return nil, &PortNotFoundError{
+			PortSpec: q.PortDir,
+			Path:     q.PortPath,
 		}
 	}
 
 	// Load fixture file
 	data, err := os.ReadFile(fixturePath)
 	if err != nil {
-		return PkgFCorrupt, "", fmt.Errorf("failed to load fixture %s: %w", fixturePath, err)
+		return nil, fmt.Errorf("failed to load fixture %s: %w", fixturePath, err)
 	}
 
-	return parseQueryOutput(pkg, string(data))
+	all, err := parseQueryOutput(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("fixture %s: %w", fixturePath, err)
+	}
+
+	vars := make(PortVars, len(q.Vars))
+	for _, name := range q.Vars {
+		vars[name] = all[name]
+	}
+	return vars, nil
 }
 
-// parseQueryOutput parses the output from make -V queries and populates the Package struct.
-// It expects 10 lines of output corresponding to the variables queried in order.
-// Returns the package flags and ignore reason (if any).
-func parseQueryOutput(pkg *Package, output string) (PackageFlags, string, error) {
-	lines := strings.Split(output, "\n")
-	if len(lines) < 10 {
-		return 0, "", fmt.Errorf("insufficient output from make (got %d lines, expected 10)", len(lines))
+// parseQueryOutput splits marker-delimited make output into variable values.
+// See queryMarker for the format. Multi-line values keep their embedded newlines.
+func parseQueryOutput(output string) (PortVars, error) {
+	vars := make(PortVars)
+	output = strings.TrimSuffix(output, "\n")
+	if output == "" {
+		return vars, nil
 	}
 
-	// Parse output
-	pkg.Version = strings.TrimSpace(lines[1])
+	current := ""
+	var value []string
+	flush := func() {
+		if current != "" {
+			vars[current] = strings.Join(value, "\n")
+		}
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		if name, ok := strings.CutPrefix(line, queryMarker+" "); ok {
+			flush()
+			current = strings.TrimSpace(name)
+			value = value[:0]
+			continue
+		}
+		if current == "" {
+			return nil, fmt.Errorf("unexpected make output before first variable: %q", line)
+		}
+		value = append(value, line)
+	}
+	flush()
+
+	return vars, nil
+}
+
+// applyPortVars populates the Package struct from queried metadata variables.
+// Returns the package flags and ignore reason (if any).
+func applyPortVars(pkg *Package, vars PortVars) (PackageFlags, string) {
+	pkg.Version = vars.Get(VarPkgVersion)
 	if pkg.Version == "" {
 		pkg.Version = "unknown"
 	}
 
 	// CRITICAL: Extract just the basename from PKGFILE
 	// The Makefile might return a full path, but we only want the filename
-	pkgFileRaw := strings.TrimSpace(lines[2])
-	if pkgFileRaw != "" {
+	if pkgFileRaw := vars.Get(VarPkgFile); pkgFileRaw != "" {
 		pkg.PkgFile = filepath.Base(pkgFileRaw)
 	}
 
@@ -136,23 +313,26 @@ func parseQueryOutput(pkg *Package, output string) (PackageFlags, string, error)
 	isMeta := pkg.PkgFile == ""
 
 	if pkg.PkgFile == "" {
-		pkgname := strings.TrimSpace(lines[0])
+		pkgname := vars.Get(VarPkgName)
 		if pkgname == "" {
 			pkgname = pkg.Name + "-" + pkg.Version
 		}
 		pkg.PkgFile = pkgname + ".pkg"
 	}
 
-	pkg.FetchDeps = strings.TrimSpace(lines[3])
-	pkg.ExtractDeps = strings.TrimSpace(lines[4])
-	pkg.PatchDeps = strings.TrimSpace(lines[5])
-	pkg.BuildDeps = strings.TrimSpace(lines[6])
-	pkg.LibDeps = strings.TrimSpace(lines[7])
-	pkg.RunDeps = strings.TrimSpace(lines[8])
+	pkg.FetchDeps = vars.Get(VarFetchDepends)
+	pkg.ExtractDeps = vars.Get(VarExtractDepends)
+	pkg.PatchDeps = vars.Get(VarPatchDepends)
+	pkg.BuildDeps = vars.Get(VarBuildDepends)
+	pkg.LibDeps = vars.Get(VarLibDepends)
+	pkg.RunDeps = vars.Get(VarRunDepends)
+	pkg.TestDeps = vars.Get(VarTestDepends)
+	pkg.Flavors = strings.Fields(vars.Get(VarFlavors))
+	pkg.MakeJobsUnsafe = vars.Get(VarMakeJobsUnsafe) != "" || vars.Get(VarDisableMakeJobs) != ""
 
 	// Compute flags based on metadata
 	var flags PackageFlags
-	ignoreReason := strings.TrimSpace(lines[9])
+	ignoreReason := vars.Get(VarIgnore)
 	if ignoreReason != "" {
 		flags |= PkgFIgnored | PkgFNoBuildIgnore
 	}
@@ -162,7 +342,7 @@ func parseQueryOutput(pkg *Package, output string) (PackageFlags, string, error)
 		flags |= PkgFMeta
 	}
 
-	return flags, ignoreReason, nil
+	return flags, ignoreReason
 }
 
 // setTestQuerier replaces the global querier with a test implementation.
@@ -0,0 +1,137 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-synth/config"
+)
+
+// TestParseQueryOutput_MultiLineValues verifies that values spanning several
+// lines and empty values are preserved between markers
+func TestParseQueryOutput_MultiLineValues(t *testing.T) {
+	output := strings.Join([]string{
+		queryMarker + " MAINTAINER",
+		"ports@example.org",
+		queryMarker + " PKGMESSAGE",
+		"first line",
+		"second line",
+		queryMarker + " IGNORE",
+		"",
+	}, "\n") + "\n"
+
+	vars, err := parseQueryOutput(output)
+	if err != nil {
+		t.Fatalf("parseQueryOutput failed: %v", err)
+	}
+
+	if got := vars.Get("MAINTAINER"); got != "ports@example.org" {
+		t.Errorf("MAINTAINER = %q, want %q", got, "ports@example.org")
+	}
+	if got := vars["PKGMESSAGE"]; got != "first line\nsecond line" {
+		t.Errorf("PKGMESSAGE = %q, want two lines", got)
+	}
+	if !vars.Has("IGNORE") || vars.Get("IGNORE") != "" {
+		t.Errorf("IGNORE should be present and empty, got %q (present=%v)", vars["IGNORE"], vars.Has("IGNORE"))
+	}
+}
+
+// TestParseQueryOutput_UnexpectedLeadingOutput verifies that output not
+// preceded by a marker is rejected instead of being misassigned
+func TestParseQueryOutput_UnexpectedLeadingOutput(t *testing.T) {
+	_, err := parseQueryOutput("stray output\n" + queryMarker + " PKGNAME\nfoo-1.0\n")
+	if err == nil {
+		t.Fatal("expected error for output before first marker")
+	}
+}
+
+// TestQueryArgs verifies marker expressions are emitted before each variable
+// and that unsafe variable names are rejected
+func TestQueryArgs(t *testing.T) {
+	args, err := queryArgs(PortQuery{
+		PortPath: "/usr/ports/editors/vim",
+		Flavor:   "python39",
+		Vars:     []string{VarPkgName, "OPTIONS_DEFINE"},
+	}, "/var/db/ports")
+	if err != nil {
+		t.Fatalf("queryArgs failed: %v", err)
+	}
+
+	want := []string{
+		"-C", "/usr/ports/editors/vim",
+		"PORT_DBDIR=/var/db/ports",
+		"FLAVOR=python39",
+		"-V", "${:U" + queryMarker + " PKGNAME}",
+		"-V", "PKGNAME",
+		"-V", "${:U" + queryMarker + " OPTIONS_DEFINE}",
+		"-V", "OPTIONS_DEFINE",
+	}
+	if strings.Join(args, " ") != strings.Join(want, " ") {
+		t.Errorf("queryArgs = %v, want %v", args, want)
+	}
+
+	if _, err := queryArgs(PortQuery{Vars: []string{"FOO}"}}, ""); err == nil {
+		t.Error("expected error for invalid variable name")
+	}
+}
+
+// TestQueryPortVars_Fixture verifies arbitrary variables can be queried and
+// that variables missing from a fixture are returned empty
+func TestQueryPortVars_Fixture(t *testing.T) {
+	restore := setTestQuerier(newTestFixtureQuerier(map[string]string{
+		"editors/vim@python39": "testdata/fixtures/editors__vim@python39.txt",
+	}))
+	defer restore()
+
+	cfg := &config.Config{DPortsPath: "/usr/ports"}
+
+	vars, err := QueryPortVars("editors/vim@python39", []string{VarPkgVersion, "LICENSE"}, cfg)
+	if err != nil {
+		t.Fatalf("QueryPortVars failed: %v", err)
+	}
+
+	if vars.Get(VarPkgVersion) == "" {
+		t.Error("expected PKGVERSION from fixture")
+	}
+	if !vars.Has("LICENSE") || vars.Get("LICENSE") != "" {
+		t.Errorf("LICENSE should be present and empty, got %q", vars["LICENSE"])
+	}
+	if len(vars) != 2 {
+		t.Errorf("expected only requested variables, got %d", len(vars))
+	}
+
+	if _, err := QueryPortVars("editors/nonexistent", []string{VarPkgName}, cfg); err == nil {
+		t.Error("expected error for port missing from fixtures")
+	}
+}
+
+// TestFixtures_Consistent checks that every fixture looks like real make -V
+// output: PKGNAME is name-version, and PKGFILE, when set, names its package
+func TestFixtures_Consistent(t *testing.T) {
+	files, err := filepath.Glob("testdata/fixtures/*.txt")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no fixtures found: %v", err)
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vars, err := parseQueryOutput(string(data))
+		if err != nil {
+			t.Errorf("%s: %v", file, err)
+			continue
+		}
+
+		pkgname, version := vars.Get(VarPkgName), vars.Get(VarPkgVersion)
+		if strings.Contains(pkgname, "/") || !strings.HasSuffix(pkgname, "-"+version) {
+			t.Errorf("%s: PKGNAME %q is not name-%s", file, pkgname, version)
+		}
+		if pkgfile := vars.Get(VarPkgFile); pkgfile != "" && filepath.Base(pkgfile) != pkgname+".pkg" {
+			t.Errorf("%s: PKGFILE %q does not match PKGNAME %q", file, pkgfile, pkgname)
+		}
+	}
+}
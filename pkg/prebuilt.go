@@ -0,0 +1,371 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go-synth/config"
+)
+
+// ErrNoCatalog is returned when a prebuilt repository has no packagesite
+// catalog in any supported form.
+var ErrNoCatalog = errors.New("no packagesite catalog found")
+
+// packagesiteFiles are the catalog names tried, in order. The plain YAML
+// file is what pkg-repo produces inside the archives; the archives are
+// unpacked with the system tar, which handles every compression pkg uses.
+var packagesiteFiles = []string{"packagesite.yaml", "packagesite.pkg", "packagesite.txz"}
+
+// prebuiltHTTPClient fetches remote catalogs and packages.
+var prebuiltHTTPClient = &http.Client{Timeout: 10 * time.Minute}
+
+// PrebuiltPackage is a package entry from a pkg repository catalog.
+type PrebuiltPackage struct {
+	Name        string            `json:"name"`
+	Origin      string            `json:"origin"`  // e.g., "editors/vim"
+	Version     string            `json:"version"` // e.g., "9.1.0470"
+	Path        string            `json:"path"`    // relative to the repository, e.g., "All/vim-9.1.0470.pkg"
+	Sum         string            `json:"sum"`     // SHA256 of the package file
+	Options     map[string]string `json:"options"` // option name -> "on"/"off"
+	Annotations map[string]string `json:"annotations"`
+}
+
+// Flavor returns the flavor the package was built with, if any.
+func (pp *PrebuiltPackage) Flavor() string {
+	return pp.Annotations["flavor"]
+}
+
+// PrebuiltCatalog indexes the packages of a pkg repository by origin and
+// flavor.
+type PrebuiltCatalog struct {
+	Source   string // Directory or URL of the repository
+	packages map[string][]*PrebuiltPackage
+}
+
+// LoadPrebuiltCatalog reads the packagesite catalog of the repository at
+// source, which is a directory, a file:// URL or an http(s):// URL.
+func LoadPrebuiltCatalog(source string) (*PrebuiltCatalog, error) {
+	if source == "" {
+		return nil, fmt.Errorf("no prebuilt repository configured")
+	}
+
+	for _, name := range packagesiteFiles {
+		rc, err := openRepoFile(source, name)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", name, err)
+		}
+
+		data, err := readCatalog(rc, name)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+
+		pkgs, err := ParsePackagesite(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		return NewPrebuiltCatalog(source, pkgs), nil
+	}
+
+	return nil, fmt.Errorf("%s: %w", source, ErrNoCatalog)
+}
+
+// NewPrebuiltCatalog builds a catalog from already parsed entries.
+func NewPrebuiltCatalog(source string, pkgs []*PrebuiltPackage) *PrebuiltCatalog {
+	c := &PrebuiltCatalog{
+		Source:   source,
+		packages: make(map[string][]*PrebuiltPackage),
+	}
+	for _, pp := range pkgs {
+		key := catalogKey(pp.Origin, pp.Flavor())
+		c.packages[key] = append(c.packages[key], pp)
+	}
+	return c
+}
+
+// Len returns the number of packages in the catalog.
+func (c *PrebuiltCatalog) Len() int {
+	n := 0
+	for _, pkgs := range c.packages {
+		n += len(pkgs)
+	}
+	return n
+}
+
+// ParsePackagesite parses a packagesite.yaml catalog: one JSON object per
+// line describing a package.
+func ParsePackagesite(r io.Reader) ([]*PrebuiltPackage, error) {
+	var pkgs []*PrebuiltPackage
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var pp PrebuiltPackage
+		if err := json.Unmarshal(line, &pp); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		if pp.Origin == "" || pp.Version == "" || pp.Path == "" {
+			return nil, fmt.Errorf("line %d: entry missing origin, version or path", lineNum)
+		}
+		pkgs = append(pkgs, &pp)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return pkgs, nil
+}
+
+// Match returns the catalog entry built from the same origin, flavor and
+// version as p, producing the same package file, or nil.
+func (c *PrebuiltCatalog) Match(p *Package) *PrebuiltPackage {
+	for _, pp := range c.packages[catalogKey(p.PortDir, p.Flavor)] {
+		if pp.Version == p.Version && filepath.Base(pp.Path) == p.PkgFile {
+			return pp
+		}
+	}
+	return nil
+}
+
+// Fetch copies the package file of pp into dir, verifying its checksum.
+// The file is written atomically so an interrupted fetch never leaves a
+// partial package in the repository.
+func (c *PrebuiltCatalog) Fetch(pp *PrebuiltPackage, dir string) (string, error) {
+	rc, err := openRepoFile(c.Source, pp.Path)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	dst := filepath.Join(dir, filepath.Base(pp.Path))
+	tmpPath := dst + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(f, hash), rc)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to fetch %s: %w", pp.Path, err)
+	}
+
+	if pp.Sum != "" {
+		if sum := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(sum, pp.Sum) {
+			os.Remove(tmpPath)
+			return "", fmt.Errorf("checksum mismatch for %s: got %s, want %s", pp.Path, sum, pp.Sum)
+		}
+	}
+
+	if err := os.Rename(tmpPath, dst); err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+	return dst, nil
+}
+
+// OptionsMatch reports whether pp was built with exactly the selection in
+// set. A port without options matches a package without options.
+func (pp *PrebuiltPackage) OptionsMatch(set OptionSet) bool {
+	if len(pp.Options) != len(set) {
+		return false
+	}
+	for name, on := range set {
+		value, ok := pp.Options[name]
+		if !ok {
+			return false
+		}
+		if (value == "on") != on {
+			return false
+		}
+	}
+	return true
+}
+
+// leveragePrebuilt replaces local builds with packages from the configured
+// prebuilt repository. A candidate is used only if a catalog entry matches
+// its origin, flavor, version and effective options, and every dependency
+// is itself up to date or prebuilt, so packages built against a locally
+// changed dependency are still rebuilt. Imported packages are marked
+// PkgFSuccess|PkgFPackaged. It returns the imported packages.
+func leveragePrebuilt(candidates []*Package, catalog *PrebuiltCatalog, cfg *config.Config, registry *BuildStateRegistry, logger interface {
+	Info(format string, args ...any)
+	Warn(format string, args ...any)
+}) []*Package {
+	// Match against the catalog first; option queries run only for
+	// packages whose version is available.
+	matches := make(map[*Package]*PrebuiltPackage)
+	for _, p := range candidates {
+		pp := catalog.Match(p)
+		if pp == nil {
+			continue
+		}
+
+		set, err := effectiveOptions(p, cfg)
+		if err != nil {
+			logger.Warn("  %s: cannot compare options with prebuilt package: %v", p.PortDir, err)
+			continue
+		}
+		if !pp.OptionsMatch(set) {
+			logger.Info("  %s: prebuilt package has different options", p.PortDir)
+			continue
+		}
+		matches[p] = pp
+	}
+
+	// Accept matches whose dependencies are all satisfied, repeating
+	// until no more can be accepted since dependencies may be candidates
+	// themselves.
+	var imported []*Package
+	for progress := true; progress; {
+		progress = false
+		for _, p := range candidates {
+			pp, ok := matches[p]
+			if !ok || !dependenciesSatisfied(p, registry) {
+				continue
+			}
+			delete(matches, p)
+
+			if _, err := catalog.Fetch(pp, filepath.Join(cfg.PackagesPath, "All")); err != nil {
+				logger.Warn("  %s: failed to import prebuilt package: %v", p.PortDir, err)
+				continue
+			}
+
+			registry.AddFlags(p, PkgFSuccess|PkgFPackaged)
+			logger.Info("  %s: using prebuilt %s", p.PortDir, p.PkgFile)
+			imported = append(imported, p)
+			progress = true
+		}
+	}
+
+	return imported
+}
+
+// dependenciesSatisfied reports whether every dependency of p is up to date,
+// prebuilt or a meta port.
+func dependenciesSatisfied(p *Package, registry *BuildStateRegistry) bool {
+	for _, link := range p.IDependOn {
+		if !registry.HasFlags(link.Pkg, PkgFSuccess) {
+			return false
+		}
+	}
+	return true
+}
+
+// effectiveOptions returns the option selection a build of p would use, as
+// the ports framework computes it from the options saved under OptionsPath.
+func effectiveOptions(p *Package, cfg *config.Config) (OptionSet, error) {
+	spec := p.PortDir
+	if p.Flavor != "" {
+		spec += "@" + p.Flavor
+	}
+
+	opts, err := QueryPortOptions(spec, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return opts.Effective, nil
+}
+
+// catalogKey indexes catalog entries by origin and flavor.
+func catalogKey(origin, flavor string) string {
+	if flavor == "" {
+		return origin
+	}
+	return origin + "@" + flavor
+}
+
+// openRepoFile opens a file relative to a repository directory or URL. A
+// missing file yields an error wrapping os.ErrNotExist.
+func openRepoFile(source, name string) (io.ReadCloser, error) {
+	u, err := url.Parse(source)
+	if err != nil || u.Scheme == "" || u.Scheme == "file" {
+		dir := source
+		if err == nil && u.Scheme == "file" {
+			dir = u.Path
+		}
+		return os.Open(filepath.Join(dir, filepath.FromSlash(name)))
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported repository scheme %q", u.Scheme)
+	}
+
+	resp, err := prebuiltHTTPClient.Get(strings.TrimSuffix(source, "/") + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %w", name, os.ErrNotExist)
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: unexpected HTTP status %s", name, resp.Status)
+	}
+}
+
+// readCatalog returns the packagesite.yaml contents from rc, unpacking
+// archived catalogs with tar.
+func readCatalog(rc io.Reader, name string) ([]byte, error) {
+	if name == "packagesite.yaml" {
+		return io.ReadAll(rc)
+	}
+
+	tmp, err := os.CreateTemp("", "go-synth-packagesite-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, rc)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("tar", "-xOf", tmp.Name(), "packagesite.yaml")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("tar: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
@@ -0,0 +1,288 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-synth/builddb"
+	"go-synth/config"
+	"go-synth/log"
+)
+
+// vimDefaultOptions is the packagesite options object matching the
+// defaults in testdata/options/editors__vim.txt
+const vimDefaultOptions = `{"CTAGS_BASE":"off","NLS":"on","XTERM_SAVE":"off","SIMD":"off","CONSOLE":"on","GTK3":"off","X11":"off","LUA":"off","PYTHON":"off"}`
+
+// writePrebuiltRepo creates a pkg repository directory holding the given
+// package files and a packagesite.yaml describing them. Each entry maps a
+// package path to its packagesite line, with %s replaced by the checksum.
+func writePrebuiltRepo(t *testing.T, entries map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	var site strings.Builder
+	for path, line := range entries {
+		content := []byte("package " + path)
+		full := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256(content)
+		fmt.Fprintf(&site, line+"\n", hex.EncodeToString(sum[:]))
+	}
+	if err := os.WriteFile(filepath.Join(dir, "packagesite.yaml"), []byte(site.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func vimEntry(options string) string {
+	return `{"name":"vim","origin":"editors/vim","version":"9.1.0470","path":"All/vim-9.1.0470.pkg","sum":"%s","options":` + options + `}`
+}
+
+func TestParsePackagesite(t *testing.T) {
+	input := `{"name":"vim","origin":"editors/vim","version":"9.1.0470","path":"All/vim-9.1.0470.pkg","sum":"abc","options":{"NLS":"on"}}
+
+{"name":"py39-six","origin":"devel/py-six","version":"1.16.0","path":"All/py39-six-1.16.0.pkg","annotations":{"flavor":"py39"}}
+`
+	pkgs, err := ParsePackagesite(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParsePackagesite failed: %v", err)
+	}
+	if len(pkgs) != 2 {
+		t.Fatalf("got %d packages, want 2", len(pkgs))
+	}
+	if pkgs[0].Options["NLS"] != "on" {
+		t.Errorf("options = %v, want NLS=on", pkgs[0].Options)
+	}
+	if pkgs[1].Flavor() != "py39" {
+		t.Errorf("Flavor() = %q, want py39", pkgs[1].Flavor())
+	}
+
+	catalog := NewPrebuiltCatalog("test", pkgs)
+	six := &Package{PortDir: "devel/py-six", Flavor: "py39", Version: "1.16.0", PkgFile: "py39-six-1.16.0.pkg"}
+	if catalog.Match(six) == nil {
+		t.Error("flavored package not matched")
+	}
+	six.Flavor = "py311"
+	if catalog.Match(six) != nil {
+		t.Error("package matched with a different flavor")
+	}
+}
+
+func TestParsePackagesite_Invalid(t *testing.T) {
+	for _, input := range []string{
+		`{"name":"vim"`,
+		`{"name":"vim","version":"1.0","path":"All/vim-1.0.pkg"}`,
+	} {
+		if _, err := ParsePackagesite(strings.NewReader(input)); err == nil {
+			t.Errorf("ParsePackagesite(%q) succeeded, want error", input)
+		}
+	}
+}
+
+func TestLoadPrebuiltCatalog(t *testing.T) {
+	dir := writePrebuiltRepo(t, map[string]string{
+		"All/vim-9.1.0470.pkg": vimEntry(vimDefaultOptions),
+	})
+
+	server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer server.Close()
+
+	for _, source := range []string{dir, "file://" + dir, server.URL} {
+		t.Run(source, func(t *testing.T) {
+			catalog, err := LoadPrebuiltCatalog(source)
+			if err != nil {
+				t.Fatalf("LoadPrebuiltCatalog failed: %v", err)
+			}
+			if catalog.Len() != 1 {
+				t.Fatalf("Len() = %d, want 1", catalog.Len())
+			}
+
+			pp := catalog.Match(&Package{PortDir: "editors/vim", Version: "9.1.0470", PkgFile: "vim-9.1.0470.pkg"})
+			if pp == nil {
+				t.Fatal("vim not matched")
+			}
+			dst, err := catalog.Fetch(pp, t.TempDir())
+			if err != nil {
+				t.Fatalf("Fetch failed: %v", err)
+			}
+			if data, _ := os.ReadFile(dst); string(data) != "package All/vim-9.1.0470.pkg" {
+				t.Errorf("fetched content = %q", data)
+			}
+		})
+	}
+
+	if _, err := LoadPrebuiltCatalog(t.TempDir()); !errors.Is(err, ErrNoCatalog) {
+		t.Errorf("empty repository: err = %v, want ErrNoCatalog", err)
+	}
+	emptyServer := httptest.NewServer(http.NotFoundHandler())
+	defer emptyServer.Close()
+	if _, err := LoadPrebuiltCatalog(emptyServer.URL); !errors.Is(err, ErrNoCatalog) {
+		t.Errorf("empty server: err = %v, want ErrNoCatalog", err)
+	}
+}
+
+func TestPrebuiltFetch_ChecksumMismatch(t *testing.T) {
+	dir := writePrebuiltRepo(t, map[string]string{
+		"All/vim-9.1.0470.pkg": vimEntry(vimDefaultOptions),
+	})
+	catalog, err := LoadPrebuiltCatalog(dir)
+	if err != nil {
+		t.Fatalf("LoadPrebuiltCatalog failed: %v", err)
+	}
+
+	pp := catalog.Match(&Package{PortDir: "editors/vim", Version: "9.1.0470", PkgFile: "vim-9.1.0470.pkg"})
+	pp.Sum = strings.Repeat("0", 64)
+
+	out := t.TempDir()
+	if _, err := catalog.Fetch(pp, out); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("Fetch error = %v, want checksum mismatch", err)
+	}
+	if entries, _ := os.ReadDir(out); len(entries) != 0 {
+		t.Errorf("failed fetch left files behind: %v", entries)
+	}
+}
+
+func TestLeveragePrebuilt(t *testing.T) {
+	newVim := func() *Package {
+		return &Package{
+			PortDir:  "editors/vim",
+			Category: "editors",
+			Name:     "vim",
+			Version:  "9.1.0470",
+			PkgFile:  "vim-9.1.0470.pkg",
+		}
+	}
+
+	tests := []struct {
+		name       string
+		options    string
+		fixture    string // PORT_OPTIONS without NLS, as make reports with NLS saved off
+		saved      string // options file content, if any
+		depBuilt   bool   // whether vim's dependency is up to date
+		wantImport bool
+	}{
+		{"defaults match", vimDefaultOptions, "", "", true, true},
+		{"different options", strings.Replace(vimDefaultOptions, `"NLS":"on"`, `"NLS":"off"`, 1), "", "", true, false},
+		{"saved options match", strings.Replace(vimDefaultOptions, `"NLS":"on"`, `"NLS":"off"`, 1), "testdata/options/editors__vim_saved.txt", "OPTIONS_FILE_UNSET+=NLS\n", true, true},
+		{"dependency needs build", vimDefaultOptions, "", "", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixture := "testdata/options/editors__vim.txt"
+			if tt.fixture != "" {
+				fixture = tt.fixture
+			}
+			restore := setTestQuerier(newTestFixtureQuerier(map[string]string{"editors/vim": fixture}))
+			defer restore()
+
+			repo := writePrebuiltRepo(t, map[string]string{
+				"All/vim-9.1.0470.pkg": vimEntry(tt.options),
+			})
+			cfg := &config.Config{
+				DPortsPath:   "/usr/ports",
+				PackagesPath: t.TempDir(),
+				OptionsPath:  t.TempDir(),
+			}
+			if tt.saved != "" {
+				optDir := filepath.Join(cfg.OptionsPath, "editors_vim")
+				if err := os.MkdirAll(optDir, 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(optDir, "options"), []byte(tt.saved), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			registry := NewBuildStateRegistry()
+			vim := newVim()
+			dep := &Package{PortDir: "devel/gettext", Category: "devel", Name: "gettext"}
+			vim.IDependOn = []*PkgLink{{Pkg: dep}}
+			if tt.depBuilt {
+				registry.AddFlags(dep, PkgFSuccess|PkgFPackaged)
+			}
+
+			catalog, err := LoadPrebuiltCatalog(repo)
+			if err != nil {
+				t.Fatalf("LoadPrebuiltCatalog failed: %v", err)
+			}
+
+			imported := leveragePrebuilt([]*Package{vim}, catalog, cfg, registry, log.NoOpLogger{})
+			if got := len(imported) == 1; got != tt.wantImport {
+				t.Fatalf("imported = %v, want import %v", imported, tt.wantImport)
+			}
+
+			pkgPath := filepath.Join(cfg.PackagesPath, "All", "vim-9.1.0470.pkg")
+			_, statErr := os.Stat(pkgPath)
+			packaged := registry.HasFlags(vim, PkgFSuccess|PkgFPackaged)
+			if tt.wantImport && (statErr != nil || !packaged) {
+				t.Errorf("package not imported: stat err %v, packaged %v", statErr, packaged)
+			}
+			if !tt.wantImport && (statErr == nil || packaged) {
+				t.Errorf("package imported unexpectedly")
+			}
+		})
+	}
+}
+
+func TestMarkPackagesNeedingBuild_PrebuiltForMissingPackage(t *testing.T) {
+	restore := setTestQuerier(newTestFixtureQuerier(map[string]string{
+		"editors/vim": "testdata/options/editors__vim.txt",
+	}))
+	defer restore()
+
+	cfg := &config.Config{
+		DPortsPath:   t.TempDir(),
+		PackagesPath: t.TempDir(),
+		OptionsPath:  t.TempDir(),
+	}
+	cfg.Prebuilt.Enabled = true
+	cfg.Prebuilt.Repository = writePrebuiltRepo(t, map[string]string{
+		"All/vim-9.1.0470.pkg": vimEntry(vimDefaultOptions),
+	})
+
+	portPath := filepath.Join(cfg.DPortsPath, "editors", "vim")
+	if err := os.MkdirAll(portPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(portPath, "Makefile"), []byte("PORTNAME=vim\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The port is unchanged since its last build, but its package is gone
+	db, err := builddb.OpenDB(filepath.Join(t.TempDir(), "builds.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	crc, err := builddb.ComputePortCRC(portPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpdateCRC("editors/vim", crc); err != nil {
+		t.Fatal(err)
+	}
+
+	vim := &Package{PortDir: "editors/vim", Category: "editors", Name: "vim", Version: "9.1.0470", PkgFile: "vim-9.1.0470.pkg"}
+	registry := NewBuildStateRegistry()
+	needBuild, err := MarkPackagesNeedingBuild([]*Package{vim}, cfg, registry, db, log.NoOpLogger{})
+	if err != nil {
+		t.Fatalf("MarkPackagesNeedingBuild failed: %v", err)
+	}
+	if needBuild != 0 || !registry.HasFlags(vim, PkgFSuccess|PkgFPackaged) {
+		t.Errorf("needBuild = %d, flags %v; want the prebuilt package used", needBuild, registry.GetFlags(vim))
+	}
+}
@@ -16,7 +16,8 @@
 #
 # Output:
 #   Fixtures are written to pkg/testdata/fixtures/ with naming pattern: category__port.txt
-#   Each fixture is exactly 10 lines containing port metadata variables.
+#   Each fixture contains port metadata variables, each preceded by a
+#   '@@go-synth@@ NAME' header line.
 #
 # Example on FreeBSD/DragonFly:
 #   cd /path/to/go-synth
@@ -99,6 +100,10 @@ case "$FIXTURE_DIR" in
         ;;
 esac
 
+# Variables captured for each port (see metadataVars in pkg/ports_interface.go)
+QUERY_VARS="PKGNAME PKGVERSION PKGFILE FETCH_DEPENDS EXTRACT_DEPENDS PATCH_DEPENDS BUILD_DEPENDS LIB_DEPENDS RUN_DEPENDS TEST_DEPENDS IGNORE MAKE_JOBS_UNSAFE DISABLE_MAKE_JOBS"
+QUERY_VAR_COUNT=$(echo $QUERY_VARS | wc -w)
+
 # Function to capture a single port's make output
 # Usage: capture_port category port [flavor]
 capture_port() {
@@ -139,30 +144,24 @@ capture_port() {
     
     # Capture make output
     # Note: We use 'cd' instead of -C for better compatibility
-    # BSD make's -V flag prints one variable per invocation, so we get 10 lines
+    # Each variable is preceded by a "@@go-synth@@ NAME" header line so values
+    # can span multiple lines (must match queryMarker in pkg/ports_interface.go)
     (
+        set --
+        for v in $QUERY_VARS; do
+            set -- "$@" -V "\${:U@@go-synth@@ $v}" -V "$v"
+        done
         cd "$port_path" && \
-        make $flavor_arg \
-            -V PKGFILE \
-            -V PKGVERSION \
-            -V PKGFILE \
-            -V FETCH_DEPENDS \
-            -V EXTRACT_DEPENDS \
-            -V PATCH_DEPENDS \
-            -V BUILD_DEPENDS \
-            -V LIB_DEPENDS \
-            -V RUN_DEPENDS \
-            -V IGNORE \
-            > "$output_file" 2>&1
+        make $flavor_arg "$@" > "$output_file"
     )
     
     if [ $? -eq 0 ]; then
-        # Verify fixture has exactly 10 lines
-        line_count=$(wc -l < "$output_file")
-        if [ "$line_count" -eq 10 ]; then
-            echo "    ✓ $output_file ($line_count lines)"
+        # Verify fixture has a header for every queried variable
+        header_count=$(grep -c '^@@go-synth@@ ' "$output_file")
+        if [ "$header_count" -eq "$QUERY_VAR_COUNT" ]; then
+            echo "    ✓ $output_file ($header_count variables)"
         else
-            echo "    ⚠ WARNING: Expected 10 lines, got $line_count"
+            echo "    ⚠ WARNING: Expected $QUERY_VAR_COUNT variables, got $header_count"
             echo "    → $output_file"
         fi
     else
@@ -255,21 +254,18 @@ ls -1 "$FIXTURE_DIR" | sed 's/^/  /'
 echo ""
 echo "Total: $(ls -1 "$FIXTURE_DIR" | wc -l) fixture files"
 echo ""
-echo "Fixture format: Each file has exactly 10 lines:"
-echo "  Line 1:  PKGFILE (e.g., vim-9.0.1234.pkg)"
-echo "  Line 2:  PKGVERSION (e.g., 9.0.1234)"
-echo "  Line 3:  PKGFILE (duplicate)"
-echo "  Line 4:  FETCH_DEPENDS"
-echo "  Line 5:  EXTRACT_DEPENDS"
-echo "  Line 6:  PATCH_DEPENDS"
-echo "  Line 7:  BUILD_DEPENDS (e.g., gmake:devel/gmake)"
-echo "  Line 8:  LIB_DEPENDS (e.g., libintl.so:devel/gettext-runtime)"
-echo "  Line 9:  RUN_DEPENDS (e.g., python39:lang/python39)"
-echo "  Line 10: IGNORE (empty if not ignored)"
+echo "Fixture format: a header line before each variable value:"
+echo "  @@go-synth@@ PKGNAME"
+echo "  vim-9.0.1234"
+echo "  @@go-synth@@ PKGVERSION"
+echo "  9.0.1234"
+echo "  ..."
+echo "  @@go-synth@@ IGNORE"
+echo "  (empty if not ignored)"
 echo ""
 echo "Next steps:"
 echo "  1. Review captured fixtures for correctness"
-echo "  2. Verify line counts (should all be exactly 10 lines)"
+echo "  2. Verify variable counts (should all be $QUERY_VAR_COUNT)"
 echo "  3. If on remote BSD system, copy fixtures back:"
 echo "     scp pkg/testdata/fixtures/*.txt user@devmachine:go-synth/pkg/testdata/fixtures/"
 echo "  4. Commit fixtures: git add pkg/testdata/fixtures/*.txt"
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"time"
@@ -9,7 +10,9 @@ import (
 
 	"go-synth/build"
 	"go-synth/builddb"
+	"go-synth/hooks"
 	"go-synth/migration"
+	"go-synth/notify"
 	"go-synth/pkg"
 )
 
@@ -32,11 +35,21 @@ import (
 func (s *Service) Build(opts BuildOptions) (*BuildResult, error) {
 	startTime := time.Now()
 
-	// Ensure no other go-synth run is active
-	if activeRunID, activeRun, err := s.db.ActiveRun(); err != nil {
-		return nil, fmt.Errorf("check active run: %w", err)
-	} else if activeRun != nil {
-		return nil, fmt.Errorf("another go-synth run (%s) started at %s is still active", activeRunID, activeRun.StartTime.Format(time.RFC3339))
+	// Ensure no other go-synth process uses the build base
+	if err := s.Lock(); err != nil {
+		return nil, err
+	}
+
+	// With the lock held, runs still marked active were interrupted
+	if cleared, err := s.db.ClearActiveLocks(); err != nil {
+		return nil, fmt.Errorf("clear interrupted runs: %w", err)
+	} else if cleared > 0 {
+		s.logger.Warn("Marked %d interrupted build run(s) as aborted", cleared)
+	}
+
+	// Test mode adds the test phase and resolves TEST_DEPENDS
+	if opts.TestMode {
+		s.cfg.TestMode = true
 	}
 
 	// Detect and perform migration if needed
@@ -77,6 +90,9 @@ func (s *Service) Build(opts BuildOptions) (*BuildResult, error) {
 		return nil, fmt.Errorf("start build run: %w", err)
 	}
 
+	s.logger.SetRunID(runID)
+	_ = hooks.Run(context.Background(), s.cfg, hooks.RunStart, hooks.Env{RunID: runID}, s.logger)
+
 	runAborted := true
 	var finalStats *build.BuildStats
 	defer func() {
@@ -87,6 +103,24 @@ func (s *Service) Build(opts BuildOptions) (*BuildResult, error) {
 		if err := s.db.FinishRun(runID, statsPayload, time.Now(), runAborted); err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to finalize build run %s: %v\n", runID, err)
 		}
+
+		env := hooks.Env{RunID: runID, Status: "success", Duration: time.Since(startTime)}
+		if finalStats != nil {
+			env.Success = finalStats.Success
+			env.Failed = finalStats.Failed
+			env.Skipped = finalStats.Skipped
+			env.Ignored = finalStats.Ignored
+		}
+		switch {
+		case runAborted:
+			env.Status = "aborted"
+		case env.Failed > 0:
+			env.Status = "failed"
+		}
+		_ = hooks.Run(context.Background(), s.cfg, hooks.RunEnd, env, s.logger)
+		_ = notify.New(s.cfg, s.db, runID, s.logger).RunEnded(env.Status, statsPayload, env.Duration)
+
+		s.autoVacuum()
 	}()
 
 	// Execute the build
@@ -113,6 +147,9 @@ func (s *Service) Build(opts BuildOptions) (*BuildResult, error) {
 
 	runAborted = false
 
+	// New package versions leave the old ones behind in the repository
+	s.reportObsolete(packages)
+
 	// Return cleanup function to caller as well (for explicit control if needed)
 	return &BuildResult{
 		Stats:     stats,
@@ -145,13 +182,17 @@ func (s *Service) detectAndMigrate() error {
 
 // parseAndResolve parses the port list and resolves all dependencies.
 func (s *Service) parseAndResolve(portList []string) ([]*pkg.Package, error) {
+	registry := pkg.NewBuildStateRegistry()
+	return s.parseAndResolveWithRegistry(portList, registry)
+}
+
+// parseAndResolveWithRegistry is like parseAndResolve but records the
+// flags found while parsing (ignored, meta, not found) in registry.
+func (s *Service) parseAndResolveWithRegistry(portList []string, registry *pkg.BuildStateRegistry) ([]*pkg.Package, error) {
 	if len(portList) == 0 {
 		return nil, fmt.Errorf("no ports specified")
 	}
 
-	// Create build state registry
-	registry := pkg.NewBuildStateRegistry()
-
 	// Create package registry
 	pkgRegistry := pkg.NewPackageRegistry()
 
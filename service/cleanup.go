@@ -27,6 +27,11 @@ import (
 //
 // Returns CleanupResult containing the number of workers cleaned and any errors.
 func (s *Service) CleanupStaleWorkers(opts CleanupOptions) (*CleanupResult, error) {
+	// Worker directories of a running build are not stale
+	if err := s.Lock(); err != nil {
+		return nil, err
+	}
+
 	result := &CleanupResult{
 		WorkersCleaned: 0,
 		Errors:         make([]error, 0),
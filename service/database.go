@@ -2,8 +2,12 @@ package service
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"time"
+
+	"go-synth/builddb"
 )
 
 // DatabaseResult contains the results of a database operation.
@@ -23,6 +27,10 @@ type DatabaseResult struct {
 //
 // Returns DatabaseResult containing information about what was removed.
 func (s *Service) ResetDatabase() (*DatabaseResult, error) {
+	if err := s.Lock(); err != nil {
+		return nil, err
+	}
+
 	result := &DatabaseResult{
 		FilesRemoved: make([]string, 0),
 	}
@@ -83,7 +91,11 @@ func (s *Service) GetDatabasePath() string {
 	return s.cfg.Database.Path
 }
 
-// BackupDatabase creates a backup of the build database.
+// BackupDatabase writes a consistent copy of the build database next to
+// it, named after the current time, and removes backups beyond
+// Database_keep_backups. It is safe while a build is running.
+//
+// Returns the path of the new backup.
 func (s *Service) BackupDatabase() (string, error) {
 	dbPath := s.cfg.Database.Path
 
@@ -92,19 +104,113 @@ func (s *Service) BackupDatabase() (string, error) {
 		return "", fmt.Errorf("database does not exist: %s", dbPath)
 	}
 
-	// Create backup file with timestamp
-	backupPath := fmt.Sprintf("%s.backup", dbPath)
+	backupPath := builddb.BackupPath(dbPath, time.Now())
+	if _, err := s.db.Backup(backupPath); err != nil {
+		return "", fmt.Errorf("failed to back up database: %w", err)
+	}
+	s.logger.Info("Database backed up to: %s", backupPath)
 
-	// Copy database file to backup location
-	input, err := os.ReadFile(dbPath)
+	removed, err := builddb.RotateBackups(dbPath, s.cfg.Database.KeepBackups)
+	for _, path := range removed {
+		s.logger.Info("Old database backup removed: %s", path)
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to read database: %w", err)
+		s.logger.Warn("Failed to remove old database backups: %v", err)
 	}
 
-	if err := os.WriteFile(backupPath, input, 0644); err != nil {
-		return "", fmt.Errorf("failed to write backup: %w", err)
+	return backupPath, nil
+}
+
+// ExportDatabase writes every bucket of the build database to w as
+// versioned JSON lines; see builddb.Export.
+func (s *Service) ExportDatabase(w io.Writer) (builddb.DumpCounts, error) {
+	counts, err := s.db.Export(w)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export database: %w", err)
 	}
+	return counts, nil
+}
 
-	s.logger.Info("Database backed up to: %s", backupPath)
-	return backupPath, nil
+// ImportDatabase merges a dump written by ExportDatabase into the build
+// database. The database is backed up first, and nothing is imported
+// while another go-synth holds the build base lock.
+//
+// Returns the entries imported and the path of the backup taken.
+func (s *Service) ImportDatabase(r io.Reader) (builddb.DumpCounts, string, error) {
+	if err := s.Lock(); err != nil {
+		return nil, "", err
+	}
+
+	backupPath, err := s.BackupDatabase()
+	if err != nil {
+		return nil, "", err
+	}
+
+	counts, err := s.db.Import(r)
+	if err != nil {
+		return nil, backupPath, fmt.Errorf("failed to import database: %w", err)
+	}
+	s.logger.Info("Imported %d database entries", counts.Total())
+	return counts, backupPath, nil
+}
+
+// retentionPolicy returns the build history retention configured by
+// Database_keep_runs and Database_keep_days.
+func (s *Service) retentionPolicy() builddb.RetentionPolicy {
+	return builddb.RetentionPolicy{
+		KeepRuns: s.cfg.Database.KeepRuns,
+		MaxAge:   time.Duration(s.cfg.Database.KeepDays) * 24 * time.Hour,
+	}
+}
+
+// PruneDatabase deletes the build history the retention settings do not
+// keep. The latest successful build of every port is always kept. With
+// neither Database_keep_runs nor Database_keep_days set, nothing is
+// deleted.
+func (s *Service) PruneDatabase() (builddb.PruneResult, error) {
+	if err := s.Lock(); err != nil {
+		return builddb.PruneResult{}, err
+	}
+
+	result, err := s.db.Prune(s.retentionPolicy(), time.Now())
+	if err != nil {
+		return builddb.PruneResult{}, fmt.Errorf("failed to prune database: %w", err)
+	}
+	s.logger.Info("Pruned %d run(s) and %d build record(s) from the build database", result.Runs, result.Builds)
+	return result, nil
+}
+
+// CompactDatabase rewrites the build database file to reclaim the space of
+// deleted records.
+func (s *Service) CompactDatabase() (*builddb.CompactResult, error) {
+	if err := s.Lock(); err != nil {
+		return nil, err
+	}
+
+	result, err := s.db.Compact()
+	if err != nil {
+		return nil, fmt.Errorf("failed to compact database: %w", err)
+	}
+	s.logger.Info("Build database compacted: %d -> %d bytes", result.SizeBefore, result.SizeAfter)
+	return result, nil
+}
+
+// autoVacuum prunes the build history and, if anything was deleted,
+// compacts the database. It runs at the end of each build run when
+// Database_auto_vacuum is enabled; failures are logged, not returned.
+func (s *Service) autoVacuum() {
+	if !s.cfg.Database.AutoVacuum || s.retentionPolicy().IsZero() {
+		return
+	}
+	result, err := s.PruneDatabase()
+	if err != nil {
+		s.logger.Warn("Auto-vacuum: %v", err)
+		return
+	}
+	if result.Total() == 0 {
+		return
+	}
+	if _, err := s.CompactDatabase(); err != nil {
+		s.logger.Warn("Auto-vacuum: %v", err)
+	}
 }
@@ -1,11 +1,17 @@
 package service
 
 import (
+	"bytes"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"go-synth/builddb"
 	"go-synth/config"
+	"go-synth/lock"
 )
 
 // TestDatabaseExists tests database file existence check
@@ -123,9 +129,80 @@ func TestBackupDatabase(t *testing.T) {
 	}
 
 	// Check that backup has expected name format
-	expectedBackup := cfg.Database.Path + ".backup"
-	if backupPath != expectedBackup {
-		t.Errorf("Backup path = %q, want %q", backupPath, expectedBackup)
+	if !strings.HasPrefix(backupPath, cfg.Database.Path+".backup-") {
+		t.Errorf("Backup path = %q, want a timestamped %s.backup-*", backupPath, cfg.Database.Path)
+	}
+
+	// Backups beyond Database_keep_backups are rotated out
+	cfg.Database.KeepBackups = 2
+	for i := 0; i < 3; i++ {
+		time.Sleep(2 * time.Millisecond) // distinct timestamps
+		if _, err := svc.BackupDatabase(); err != nil {
+			t.Fatalf("BackupDatabase() failed: %v", err)
+		}
+	}
+	backups, err := builddb.ListBackups(cfg.Database.Path)
+	if err != nil {
+		t.Fatalf("ListBackups() failed: %v", err)
+	}
+	if len(backups) != 2 {
+		t.Errorf("%d backups left, want 2: %v", len(backups), backups)
+	}
+}
+
+// TestExportImportDatabase tests moving history between databases
+func TestExportImportDatabase(t *testing.T) {
+	newService := func(dir string) *Service {
+		cfg := &config.Config{
+			BuildBase: dir,
+			LogsPath:  filepath.Join(dir, "logs"),
+		}
+		cfg.Database.Path = filepath.Join(dir, "build.db")
+		if err := os.MkdirAll(cfg.LogsPath, 0755); err != nil {
+			t.Fatalf("Failed to create logs dir: %v", err)
+		}
+		svc, err := NewService(cfg)
+		if err != nil {
+			t.Fatalf("NewService() failed: %v", err)
+		}
+		t.Cleanup(func() { svc.Close() })
+		return svc
+	}
+
+	src := newService(t.TempDir())
+	if err := src.db.UpdateCRC("editors/vim", 42); err != nil {
+		t.Fatalf("UpdateCRC() failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if _, err := src.ExportDatabase(&buf); err != nil {
+		t.Fatalf("ExportDatabase() failed: %v", err)
+	}
+
+	dst := newService(t.TempDir())
+	counts, backupPath, err := dst.ImportDatabase(&buf)
+	if err != nil {
+		t.Fatalf("ImportDatabase() failed: %v", err)
+	}
+	if counts[builddb.BucketCRCIndex] != 1 {
+		t.Errorf("imported %v, want 1 crc_index entry", counts)
+	}
+	if _, err := os.Stat(backupPath); err != nil {
+		t.Errorf("no backup before import: %v", err)
+	}
+	if crc, ok, _ := dst.db.GetCRC("editors/vim"); !ok || crc != 42 {
+		t.Errorf("GetCRC() after import = %d, %v; want 42", crc, ok)
+	}
+
+	// Not while another go-synth holds the build base
+	other := newService(t.TempDir())
+	other.cfg.BuildBase = src.cfg.BuildBase
+	if err := src.Lock(); err != nil {
+		t.Fatalf("Lock() failed: %v", err)
+	}
+	_, _, err = other.ImportDatabase(strings.NewReader(""))
+	var held *lock.HeldError
+	if !errors.As(err, &held) {
+		t.Errorf("ImportDatabase() with the build base locked: error = %v, want HeldError", err)
 	}
 }
 
@@ -303,3 +380,64 @@ func TestResetDatabase_WithLegacyFiles(t *testing.T) {
 		t.Error("Legacy CRC backup still exists")
 	}
 }
+
+// TestPruneDatabase tests that retention settings prune old runs
+func TestPruneDatabase(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := &config.Config{
+		BuildBase: tmpDir,
+		LogsPath:  filepath.Join(tmpDir, "logs"),
+	}
+	cfg.Database.Path = filepath.Join(tmpDir, "build.db")
+
+	if err := os.MkdirAll(cfg.LogsPath, 0755); err != nil {
+		t.Fatalf("Failed to create logs dir: %v", err)
+	}
+
+	svc, err := NewService(cfg)
+	if err != nil {
+		t.Fatalf("NewService() failed: %v", err)
+	}
+	defer svc.Close()
+
+	now := time.Now()
+	for i, id := range []string{"run-old", "run-new"} {
+		start := now.Add(time.Duration(i-2) * time.Hour)
+		if err := svc.db.StartRun(id, start); err != nil {
+			t.Fatalf("StartRun(%s) failed: %v", id, err)
+		}
+		if err := svc.db.FinishRun(id, builddb.RunStats{}, start.Add(time.Minute), false); err != nil {
+			t.Fatalf("FinishRun(%s) failed: %v", id, err)
+		}
+	}
+
+	// No retention configured: nothing is deleted
+	result, err := svc.PruneDatabase()
+	if err != nil {
+		t.Fatalf("PruneDatabase() failed: %v", err)
+	}
+	if result.Total() != 0 {
+		t.Errorf("PruneDatabase() without retention deleted %d records", result.Total())
+	}
+
+	cfg.Database.KeepRuns = 1
+	result, err = svc.PruneDatabase()
+	if err != nil {
+		t.Fatalf("PruneDatabase() failed: %v", err)
+	}
+	if result.Runs != 1 {
+		t.Errorf("PruneDatabase() deleted %d runs, want 1", result.Runs)
+	}
+
+	compact, err := svc.CompactDatabase()
+	if err != nil {
+		t.Fatalf("CompactDatabase() failed: %v", err)
+	}
+	if compact.SizeAfter == 0 {
+		t.Error("CompactDatabase() reported an empty database")
+	}
+	if _, err := svc.db.GetRun("run-new"); err != nil {
+		t.Errorf("GetRun(run-new) after compaction failed: %v", err)
+	}
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go-synth/pkg"
+)
+
+// StatusEverything resolves the entire ports tree and reports, per
+// category, how many packages are up-to-date, need a build, are ignored or
+// are broken, without building anything. It also checks the repository
+// for obsolete and orphaned packages, logging them to
+// 06_obsolete_packages.log if the service holds the build base lock; see
+// PruneObsolete.
+//
+// This method handles all the business logic but does not interact with
+// the user. Resolving a full ports tree takes a while; progress goes to
+// the build logs.
+func (s *Service) StatusEverything(opts EverythingOptions) (*EverythingResult, error) {
+	ports, err := pkg.GetAllPorts(s.cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ports: %w", err)
+	}
+
+	registry := pkg.NewBuildStateRegistry()
+	packages, err := s.resolveTree(ports, registry)
+	if err != nil {
+		return nil, err
+	}
+
+	// A status report must not download anything, so leverage_prebuilt is
+	// left out of the check
+	cfg := *s.cfg
+	cfg.Prebuilt.Enabled = false
+	if _, err := pkg.MarkPackagesNeedingBuild(packages, &cfg, registry, s.db, s.logger); err != nil {
+		return nil, fmt.Errorf("failed to check build status: %w", err)
+	}
+
+	result := summarizeTree(ports, packages, registry, opts.Top)
+
+	// Packages of broken ports are unknown, so none can be called orphaned
+	report, err := pkg.FindObsoletePackages(s.cfg, packages, len(result.Broken) == 0)
+	if err != nil {
+		return nil, err
+	}
+	s.logObsolete(report)
+	result.Repository = &ObsoleteResult{Report: report}
+
+	return result, nil
+}
+
+// resolveTree resolves every port of the tree, like
+// parseAndResolveWithRegistry, including the non-default flavors of
+// flavored ports, which produce packages of their own.
+func (s *Service) resolveTree(ports []string, registry *pkg.BuildStateRegistry) ([]*pkg.Package, error) {
+	pkgRegistry := pkg.NewPackageRegistry()
+
+	packages, err := pkg.ParsePortList(ports, s.cfg, registry, pkgRegistry, s.logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse port list: %w", err)
+	}
+	flavored, err := pkg.ParseFlavors(packages, s.cfg, registry, pkgRegistry, s.logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse flavors: %w", err)
+	}
+	packages = append(packages, flavored...)
+
+	if err := pkg.ResolveDependencies(packages, s.cfg, registry, pkgRegistry, s.logger); err != nil {
+		return nil, fmt.Errorf("failed to resolve dependencies: %w", err)
+	}
+
+	return pkgRegistry.AllPackages(), nil
+}
+
+// summarizeTree counts packages by category and status. Ports of the
+// tree that did not resolve to any package are counted as broken.
+func summarizeTree(ports []string, packages []*pkg.Package, registry *pkg.BuildStateRegistry, top int) *EverythingResult {
+	result := &EverythingResult{
+		Ports:    len(ports),
+		Packages: len(packages),
+		Broken:   []string{},
+	}
+
+	categories := make(map[string]*CategoryStatus)
+	category := func(name string) *CategoryStatus {
+		if categories[name] == nil {
+			categories[name] = &CategoryStatus{Category: name}
+		}
+		return categories[name]
+	}
+
+	resolved := make(map[string]bool) // origins with at least one package
+	var rebuilds []RebuildImpact
+	for _, p := range packages {
+		cs := category(p.Category)
+		flags := registry.GetFlags(p)
+		switch {
+		case registry.HasAnyFlags(p, pkg.PkgFNotFound|pkg.PkgFCorrupt):
+			cs.Broken++
+			result.Broken = append(result.Broken, p.PortDir)
+			continue
+		case flags.Has(pkg.PkgFIgnored):
+			cs.Ignored++
+		case flags.Has(pkg.PkgFPackaged) || flags.Has(pkg.PkgFMeta):
+			cs.UpToDate++
+		default:
+			cs.NeedsBuild++
+			rebuilds = append(rebuilds, RebuildImpact{
+				PortDir:    p.PortDir,
+				Version:    p.Version,
+				Dependents: countDependents(p),
+			})
+		}
+		resolved[p.Category+"/"+p.Name] = true
+	}
+
+	for _, origin := range ports {
+		if resolved[origin] {
+			continue
+		}
+		cat, _, _ := strings.Cut(origin, "/")
+		category(cat).Broken++
+		result.Broken = append(result.Broken, origin)
+	}
+	sort.Strings(result.Broken)
+
+	for _, cs := range categories {
+		result.Totals.UpToDate += cs.UpToDate
+		result.Totals.NeedsBuild += cs.NeedsBuild
+		result.Totals.Ignored += cs.Ignored
+		result.Totals.Broken += cs.Broken
+		result.Categories = append(result.Categories, *cs)
+	}
+	sort.Slice(result.Categories, func(i, j int) bool {
+		return result.Categories[i].Category < result.Categories[j].Category
+	})
+
+	sort.SliceStable(rebuilds, func(i, j int) bool {
+		if rebuilds[i].Dependents != rebuilds[j].Dependents {
+			return rebuilds[i].Dependents > rebuilds[j].Dependents
+		}
+		return rebuilds[i].PortDir < rebuilds[j].PortDir
+	})
+	result.TopRebuilds = rebuilds[:min(top, len(rebuilds))]
+	if result.TopRebuilds == nil {
+		result.TopRebuilds = []RebuildImpact{}
+	}
+
+	return result
+}
+
+// countDependents returns the number of packages depending on p, directly
+// or through other packages.
+func countDependents(p *pkg.Package) int {
+	seen := map[*pkg.Package]bool{p: true}
+	queue := []*pkg.Package{p}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		for _, link := range next.DependsOnMe {
+			if !seen[link.Pkg] {
+				seen[link.Pkg] = true
+				queue = append(queue, link.Pkg)
+			}
+		}
+	}
+	return len(seen) - 1
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"go-synth/pkg"
+)
+
+// link records that dependent depends on dep.
+func link(dependent, dep *pkg.Package) {
+	dependent.IDependOn = append(dependent.IDependOn, &pkg.PkgLink{Pkg: dep, DepType: pkg.DepTypeBuild})
+	dep.DependsOnMe = append(dep.DependsOnMe, &pkg.PkgLink{Pkg: dependent, DepType: pkg.DepTypeBuild})
+}
+
+func TestSummarizeTree(t *testing.T) {
+	newPkg := func(portDir, version string) *pkg.Package {
+		origin, _, _ := strings.Cut(portDir, "@")
+		category, name, _ := strings.Cut(origin, "/")
+		return &pkg.Package{PortDir: portDir, Category: category, Name: name, Version: version}
+	}
+	pkgconf := newPkg("devel/pkgconf", "2.2.0")
+	gettext := newPkg("devel/gettext-runtime", "0.22.5")
+	vim := newPkg("editors/vim", "9.1.0470")
+	neovim := newPkg("editors/neovim", "0.10.0")
+	oldvi := newPkg("editors/oldvi", "1.0")
+	meta := newPkg("x11/xorg", "7.7")
+	link(gettext, pkgconf)
+	link(vim, gettext)
+	link(neovim, gettext)
+	link(meta, vim)
+
+	registry := pkg.NewBuildStateRegistry()
+	registry.AddFlags(gettext, pkg.PkgFPackaged)
+	registry.AddFlags(oldvi, pkg.PkgFIgnored)
+	registry.AddFlags(meta, pkg.PkgFMeta)
+
+	ports := []string{"devel/pkgconf", "devel/gettext-runtime", "editors/vim", "editors/neovim",
+		"editors/oldvi", "editors/broken", "x11/xorg"}
+	packages := []*pkg.Package{pkgconf, gettext, vim, neovim, oldvi, meta}
+
+	result := summarizeTree(ports, packages, registry, 2)
+
+	want := []CategoryStatus{
+		{Category: "devel", UpToDate: 1, NeedsBuild: 1},
+		{Category: "editors", NeedsBuild: 2, Ignored: 1, Broken: 1},
+		{Category: "x11", UpToDate: 1},
+	}
+	if !slices.Equal(result.Categories, want) {
+		t.Errorf("Categories = %+v, want %+v", result.Categories, want)
+	}
+	if result.Totals != (CategoryStatus{UpToDate: 2, NeedsBuild: 3, Ignored: 1, Broken: 1}) {
+		t.Errorf("Totals = %+v", result.Totals)
+	}
+	if !slices.Equal(result.Broken, []string{"editors/broken"}) {
+		t.Errorf("Broken = %v", result.Broken)
+	}
+
+	// pkgconf is needed by everything through gettext; the rest tie
+	wantTop := []RebuildImpact{
+		{PortDir: "devel/pkgconf", Version: "2.2.0", Dependents: 4},
+		{PortDir: "editors/vim", Version: "9.1.0470", Dependents: 1},
+	}
+	if !slices.Equal(result.TopRebuilds, wantTop) {
+		t.Errorf("TopRebuilds = %+v, want %+v", result.TopRebuilds, wantTop)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("json.Marshal() failed: %v", err)
+	}
+	for _, want := range []string{`"needs_build":3`, `"top_rebuilds":[{"port":"devel/pkgconf"`, `"broken":["editors/broken"]`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("JSON lacks %s:\n%s", want, data)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 
 	"go-synth/builddb"
+	"go-synth/log"
 	"go-synth/migration"
 )
 
@@ -193,6 +194,7 @@ func (s *Service) GetLegacyCRCFile() (string, error) {
 
 // InitDatabase explicitly initializes just the database without full initialization.
 // This is useful for commands that need the database but don't need full init.
+// Existing databases are migrated to the current schema.
 func InitDatabase(dbPath string) (*builddb.DB, error) {
-	return builddb.OpenDB(dbPath)
+	return migration.OpenDB(dbPath, log.NoOpLogger{})
 }
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"fmt"
+
+	"go-synth/pkg"
+)
+
+// PruneObsolete removes the obsolete and orphaned packages found by
+// StatusEverything from the repository, recording what was removed in
+// result. Previous versions kept by Keep_obsolete_packages stay. The
+// repository catalog is not updated.
+func (s *Service) PruneObsolete(result *ObsoleteResult) error {
+	if err := s.Lock(); err != nil {
+		return err
+	}
+
+	removed, err := pkg.PruneObsoletePackages(s.cfg, result.Report)
+	result.Removed = removed
+	result.Freed = 0
+	for _, rp := range removed {
+		result.Freed += rp.Size
+	}
+	s.logger.Info("Pruned %d obsolete package(s)", len(removed))
+	if err != nil {
+		return fmt.Errorf("failed to prune packages: %w", err)
+	}
+	return nil
+}
+
+// reportObsolete logs the previous versions of packages left in the
+// repository after a run. Only packages of the run are considered; see
+// StatusEverything for the whole tree.
+func (s *Service) reportObsolete(packages []*pkg.Package) {
+	report, err := pkg.FindObsoletePackages(s.cfg, packages, false)
+	if err != nil {
+		s.logger.Warn("Failed to check for obsolete packages: %v", err)
+		return
+	}
+	s.logObsolete(report)
+	if len(report.Obsolete) > 0 {
+		s.logger.Info("%d obsolete package(s) in the repository, see 06_obsolete_packages.log", len(report.Obsolete))
+	}
+}
+
+// logObsolete writes the obsolete and orphaned packages of report to
+// 06_obsolete_packages.log.
+func (s *Service) logObsolete(report *pkg.ObsoleteReport) {
+	for _, rp := range report.Obsolete {
+		s.logger.Obsolete(rp.File)
+	}
+	for _, rp := range report.Orphaned {
+		s.logger.Orphaned(rp.File)
+	}
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"fmt"
+
+	"go-synth/pkg"
+)
+
+// ConfigurePortOptions loads a port's option definitions and saved options,
+// applies opts.Settings and optionally writes the options file.
+//
+// Settings are applied on top of the saved selection (or OPTIONS_DEFAULT when
+// opts.Reset is set or nothing is saved). When opts.Save is set the resulting
+// selection is validated against the port's option groups before it is written.
+//
+// The caller is responsible for displaying the result and for any interactive
+// editing; use SavePortOptions to write a selection edited by the caller.
+func (s *Service) ConfigurePortOptions(opts PortOptionsOptions) (*PortOptionsResult, error) {
+	defs, err := pkg.QueryPortOptions(opts.PortSpec, s.cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query options for %s: %w", opts.PortSpec, err)
+	}
+
+	saved, err := pkg.ReadSavedOptions(s.cfg, defs.PortDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read saved options for %s: %w", defs.PortDir, err)
+	}
+
+	result := &PortOptionsResult{
+		Options: defs,
+		Saved:   saved,
+		Path:    pkg.OptionsFilePath(s.cfg, defs.PortDir),
+	}
+
+	if opts.Reset {
+		result.Selected = defs.DefaultSet()
+	} else {
+		result.Selected = defs.Resolve(saved)
+	}
+
+	for _, setting := range opts.Settings {
+		name, on, err := pkg.ParseOptionSetting(setting)
+		if err != nil {
+			return nil, err
+		}
+		if err := defs.Apply(result.Selected, name, on); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Save && len(defs.All()) > 0 {
+		if err := s.SavePortOptions(defs, result.Selected); err != nil {
+			return nil, err
+		}
+		result.Written = true
+	}
+
+	return result, nil
+}
+
+// SavePortOptions validates a selection and writes it to the port's options
+// file under OptionsPath.
+func (s *Service) SavePortOptions(defs *pkg.PortOptions, selected pkg.OptionSet) error {
+	if err := defs.Validate(selected); err != nil {
+		return err
+	}
+
+	if err := pkg.WriteSavedOptions(s.cfg, defs, selected); err != nil {
+		return err
+	}
+
+	s.logger.Info("Saved options for %s: %v", defs.PortDir, selected.Enabled())
+	return nil
+}
+
+// DiffOptions compares every saved options file under OptionsPath with the
+// defaults of its port and reports ports whose selection differs.
+//
+// Ports that can no longer be queried (e.g., removed from the ports tree)
+// are reported with Err set so the caller can suggest removing them.
+func (s *Service) DiffOptions() (*OptionsDiffResult, error) {
+	ports, err := pkg.SavedOptionPorts(s.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &OptionsDiffResult{
+		Checked: len(ports),
+		Ports:   make([]OptionsDiff, 0),
+	}
+
+	for _, portDir := range ports {
+		diff := OptionsDiff{PortDir: portDir}
+
+		defs, err := pkg.QueryPortOptions(portDir, s.cfg)
+		if err != nil {
+			diff.Err = err
+			result.Ports = append(result.Ports, diff)
+			continue
+		}
+
+		saved, err := pkg.ReadSavedOptions(s.cfg, portDir)
+		if err != nil {
+			diff.Err = err
+			result.Ports = append(result.Ports, diff)
+			continue
+		}
+
+		selected := defs.Resolve(saved)
+		for _, name := range defs.All() {
+			switch {
+			case selected[name] && !defs.Defaults[name]:
+				diff.Enabled = append(diff.Enabled, name)
+			case !selected[name] && defs.Defaults[name]:
+				diff.Disabled = append(diff.Disabled, name)
+			}
+		}
+
+		if len(diff.Enabled) > 0 || len(diff.Disabled) > 0 {
+			result.Ports = append(result.Ports, diff)
+		}
+	}
+
+	return result, nil
+}
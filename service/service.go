@@ -23,7 +23,9 @@ import (
 
 	"go-synth/builddb"
 	"go-synth/config"
+	"go-synth/lock"
 	"go-synth/log"
+	"go-synth/migration"
 )
 
 // Service coordinates business logic across go-synth subsystems.
@@ -48,25 +50,25 @@ type Service struct {
 	cfg           *config.Config
 	logger        *log.Logger
 	db            *builddb.DB
-	activeCleanup func() // Cleanup function for active build (set immediately when workers created)
+	buildLock     *lock.Lock // Build base lock, taken by commands that change it
+	activeCleanup func()     // Cleanup function for active build (set immediately when workers created)
 	cleanupMu     sync.Mutex
 }
 
 // NewService creates a new Service instance with the given configuration.
 //
-// It initializes the logger and opens the build database. The caller is responsible
-// for calling Close() to release resources (typically via defer).
+// It initializes the logger and opens the build database, migrating it to the
+// current schema if needed. The run logs are only opened, replacing those
+// of the previous run, once Lock takes the build base lock, so commands
+// that do not build leave a running build's logs alone. The caller is
+// responsible for calling Close() to release resources (typically via defer).
 //
-// Returns an error if logger initialization or database opening fails.
+// Returns an error if database opening fails.
 func NewService(cfg *config.Config) (*Service, error) {
-	// Initialize logger
-	logger, err := log.NewLogger(cfg)
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize logger: %w", err)
-	}
+	logger := log.NewDeferredLogger(cfg)
 
-	// Open build database
-	db, err := builddb.OpenDB(cfg.Database.Path)
+	// Open build database, migrating it to the current schema
+	db, err := migration.OpenDB(cfg.Database.Path, logger)
 	if err != nil {
 		logger.Close()
 		return nil, fmt.Errorf("failed to open build database: %w", err)
@@ -93,6 +95,10 @@ func NewService(cfg *config.Config) (*Service, error) {
 func (s *Service) Close() error {
 	var errs []error
 
+	if err := s.buildLock.Release(); err != nil {
+		errs = append(errs, fmt.Errorf("build base lock release: %w", err))
+	}
+
 	// Close database and logger
 	if s.db != nil {
 		if err := s.db.Close(); err != nil {
@@ -111,6 +117,39 @@ func (s *Service) Close() error {
 	return nil
 }
 
+// Lock takes the build base lock for this service until Close, so that no
+// other go-synth process uses the same worker slots, logs and build
+// database. Operations that change the build base take it themselves;
+// calling Lock first lets the caller fail before asking the user anything.
+// Holding the lock, it then opens the run logs.
+//
+// Returns a *lock.HeldError if another process holds the lock. A lock
+// left by a crashed process is taken over with a warning.
+func (s *Service) Lock() error {
+	if s.buildLock != nil {
+		return nil
+	}
+	l, err := lock.Acquire(s.cfg.BuildBase)
+	if err != nil {
+		return err
+	}
+	if err := s.logger.Open(); err != nil {
+		l.Release()
+		return fmt.Errorf("failed to open logs: %w", err)
+	}
+	if l.Stale != nil {
+		s.logger.Warn("Took over the build base lock of %s, which is no longer running", l.Stale)
+	}
+	s.buildLock = l
+	return nil
+}
+
+// LockHolder returns the process holding the build base lock, or nil if
+// none does. It does not take the lock.
+func (s *Service) LockHolder() (*lock.Holder, error) {
+	return lock.Read(s.cfg.BuildBase)
+}
+
 // Config returns the service's configuration.
 func (s *Service) Config() *config.Config {
 	return s.cfg
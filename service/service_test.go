@@ -113,6 +113,51 @@ func TestService_Close(t *testing.T) {
 	}
 }
 
+// TestService_LogsOpenedWithLock tests that only the service holding the
+// build base lock opens the run logs
+func TestService_LogsOpenedWithLock(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		BuildBase: tmpDir,
+		LogsPath:  filepath.Join(tmpDir, "logs"),
+	}
+	cfg.Database.Path = filepath.Join(tmpDir, "build.db")
+	results := filepath.Join(cfg.LogsPath, "00_last_results.log")
+
+	building, err := NewService(cfg)
+	if err != nil {
+		t.Fatalf("NewService() failed: %v", err)
+	}
+	defer building.Close()
+	if _, err := os.Stat(results); !os.IsNotExist(err) {
+		t.Fatal("NewService() created the run logs before taking the lock")
+	}
+	if err := building.Lock(); err != nil {
+		t.Fatalf("Lock() failed: %v", err)
+	}
+	building.logger.Info("building")
+	before, err := os.ReadFile(results)
+	if err != nil {
+		t.Fatalf("Lock() did not open the run logs: %v", err)
+	}
+
+	// Another command, and another build that cannot take the lock, leave
+	// the running build's logs alone
+	other, err := NewService(cfg)
+	if err != nil {
+		t.Fatalf("NewService() failed: %v", err)
+	}
+	if err := other.Lock(); err == nil {
+		t.Error("second Lock() succeeded")
+	}
+	other.logger.Info("other")
+	other.Close()
+
+	if after, _ := os.ReadFile(results); string(after) != string(before) {
+		t.Errorf("results log changed from %q to %q", before, after)
+	}
+}
+
 // TestService_Config tests Config() accessor
 func TestService_Config(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -79,3 +79,74 @@ type DatabaseOptions struct {
 	Backup bool // Create backup before operation
 	Force  bool // Force operation without confirmation
 }
+
+// PortOptionsOptions contains options for the ConfigurePortOptions service.
+type PortOptionsOptions struct {
+	PortSpec string   // Port to configure (e.g., "editors/vim")
+	Settings []string // Option settings in NAME=on|off form
+	Reset    bool     // Ignore saved options and start from OPTIONS_DEFAULT
+	Save     bool     // Write the options file after applying settings
+}
+
+// PortOptionsResult contains the results of a port options operation.
+type PortOptionsResult struct {
+	Options  *pkg.PortOptions  // Option definitions from the port Makefile
+	Saved    *pkg.SavedOptions // Saved options before any changes (nil if none)
+	Selected pkg.OptionSet     // Effective selection after applying settings
+	Path     string            // Path of the port's options file
+	Written  bool              // Whether the options file was written
+}
+
+// OptionsDiff describes how a port's saved options differ from its defaults.
+type OptionsDiff struct {
+	PortDir  string   // Port directory (e.g., "editors/vim")
+	Enabled  []string // Options enabled that are off by default
+	Disabled []string // Options disabled that are on by default
+	Err      error    // Non-nil if the port could not be queried
+}
+
+// OptionsDiffResult contains the results of an options diff.
+type OptionsDiffResult struct {
+	Checked int           // Number of ports with saved options
+	Ports   []OptionsDiff // Ports that differ from defaults or failed to query
+}
+
+// ObsoleteResult contains the results of an obsolete package check.
+type ObsoleteResult struct {
+	Report  *pkg.ObsoleteReport `json:"report"`            // Obsolete, orphaned and kept package files
+	Removed []pkg.RepoPackage   `json:"removed,omitempty"` // Packages removed by PruneObsolete
+	Freed   int64               `json:"freed,omitempty"`   // Bytes freed by PruneObsolete
+}
+
+// EverythingOptions contains options for the StatusEverything service.
+type EverythingOptions struct {
+	Top int // Number of ports to list in TopRebuilds
+}
+
+// EverythingResult contains the status of the entire ports tree.
+type EverythingResult struct {
+	Ports       int              `json:"ports"`        // Ports in the tree
+	Packages    int              `json:"packages"`     // Packages resolved, one per flavor
+	Totals      CategoryStatus   `json:"totals"`       // Counts over all categories
+	Categories  []CategoryStatus `json:"categories"`   // Counts per category, sorted by name
+	Broken      []string         `json:"broken"`       // Ports that could not be parsed or resolved
+	TopRebuilds []RebuildImpact  `json:"top_rebuilds"` // Ports needing a build with the most dependents
+	Repository  *ObsoleteResult  `json:"repository"`   // Obsolete and orphaned repository packages
+}
+
+// CategoryStatus counts the packages of a category by status.
+type CategoryStatus struct {
+	Category   string `json:"category,omitempty"`
+	UpToDate   int    `json:"up_to_date"`
+	NeedsBuild int    `json:"needs_build"`
+	Ignored    int    `json:"ignored"`
+	Broken     int    `json:"broken"`
+}
+
+// RebuildImpact describes a port needing a build by the number of packages
+// depending on it.
+type RebuildImpact struct {
+	PortDir    string `json:"port"`
+	Version    string `json:"version"`
+	Dependents int    `json:"dependents"` // Packages depending on it, directly or indirectly
+}
@@ -36,11 +36,17 @@ func NewStatsCollector(ctx context.Context, maxWorkers int, throttler *WorkerThr
 	collectorCtx, cancel := context.WithCancel(ctx)
 	now := time.Now()
 
+	slots := make([]WorkerSlot, maxWorkers)
+	for i := range slots {
+		slots[i].ID = i
+	}
+
 	sc := &StatsCollector{
 		topInfo: TopInfo{
 			MaxWorkers:    maxWorkers,
 			DynMaxWorkers: maxWorkers, // Default to maxWorkers until throttler updates it
 			StartTime:     now,
+			Workers:       slots,
 		},
 		bucketStart: now,
 		startTime:   now,
@@ -101,11 +107,57 @@ func (sc *StatsCollector) UpdateQueuedCount(queued int) {
 	sc.topInfo.Queued = queued
 }
 
+// StartWorkerSlot records that worker id started building version of
+// portDir, whose build log is logPath. Unknown worker IDs are ignored.
+func (sc *StatsCollector) StartWorkerSlot(id int, portDir, version, logPath string, started time.Time) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if id >= 0 && id < len(sc.topInfo.Workers) {
+		sc.topInfo.Workers[id] = WorkerSlot{ID: id, PortDir: portDir, Version: version, Started: started, LogPath: logPath}
+	}
+}
+
+// SetWorkerPhase records the build phase worker id has reached.
+func (sc *StatsCollector) SetWorkerPhase(id int, phase string) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if id >= 0 && id < len(sc.topInfo.Workers) {
+		sc.topInfo.Workers[id].Phase = phase
+	}
+}
+
+// SetWorkerMemoryHeavy marks the build on worker id as memory-heavy with
+// the given memory estimate.
+func (sc *StatsCollector) SetWorkerMemoryHeavy(id int, estimate int64) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if id >= 0 && id < len(sc.topInfo.Workers) {
+		sc.topInfo.Workers[id].MemoryHeavy = estimate
+	}
+}
+
+// ClearWorkerSlot marks worker id idle.
+func (sc *StatsCollector) ClearWorkerSlot(id int) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if id >= 0 && id < len(sc.topInfo.Workers) {
+		sc.topInfo.Workers[id] = WorkerSlot{ID: id}
+	}
+}
+
 // GetSnapshot returns a thread-safe copy of the current TopInfo.
 func (sc *StatsCollector) GetSnapshot() TopInfo {
 	sc.mu.RLock()
 	defer sc.mu.RUnlock()
-	return sc.topInfo
+	return sc.snapshotLocked()
+}
+
+// snapshotLocked copies topInfo, including its worker slots.
+// Must be called with lock held.
+func (sc *StatsCollector) snapshotLocked() TopInfo {
+	snapshot := sc.topInfo
+	snapshot.Workers = append([]WorkerSlot(nil), sc.topInfo.Workers...)
+	return snapshot
 }
 
 // AddConsumer registers a stats consumer to receive updates on each tick.
@@ -176,7 +228,7 @@ func (sc *StatsCollector) tick() {
 	}
 
 	// Copy snapshot for consumers (outside lock)
-	snapshot := sc.topInfo
+	snapshot := sc.snapshotLocked()
 	consumers := sc.consumers
 
 	sc.mu.Unlock()
@@ -259,6 +259,42 @@ func TestUpdateMethods(t *testing.T) {
 	}
 }
 
+// TestWorkerSlots verifies worker slot updates and that snapshots are copies
+func TestWorkerSlots(t *testing.T) {
+	sc := NewStatsCollector(context.Background(), 3, nil)
+	defer sc.Close()
+
+	snapshot := sc.GetSnapshot()
+	if len(snapshot.Workers) != 3 {
+		t.Fatalf("Workers = %d slots, want 3", len(snapshot.Workers))
+	}
+	for i, w := range snapshot.Workers {
+		if w.ID != i || !w.Idle() {
+			t.Errorf("slot %d = %+v, want idle worker %d", i, w, i)
+		}
+	}
+
+	started := time.Now()
+	sc.StartWorkerSlot(1, "editors/vim", "9.1", "/logs/editors___vim.log", started)
+	sc.SetWorkerPhase(1, "build")
+	sc.SetWorkerMemoryHeavy(1, 12<<30)
+	sc.StartWorkerSlot(7, "shells/bash", "5.2", "", started) // out of range, ignored
+
+	snapshot = sc.GetSnapshot()
+	w := snapshot.Workers[1]
+	if w.Idle() || w.PortDir != "editors/vim" || w.Version != "9.1" || w.Phase != "build" || w.LogPath != "/logs/editors___vim.log" || !w.Started.Equal(started) || w.MemoryHeavy != 12<<30 {
+		t.Errorf("slot 1 = %+v, want memory-heavy editors/vim in build", w)
+	}
+
+	sc.ClearWorkerSlot(1)
+	if snapshot.Workers[1] != w {
+		t.Errorf("earlier snapshot changed by ClearWorkerSlot")
+	}
+	if got := sc.GetSnapshot().Workers[1]; !got.Idle() || got.ID != 1 {
+		t.Errorf("slot 1 after clear = %+v, want idle", got)
+	}
+}
+
 // TestElapsedTime verifies elapsed time calculation
 func TestElapsedTime(t *testing.T) {
 	ctx := context.Background()
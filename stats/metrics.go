@@ -0,0 +1,290 @@
+package stats
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MetricsContentType is the media type of the exposition MetricsExporter
+// serves.
+const MetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// Histogram bucket upper bounds, in seconds. Port builds range from a
+// minute to most of a day; phases from a second to hours.
+var (
+	portBuildBuckets = []float64{60, 300, 600, 1800, 3600, 7200, 14400, 28800, 57600, 86400}
+	phaseBuckets     = []float64{1, 5, 15, 60, 300, 900, 1800, 3600, 7200, 14400}
+)
+
+// MetricsExporter implements StatsConsumer and serves the build metrics in
+// the OpenMetrics text format, for Prometheus and compatible scrapers:
+//
+//   - gauges of active, maximum and dynamic maximum workers, load, swap,
+//     build rate, queued and remaining packages, and elapsed time
+//   - a counter of finished packages by status (built, failed, skipped,
+//     ignored)
+//   - histograms of port build duration by outcome and of phase duration
+//     by phase, fed by ObservePortBuild and ObservePhase
+//   - the size of the build database file
+//
+// The exporter lives as long as the build, so the metrics describe the
+// current run; scrapers see the counters start over with each run.
+type MetricsExporter struct {
+	dbPath string
+
+	mu     sync.Mutex
+	info   TopInfo
+	builds map[string]*histogram // by outcome
+	phases map[string]*histogram // by phase
+
+	server *http.Server
+}
+
+// histogram counts observations into cumulative buckets.
+type histogram struct {
+	bounds []float64
+	counts []uint64 // counts[i] observations <= bounds[i]
+	count  uint64
+	sum    float64
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]uint64, len(bounds))}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, b := range h.bounds {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// NewMetricsExporter creates a metrics exporter reporting the size of the
+// build database at dbPath.
+func NewMetricsExporter(dbPath string) *MetricsExporter {
+	return &MetricsExporter{
+		dbPath: dbPath,
+		builds: make(map[string]*histogram),
+		phases: make(map[string]*histogram),
+	}
+}
+
+// OnStatsUpdate records the latest snapshot. Called by StatsCollector at
+// 1 Hz during builds.
+func (m *MetricsExporter) OnStatsUpdate(info TopInfo) {
+	m.mu.Lock()
+	m.info = info
+	m.mu.Unlock()
+}
+
+// ObservePortBuild records how long a port build took and how it ended:
+// "success", "failed" or "timeout".
+func (m *MetricsExporter) ObservePortBuild(status string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h := m.builds[status]
+	if h == nil {
+		h = newHistogram(portBuildBuckets)
+		m.builds[status] = h
+	}
+	h.observe(d.Seconds())
+}
+
+// ObservePhase records how long a build phase took.
+func (m *MetricsExporter) ObservePhase(phase string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h := m.phases[phase]
+	if h == nil {
+		h = newHistogram(phaseBuckets)
+		m.phases[phase] = h
+	}
+	h.observe(d.Seconds())
+}
+
+// Listen serves the metrics on addr (host:port) at /metrics until Close,
+// and returns the address listened on.
+func (m *MetricsExporter) Listen(addr string) (net.Addr, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("metrics listener: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", m)
+	m.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go m.server.Serve(ln)
+	return ln.Addr(), nil
+}
+
+// Close stops the listener started by Listen, if any.
+func (m *MetricsExporter) Close() error {
+	if m.server == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	return m.server.Shutdown(ctx)
+}
+
+// ServeHTTP writes the metrics exposition.
+func (m *MetricsExporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", MetricsContentType)
+	m.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the OpenMetrics text format to w.
+func (m *MetricsExporter) WriteTo(w io.Writer) (int64, error) {
+	var dbSize float64
+	if info, err := os.Stat(m.dbPath); err == nil {
+		dbSize = float64(info.Size())
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := &exposition{w: bufio.NewWriter(w)}
+	info := m.info
+
+	e.gauge("go_synth_workers_active", "", "Workers currently building", float64(info.ActiveWorkers))
+	e.gauge("go_synth_workers_max", "", "Configured number of workers", float64(info.MaxWorkers))
+	e.gauge("go_synth_workers_dynmax", "", "Workers allowed after load, swap and memory throttling", float64(info.DynMaxWorkers))
+	e.gauge("go_synth_load", "", "Adjusted 1-minute load average", info.Load)
+	e.gauge("go_synth_swap_ratio", "ratio", "Fraction of swap in use", float64(info.SwapPct)/100)
+	e.gauge("go_synth_build_rate", "", "Packages built per hour over the last minute", info.Rate)
+	e.gauge("go_synth_packages_queued", "", "Packages in the build run", float64(info.Queued))
+	e.gauge("go_synth_packages_remaining", "", "Packages not yet built, failed or ignored", float64(info.Remaining))
+	e.gauge("go_synth_build_elapsed_seconds", "seconds", "Time since the build run started", info.Elapsed.Seconds())
+
+	e.header("go_synth_packages", "counter", "", "Packages finished in the build run, by status")
+	for _, c := range []struct {
+		status string
+		n      int
+	}{
+		{"built", info.Built},
+		{"failed", info.Failed},
+		{"skipped", info.Skipped},
+		{"ignored", info.Ignored},
+	} {
+		e.sample("go_synth_packages_total", labels("status", c.status), float64(c.n))
+	}
+
+	e.histogram("go_synth_port_build_duration_seconds", "Duration of port builds, by outcome", "status", m.builds)
+	e.histogram("go_synth_phase_duration_seconds", "Duration of build phases, by phase", "phase", m.phases)
+
+	e.gauge("go_synth_builddb_size_bytes", "bytes", "Size of the build database file", dbSize)
+
+	e.printf("# EOF\n")
+	return e.n, e.flush()
+}
+
+// exposition writes OpenMetrics text, keeping the first error.
+type exposition struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (e *exposition) printf(format string, args ...any) {
+	if e.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(e.w, format, args...)
+	e.n += int64(n)
+	e.err = err
+}
+
+func (e *exposition) flush() error {
+	if e.err != nil {
+		return e.err
+	}
+	return e.w.Flush()
+}
+
+func (e *exposition) header(name, typ, unit, help string) {
+	e.printf("# TYPE %s %s\n", name, typ)
+	if unit != "" {
+		e.printf("# UNIT %s %s\n", name, unit)
+	}
+	e.printf("# HELP %s %s\n", name, help)
+}
+
+func (e *exposition) sample(name, labels string, v float64) {
+	e.printf("%s%s %s\n", name, labels, formatFloat(v))
+}
+
+func (e *exposition) gauge(name, unit, help string, v float64) {
+	e.header(name, "gauge", unit, help)
+	e.sample(name, "", v)
+}
+
+// histogram writes one family with a series per key of hs, labeled label.
+func (e *exposition) histogram(name, help, label string, hs map[string]*histogram) {
+	e.header(name, "histogram", "seconds", help)
+
+	keys := make([]string, 0, len(hs))
+	for k := range hs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		h := hs[k]
+		for i, b := range h.bounds {
+			e.sample(name+"_bucket", labels(label, k, "le", formatFloat(b)), float64(h.counts[i]))
+		}
+		e.sample(name+"_bucket", labels(label, k, "le", "+Inf"), float64(h.count))
+		e.sample(name+"_sum", labels(label, k), h.sum)
+		e.sample(name+"_count", labels(label, k), float64(h.count))
+	}
+}
+
+// labels formats name/value pairs as a label set, e.g. {status="built"}.
+func labels(pairs ...string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(pairs[i])
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(pairs[i+1]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatFloat formats v the way OpenMetrics expects, e.g. 60 as "60.0".
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	s := strconv.FormatFloat(v, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
@@ -0,0 +1,212 @@
+package stats
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scrapeMetrics fetches /metrics from addr and returns the body lines.
+func scrapeMetrics(t *testing.T, addr string) []string {
+	t.Helper()
+
+	resp, err := http.Get("http://" + addr + "/metrics")
+	if err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("scrape status = %d, want 200", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != MetricsContentType {
+		t.Errorf("Content-Type = %q, want %q", ct, MetricsContentType)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(body), "# EOF\n") {
+		t.Errorf("exposition does not end with # EOF")
+	}
+	return strings.Split(strings.TrimSuffix(string(body), "\n"), "\n")
+}
+
+var (
+	metadataLine = regexp.MustCompile(`^# (TYPE|UNIT|HELP) ([a-zA-Z_:][a-zA-Z0-9_:]*) (.+)$`)
+	sampleLine   = regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:]*)(\{[a-zA-Z_][a-zA-Z0-9_]*="(?:[^"\\]|\\.)*"(?:,[a-zA-Z_][a-zA-Z0-9_]*="(?:[^"\\]|\\.)*")*\})? (\S+)$`)
+)
+
+// checkExposition checks the OpenMetrics structure of lines: every sample
+// belongs to the family declared last, with a suffix its type allows, and
+// no family is declared twice. It returns the samples by name and labels.
+func checkExposition(t *testing.T, lines []string) map[string]string {
+	t.Helper()
+
+	suffixes := map[string][]string{
+		"gauge":     {""},
+		"counter":   {"_total", "_created"},
+		"histogram": {"_bucket", "_sum", "_count", "_created"},
+	}
+	samples := make(map[string]string)
+	declared := make(map[string]bool)
+	family, typ := "", ""
+
+	for i, line := range lines {
+		if line == "# EOF" {
+			if i != len(lines)-1 {
+				t.Errorf("line %d: # EOF before the end", i+1)
+			}
+			continue
+		}
+		if m := metadataLine.FindStringSubmatch(line); m != nil {
+			if m[1] == "TYPE" {
+				if declared[m[2]] {
+					t.Errorf("line %d: family %s declared twice", i+1, m[2])
+				}
+				declared[m[2]] = true
+				family, typ = m[2], m[3]
+				if suffixes[typ] == nil {
+					t.Errorf("line %d: unknown type %s", i+1, typ)
+				}
+			} else if m[2] != family {
+				t.Errorf("line %d: %s metadata outside its family", i+1, m[2])
+			}
+			if m[1] == "UNIT" && !strings.HasSuffix(family, "_"+m[3]) {
+				t.Errorf("line %d: family %s does not end in its unit %s", i+1, family, m[3])
+			}
+			continue
+		}
+
+		m := sampleLine.FindStringSubmatch(line)
+		if m == nil {
+			t.Errorf("line %d: malformed: %q", i+1, line)
+			continue
+		}
+		ok := false
+		for _, suffix := range suffixes[typ] {
+			ok = ok || m[1] == family+suffix
+		}
+		if !ok {
+			t.Errorf("line %d: sample %s outside family %s (%s)", i+1, m[1], family, typ)
+		}
+		samples[m[1]+m[2]] = m[3]
+	}
+	return samples
+}
+
+func TestMetricsExporter(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "builds.db")
+	if err := os.WriteFile(dbPath, make([]byte, 32768), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewMetricsExporter(dbPath)
+	addr, err := m.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer m.Close()
+
+	m.OnStatsUpdate(TopInfo{
+		ActiveWorkers: 3,
+		MaxWorkers:    8,
+		DynMaxWorkers: 6,
+		Load:          2.5,
+		SwapPct:       12,
+		Rate:          30,
+		Elapsed:       90 * time.Second,
+		Queued:        20,
+		Built:         5,
+		Failed:        1,
+		Skipped:       2,
+		Ignored:       1,
+		Remaining:     13,
+	})
+	m.ObservePortBuild("success", 45*time.Second)
+	m.ObservePortBuild("success", 20*time.Minute)
+	m.ObservePortBuild("failed", 3*time.Hour)
+	m.ObservePhase("build", 4*time.Second)
+	m.ObservePhase("build", 10*time.Minute)
+	m.ObservePhase(`odd"phase`, time.Second)
+
+	samples := checkExposition(t, scrapeMetrics(t, addr.String()))
+
+	want := map[string]string{
+		"go_synth_workers_active":                   "3.0",
+		"go_synth_workers_max":                      "8.0",
+		"go_synth_workers_dynmax":                   "6.0",
+		"go_synth_load":                             "2.5",
+		"go_synth_swap_ratio":                       "0.12",
+		"go_synth_packages_remaining":               "13.0",
+		"go_synth_build_elapsed_seconds":            "90.0",
+		`go_synth_packages_total{status="built"}`:   "5.0",
+		`go_synth_packages_total{status="failed"}`:  "1.0",
+		`go_synth_packages_total{status="skipped"}`: "2.0",
+		`go_synth_packages_total{status="ignored"}`: "1.0",
+		`go_synth_port_build_duration_seconds_bucket{status="success",le="60.0"}`:   "1.0",
+		`go_synth_port_build_duration_seconds_bucket{status="success",le="1800.0"}`: "2.0",
+		`go_synth_port_build_duration_seconds_bucket{status="success",le="+Inf"}`:   "2.0",
+		`go_synth_port_build_duration_seconds_sum{status="success"}`:                "1245.0",
+		`go_synth_port_build_duration_seconds_count{status="failed"}`:               "1.0",
+		`go_synth_port_build_duration_seconds_bucket{status="failed",le="7200.0"}`:  "0.0",
+		`go_synth_phase_duration_seconds_bucket{phase="build",le="5.0"}`:            "1.0",
+		`go_synth_phase_duration_seconds_bucket{phase="build",le="900.0"}`:          "2.0",
+		`go_synth_phase_duration_seconds_count{phase="build"}`:                      "2.0",
+		`go_synth_phase_duration_seconds_count{phase="odd\"phase"}`:                 "1.0",
+		"go_synth_builddb_size_bytes":                                               "32768.0",
+	}
+	for name, value := range want {
+		if got, ok := samples[name]; !ok {
+			t.Errorf("missing sample %s", name)
+		} else if got != value {
+			t.Errorf("%s = %s, want %s", name, got, value)
+		}
+	}
+}
+
+func TestMetricsExporter_Empty(t *testing.T) {
+	m := NewMetricsExporter(filepath.Join(t.TempDir(), "missing.db"))
+
+	var b strings.Builder
+	if _, err := m.WriteTo(&b); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(b.String()))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	samples := checkExposition(t, lines)
+
+	// Histograms without observations declare the family only
+	for name := range samples {
+		if strings.HasPrefix(name, "go_synth_port_build_duration_seconds") {
+			t.Errorf("unexpected sample %s before any build", name)
+		}
+	}
+	if samples["go_synth_builddb_size_bytes"] != "0.0" {
+		t.Errorf("builddb size of a missing database = %s, want 0.0", samples["go_synth_builddb_size_bytes"])
+	}
+}
+
+func TestFormatFloat(t *testing.T) {
+	tests := map[float64]string{
+		0:    "0.0",
+		60:   "60.0",
+		0.12: "0.12",
+		2.5:  "2.5",
+		1e21: "1e+21",
+	}
+	for v, want := range tests {
+		if got := formatFloat(v); got != want {
+			t.Errorf("formatFloat(%v) = %q, want %q", v, got, want)
+		}
+	}
+}
@@ -45,6 +45,29 @@ type TopInfo struct {
 	Skipped   int // Skipped due to dependencies
 	Meta      int // Metaports (no actual build)
 	Remaining int // Calculated: Queued - (Built + Failed + Ignored)
+
+	// Worker slots, one per configured worker, indexed by worker ID
+	Workers []WorkerSlot
+}
+
+// WorkerSlot describes what one worker is doing. PortDir is empty while
+// the worker is idle.
+type WorkerSlot struct {
+	ID      int       // Worker ID (SL00 is 0)
+	PortDir string    // Port being built
+	Version string    // Version of the port being built
+	Phase   string    // Current build phase
+	Started time.Time // Start of the port build
+	LogPath string    // Build log of the port
+
+	// MemoryHeavy is the memory estimate of a build expected to need more
+	// than its share of Memory_target, 0 for other builds
+	MemoryHeavy int64
+}
+
+// Idle reports whether the worker is building nothing.
+func (w WorkerSlot) Idle() bool {
+	return w.PortDir == ""
 }
 
 // BuildStatus replaces C's DLOG_* bitwise flags with typed enum.
@@ -0,0 +1,298 @@
+// Package web serves the read-only status API and dashboard of
+// `go-synth serve`.
+//
+// Everything the server shows comes from the build database, opened in
+// shared mode, and from the build logs, so it runs alongside a build in
+// another process and never takes the build base lock. The API is:
+//
+//	GET /api/status          Active run, its live stats and what each worker builds
+//	GET /api/runs?limit=N    Build runs, newest first
+//	GET /api/runs/{id}       One run and the packages it built
+//	GET /api/ports/{port}    Build history and CRC of a port, e.g. editors/vim
+//	GET /api/logs/{port}     The tail of a port's build log as text (?lines=N)
+//	GET /api/tail/{port}     The same tail, then new output as Server-Sent Events
+//	GET /                    The dashboard
+//
+// Example usage:
+//
+//	srv := web.New(cfg, db)
+//	http.ListenAndServe(cfg.HTTPListen, srv)
+package web
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-synth/builddb"
+	"go-synth/config"
+	"go-synth/lock"
+	"go-synth/log"
+	"go-synth/stats"
+)
+
+//go:embed dashboard.html
+var assets embed.FS
+
+// Defaults for the limit and lines query parameters
+const (
+	defaultRunLimit   = 20
+	defaultBuildLimit = 20
+	defaultTailLines  = 100
+	maxTailLines      = 5000
+)
+
+// Server is an http.Handler serving the status API and dashboard.
+type Server struct {
+	cfg *config.Config
+	db  *builddb.DB
+	mux *http.ServeMux
+
+	// TailInterval is how often streamed logs are checked for new output.
+	TailInterval time.Duration
+
+	// KeepAlive is how often an idle log stream sends a comment, so
+	// proxies do not close it.
+	KeepAlive time.Duration
+}
+
+// New returns a Server reading the build database db and the logs under
+// cfg.LogsPath.
+func New(cfg *config.Config, db *builddb.DB) *Server {
+	s := &Server{
+		cfg:          cfg,
+		db:           db,
+		mux:          http.NewServeMux(),
+		TailInterval: 500 * time.Millisecond,
+		KeepAlive:    15 * time.Second,
+	}
+
+	s.mux.HandleFunc("GET /{$}", s.handleDashboard)
+	s.mux.HandleFunc("GET /api/status", s.handleStatus)
+	s.mux.HandleFunc("GET /api/runs", s.handleRuns)
+	s.mux.HandleFunc("GET /api/runs/{id}", s.handleRun)
+	s.mux.HandleFunc("GET /api/ports/{port...}", s.handlePort)
+	s.mux.HandleFunc("GET /api/logs/{port...}", s.handleLog)
+	s.mux.HandleFunc("GET /api/tail/{port...}", s.handleTail)
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Status is the response of /api/status. Run and Top are nil when no
+// build is running; Lock is nil when no process holds the build base.
+type Status struct {
+	Run     *builddb.RunEntry `json:"run"`
+	Top     *stats.TopInfo    `json:"top"`
+	Workers []WorkerStatus    `json:"workers"`
+	Lock    *lock.Holder      `json:"lock"`
+}
+
+// WorkerStatus is what one worker of the active run is building.
+type WorkerStatus struct {
+	ID      int       `json:"id"`
+	PortDir string    `json:"portdir"`
+	Version string    `json:"version"`
+	Phase   string    `json:"phase"`
+	Started time.Time `json:"started"`
+	Elapsed float64   `json:"elapsed"` // Seconds
+
+	MemoryHeavy int64 `json:"memory_heavy,omitempty"` // Memory estimate of a memory-heavy build, in bytes
+}
+
+// RunDetail is the response of /api/runs/{id}.
+type RunDetail struct {
+	Run      builddb.RunEntry           `json:"run"`
+	Packages []builddb.RunPackageRecord `json:"packages"`
+}
+
+// PortStatus is the response of /api/ports/{port}.
+type PortStatus struct {
+	PortDir string                `json:"portdir"`
+	CRC     string                `json:"crc,omitempty"` // Hex CRC of the last build, empty if never built
+	Builds  []builddb.BuildRecord `json:"builds"`        // Newest first
+	Log     string                `json:"log"`           // Build log path
+}
+
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	data, err := assets.ReadFile("dashboard.html")
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(data)
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	status := Status{Workers: []WorkerStatus{}}
+
+	runID, rec, err := s.db.ActiveRun()
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if rec != nil {
+		status.Run = &builddb.RunEntry{ID: runID, RunRecord: *rec}
+		if rec.LiveSnapshot != "" {
+			var top stats.TopInfo
+			if err := json.Unmarshal([]byte(rec.LiveSnapshot), &top); err == nil {
+				status.Top = &top
+			}
+		}
+		status.Run.LiveSnapshot = ""
+	}
+
+	// The live snapshot is the only record of what each worker is doing
+	if status.Top != nil {
+		now := time.Now()
+		for _, slot := range status.Top.Workers {
+			if slot.Idle() {
+				continue
+			}
+			status.Workers = append(status.Workers, WorkerStatus{
+				ID:          slot.ID,
+				PortDir:     slot.PortDir,
+				Version:     slot.Version,
+				Phase:       slot.Phase,
+				Started:     slot.Started,
+				Elapsed:     now.Sub(slot.Started).Seconds(),
+				MemoryHeavy: slot.MemoryHeavy,
+			})
+		}
+	}
+
+	if holder, err := lock.Read(s.cfg.BuildBase); err == nil {
+		status.Lock = holder
+	}
+
+	writeJSON(w, status)
+}
+
+func (s *Server) handleRuns(w http.ResponseWriter, r *http.Request) {
+	limit, err := intParam(r, "limit", defaultRunLimit, 0)
+	if err != nil {
+		httpError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	runs, err := s.db.ListRuns(limit)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if runs == nil {
+		runs = []builddb.RunEntry{}
+	}
+	for i := range runs {
+		runs[i].LiveSnapshot = "" // see /api/status
+	}
+	writeJSON(w, runs)
+}
+
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	rec, err := s.db.GetRun(id)
+	if builddb.IsRecordNotFound(err) {
+		httpError(w, http.StatusNotFound, fmt.Errorf("no run %s", id))
+		return
+	}
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	packages, err := s.db.ListRunPackages(id)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if packages == nil {
+		packages = []builddb.RunPackageRecord{}
+	}
+	rec.LiveSnapshot = ""
+	writeJSON(w, RunDetail{Run: builddb.RunEntry{ID: id, RunRecord: *rec}, Packages: packages})
+}
+
+func (s *Server) handlePort(w http.ResponseWriter, r *http.Request) {
+	portDir, ok := portParam(w, r)
+	if !ok {
+		return
+	}
+	limit, err := intParam(r, "limit", defaultBuildLimit, 0)
+	if err != nil {
+		httpError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	builds, err := s.db.BuildsFor(portDir, limit)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if builds == nil {
+		builds = []builddb.BuildRecord{}
+	}
+	status := PortStatus{PortDir: portDir, Builds: builds, Log: log.PackageLogPath(s.cfg, portDir)}
+
+	crc, found, err := s.db.GetCRC(portDir)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if found {
+		status.CRC = fmt.Sprintf("%08x", crc)
+	}
+	writeJSON(w, status)
+}
+
+// portParam returns the port of a /api/{ports,logs,tail}/{port} request.
+// Ports are category/name origins; anything else is refused, since the
+// name becomes part of a log file path.
+func portParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	portDir := r.PathValue("port")
+	category, name, ok := strings.Cut(portDir, "/")
+	if !ok || category == "" || name == "" || strings.Contains(name, "/") ||
+		strings.HasPrefix(category, ".") || strings.HasPrefix(name, ".") {
+		httpError(w, http.StatusBadRequest, fmt.Errorf("expected a category/name port, got %q", portDir))
+		return "", false
+	}
+	return portDir, true
+}
+
+// intParam parses the non-negative integer query parameter name. A limit
+// above zero caps it.
+func intParam(r *http.Request, name string, def, limit int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s: expected a number of 0 or more, got %q", name, value)
+	}
+	if limit > 0 && n > limit {
+		n = limit
+	}
+	return n, nil
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func httpError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,330 @@
+package web
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-synth/builddb"
+	"go-synth/config"
+	"go-synth/log"
+	"go-synth/stats"
+)
+
+// setupServer returns a test server over a fresh build database.
+func setupServer(t *testing.T) (*httptest.Server, *builddb.DB, *config.Config) {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		BuildBase: tmpDir,
+		LogsPath:  filepath.Join(tmpDir, "logs"),
+	}
+	if err := os.MkdirAll(cfg.LogsPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := builddb.OpenDBShared(filepath.Join(tmpDir, "builds.db"))
+	if err != nil {
+		t.Fatalf("OpenDBShared failed: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	srv := New(cfg, db)
+	srv.TailInterval = 10 * time.Millisecond
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	return ts, db, cfg
+}
+
+// getJSON fetches path and decodes the response into v.
+func getJSON(t *testing.T, ts *httptest.Server, path string, v any) int {
+	t.Helper()
+
+	resp, err := http.Get(ts.URL + path)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", path, err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("GET %s: decode: %v", path, err)
+	}
+	return resp.StatusCode
+}
+
+func TestStatus(t *testing.T) {
+	ts, db, _ := setupServer(t)
+
+	var idle Status
+	if code := getJSON(t, ts, "/api/status", &idle); code != http.StatusOK {
+		t.Fatalf("GET /api/status = %d, want 200", code)
+	}
+	if idle.Run != nil || idle.Top != nil || len(idle.Workers) != 0 {
+		t.Errorf("idle status = %+v, want no run", idle)
+	}
+
+	start := time.Now().Add(-time.Minute)
+	if err := db.StartRun("run-1", start); err != nil {
+		t.Fatal(err)
+	}
+	top := stats.TopInfo{ActiveWorkers: 1, MaxWorkers: 2, Built: 3, Queued: 10, Workers: []stats.WorkerSlot{
+		{ID: 0},
+		{ID: 1, PortDir: "editors/vim", Version: "9.1", Phase: "build", Started: start, MemoryHeavy: 12 << 30},
+	}}
+	snapshot, err := json.Marshal(&top)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpdateRunSnapshot("run-1", string(snapshot)); err != nil {
+		t.Fatal(err)
+	}
+
+	var st Status
+	getJSON(t, ts, "/api/status", &st)
+	if st.Run == nil || st.Run.ID != "run-1" {
+		t.Fatalf("status run = %+v, want run-1", st.Run)
+	}
+	if st.Run.LiveSnapshot != "" {
+		t.Errorf("status run carries the raw snapshot")
+	}
+	if st.Top == nil || st.Top.Built != 3 || st.Top.Queued != 10 {
+		t.Errorf("status top = %+v, want Built 3 of 10", st.Top)
+	}
+	if len(st.Workers) != 1 {
+		t.Fatalf("status workers = %+v, want only the busy worker", st.Workers)
+	}
+	w := st.Workers[0]
+	if w.ID != 1 || w.PortDir != "editors/vim" || w.Version != "9.1" || w.Phase != "build" || w.Elapsed < 59 || w.MemoryHeavy != 12<<30 {
+		t.Errorf("worker = %+v, want memory-heavy editors/vim 9.1 in build on worker 1 for a minute", w)
+	}
+}
+
+func TestRuns(t *testing.T) {
+	ts, db, _ := setupServer(t)
+
+	base := time.Now().Add(-time.Hour)
+	for i, id := range []string{"run-1", "run-2"} {
+		if err := db.StartRun(id, base.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.FinishRun(id, builddb.RunStats{Total: 1, Success: 1}, base.Add(time.Duration(i)*time.Minute+time.Second), false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.PutRunPackage("run-1", &builddb.RunPackageRecord{PortDir: "editors/vim", Version: "9.1", Status: builddb.RunStatusSuccess}); err != nil {
+		t.Fatal(err)
+	}
+
+	var runs []builddb.RunEntry
+	getJSON(t, ts, "/api/runs", &runs)
+	if len(runs) != 2 || runs[0].ID != "run-2" {
+		t.Errorf("GET /api/runs = %+v, want run-2, run-1", runs)
+	}
+	getJSON(t, ts, "/api/runs?limit=1", &runs)
+	if len(runs) != 1 {
+		t.Errorf("GET /api/runs?limit=1 returned %d runs", len(runs))
+	}
+
+	var detail RunDetail
+	if code := getJSON(t, ts, "/api/runs/run-1", &detail); code != http.StatusOK {
+		t.Fatalf("GET /api/runs/run-1 = %d, want 200", code)
+	}
+	if detail.Run.ID != "run-1" || len(detail.Packages) != 1 || detail.Packages[0].PortDir != "editors/vim" {
+		t.Errorf("run detail = %+v, want run-1 with editors/vim", detail)
+	}
+
+	var errResp map[string]string
+	if code := getJSON(t, ts, "/api/runs/no-such-run", &errResp); code != http.StatusNotFound || errResp["error"] == "" {
+		t.Errorf("GET unknown run = %d %v, want 404 with an error", code, errResp)
+	}
+	if code := getJSON(t, ts, "/api/runs?limit=x", &errResp); code != http.StatusBadRequest {
+		t.Errorf("GET /api/runs?limit=x = %d, want 400", code)
+	}
+}
+
+func TestPort(t *testing.T) {
+	ts, db, cfg := setupServer(t)
+
+	rec := &builddb.BuildRecord{UUID: "uuid-1", PortDir: "editors/vim", Version: "9.1", Status: "success", StartTime: time.Now()}
+	if err := db.SaveRecord(rec); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpdateCRC("editors/vim", 0xdeadbeef); err != nil {
+		t.Fatal(err)
+	}
+
+	var port PortStatus
+	if code := getJSON(t, ts, "/api/ports/editors/vim", &port); code != http.StatusOK {
+		t.Fatalf("GET /api/ports/editors/vim = %d, want 200", code)
+	}
+	if port.CRC != "deadbeef" || len(port.Builds) != 1 || port.Builds[0].UUID != "uuid-1" {
+		t.Errorf("port status = %+v, want CRC deadbeef and build uuid-1", port)
+	}
+	if port.Log != log.PackageLogPath(cfg, "editors/vim") {
+		t.Errorf("port log = %s, want %s", port.Log, log.PackageLogPath(cfg, "editors/vim"))
+	}
+
+	for _, path := range []string{"/api/ports/vim", "/api/ports/editors/vim/extra", "/api/logs/../etc/passwd", "/api/logs/editors/..x"} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			t.Errorf("GET %s = 200, want it refused", path)
+		}
+	}
+}
+
+func TestLog(t *testing.T) {
+	ts, _, cfg := setupServer(t)
+
+	var lines []string
+	for i := 0; i < 200; i++ {
+		lines = append(lines, "line "+strings.Repeat("x", i%7))
+	}
+	content := strings.Join(lines, "\n") + "\npartial"
+	if err := os.WriteFile(log.PackageLogPath(cfg, "editors/vim"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Get(ts.URL + "/api/logs/editors/vim?lines=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var got []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		got = append(got, scanner.Text())
+	}
+	if strings.Join(got, "|") != strings.Join(lines[197:], "|") {
+		t.Errorf("log tail = %q, want %q", got, lines[197:])
+	}
+
+	resp, err = http.Get(ts.URL + "/api/logs/editors/emacs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET log of unbuilt port = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestTail(t *testing.T) {
+	ts, _, cfg := setupServer(t)
+
+	path := log.PackageLogPath(cfg, "editors/vim")
+	if err := os.WriteFile(path, []byte("one\ntwo\nthr"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL+"/api/tail/editors/vim?lines=1", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /api/tail failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	events := make(chan string)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(resp.Body)
+		event := ""
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				event = strings.TrimPrefix(line, "event: ") + ":"
+			case strings.HasPrefix(line, "data: "):
+				events <- event + strings.TrimPrefix(line, "data: ")
+				event = ""
+			}
+		}
+	}()
+	next := func() string {
+		t.Helper()
+		select {
+		case ev := <-events:
+			return ev
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for an event")
+			return ""
+		}
+	}
+
+	if ev := next(); ev != "two" {
+		t.Errorf("first event = %q, want the last complete line", ev)
+	}
+
+	// The partial line is sent once finished
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("ee\nfour\n")
+	f.Close()
+	if ev := next(); ev != "three" {
+		t.Errorf("event = %q, want three", ev)
+	}
+	if ev := next(); ev != "four" {
+		t.Errorf("event = %q, want four", ev)
+	}
+
+	// A new build replaces the log
+	if err := os.WriteFile(path, []byte("new\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if ev := next(); ev != "reset:editors/vim" {
+		t.Errorf("event = %q, want a reset", ev)
+	}
+	if ev := next(); ev != "new" {
+		t.Errorf("event = %q, want new", ev)
+	}
+
+	// A build with history moves the latest symlink, even to a longer log
+	pl := log.NewPackageLogger(cfg, "editors/vim", "build-2")
+	pl.WriteString("a much longer first line\n")
+	pl.Close()
+	if ev := next(); ev != "reset:editors/vim" {
+		t.Errorf("event = %q, want a reset", ev)
+	}
+	if ev := next(); ev != "a much longer first line" {
+		t.Errorf("event = %q, want the new build's log", ev)
+	}
+}
+
+func TestDashboard(t *testing.T) {
+	ts, _, _ := setupServer(t)
+
+	resp, err := http.Get(ts.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("GET / = %d %s, want the dashboard", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	resp, err = http.Get(ts.URL + "/nothing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /nothing = %d, want 404", resp.StatusCode)
+	}
+}
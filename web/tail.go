@@ -0,0 +1,193 @@
+package web
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"go-synth/log"
+)
+
+// tailWindow is how far back from the end of a log tailLines looks, and
+// the most a log stream reads at once.
+const tailWindow = 1 << 20
+
+func (s *Server) handleLog(w http.ResponseWriter, r *http.Request) {
+	portDir, ok := portParam(w, r)
+	if !ok {
+		return
+	}
+	n, err := intParam(r, "lines", defaultTailLines, maxTailLines)
+	if err != nil {
+		httpError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	lines, _, err := tailLines(log.PackageLogPath(s.cfg, portDir), n)
+	if errors.Is(err, os.ErrNotExist) {
+		httpError(w, http.StatusNotFound, fmt.Errorf("no build log for %s", portDir))
+		return
+	}
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, line := range lines {
+		io.WriteString(w, line+"\n")
+	}
+}
+
+// handleTail streams a port's build log as Server-Sent Events: the last
+// lines, then each line as it is written, one "data:" event per line. When
+// the log is replaced by a new build of the port, a "reset" event is sent
+// and the new log is streamed from its start. A log that does not exist
+// yet is waited for. The stream ends when the client goes away.
+func (s *Server) handleTail(w http.ResponseWriter, r *http.Request) {
+	portDir, ok := portParam(w, r)
+	if !ok {
+		return
+	}
+	n, err := intParam(r, "lines", defaultTailLines, maxTailLines)
+	if err != nil {
+		httpError(w, http.StatusBadRequest, err)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		httpError(w, http.StatusInternalServerError, fmt.Errorf("streaming not supported"))
+		return
+	}
+
+	path := log.PackageLogPath(s.cfg, portDir)
+	lines, offset, err := tailLines(path, n)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // nginx
+	w.WriteHeader(http.StatusOK)
+	for _, line := range lines {
+		writeEvent(w, "", line)
+	}
+	flusher.Flush()
+
+	// The path is a symlink to the latest build's log; a new build moves it
+	current, _ := os.Stat(path)
+
+	poll := time.NewTicker(s.TailInterval)
+	defer poll.Stop()
+	keepAlive := time.NewTicker(s.KeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			io.WriteString(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case <-poll.C:
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			if info.Size() < offset || (current != nil && !os.SameFile(current, info)) {
+				// A new build started a new log
+				writeEvent(w, "reset", portDir)
+				offset = 0
+			}
+			current = info
+			if info.Size() == offset {
+				continue
+			}
+			lines, next, err := readLines(path, offset, min(info.Size(), offset+tailWindow))
+			if err != nil {
+				continue
+			}
+			offset = next
+			for _, line := range lines {
+				writeEvent(w, "", line)
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent writes one Server-Sent Event. An empty event is a message.
+func writeEvent(w io.Writer, event, data string) {
+	if event != "" {
+		fmt.Fprintf(w, "event: %s\n", event)
+	}
+	fmt.Fprintf(w, "data: %s\n\n", data)
+}
+
+// tailLines returns the last n complete lines of the file at path, and the
+// offset just past them, where following the file continues.
+func tailLines(path string, n int) ([]string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	start := max(info.Size()-tailWindow, 0)
+	lines, next, err := readLinesFrom(f, start, info.Size())
+	if err != nil {
+		return nil, 0, err
+	}
+	if start > 0 && len(lines) > 0 {
+		lines = lines[1:] // Cut by the window
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, next, nil
+}
+
+// readLines returns the complete lines of the file at path between the
+// offsets from and to, and the offset just past the last of them. A line
+// still being written is left for the next read.
+func readLines(path string, from, to int64) ([]string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, from, err
+	}
+	defer f.Close()
+	return readLinesFrom(f, from, to)
+}
+
+func readLinesFrom(f *os.File, from, to int64) ([]string, int64, error) {
+	data := make([]byte, to-from)
+	n, err := f.ReadAt(data, from)
+	if err != nil && err != io.EOF {
+		return nil, from, err
+	}
+	data = data[:n]
+	end := bytes.LastIndexByte(data, '\n')
+	if end < 0 {
+		if len(data) < tailWindow {
+			return nil, from, nil
+		}
+		end = len(data) // A line longer than the window; pass it on in parts
+	}
+
+	lines := strings.Split(string(data[:end]), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines, min(from+int64(end)+1, to), nil
+}
@@ -9,9 +9,12 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"os/signal"
 	"syscall"
 	"time"
 
+	"go-synth/config"
+	"go-synth/environment"
 	"go-synth/environment/bsd"
 )
 
@@ -22,6 +25,7 @@ type workerHelperArgs struct {
 	command    string
 	args       []string
 	timeout    time.Duration
+	limits     config.Limits
 }
 
 // parseWorkerHelperArgs parses command-line arguments for worker helper mode.
@@ -30,6 +34,9 @@ type workerHelperArgs struct {
 //
 //	go-synth --worker-helper --chroot=/path --workdir=/dir --timeout=5m -- /usr/bin/make arg1 arg2
 //
+// The optional --nice, --cpu-time, --file-size (bytes), --open-files and
+// --address-space (bytes) flags set the limits applied to the command.
+//
 // Everything after -- is the actual command to execute.
 func parseWorkerHelperArgs() (*workerHelperArgs, error) {
 	fs := flag.NewFlagSet("worker-helper", flag.ExitOnError)
@@ -38,6 +45,13 @@ func parseWorkerHelperArgs() (*workerHelperArgs, error) {
 	workDir := fs.String("workdir", "", "Working directory inside chroot")
 	timeout := fs.Duration("timeout", 0, "Command timeout (0 = no timeout)")
 
+	var limits config.Limits
+	fs.IntVar(&limits.Nice, "nice", 0, "Nice value for the command")
+	fs.DurationVar(&limits.CPUTime, "cpu-time", 0, "CPU time limit")
+	fs.Int64Var(&limits.FileSize, "file-size", 0, "File size limit in bytes")
+	fs.IntVar(&limits.OpenFiles, "open-files", 0, "Open files limit")
+	fs.Int64Var(&limits.AddressSpace, "address-space", 0, "Address space limit in bytes")
+
 	// Parse flags up to --
 	args := os.Args[1:] // Skip program name
 
@@ -79,6 +93,7 @@ func parseWorkerHelperArgs() (*workerHelperArgs, error) {
 		command:    commandArgs[0],
 		args:       commandArgs[1:],
 		timeout:    *timeout,
+		limits:     limits,
 	}, nil
 }
 
@@ -88,9 +103,14 @@ func parseWorkerHelperArgs() (*workerHelperArgs, error) {
 //  1. Parse arguments
 //  2. Acquire reaper status (PROC_REAP_ACQUIRE)
 //  3. Enter chroot
-//  4. Execute the phase command
-//  5. On exit, kill all descendants (PROC_REAP_KILL)
-//  6. Return with same exit code as phase command
+//  4. Apply nice value and resource limits
+//  5. Execute the phase command
+//  6. On exit, kill all descendants (PROC_REAP_KILL)
+//  7. Return with same exit code as phase command
+//
+// SIGTERM or SIGINT, which Execute sends when the phase is cancelled or
+// times out, stops the command and kills all descendants before the
+// helper exits.
 func runWorkerHelper() int {
 	args, err := parseWorkerHelperArgs()
 	if err != nil {
@@ -130,7 +150,13 @@ func runWorkerHelper() int {
 		return 1
 	}
 
-	// Step 3: Execute the phase command
+	// Step 3: Apply limits; the command and its children inherit them
+	if err := environment.ApplyLimits(args.limits); err != nil {
+		fmt.Fprintf(os.Stderr, "worker-helper: %v\n", err)
+		return 1
+	}
+
+	// Step 4: Execute the phase command
 	ctx := context.Background()
 	if args.timeout > 0 {
 		var cancel context.CancelFunc
@@ -138,7 +164,10 @@ func runWorkerHelper() int {
 		defer cancel()
 	}
 
-	cmd := exec.CommandContext(ctx, args.command, args.args...)
+	sigCtx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	cmd := exec.CommandContext(sigCtx, args.command, args.args...)
 	// Use /dev/null for stdin (opened before chroot)
 	cmd.Stdin = devNull
 	cmd.Stdout = os.Stdout
@@ -146,13 +175,22 @@ func runWorkerHelper() int {
 	cmd.SysProcAttr = &syscall.SysProcAttr{
 		Setpgid: true, // Create new process group for signal isolation
 	}
+	// Kill the command's whole process group; ReapAll gets the rest
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
 
 	if err := cmd.Run(); err != nil {
-		// Step 4: Kill all descendants before returning error
+		// Step 5: Kill all descendants before returning error
 		if killErr := bsd.ReapAll(); killErr != nil {
 			fmt.Fprintf(os.Stderr, "worker-helper: warning: failed to kill descendants: %v\n", killErr)
 		}
 
+		if ctx.Err() == nil && sigCtx.Err() != nil {
+			fmt.Fprintf(os.Stderr, "worker-helper: stopped, killed %s and its descendants\n", args.command)
+			return 1
+		}
+
 		// Return the command's exit code
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			return exitErr.ExitCode()
@@ -163,7 +201,7 @@ func runWorkerHelper() int {
 		return 1
 	}
 
-	// Step 5: Success - still kill descendants before exit (in case any background processes)
+	// Step 6: Success - still kill descendants before exit (in case any background processes)
 	if killErr := bsd.ReapAll(); killErr != nil {
 		fmt.Fprintf(os.Stderr, "worker-helper: warning: failed to kill descendants: %v\n", killErr)
 	}
@@ -0,0 +1,106 @@
+//go:build dragonfly && integration
+// +build dragonfly,integration
+
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"go-synth/config"
+	"go-synth/environment"
+	"go-synth/environment/bsd"
+	"go-synth/log"
+)
+
+// TestMain lets the test binary act as the worker helper, which Execute
+// starts as os.Executable().
+func TestMain(m *testing.M) {
+	for _, arg := range os.Args[1:] {
+		if arg == "--worker-helper" {
+			os.Exit(runWorkerHelper())
+		}
+	}
+	os.Exit(m.Run())
+}
+
+// TestWorkerHelper_TimeoutKillsDescendants verifies that a command that
+// times out leaves no processes behind, including ones that left the
+// command's process group.
+//
+// Run with:
+//
+//	doas go test -tags=integration -run TestWorkerHelper_TimeoutKillsDescendants .
+func TestWorkerHelper_TimeoutKillsDescendants(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("This test requires root privileges. Run with: doas go test -tags=integration")
+	}
+
+	tmpRoot := t.TempDir()
+	cfg := &config.Config{
+		BuildBase:     filepath.Join(tmpRoot, "build"),
+		SystemPath:    "/",
+		DistFilesPath: filepath.Join(tmpRoot, "distfiles"),
+		PackagesPath:  filepath.Join(tmpRoot, "packages"),
+		DPortsPath:    filepath.Join(tmpRoot, "dports"),
+		OptionsPath:   filepath.Join(tmpRoot, "options"),
+	}
+	for _, dir := range []string{cfg.DistFilesPath, cfg.PackagesPath, cfg.DPortsPath, cfg.OptionsPath, filepath.Join(cfg.BuildBase, "Template", "etc")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	env := bsd.NewBSDEnvironment()
+	defer env.Cleanup()
+	if err := env.Setup(98, cfg, log.NoOpLogger{}); err != nil {
+		t.Fatalf("Setup() failed: %v", err)
+	}
+	baseDir := env.(*bsd.BSDEnvironment).GetBasePath()
+
+	// With job control on, the background sleep gets its own process group,
+	// so killing the command's group alone would miss it
+	script := "#!/bin/sh\nset -m\nsleep 9999 &\necho $! > /tmp/child.pid\nsleep 9999\n"
+	if err := os.WriteFile(filepath.Join(baseDir, "tmp", "spawn.sh"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	_, err := env.Execute(context.Background(), &environment.ExecCommand{
+		Command: "/bin/sh",
+		Args:    []string{"/tmp/spawn.sh"},
+		Timeout: 2 * time.Second,
+	})
+	var execErr *environment.ErrExecutionFailed
+	if !errors.As(err, &execErr) || execErr.Op != "timeout" {
+		t.Fatalf("Execute() = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Execute() took %v; the helper did not exit on SIGTERM", elapsed)
+	}
+
+	data, err := os.ReadFile(filepath.Join(baseDir, "tmp", "child.pid"))
+	if err != nil {
+		t.Fatalf("background child did not start: %v", err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The killed child is reaped by init shortly after the helper exits
+	deadline := time.Now().Add(2 * time.Second)
+	for syscall.Kill(pid, 0) == nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("background child %d survived the timeout", pid)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}